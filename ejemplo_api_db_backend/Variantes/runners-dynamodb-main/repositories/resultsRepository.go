@@ -1,8 +1,10 @@
 package repositories
 
 import (
+	"context"
 	"net/http"
 	"runners-dynamodb/models"
+	"runners-dynamodb/stats"
 	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -219,3 +221,38 @@ func (rr ResultsRepository) GetTenBestResultsFromYear(year int) ([]*models.Resul
 
 	return results, nil
 }
+
+// GetResultsAggregates devuelve los agregados de los resultados del runner por distancia y año. DynamoDB no puede agregar en la consulta, así que leemos los resultados del runner y los agregamos en Go igual que la consulta de Postgres
+func (rr ResultsRepository) GetResultsAggregates(ctx context.Context, runnerId string) ([]*models.ResultsAggregate, *models.ResponseError) {
+	results, responseErr := rr.GetAllRunnersResults(runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	aggregates, err := stats.Aggregate(results)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return aggregates, nil
+}
+
+// GetRunnerSplits devuelve los tiempos de paso de los resultados del runner, agrupados por resultado. Se guardan como un atributo más del elemento del resultado
+func (rr ResultsRepository) GetRunnerSplits(ctx context.Context, runnerId string) (map[string][]*models.Split, *models.ResponseError) {
+	results, responseErr := rr.GetAllRunnersResults(runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	splits := make(map[string][]*models.Split)
+	for _, result := range results {
+		if len(result.Splits) > 0 {
+			splits[result.ID] = result.Splits
+		}
+	}
+
+	return splits, nil
+}
@@ -1,8 +1,10 @@
 package repositories
 
 import (
+	"context"
 	"net/http"
 	"runners-dynamodb/models"
+	"runners-dynamodb/search"
 	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -254,3 +256,15 @@ func (rr RunnersRepository) GetRunnersByIdMap(runnerIds map[string]struct{}) (ma
 
 	return runnersMap, nil
 }
+
+// SearchRunners busca runners activos por nombre con search.Rank
+func (rr RunnersRepository) SearchRunners(ctx context.Context, query string, limit int, offset int) ([]*models.RunnerMatch, int, *models.ResponseError) {
+	runners, responseErr := rr.GetAllRunners()
+	if responseErr != nil {
+		return nil, 0, responseErr
+	}
+
+	matches, total := search.Rank(query, runners, limit, offset)
+
+	return matches, total, nil
+}
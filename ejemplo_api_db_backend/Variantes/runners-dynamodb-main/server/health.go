@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"runners-dynamodb/health"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// tablas que crean los scripts de dbscripts
+var dynamoTables = []string{"Runners", "Results"}
+
+// InitHealth registra las comprobaciones de salud con DynamoDB. El equivalente de las migraciones es que las tablas existan y estén activas
+func InitHealth(timeout time.Duration, db *dynamodb.DynamoDB) *health.Registry {
+	registry := health.NewRegistry(timeout)
+
+	registry.Register("database", true, func(ctx context.Context) error {
+		for _, table := range dynamoTables {
+			output, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+				TableName: aws.String(table),
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", table, err)
+			}
+
+			status := aws.StringValue(output.Table.TableStatus)
+			if status != dynamodb.TableStatusActive {
+				return fmt.Errorf("table %s is %s", table, status)
+			}
+		}
+
+		return nil
+	})
+
+	return registry
+}
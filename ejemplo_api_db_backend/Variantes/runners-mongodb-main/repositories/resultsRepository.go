@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"runners-mongodb/models"
+	"runners-mongodb/stats"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -61,6 +62,7 @@ func (rr ResultsRepository) CreateResult(result *models.Result, currentYear int)
 		Location:   result.Location,
 		Position:   result.Position,
 		Year:       result.Year,
+		Splits:     result.Splits,
 	}, nil
 }
 
@@ -133,3 +135,38 @@ func (rr ResultsRepository) GetRunnerByResultId(resultId string) (*models.Runner
 
 	return runner, nil
 }
+
+// GetResultsAggregates devuelve los agregados de los resultados del runner por distancia y año. Los resultados están dentro del documento del runner, así que los leemos y agregamos en Go igual que la consulta de Postgres
+func (rr ResultsRepository) GetResultsAggregates(ctx context.Context, runnerId string) ([]*models.ResultsAggregate, *models.ResponseError) {
+	runner, responseErr := RunnersRepository{client: rr.client}.GetRunner(runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	aggregates, err := stats.Aggregate(runner.Results)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return aggregates, nil
+}
+
+// GetRunnerSplits devuelve los tiempos de paso de los resultados del runner, agrupados por resultado. Se guardan dentro de cada resultado del documento del runner
+func (rr ResultsRepository) GetRunnerSplits(ctx context.Context, runnerId string) (map[string][]*models.Split, *models.ResponseError) {
+	runner, responseErr := RunnersRepository{client: rr.client}.GetRunner(runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	splits := make(map[string][]*models.Split)
+	for _, result := range runner.Results {
+		if len(result.Splits) > 0 {
+			splits[result.ID] = result.Splits
+		}
+	}
+
+	return splits, nil
+}
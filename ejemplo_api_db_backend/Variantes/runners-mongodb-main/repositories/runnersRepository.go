@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"runners-mongodb/models"
+	"runners-mongodb/search"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -264,3 +265,15 @@ func (rr RunnersRepository) GetRunnersByYear(year int) ([]*models.Runner, *model
 
 	return runners, nil
 }
+
+// SearchRunners busca runners activos por nombre con search.Rank
+func (rr RunnersRepository) SearchRunners(ctx context.Context, query string, limit int, offset int) ([]*models.RunnerMatch, int, *models.ResponseError) {
+	runners, responseErr := rr.GetAllRunners()
+	if responseErr != nil {
+		return nil, 0, responseErr
+	}
+
+	matches, total := search.Rank(query, runners, limit, offset)
+
+	return matches, total, nil
+}
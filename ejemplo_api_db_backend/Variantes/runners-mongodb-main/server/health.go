@@ -0,0 +1,21 @@
+package server
+
+import (
+	"context"
+	"runners-mongodb/health"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// InitHealth registra las comprobaciones de salud con MongoDB. No hay migraciones: las colecciones se crean al insertar
+func InitHealth(timeout time.Duration, client *mongo.Client) *health.Registry {
+	registry := health.NewRegistry(timeout)
+
+	registry.Register("database", true, func(ctx context.Context) error {
+		return client.Ping(ctx, readpref.Primary())
+	})
+
+	return registry
+}
@@ -1,10 +1,12 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"runners-mysql/models"
+	"runners-mysql/search"
 	"strconv"
 )
 
@@ -355,3 +357,15 @@ func (rr RunnersRepository) GetRunnersByYear(year int) ([]*models.Runner, *model
 
 	return runners, nil
 }
+
+// SearchRunners busca runners activos por nombre con search.Rank
+func (rr RunnersRepository) SearchRunners(ctx context.Context, query string, limit int, offset int) ([]*models.RunnerMatch, int, *models.ResponseError) {
+	runners, responseErr := rr.GetAllRunners()
+	if responseErr != nil {
+		return nil, 0, responseErr
+	}
+
+	matches, total := search.Rank(query, runners, limit, offset)
+
+	return matches, total, nil
+}
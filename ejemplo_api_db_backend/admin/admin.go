@@ -0,0 +1,106 @@
+// Package admin tiene las plantillas y los archivos estáticos de la consola de administración, incluidos en el binario, y la sesión y la protección CSRF de la consola
+package admin
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prefix es la ruta de la consola
+const Prefix = "/admin"
+
+//go:embed templates
+var templateFiles embed.FS
+
+//go:embed static
+var staticFiles embed.FS
+
+// Page son los datos de una página de la consola. Data tiene los de la propia página
+type Page struct {
+	Title     string
+	Principal *models.Principal // usuario de la sesión, nil en el login
+	CSRFToken string
+	Message   string // resultado de la última acción, por ejemplo "Runner guardado"
+	Error     string
+	Data      interface{}
+}
+
+// Console renderiza las páginas de la consola. Cada página es una plantilla con el layout común
+type Console struct {
+	pages map[string]*template.Template
+}
+
+// New carga las plantillas de la consola
+func New() (*Console, error) {
+	names, err := fs.Glob(templateFiles, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	functions := template.FuncMap{
+		"add": func(a int, b int) int { return a + b },
+	}
+
+	pages := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		page := strings.TrimSuffix(strings.TrimPrefix(name, "templates/"), ".html")
+		if page == "layout" {
+			continue
+		}
+
+		tmpl, err := template.New("layout.html").Funcs(functions).ParseFS(templateFiles, "templates/layout.html", name)
+		if err != nil {
+			return nil, err
+		}
+		pages[page] = tmpl
+	}
+
+	return &Console{pages: pages}, nil
+}
+
+// Render responde con la página. La página se renderiza antes de escribir la respuesta, para que un error de la plantilla no deje la respuesta a medias
+func (c *Console) Render(ctx *gin.Context, status int, name string, page *Page) {
+	page.CSRFToken = CSRFToken(ctx)
+
+	var body bytes.Buffer
+	err := c.pages[name].Execute(&body, page)
+	if err != nil {
+		logging.Error(ctx.Request.Context(), "Error while rendering admin page", "page", name, "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Data(status, "text/html; charset=utf-8", body.Bytes())
+}
+
+// RenderError responde con la página de error
+func (c *Console) RenderError(ctx *gin.Context, principal *models.Principal, responseErr *models.ResponseError) {
+	if responseErr.Status >= http.StatusInternalServerError {
+		logging.Error(ctx.Request.Context(), "Admin request failed", "status", responseErr.Status, "error", responseErr.Message)
+	}
+
+	c.Render(ctx, responseErr.Status, "error", &Page{
+		Title:     http.StatusText(responseErr.Status),
+		Principal: principal,
+		Error:     responseErr.Message,
+	})
+	ctx.Abort()
+}
+
+// StaticHandler sirve los archivos estáticos en Prefix + "/static/*file"
+func StaticHandler() gin.HandlerFunc {
+	static, _ := fs.Sub(staticFiles, "static")
+	files := http.StripPrefix(Prefix+"/static", http.FileServer(http.FS(static)))
+
+	return func(ctx *gin.Context) {
+		files.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+}
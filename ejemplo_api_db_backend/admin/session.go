@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// cookie con el token de acceso del usuario, que es la sesión de la consola
+	sessionCookie = "admin_session"
+	// cookie con el token CSRF del navegador
+	csrfCookie = "admin_csrf"
+	// CSRFField es el campo oculto de los formularios con el token CSRF
+	CSRFField = "csrf_token"
+	// clave del token CSRF en el contexto de Gin
+	csrfKey = "admin.csrf"
+)
+
+// Session devuelve el token de acceso de la sesión, o una cadena vacía si no hay sesión
+func Session(ctx *gin.Context) string {
+	accessToken, _ := ctx.Cookie(sessionCookie)
+	return accessToken
+}
+
+// SetSession abre la sesión con el token de acceso del usuario. La cookie no es accesible desde JavaScript y el navegador solo la envía en las peticiones a la consola que se hacen desde la propia consola
+func SetSession(ctx *gin.Context, accessToken string) {
+	setCookie(ctx, sessionCookie, accessToken, 0)
+}
+
+func ClearSession(ctx *gin.Context) {
+	setCookie(ctx, sessionCookie, "", -1)
+}
+
+// CSRFToken devuelve el token CSRF de la petición, que los formularios envían en el campo CSRFField
+func CSRFToken(ctx *gin.Context) string {
+	return ctx.GetString(csrfKey)
+}
+
+// CSRF protege los formularios de la consola con el patrón double submit: cada navegador tiene un token aleatorio en una cookie, y los formularios lo envían también en un campo oculto. Otra web puede hacer que el navegador envíe la cookie, pero no puede leerla para ponerla en el formulario. Además se rechazan las peticiones con la cabecera Origin de otro sitio
+func CSRF() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token, err := ctx.Cookie(csrfCookie)
+		if err != nil || token == "" {
+			token = randomToken()
+			setCookie(ctx, csrfCookie, token, 0)
+		}
+		ctx.Set(csrfKey, token)
+
+		if ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead {
+			ctx.Next()
+			return
+		}
+
+		if origin := ctx.GetHeader("Origin"); origin != "" && !sameOrigin(origin, ctx.Request.Host) {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(ctx.PostForm(CSRFField)), []byte(token)) != 1 {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// las cookies de la consola solo se envían a la consola, y en HTTPS solo por HTTPS
+func setCookie(ctx *gin.Context, name string, value string, maxAge int) {
+	ctx.SetSameSite(http.SameSiteStrictMode)
+	ctx.SetCookie(name, value, maxAge, Prefix, "", ctx.Request.TLS != nil, true)
+}
+
+func sameOrigin(origin string, host string) bool {
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == host
+}
+
+func randomToken() string {
+	value := make([]byte, 32)
+	rand.Read(value)
+	return base64.RawURLEncoding.EncodeToString(value)
+}
@@ -0,0 +1,116 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/versioning"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Esquema de la cabecera Authorization con la que se envían las claves: "Authorization: ApiKey rk_1a2b3c4d..."
+const Scheme = "ApiKey"
+
+// las claves empiezan por rk_ y el prefijo que se guarda en claro son los 8 caracteres siguientes
+const (
+	keyPrefix    = "rk_"
+	prefixLength = len(keyPrefix) + 8
+	secretBytes  = 32
+)
+
+// recurso de los permisos según el primer segmento de la ruta sin versión. Las rutas que no están no se pueden usar con claves de API
+var resources = map[string]string{
+	"runner":  "runners",
+	"result":  "results",
+	"live":    "results",
+	"club":    "clubs",
+	"export":  "export",
+	"webhook": "webhooks",
+	"health":  "health",
+}
+
+type credentialsKey struct{}
+
+// Credentials es la clave de API de la petición y el permiso que necesita la ruta
+type Credentials struct {
+	Key   string
+	Scope string
+}
+
+// WithCredentials devuelve un contexto con la clave de API de la petición
+func WithCredentials(ctx context.Context, credentials *Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, credentials)
+}
+
+// FromContext devuelve la clave de API de la petición, o nil si la petición no trae una
+func FromContext(ctx context.Context) *Credentials {
+	credentials, _ := ctx.Value(credentialsKey{}).(*Credentials)
+	return credentials
+}
+
+// Generate crea una clave nueva. Devuelve la clave, que solo se enseña al crearla, y su prefijo
+func Generate() (string, string, error) {
+	secret := make([]byte, secretBytes)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	key := keyPrefix + hex.EncodeToString(secret)
+	return key, key[:prefixLength], nil
+}
+
+// Hash es el hash con el que se guarda la clave. Las claves son aleatorias y largas, así que basta con SHA-256 y se pueden buscar por su hash
+func Hash(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// FromHeader devuelve la clave de la cabecera Authorization, o "" si la cabecera no usa el esquema ApiKey
+func FromHeader(authorization string) string {
+	scheme, key, found := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !found || !strings.EqualFold(scheme, Scheme) {
+		return ""
+	}
+
+	return strings.TrimSpace(key)
+}
+
+// RequiredScope devuelve el permiso que necesita una clave para la ruta de Gin: runners:read para GET /v1/runner/:id, results:write para POST /v1/result.
+// Devuelve "" si la ruta no se puede usar con claves de API. GraphQL necesita runners:read, y sus mutaciones además runners:write
+func RequiredScope(method string, route string) string {
+	route = versioning.Unversioned(route)
+	if route == "/graphql" {
+		return models.ScopeRunnersRead
+	}
+
+	segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+	resource, found := resources[segment]
+	if !found {
+		return ""
+	}
+
+	if method == http.MethodGet || method == http.MethodHead {
+		return resource + ":read"
+	}
+
+	return resource + ":write"
+}
+
+// Middleware guarda en el contexto la clave de API de la cabecera Authorization con el permiso que necesita la ruta. La clave se comprueba al autenticar la petición, como los tokens
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := FromHeader(ctx.GetHeader("Authorization"))
+		if key != "" {
+			credentials := &Credentials{Key: key, Scope: RequiredScope(ctx.Request.Method, ctx.FullPath())}
+			ctx.Request = ctx.Request.WithContext(WithCredentials(ctx.Request.Context(), credentials))
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package apikeys
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, err := Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Len(t, prefix, len("rk_")+8)
+	assert.Len(t, Hash(key), 64)
+	assert.NotEqual(t, key, Hash(key))
+
+	other, _, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestFromHeader(t *testing.T) {
+	assert.Equal(t, "rk_1234", FromHeader("ApiKey rk_1234"))
+	assert.Equal(t, "rk_1234", FromHeader("apikey  rk_1234 "))
+	assert.Empty(t, FromHeader("Bearer rk_1234"))
+	assert.Empty(t, FromHeader("ApiKey"))
+	assert.Empty(t, FromHeader(""))
+}
+
+func TestRequiredScope(t *testing.T) {
+	assert.Equal(t, "results:write", RequiredScope(http.MethodPost, "/v1/result"))
+	assert.Equal(t, "results:write", RequiredScope(http.MethodPost, "/v2/result/batch"))
+	assert.Equal(t, "runners:read", RequiredScope(http.MethodGet, "/v1/runner/:id"))
+	assert.Equal(t, "runners:write", RequiredScope(http.MethodPut, "/v2/runner/:id"))
+	assert.Equal(t, "results:read", RequiredScope(http.MethodGet, "/v1/live/results"))
+	assert.Equal(t, "health:read", RequiredScope(http.MethodGet, "/health"))
+	assert.Equal(t, "runners:read", RequiredScope(http.MethodPost, "/graphql"))
+
+	// las claves no sirven para gestionar claves, ni para el autoservicio de los runners
+	assert.Empty(t, RequiredScope(http.MethodPost, "/v1/apikey"))
+	assert.Empty(t, RequiredScope(http.MethodGet, "/v1/me/runner"))
+	assert.Empty(t, RequiredScope(http.MethodGet, ""))
+}
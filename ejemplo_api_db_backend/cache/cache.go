@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/metrics"
+	"runners-postgresql/models"
+	"time"
+)
+
+// Store es el almacenamiento de la caché. Hay una implementación en memoria (LRU) y otra que habla el protocolo de Redis
+type Store interface {
+	// devuelve el valor y si estaba en la caché
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// ReadThrough es una caché de lectura: si el valor no está en el store se carga con la función indicada y se guarda. Las cargas concurrentes de una misma clave se agrupan en una sola
+type ReadThrough struct {
+	name  string
+	store Store
+	ttl   time.Duration
+	group group
+}
+
+// New crea una caché de lectura. El nombre se usa como prefijo de las claves y como etiqueta de las métricas. Si store es nil no se cachea nada, pero se siguen agrupando las cargas concurrentes
+func New(name string, store Store, ttl time.Duration) *ReadThrough {
+	return &ReadThrough{
+		name:  name,
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Get deja en dest el valor de la clave. Si no está en la caché lo obtiene con load. Los valores se guardan serializados en JSON, de modo que cada llamada obtiene su propia copia
+func (rt *ReadThrough) Get(ctx context.Context, key string, dest interface{}, load func() (interface{}, *models.ResponseError)) *models.ResponseError {
+	// una caché nil no cachea
+	if rt == nil {
+		value, responseErr := load()
+		if responseErr != nil {
+			return responseErr
+		}
+		return copyValue(value, dest)
+	}
+
+	key = rt.name + ":" + key
+
+	if rt.store != nil {
+		data, found, err := rt.store.Get(ctx, key)
+		if err != nil {
+			// un fallo de la caché no debe hacer fallar la petición
+			logging.Error(ctx, "Error while reading cache", "cache", rt.name, "error", err)
+		}
+
+		if found {
+			metrics.CacheRequestsCounter.WithLabelValues(rt.name, "hit").Inc()
+			err = json.Unmarshal(data, dest)
+			if err == nil {
+				return nil
+			}
+			logging.Error(ctx, "Error while decoding cached value", "cache", rt.name, "error", err)
+		}
+	}
+
+	metrics.CacheRequestsCounter.WithLabelValues(rt.name, "miss").Inc()
+
+	// si hay varias peticiones concurrentes con la misma clave solo una consulta la base de datos
+	data, responseErr := rt.group.do(key, func() ([]byte, *models.ResponseError) {
+		value, responseErr := load()
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		if rt.store != nil {
+			err = rt.store.Set(ctx, key, data, rt.ttl)
+			if err != nil {
+				logging.Error(ctx, "Error while writing cache", "cache", rt.name, "error", err)
+			}
+		}
+
+		return data, nil
+	})
+	if responseErr != nil {
+		return responseErr
+	}
+
+	err := json.Unmarshal(data, dest)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// Invalidate borra las claves de la caché. Se llama después de confirmar los cambios en la base de datos
+func (rt *ReadThrough) Invalidate(ctx context.Context, keys ...string) {
+	if rt == nil || rt.store == nil || len(keys) == 0 {
+		return
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = rt.name + ":" + key
+	}
+
+	err := rt.store.Delete(ctx, prefixed...)
+	if err != nil {
+		logging.Error(ctx, "Error while invalidating cache", "cache", rt.name, "error", err)
+		return
+	}
+
+	metrics.CacheInvalidationsCounter.WithLabelValues(rt.name).Add(float64(len(keys)))
+}
+
+func copyValue(value interface{}, dest interface{}) *models.ResponseError {
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, dest)
+	}
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
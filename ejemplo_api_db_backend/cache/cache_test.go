@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/cache/redistest"
+	"runners-postgresql/models"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU(2)
+
+	lru.Set(ctx, "a", []byte("1"), 0)
+	lru.Set(ctx, "b", []byte("2"), 0)
+	// al leer "a" pasa a ser la más reciente, así que al llenarse se descarta "b"
+	lru.Get(ctx, "a")
+	lru.Set(ctx, "c", []byte("3"), 0)
+
+	_, found, _ := lru.Get(ctx, "b")
+	assert.False(t, found)
+	value, found, _ := lru.Get(ctx, "a")
+	assert.True(t, found)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, lru.Len())
+}
+
+func TestLRUExpiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lru := NewLRU(10)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(ctx, "a", []byte("1"), time.Minute)
+	_, found, _ := lru.Get(ctx, "a")
+	assert.True(t, found)
+
+	now = now.Add(time.Minute)
+	_, found, _ = lru.Get(ctx, "a")
+	assert.False(t, found)
+	assert.Equal(t, 0, lru.Len())
+}
+
+func TestReadThrough(t *testing.T) {
+	ctx := context.Background()
+	readThrough := New("runners", NewLRU(10), time.Minute)
+
+	var loads int32
+	load := func() (interface{}, *models.ResponseError) {
+		atomic.AddInt32(&loads, 1)
+		// simulamos una consulta lenta para que las peticiones concurrentes coincidan
+		time.Sleep(50 * time.Millisecond)
+		return &models.Runner{ID: "1", FirstName: "John"}, nil
+	}
+
+	// las peticiones concurrentes con la misma clave hacen una sola carga
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var runner models.Runner
+			assert.Nil(t, readThrough.Get(ctx, "1", &runner, load))
+			assert.Equal(t, "John", runner.FirstName)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads)
+
+	// ahora el valor está en la caché
+	var runner models.Runner
+	assert.Nil(t, readThrough.Get(ctx, "1", &runner, load))
+	assert.Equal(t, int32(1), loads)
+
+	// tras invalidar se vuelve a cargar
+	readThrough.Invalidate(ctx, "1")
+	assert.Nil(t, readThrough.Get(ctx, "1", &runner, load))
+	assert.Equal(t, int32(2), loads)
+}
+
+func TestReadThroughErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	readThrough := New("runners", NewLRU(10), time.Minute)
+
+	var runner models.Runner
+	responseErr := readThrough.Get(ctx, "1", &runner, func() (interface{}, *models.ResponseError) {
+		return nil, &models.ResponseError{Message: "Runner not found", Status: http.StatusNotFound}
+	})
+	assert.Equal(t, http.StatusNotFound, responseErr.Status)
+
+	responseErr = readThrough.Get(ctx, "1", &runner, func() (interface{}, *models.ResponseError) {
+		return &models.Runner{ID: "1"}, nil
+	})
+	assert.Nil(t, responseErr)
+	assert.Equal(t, "1", runner.ID)
+}
+
+func TestNilReadThrough(t *testing.T) {
+	var readThrough *ReadThrough
+
+	var role string
+	responseErr := readThrough.Get(context.Background(), "token", &role, func() (interface{}, *models.ResponseError) {
+		return "admin", nil
+	})
+	assert.Nil(t, responseErr)
+	assert.Equal(t, "admin", role)
+
+	readThrough.Invalidate(context.Background(), "token")
+}
+
+func TestRedis(t *testing.T) {
+	server := redistest.NewServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	redis := NewRedis(server.Addr, "secret", 1, 2, time.Second)
+	assert.NoError(t, redis.Ping(ctx))
+
+	_, found, err := redis.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, redis.Set(ctx, "a", []byte("{\"id\":\"1\"}"), time.Minute))
+	value, found, err := redis.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "{\"id\":\"1\"}", string(value))
+
+	assert.NoError(t, redis.Delete(ctx, "a", "b"))
+	_, found, _ = redis.Get(ctx, "a")
+	assert.False(t, found)
+
+	// los errores del servidor no invalidan la conexión
+	_, err = redis.do(ctx, "UNKNOWN")
+	assert.IsType(t, RedisError(""), err)
+	assert.NoError(t, redis.Ping(ctx))
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRU es una caché en memoria con un número máximo de entradas. Cuando se llena se descarta la entrada usada hace más tiempo
+type LRU struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // la entrada más reciente está al principio
+	now      func() time.Time
+}
+
+func NewLRU(capacity int) *LRU {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+
+	return &LRU{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !l.now().Before(entry.expiresAt) {
+		// la entrada ha caducado
+		l.order.Remove(element)
+		delete(l.entries, key)
+		return nil, false, nil
+	}
+
+	l.order.MoveToFront(element)
+
+	return entry.value, true, nil
+}
+
+// Set guarda el valor. Con ttl cero la entrada no caduca
+func (l *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = l.now().Add(ttl)
+	}
+
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(element)
+		return nil
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+
+	if l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
+
+func (l *LRU) Delete(ctx context.Context, keys ...string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, key := range keys {
+		if element, ok := l.entries[key]; ok {
+			l.order.Remove(element)
+			delete(l.entries, key)
+		}
+	}
+
+	return nil
+}
+
+func (l *LRU) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.order.Len()
+}
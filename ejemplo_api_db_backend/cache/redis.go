@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Redis es un cliente mínimo del protocolo de Redis (RESP) con los comandos que necesitan la caché (GET, SET y DEL) y los límites de peticiones (INCR y PEXPIRE). Sirve con Redis y con cualquier servidor compatible
+type Redis struct {
+	address  string
+	password string
+	db       int
+	timeout  time.Duration
+	// las conexiones libres. La capacidad del canal es el tamaño máximo del pool
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// error devuelto por el servidor (respuestas que empiezan por '-')
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+func NewRedis(address string, password string, db int, poolSize int, timeout time.Duration) *Redis {
+	if poolSize <= 0 {
+		poolSize = 10
+	}
+	if timeout <= 0 {
+		timeout = 500 * time.Millisecond
+	}
+
+	return &Redis{
+		address:  address,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		pool:     make(chan *redisConn, poolSize),
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected reply %v", reply)
+	}
+
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := r.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Incr incrementa un contador y devuelve su nuevo valor. Al crear el contador le pone la caducidad ttl
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	reply, err := r.do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+
+	if value == 1 && ttl > 0 {
+		_, err = r.do(ctx, "PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return value, nil
+}
+
+// Ping comprueba que el servidor responde
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+// do envía un comando y lee la respuesta. Si la conexión falla se descarta
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(r.deadline(ctx), args...)
+	if err != nil {
+		var redisErr RedisError
+		if !errors.As(err, &redisErr) {
+			conn.conn.Close()
+			return nil, err
+		}
+	}
+
+	r.put(conn)
+
+	return reply, err
+}
+
+func (r *Redis) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(r.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+
+	return deadline
+}
+
+func (r *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{
+		conn:   netConn,
+		reader: bufio.NewReader(netConn),
+		writer: bufio.NewWriter(netConn),
+	}
+
+	if r.password != "" {
+		_, err = conn.do(r.deadline(ctx), "AUTH", r.password)
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	if r.db != 0 {
+		_, err = conn.do(r.deadline(ctx), "SELECT", strconv.Itoa(r.db))
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (r *Redis) put(conn *redisConn) {
+	select {
+	case r.pool <- conn:
+	default:
+		// el pool está lleno
+		conn.conn.Close()
+	}
+}
+
+func (c *redisConn) do(deadline time.Time, args ...string) (interface{}, error) {
+	c.conn.SetDeadline(deadline)
+
+	// los comandos se envían como un array de bulk strings
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	err := c.writer.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return readReply(c.reader)
+}
+
+// readReply lee una respuesta RESP. Los bulk strings se devuelven como []byte, los enteros como int64 y los arrays como []interface{}
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		buffer := make([]byte, length+2)
+		_, err = io.ReadFull(reader, buffer)
+		if err != nil {
+			return nil, err
+		}
+		return buffer[:length], nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, length)
+		for i := range items {
+			items[i], err = readReply(reader)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("malformed reply %q", line)
+	}
+
+	return line[:len(line)-2], nil
+}
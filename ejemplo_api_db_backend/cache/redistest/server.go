@@ -0,0 +1,239 @@
+// Package redistest arranca un servidor local que habla el protocolo de Redis, al estilo de httptest. Sirve para los tests y para desarrollar sin un Redis real
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Server implementa en memoria los comandos PING, AUTH, SELECT, GET, SET (con PX y EX), DEL, INCR, PEXPIRE y PTTL
+type Server struct {
+	Addr     string
+	listener net.Listener
+	mutex    sync.Mutex
+	data     map[string]*entry
+	wg       sync.WaitGroup
+}
+
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("redistest: failed to listen: %v", err))
+	}
+
+	server := &Server{
+		Addr:     listener.Addr().String(),
+		listener: listener,
+		data:     make(map[string]*entry),
+	}
+
+	server.wg.Add(1)
+	go server.serve()
+
+	return server
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// Keys devuelve el número de claves guardadas (incluidas las caducadas que no se han consultado)
+func (s *Server) Keys() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.data)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		s.execute(writer, args)
+
+		if writer.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) execute(w *bufio.Writer, args []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(args) == 0 {
+		fmt.Fprint(w, "-ERR empty command\r\n")
+		return
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		fmt.Fprint(w, "+PONG\r\n")
+	case "AUTH", "SELECT":
+		fmt.Fprint(w, "+OK\r\n")
+	case "GET":
+		e := s.lookup(args[1])
+		if e == nil {
+			fmt.Fprint(w, "$-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(e.value), e.value)
+	case "SET":
+		e := &entry{value: args[2]}
+		for i := 3; i+1 < len(args); i += 2 {
+			amount, _ := strconv.ParseInt(args[i+1], 10, 64)
+			switch strings.ToUpper(args[i]) {
+			case "PX":
+				e.expiresAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+			case "EX":
+				e.expiresAt = time.Now().Add(time.Duration(amount) * time.Second)
+			}
+		}
+		s.data[args[1]] = e
+		fmt.Fprint(w, "+OK\r\n")
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if s.lookup(key) != nil {
+				deleted++
+			}
+			delete(s.data, key)
+		}
+		fmt.Fprintf(w, ":%d\r\n", deleted)
+	case "INCR":
+		e := s.lookup(args[1])
+		if e == nil {
+			e = &entry{value: "0"}
+			s.data[args[1]] = e
+		}
+		value, err := strconv.ParseInt(e.value, 10, 64)
+		if err != nil {
+			fmt.Fprint(w, "-ERR value is not an integer or out of range\r\n")
+			return
+		}
+		e.value = strconv.FormatInt(value+1, 10)
+		fmt.Fprintf(w, ":%d\r\n", value+1)
+	case "PEXPIRE":
+		e := s.lookup(args[1])
+		if e == nil {
+			fmt.Fprint(w, ":0\r\n")
+			return
+		}
+		amount, _ := strconv.ParseInt(args[2], 10, 64)
+		e.expiresAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+		fmt.Fprint(w, ":1\r\n")
+	case "PTTL":
+		e := s.lookup(args[1])
+		if e == nil {
+			fmt.Fprint(w, ":-2\r\n")
+			return
+		}
+		if e.expiresAt.IsZero() {
+			fmt.Fprint(w, ":-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, ":%d\r\n", time.Until(e.expiresAt).Milliseconds())
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// hay que llamarlo con el mutex cogido
+func (s *Server) lookup(key string) *entry {
+	e, ok := s.data[key]
+	if !ok {
+		return nil
+	}
+
+	if !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt) {
+		delete(s.data, key)
+		return nil
+	}
+
+	return e
+}
+
+// readCommand lee un comando enviado como array de bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("unexpected argument %q", line)
+		}
+
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		buffer := make([]byte, length+2)
+		_, err = io.ReadFull(reader, buffer)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = string(buffer[:length])
+	}
+
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
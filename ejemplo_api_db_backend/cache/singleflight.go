@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"runners-postgresql/models"
+	"sync"
+)
+
+// call es una carga en curso. El resto de peticiones que piden la misma clave esperan a que termine y reciben el mismo resultado
+type call struct {
+	wg          sync.WaitGroup
+	data        []byte
+	responseErr *models.ResponseError
+}
+
+// group agrupa las cargas concurrentes de una misma clave (singleflight)
+type group struct {
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+func (g *group) do(key string, fn func() ([]byte, *models.ResponseError)) ([]byte, *models.ResponseError) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+
+	if c, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		c.wg.Wait()
+		return c.data, c.responseErr
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	c.data, c.responseErr = fn()
+	c.wg.Done()
+
+	g.mutex.Lock()
+	delete(g.calls, key)
+	g.mutex.Unlock()
+
+	return c.data, c.responseErr
+}
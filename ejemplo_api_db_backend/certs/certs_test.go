@@ -0,0 +1,188 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runners-postgresql/config"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutualTLSMapsSubjectToRole(t *testing.T) {
+	ca := newCertificate(t, pkix.Name{CommonName: "Runners CA"}, nil)
+	server := newCertificate(t, pkix.Name{CommonName: "localhost"}, &ca)
+	importer := newCertificate(t, pkix.Name{CommonName: "results-importer", Organization: []string{"Runners"}}, &ca)
+	other := newCertificate(t, pkix.Name{CommonName: "grafana"}, &ca)
+
+	dir := t.TempDir()
+	tlsConfig := config.TLSConfig{
+		CertFile:     writePEM(t, dir, "tls.crt", "CERTIFICATE", server.Certificate[0]),
+		KeyFile:      writeKey(t, dir, "tls.key", server),
+		ClientCAFile: writePEM(t, dir, "ca.crt", "CERTIFICATE", ca.Certificate[0]),
+		ClientAuth:   "optional",
+	}
+	reloader, err := NewReloader("test", tlsConfig)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware([]config.TLSClientRole{{Subject: "CN=results-importer,O=Runners", Role: "admin"}}))
+	router.GET("/whoami", func(ctx *gin.Context) {
+		principal := PrincipalFromContext(ctx.Request.Context())
+		if principal == nil {
+			ctx.Status(http.StatusUnauthorized)
+			return
+		}
+		ctx.String(http.StatusOK, principal.Role)
+	})
+
+	httpServer := httptest.NewUnstartedServer(router)
+	httpServer.TLS = reloader.TLSConfig()
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	// el certificado con un rol en la configuración se autentica sin token
+	response := get(t, httpServer.URL, ca, &importer)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "HTTP/2.0", response.Proto)
+
+	// un certificado válido sin rol, o ningún certificado, no dan ningún rol
+	assert.Equal(t, http.StatusUnauthorized, get(t, httpServer.URL, ca, &other).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, get(t, httpServer.URL, ca, nil).StatusCode)
+
+	// un certificado firmado por otra CA no llega a establecer la conexión
+	unknownCA := newCertificate(t, pkix.Name{CommonName: "Other CA"}, nil)
+	forged := newCertificate(t, pkix.Name{CommonName: "results-importer", Organization: []string{"Runners"}}, &unknownCA)
+	_, err = client(ca, &forged).Get(httpServer.URL + "/whoami")
+	assert.Error(t, err)
+}
+
+func TestReloadUsesNewCertificate(t *testing.T) {
+	ca := newCertificate(t, pkix.Name{CommonName: "Runners CA"}, nil)
+	first := newCertificate(t, pkix.Name{CommonName: "localhost"}, &ca)
+
+	dir := t.TempDir()
+	tlsConfig := config.TLSConfig{
+		CertFile:   writePEM(t, dir, "tls.crt", "CERTIFICATE", first.Certificate[0]),
+		KeyFile:    writeKey(t, dir, "tls.key", first),
+		ClientAuth: "none",
+	}
+	reloader, err := NewReloader("test", tlsConfig)
+	require.NoError(t, err)
+
+	httpServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	httpServer.TLS = reloader.TLSConfig()
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	// sin cambios en los archivos no se recarga nada
+	reloaded, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// el certificado nuevo con la clave antigua no es válido: se mantiene el que hay
+	second := newCertificate(t, pkix.Name{CommonName: "localhost"}, &ca)
+	writePEM(t, dir, "tls.crt", "CERTIFICATE", second.Certificate[0])
+	_, err = reloader.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, first.Leaf.SerialNumber, servedSerial(t, httpServer.URL, ca))
+
+	writeKey(t, dir, "tls.key", second)
+	reloaded, err = reloader.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, second.Leaf.SerialNumber, servedSerial(t, httpServer.URL, ca))
+}
+
+func newCertificate(t *testing.T, subject pkix.Name, issuer *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	parent, signer := template, interface{}(key)
+	if issuer == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parent, signer = issuer.Leaf, issuer.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+
+	return path
+}
+
+func writeKey(t *testing.T, dir string, name string, certificate tls.Certificate) string {
+	der, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	require.NoError(t, err)
+
+	return writePEM(t, dir, name, "PRIVATE KEY", der)
+}
+
+func client(ca tls.Certificate, certificate *tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if certificate != nil {
+		// el cliente envía el certificado aunque no lo haya firmado ninguna de las CAs que anuncia el servidor
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certificate, nil
+		}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true}}
+}
+
+func get(t *testing.T, url string, ca tls.Certificate, certificate *tls.Certificate) *http.Response {
+	response, err := client(ca, certificate).Get(url + "/whoami")
+	require.NoError(t, err)
+	response.Body.Close()
+
+	return response
+}
+
+func servedSerial(t *testing.T, url string, ca tls.Certificate) *big.Int {
+	response, err := client(ca, nil).Get(url)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	return response.TLS.PeerCertificates[0].SerialNumber
+}
@@ -0,0 +1,72 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"runners-postgresql/config"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type principalKey struct{}
+
+// WithPrincipal devuelve un contexto con el usuario autenticado por su certificado
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext devuelve el usuario autenticado por su certificado, o nil si la petición no trae un certificado válido con un rol asociado
+func PrincipalFromContext(ctx context.Context) *models.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return principal
+}
+
+// Identities asocia los sujetos de los certificados de cliente con el usuario que representan
+type Identities map[string]*models.Principal
+
+// NewIdentities crea las identidades de los certificados a partir de los roles de la configuración
+func NewIdentities(clientRoles []config.TLSClientRole) Identities {
+	identities := make(Identities, len(clientRoles))
+	for _, clientRole := range clientRoles {
+		identities[clientRole.Subject] = &models.Principal{
+			Username: clientRole.Subject,
+			Role:     clientRole.Role,
+			ClubID:   clientRole.ClubID,
+		}
+	}
+
+	return identities
+}
+
+// Principal devuelve el usuario del certificado de cliente de la conexión, o nil si no hay certificado o su sujeto no tiene rol. El certificado ya lo ha validado el servidor TLS con las CAs de los clientes
+func (i Identities) Principal(ctx context.Context, state *tls.ConnectionState) *models.Principal {
+	// VerifiedChains solo tiene cadenas si el cliente ha enviado un certificado y es válido
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil
+	}
+
+	subject := state.VerifiedChains[0][0].Subject.String()
+	principal, found := i[subject]
+	if !found {
+		logging.Debug(ctx, "Client certificate without role", "subject", subject)
+		return nil
+	}
+
+	return principal
+}
+
+// Middleware identifica a los servicios internos que se autentican con un certificado de cliente. Si el sujeto del certificado tiene un rol en la configuración, la petición se trata como la de un usuario con ese rol aunque no traiga token
+func Middleware(clientRoles []config.TLSClientRole) gin.HandlerFunc {
+	identities := NewIdentities(clientRoles)
+
+	return func(ctx *gin.Context) {
+		principal := identities.Principal(ctx.Request.Context(), ctx.Request.TLS)
+		if principal != nil {
+			ctx.Request = ctx.Request.WithContext(WithPrincipal(ctx.Request.Context(), principal))
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,145 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runners-postgresql/config"
+	"runners-postgresql/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader mantiene la configuración TLS de un servidor a partir de los archivos del certificado, la clave y las CAs de los clientes, y la vuelve a leer cuando cambian. Así un certificado renovado (por ejemplo, por cert-manager en un secreto de Kubernetes) se usa en las conexiones nuevas sin reiniciar
+type Reloader struct {
+	name    string
+	config  config.TLSConfig
+	current atomic.Pointer[tls.Config]
+	mutex   sync.Mutex
+	files   [][]byte // contenido de los archivos con el que se creó la configuración en vigor
+}
+
+// NewReloader lee los archivos. Un error en la configuración inicial impide arrancar el servidor
+func NewReloader(name string, config config.TLSConfig) (*Reloader, error) {
+	reloader := &Reloader{
+		name:   name,
+		config: config,
+	}
+
+	_, err := reloader.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// TLSConfig devuelve la configuración para el servidor. Cada conexión nueva usa la configuración en vigor en ese momento
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// Reload vuelve a leer los archivos y, si han cambiado, crea la nueva configuración. Si los archivos no son válidos (por ejemplo, se ha leído el certificado nuevo con la clave antigua) se mantiene la configuración en vigor
+func (r *Reloader) Reload() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	paths := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		paths = append(paths, r.config.ClientCAFile)
+	}
+
+	files := make([][]byte, len(paths))
+	for i, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		files[i] = content
+	}
+
+	if r.files != nil && equal(r.files, files) {
+		return false, nil
+	}
+
+	certificate, err := tls.X509KeyPair(files[0], files[1])
+	if err != nil {
+		return false, fmt.Errorf("%s, %s: %w", r.config.CertFile, r.config.KeyFile, err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+		NextProtos:   []string{"h2", "http/1.1"},
+		ClientAuth:   clientAuthType(r.config.ClientAuth),
+	}
+
+	if r.config.ClientCAFile != "" {
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(files[2]) {
+			return false, fmt.Errorf("%s: %w", r.config.ClientCAFile, errors.New("no PEM certificates found"))
+		}
+		tlsConfig.ClientCAs = clientCAs
+	}
+
+	r.current.Store(tlsConfig)
+	r.files = files
+	metrics.TLSCertificateExpiry.WithLabelValues(r.name).Set(float64(certificate.Leaf.NotAfter.Unix()))
+	slog.Info("TLS certificate loaded", "server", r.name, "subject", certificate.Leaf.Subject.String(), "not_after", certificate.Leaf.NotAfter)
+
+	return true, nil
+}
+
+// Watch comprueba los archivos cada reload_interval, hasta que se cancela el contexto. Comparamos el contenido en lugar de la fecha de modificación porque Kubernetes actualiza los secretos cambiando un enlace simbólico
+func (r *Reloader) Watch(ctx context.Context) {
+	ticker := time.NewTicker(r.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := r.Reload()
+			if err != nil {
+				slog.Error("TLS certificate not reloaded", "server", r.name, "error", err)
+			}
+		}
+	}
+}
+
+func equal(a [][]byte, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// la configuración ya está validada, así que cualquier otro valor es "none"
+func clientAuthType(clientAuth string) tls.ClientAuthType {
+	switch clientAuth {
+	case "optional":
+		return tls.VerifyClientCertIfGiven
+	case "require":
+		return tls.RequireAndVerifyClientCert
+	}
+
+	return tls.NoClientCert
+}
@@ -1,12 +1,346 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
-func InitConfig(fileName string) *viper.Viper {
+// prefijo de las variables de entorno que sobrescriben la configuración. Por ejemplo, RUNNERS_DATABASE_MAX_OPEN_CONNECTIONS sobrescribe database.max_open_connections
+const envPrefix = "RUNNERS"
+
+// claves con secretos. Cada una se puede leer de un archivo indicando su ruta en la clave terminada en _file (por ejemplo, database.connection_string_file o RUNNERS_DATABASE_CONNECTION_STRING_FILE)
+var secretKeys = []string{
+	"database.connection_string",
+	"cache.redis_password",
+	"oidc.client_secret",
+}
+
+// Config es la configuración de la aplicación. Se lee del archivo de configuración, y cualquier clave se puede sobrescribir con una variable de entorno
+type Config struct {
+	Database   DatabaseConfig   `mapstructure:"database"`
+	HTTP       HTTPConfig       `mapstructure:"http"`
+	Metrics    MetricsConfig    `mapstructure:"metrics"`
+	GRPC       GRPCConfig       `mapstructure:"grpc"`
+	OpenAPI    OpenAPIConfig    `mapstructure:"openapi"`
+	GraphQL    GraphQLConfig    `mapstructure:"graphql"`
+	Versioning VersioningConfig `mapstructure:"versioning"`
+	OIDC       OIDCConfig       `mapstructure:"oidc"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Shutdown   ShutdownConfig   `mapstructure:"shutdown"`
+	Health     HealthConfig     `mapstructure:"health"`
+	Log        LogConfig        `mapstructure:"log"`
+	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
+	Webhooks   WebhooksConfig   `mapstructure:"webhooks"`
+	Live       LiveConfig       `mapstructure:"live"`
+	Cache      CacheConfig      `mapstructure:"cache"`
+}
+
+type DatabaseConfig struct {
+	ConnectionString      string        `mapstructure:"connection_string"`
+	MaxIdleConnections    int           `mapstructure:"max_idle_connections"`
+	MaxOpenConnections    int           `mapstructure:"max_open_connections"`
+	ConnectionMaxLifetime time.Duration `mapstructure:"connection_max_lifetime"`
+	DriverName            string        `mapstructure:"driver_name"`
+}
+
+type HTTPConfig struct {
+	ServerAddress     string        `mapstructure:"server_address"`
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"` // no se aplica al feed en directo ni a las exportaciones
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	TrustedProxies    []string      `mapstructure:"trusted_proxies"` // IPs o rangos CIDR de los balanceadores de los que se acepta X-Forwarded-For. Vacío no confía en ninguno
+	TLS               TLSConfig     `mapstructure:"tls"`
+}
+
+// ShutdownConfig controla la parada ordenada de la aplicación
+type ShutdownConfig struct {
+	DrainDelay time.Duration `mapstructure:"drain_delay"` // tiempo entre dejar de estar listo y dejar de aceptar peticiones
+	Timeout    time.Duration `mapstructure:"timeout"`     // plazo para que terminen las peticiones en curso y se detengan los componentes
+}
+
+// HealthConfig controla las comprobaciones de salud de /readyz y /health
+type HealthConfig struct {
+	Timeout        time.Duration `mapstructure:"timeout"`         // tiempo máximo de cada comprobación
+	PoolSaturation float64       `mapstructure:"pool_saturation"` // fracción de conexiones en uso a partir de la cual el pool se considera saturado
+}
+
+// MetricsConfig indica dónde se exponen las métricas de Prometheus
+type MetricsConfig struct {
+	Address string    `mapstructure:"address"`
+	TLS     TLSConfig `mapstructure:"tls"`
+}
+
+// GRPCConfig configura la API gRPC para los servicios internos. Tiene su propia configuración TLS, y los certificados de cliente pueden tener un rol como en la API REST
+type GRPCConfig struct {
+	Enabled bool      `mapstructure:"enabled"`
+	Address string    `mapstructure:"address"`
+	TLS     TLSConfig `mapstructure:"tls"`
+}
+
+// OpenAPIConfig controla la validación de las peticiones con la especificación de la API
+type OpenAPIConfig struct {
+	ValidateRequests bool `mapstructure:"validate_requests"` // responde 400 a las peticiones que no cumplen la especificación
+}
+
+// GraphQLConfig limita el tamaño de las consultas de GraphQL
+type GraphQLConfig struct {
+	MaxDepth      int `mapstructure:"max_depth"`      // número máximo de campos anidados
+	MaxComplexity int `mapstructure:"max_complexity"` // número máximo estimado de campos en la respuesta
+}
+
+// VersioningConfig controla las versiones de la API REST (/v1 y /v2)
+type VersioningConfig struct {
+	Default         string `mapstructure:"default"`          // versión de las peticiones que no la indican en la ruta ni en la cabecera Accept
+	Deprecated      string `mapstructure:"deprecated"`       // versión obsoleta, que responde con las cabeceras Deprecation y Sunset. Vacía si no hay ninguna
+	DeprecatedSince string `mapstructure:"deprecated_since"` // fecha desde la que la versión es obsoleta (AAAA-MM-DD)
+	Sunset          string `mapstructure:"sunset"`           // fecha en que la versión obsoleta dejará de funcionar (AAAA-MM-DD). Opcional
+}
+
+// formato de las fechas de la configuración de las versiones
+const versioningDateLayout = "2006-01-02"
+
+// DeprecationDates devuelve las fechas de la versión obsoleta. La configuración ya está validada, así que una fecha vacía se devuelve como el instante cero
+func (vc VersioningConfig) DeprecationDates() (time.Time, time.Time) {
+	deprecatedSince, _ := time.Parse(versioningDateLayout, vc.DeprecatedSince)
+	sunset, _ := time.Parse(versioningDateLayout, vc.Sunset)
+
+	return deprecatedSince, sunset
+}
+
+// OIDCConfig activa el login con un proveedor de identidad externo (OpenID Connect), con el flujo authorization code y PKCE
+type OIDCConfig struct {
+	Enabled      bool              `mapstructure:"enabled"`
+	Issuer       string            `mapstructure:"issuer"` // la configuración del proveedor se descubre en <issuer>/.well-known/openid-configuration
+	ClientID     string            `mapstructure:"client_id"`
+	ClientSecret string            `mapstructure:"client_secret"` // vacío si el cliente es público y solo usa PKCE
+	RedirectURL  string            `mapstructure:"redirect_url"`  // URL de /auth/oidc/callback registrada en el proveedor
+	Scopes       []string          `mapstructure:"scopes"`
+	RoleClaim    string            `mapstructure:"role_claim"`   // claim con los grupos o roles del usuario. Los anidados se indican con puntos: realm_access.roles
+	DefaultRole  string            `mapstructure:"default_role"` // rol de los usuarios sin ninguno de role_mappings: "runner", o vacío para no dejarles entrar
+	RoleMappings []OIDCRoleMapping `mapstructure:"role_mappings"`
+	StateTTL     time.Duration     `mapstructure:"state_ttl"` // tiempo máximo para iniciar sesión en el proveedor
+	Timeout      time.Duration     `mapstructure:"timeout"`   // tiempo máximo de las peticiones al proveedor
+}
+
+// OIDCRoleMapping asocia un valor del claim de roles a un rol de la aplicación. Si el usuario tiene varios gana el primero de la lista
+type OIDCRoleMapping struct {
+	Value  string `mapstructure:"value"`
+	Role   string `mapstructure:"role"`    // "admin", "club_admin" o "runner"
+	ClubID string `mapstructure:"club_id"` // club que administra con el rol club_admin
+}
+
+// TLSConfig activa HTTPS en un servidor cuando se indican el certificado y la clave. Los archivos se vuelven a leer cada reload_interval, de modo que un certificado renovado se usa sin reiniciar
+type TLSConfig struct {
+	CertFile       string          `mapstructure:"cert_file"`
+	KeyFile        string          `mapstructure:"key_file"`
+	ClientCAFile   string          `mapstructure:"client_ca_file"` // CAs con las que se validan los certificados de los clientes
+	ClientAuth     string          `mapstructure:"client_auth"`    // "none", "optional" (se valida el certificado si el cliente lo envía) o "require"
+	ReloadInterval time.Duration   `mapstructure:"reload_interval"`
+	ClientRoles    []TLSClientRole `mapstructure:"client_roles"` // solo en http.tls y grpc.tls
+}
+
+// Enabled indica si el servidor usa HTTPS
+func (tc TLSConfig) Enabled() bool {
+	return tc.CertFile != ""
+}
+
+// TLSClientRole asocia el sujeto del certificado de un cliente (por ejemplo, "CN=results-importer,O=Runners") a un rol, que el cliente tiene sin necesidad de token
+type TLSClientRole struct {
+	Subject string `mapstructure:"subject"`
+	Role    string `mapstructure:"role"`    // "admin" o "club_admin"
+	ClubID  string `mapstructure:"club_id"` // club que administra con el rol club_admin
+}
+
+// TracingConfig indica a dónde se exportan las trazas
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`     // "otlp" (OTLP/HTTP), "file" (un JSON por span) o "none"
+	Endpoint    string  `mapstructure:"endpoint"`     // URL del colector OTLP/HTTP
+	File        string  `mapstructure:"file"`         // archivo de las trazas con el exportador "file"
+	SampleRatio float64 `mapstructure:"sample_ratio"` // fracción de las trazas nuevas que se registran
+	ServiceName string  `mapstructure:"service_name"`
+}
+
+// LogConfig configura los logs. El nivel se puede recargar en caliente con SIGHUP
+type LogConfig struct {
+	Level  string `mapstructure:"level"`  // debug, info, warn o error
+	Format string `mapstructure:"format"` // json o text
+}
+
+// SlogLevel devuelve el nivel de log. La configuración ya está validada, así que un nivel desconocido se trata como info
+func (lc LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(lc.Level)); err != nil {
+		return slog.LevelInfo
+	}
+
+	return level
+}
+
+// RateLimitConfig son los límites de peticiones por cliente. Se pueden recargar en caliente con SIGHUP, salvo el almacenamiento
+type RateLimitConfig struct {
+	Enabled           bool                      `mapstructure:"enabled"`
+	RequestsPerSecond float64                   `mapstructure:"requests_per_second"` // límite de las rutas que no están en ningún grupo
+	Burst             int                       `mapstructure:"burst"`
+	MaxConcurrent     int                       `mapstructure:"max_concurrent"` // 0 sin límite
+	Store             string                    `mapstructure:"store"`          // "memory" (cada réplica lleva su cuenta) o "redis" (compartido, con la conexión de la sección cache)
+	Groups            map[string]RateLimitGroup `mapstructure:"groups"`
+}
+
+// RateLimitGroup es un límite propio para un grupo de rutas. Cada cliente tiene una cuenta por grupo
+type RateLimitGroup struct {
+	Routes            []string `mapstructure:"routes"` // método y plantilla de la ruta: "POST /login", "GET /runner/:id"
+	RequestsPerSecond float64  `mapstructure:"requests_per_second"`
+	Burst             int      `mapstructure:"burst"`
+}
+
+type WebhooksConfig struct {
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	BatchSize      int           `mapstructure:"batch_size"`
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+}
+
+type LiveConfig struct {
+	ReplaySize int           `mapstructure:"replay_size"`
+	QueueSize  int           `mapstructure:"queue_size"`
+	Heartbeat  time.Duration `mapstructure:"heartbeat"`
+}
+
+type CacheConfig struct {
+	Backend       string        `mapstructure:"backend"` // "memory" (LRU en memoria), "redis" o "none"
+	Size          int           `mapstructure:"size"`
+	TTL           time.Duration `mapstructure:"ttl"`
+	RolesTTL      time.Duration `mapstructure:"roles_ttl"`
+	RedisAddress  string        `mapstructure:"redis_address"`
+	RedisPassword string        `mapstructure:"redis_password"`
+	RedisDB       int           `mapstructure:"redis_db"`
+	RedisPoolSize int           `mapstructure:"redis_pool_size"`
+	RedisTimeout  time.Duration `mapstructure:"redis_timeout"`
+}
+
+// valores por defecto. Además de dar un valor a las claves que no estén en el archivo, hacen que viper conozca todas las claves y las busque en las variables de entorno
+func setDefaults(config *viper.Viper) {
+	config.SetDefault("database.connection_string", "")
+	config.SetDefault("database.max_idle_connections", 5)
+	config.SetDefault("database.max_open_connections", 20)
+	config.SetDefault("database.connection_max_lifetime", "60s")
+	config.SetDefault("database.driver_name", "postgres")
+
+	config.SetDefault("http.server_address", ":8080")
+	config.SetDefault("http.read_timeout", "15s")
+	config.SetDefault("http.read_header_timeout", "5s")
+	config.SetDefault("http.write_timeout", "30s")
+	config.SetDefault("http.idle_timeout", "60s")
+	config.SetDefault("http.trusted_proxies", []string{})
+	setTLSDefaults(config, "http.tls")
+
+	config.SetDefault("metrics.address", ":9000")
+	setTLSDefaults(config, "metrics.tls")
+
+	config.SetDefault("grpc.enabled", false)
+	config.SetDefault("grpc.address", ":9090")
+	setTLSDefaults(config, "grpc.tls")
+
+	config.SetDefault("openapi.validate_requests", false)
+
+	config.SetDefault("graphql.max_depth", 5)
+	config.SetDefault("graphql.max_complexity", 2000)
+
+	config.SetDefault("versioning.default", "v1")
+	config.SetDefault("versioning.deprecated", "")
+	config.SetDefault("versioning.deprecated_since", "")
+	config.SetDefault("versioning.sunset", "")
+
+	config.SetDefault("oidc.enabled", false)
+	config.SetDefault("oidc.issuer", "")
+	config.SetDefault("oidc.client_id", "")
+	config.SetDefault("oidc.client_secret", "")
+	config.SetDefault("oidc.redirect_url", "http://localhost:8080/auth/oidc/callback")
+	config.SetDefault("oidc.scopes", []string{"openid", "profile", "email"})
+	config.SetDefault("oidc.role_claim", "groups")
+	config.SetDefault("oidc.default_role", "runner")
+	config.SetDefault("oidc.state_ttl", "10m")
+	config.SetDefault("oidc.timeout", "5s")
+
+	config.SetDefault("tracing.exporter", "none")
+	config.SetDefault("tracing.endpoint", "http://localhost:4318/v1/traces")
+	config.SetDefault("tracing.file", "traces.json")
+	config.SetDefault("tracing.sample_ratio", 1.0)
+	config.SetDefault("tracing.service_name", "runners")
+
+	config.SetDefault("shutdown.drain_delay", "5s")
+	config.SetDefault("shutdown.timeout", "20s")
+
+	config.SetDefault("health.timeout", "2s")
+	config.SetDefault("health.pool_saturation", 0.9)
+
+	config.SetDefault("log.level", "info")
+	config.SetDefault("log.format", "json")
+
+	config.SetDefault("rate_limit.enabled", false)
+	config.SetDefault("rate_limit.requests_per_second", 10)
+	config.SetDefault("rate_limit.burst", 20)
+	config.SetDefault("rate_limit.max_concurrent", 0)
+	config.SetDefault("rate_limit.store", "memory")
+
+	config.SetDefault("webhooks.poll_interval", "2s")
+	config.SetDefault("webhooks.batch_size", 50)
+	config.SetDefault("webhooks.max_attempts", 8)
+	config.SetDefault("webhooks.retry_base_delay", "5s")
+	config.SetDefault("webhooks.retry_max_delay", "1h")
+	config.SetDefault("webhooks.request_timeout", "10s")
+
+	config.SetDefault("live.replay_size", 100)
+	config.SetDefault("live.queue_size", 64)
+	config.SetDefault("live.heartbeat", "15s")
+
+	config.SetDefault("cache.backend", "memory")
+	config.SetDefault("cache.size", 10000)
+	config.SetDefault("cache.ttl", "30s")
+	config.SetDefault("cache.roles_ttl", "10s")
+	config.SetDefault("cache.redis_address", "localhost:6379")
+	config.SetDefault("cache.redis_password", "")
+	config.SetDefault("cache.redis_db", 0)
+	config.SetDefault("cache.redis_pool_size", 10)
+	config.SetDefault("cache.redis_timeout", "500ms")
+
+	for _, key := range secretKeys {
+		config.SetDefault(key+"_file", "")
+	}
+}
+
+func setTLSDefaults(config *viper.Viper, prefix string) {
+	config.SetDefault(prefix+".cert_file", "")
+	config.SetDefault(prefix+".key_file", "")
+	config.SetDefault(prefix+".client_ca_file", "")
+	config.SetDefault(prefix+".client_auth", "none")
+	config.SetDefault(prefix+".reload_interval", "1m")
+}
+
+// InitConfig lee la configuración y termina la aplicación si no es válida, informando de todos los errores a la vez
+func InitConfig(fileName string) *Config {
+	config, err := Load(fileName)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	return config
+}
+
+// Load lee el archivo de configuración, aplica los valores por defecto, las variables de entorno y los secretos en archivos, y valida el resultado. El archivo es opcional si toda la configuración llega por variables de entorno
+func Load(fileName string) (*Config, error) {
 	// instanciamos viper
 	config := viper.New()
 
@@ -17,11 +351,240 @@ func InitConfig(fileName string) *viper.Viper {
 	config.AddConfigPath(".")
 	config.AddConfigPath("$HOME")
 
+	setDefaults(config)
+
+	// cualquier clave se puede sobrescribir con RUNNERS_<SECCION>_<CLAVE>
+	config.SetEnvPrefix(envPrefix)
+	config.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	config.AutomaticEnv()
+
 	// leemos el archivo de configuración
 	err := config.ReadInConfig()
 	if err != nil {
-		log.Fatal("Error while parsing configuration file", err)
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("error while parsing configuration file: %w", err)
+		}
+		slog.Warn("Configuration file not found, using defaults and environment variables", "file", fileName)
 	}
 
-	return config
+	problems := make([]error, 0)
+	for _, key := range secretKeys {
+		err := readSecretFile(config, key)
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	var result Config
+	err = config.Unmarshal(&result)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("error while decoding configuration: %w", err))
+		return nil, errors.Join(problems...)
+	}
+
+	err = result.Validate()
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	return &result, nil
+}
+
+// si la clave terminada en _file tiene una ruta, el valor del secreto es el contenido de ese archivo
+func readSecretFile(config *viper.Viper, key string) error {
+	path := config.GetString(key + "_file")
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s_file: %w", key, err)
+	}
+
+	config.Set(key, strings.TrimSpace(string(content)))
+	return nil
+}
+
+// Validate comprueba toda la configuración y devuelve todos los errores juntos
+func (c *Config) Validate() error {
+	problems := make([]error, 0)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Database.ConnectionString != "", "database.connection_string is required")
+	check(c.Database.DriverName != "", "database.driver_name is required")
+	check(c.Database.MaxIdleConnections >= 0, "database.max_idle_connections must not be negative")
+	check(c.Database.MaxOpenConnections >= 0, "database.max_open_connections must not be negative")
+	check(c.Database.MaxOpenConnections == 0 || c.Database.MaxIdleConnections <= c.Database.MaxOpenConnections,
+		"database.max_idle_connections (%d) must not exceed database.max_open_connections (%d)", c.Database.MaxIdleConnections, c.Database.MaxOpenConnections)
+	check(c.Database.ConnectionMaxLifetime >= 0, "database.connection_max_lifetime must not be negative")
+
+	check(c.HTTP.ServerAddress != "", "http.server_address is required")
+	check(c.HTTP.ReadTimeout >= 0, "http.read_timeout must not be negative")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "http.read_header_timeout must not be negative")
+	check(c.HTTP.WriteTimeout >= 0, "http.write_timeout must not be negative")
+	check(c.HTTP.IdleTimeout >= 0, "http.idle_timeout must not be negative")
+	for _, proxy := range c.HTTP.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "http.trusted_proxies: %q is not an IP or a CIDR range", proxy)
+	}
+	check(c.Metrics.Address != "", "metrics.address is required")
+	check(c.Metrics.Address != c.HTTP.ServerAddress, "metrics.address must be different from http.server_address")
+	problems = append(problems, c.HTTP.TLS.validate("http.tls")...)
+	problems = append(problems, c.Metrics.TLS.validate("metrics.tls")...)
+	check(len(c.Metrics.TLS.ClientRoles) == 0, "metrics.tls.client_roles is not supported: any client certificate signed by metrics.tls.client_ca_file can read the metrics")
+	if c.GRPC.Enabled {
+		check(c.GRPC.Address != "", "grpc.address is required")
+		check(c.GRPC.Address != c.HTTP.ServerAddress && c.GRPC.Address != c.Metrics.Address, "grpc.address must be different from http.server_address and metrics.address")
+		problems = append(problems, c.GRPC.TLS.validate("grpc.tls")...)
+	}
+
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth must be positive")
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity must be positive")
+
+	check(c.Versioning.Default == "v1" || c.Versioning.Default == "v2", "versioning.default %q is not one of v1 or v2", c.Versioning.Default)
+	if c.Versioning.Deprecated != "" {
+		check(c.Versioning.Deprecated == "v1" || c.Versioning.Deprecated == "v2", "versioning.deprecated %q is not one of v1 or v2", c.Versioning.Deprecated)
+		deprecatedSince, err := time.Parse(versioningDateLayout, c.Versioning.DeprecatedSince)
+		check(err == nil, "versioning.deprecated_since %q is not a date such as 2026-10-19", c.Versioning.DeprecatedSince)
+		if c.Versioning.Sunset != "" {
+			sunset, err := time.Parse(versioningDateLayout, c.Versioning.Sunset)
+			check(err == nil, "versioning.sunset %q is not a date such as 2027-04-30", c.Versioning.Sunset)
+			check(err != nil || sunset.After(deprecatedSince), "versioning.sunset must be after versioning.deprecated_since")
+		}
+	}
+
+	if c.OIDC.Enabled {
+		problems = append(problems, c.OIDC.validate()...)
+	}
+
+	check(c.Tracing.Exporter == "otlp" || c.Tracing.Exporter == "file" || c.Tracing.Exporter == "none", "tracing.exporter %q is not one of otlp, file or none", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint is required with the otlp exporter")
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file is required with the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+
+	check(c.Shutdown.DrainDelay >= 0, "shutdown.drain_delay must not be negative")
+	check(c.Shutdown.Timeout > 0, "shutdown.timeout must be positive")
+
+	check(c.Health.Timeout > 0, "health.timeout must be positive")
+	check(c.Health.PoolSaturation > 0 && c.Health.PoolSaturation <= 1, "health.pool_saturation must be between 0 and 1")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level %q is not one of debug, info, warn or error", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format %q is not one of json or text", c.Log.Format)
+
+	check(c.RateLimit.RequestsPerSecond > 0, "rate_limit.requests_per_second must be positive")
+	check(c.RateLimit.Burst > 0, "rate_limit.burst must be positive")
+	check(c.RateLimit.MaxConcurrent >= 0, "rate_limit.max_concurrent must not be negative")
+	check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "redis", "rate_limit.store %q is not one of memory or redis", c.RateLimit.Store)
+	check(c.RateLimit.Store != "redis" || c.Cache.RedisAddress != "", "cache.redis_address is required with the redis rate_limit.store")
+	groupsByRoute := make(map[string]string)
+	for name, group := range c.RateLimit.Groups {
+		check(group.RequestsPerSecond > 0, "rate_limit.groups.%s.requests_per_second must be positive", name)
+		check(group.Burst > 0, "rate_limit.groups.%s.burst must be positive", name)
+		check(len(group.Routes) > 0, "rate_limit.groups.%s.routes is required", name)
+		for _, route := range group.Routes {
+			method, path, found := strings.Cut(route, " ")
+			check(found && method == strings.ToUpper(method) && strings.HasPrefix(path, "/"), "rate_limit.groups.%s.routes: %q is not a method and a route such as \"GET /runner\"", name, route)
+			other, repeated := groupsByRoute[route]
+			check(!repeated, "rate_limit.groups.%s.routes: %q is already in group %s", name, route, other)
+			groupsByRoute[route] = name
+		}
+	}
+
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(c.Webhooks.BatchSize > 0, "webhooks.batch_size must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.RetryBaseDelay > 0, "webhooks.retry_base_delay must be positive")
+	check(c.Webhooks.RetryMaxDelay >= c.Webhooks.RetryBaseDelay, "webhooks.retry_max_delay must not be lower than webhooks.retry_base_delay")
+	check(c.Webhooks.RequestTimeout > 0, "webhooks.request_timeout must be positive")
+
+	check(c.Live.ReplaySize >= 0, "live.replay_size must not be negative")
+	check(c.Live.QueueSize > 0, "live.queue_size must be positive")
+	check(c.Live.Heartbeat > 0, "live.heartbeat must be positive")
+
+	switch c.Cache.Backend {
+	case "", "none":
+	case "memory":
+		check(c.Cache.Size > 0, "cache.size must be positive")
+	case "redis":
+		check(c.Cache.RedisAddress != "", "cache.redis_address is required")
+		check(c.Cache.RedisPoolSize > 0, "cache.redis_pool_size must be positive")
+		check(c.Cache.RedisTimeout > 0, "cache.redis_timeout must be positive")
+	default:
+		check(false, "cache.backend %q is not one of memory, redis or none", c.Cache.Backend)
+	}
+	check(c.Cache.TTL > 0, "cache.ttl must be positive")
+	check(c.Cache.RolesTTL > 0, "cache.roles_ttl must be positive")
+
+	return errors.Join(problems...)
+}
+
+func (oc OIDCConfig) validate() []error {
+	problems := make([]error, 0)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf("oidc."+format, args...))
+		}
+	}
+
+	issuer, err := url.Parse(oc.Issuer)
+	check(err == nil && (issuer.Scheme == "https" || issuer.Scheme == "http") && issuer.Host != "", "issuer %q is not a URL", oc.Issuer)
+	check(oc.ClientID != "", "client_id is required")
+	redirectURL, err := url.Parse(oc.RedirectURL)
+	check(err == nil && redirectURL.IsAbs() && strings.HasSuffix(redirectURL.Path, "/auth/oidc/callback"), "redirect_url %q is not the URL of /auth/oidc/callback", oc.RedirectURL)
+	openid := false
+	for _, scope := range oc.Scopes {
+		openid = openid || scope == "openid"
+	}
+	check(openid, "scopes must include openid")
+	check(oc.DefaultRole == "" || oc.DefaultRole == "runner", "default_role %q is not runner or empty", oc.DefaultRole)
+	check(oc.RoleClaim != "" || len(oc.RoleMappings) == 0, "role_claim is required with role_mappings")
+	check(oc.StateTTL > 0, "state_ttl must be positive")
+	check(oc.Timeout > 0, "timeout must be positive")
+
+	for i, mapping := range oc.RoleMappings {
+		check(mapping.Value != "", "role_mappings[%d].value is required", i)
+		check(mapping.Role == "admin" || mapping.Role == "club_admin" || mapping.Role == "runner", "role_mappings[%d].role %q is not one of admin, club_admin or runner", i, mapping.Role)
+		check(mapping.Role != "club_admin" || mapping.ClubID != "", "role_mappings[%d].club_id is required with the club_admin role", i)
+	}
+
+	return problems
+}
+
+func (tc TLSConfig) validate(prefix string) []error {
+	problems := make([]error, 0)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(prefix+"."+format, args...))
+		}
+	}
+
+	check((tc.CertFile == "") == (tc.KeyFile == ""), "cert_file and key_file must be set together")
+	check(tc.ClientAuth == "none" || tc.ClientAuth == "optional" || tc.ClientAuth == "require", "client_auth %q is not one of none, optional or require", tc.ClientAuth)
+	check(tc.ClientAuth == "none" || tc.Enabled(), "client_auth requires cert_file and key_file")
+	check(tc.ClientAuth == "none" || tc.ClientCAFile != "", "client_ca_file is required with client_auth %q", tc.ClientAuth)
+	check(tc.ReloadInterval > 0, "reload_interval must be positive")
+	check(tc.ClientAuth != "none" || len(tc.ClientRoles) == 0, "client_roles requires client_auth optional or require")
+
+	subjects := make(map[string]bool)
+	for i, clientRole := range tc.ClientRoles {
+		check(clientRole.Subject != "", "client_roles[%d].subject is required", i)
+		check(!subjects[clientRole.Subject], "client_roles[%d].subject %q is repeated", i, clientRole.Subject)
+		subjects[clientRole.Subject] = true
+		check(clientRole.Role == "admin" || clientRole.Role == "club_admin", "client_roles[%d].role %q is not one of admin or club_admin", i, clientRole.Role)
+		check(clientRole.Role != "club_admin" || clientRole.ClubID != "", "client_roles[%d].club_id is required with the club_admin role", i)
+	}
+
+	return problems
 }
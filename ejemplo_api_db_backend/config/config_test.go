@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEnvironmentOverrides(t *testing.T) {
+	// sin archivo de configuración, todo llega de los valores por defecto y las variables de entorno
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	secret := filepath.Join(t.TempDir(), "connection_string")
+	require.NoError(t, os.WriteFile(secret, []byte("host=db dbname=runners\n"), 0600))
+
+	t.Setenv("RUNNERS_DATABASE_CONNECTION_STRING_FILE", secret)
+	t.Setenv("RUNNERS_DATABASE_MAX_OPEN_CONNECTIONS", "40")
+	t.Setenv("RUNNERS_CACHE_TTL", "1m")
+
+	config, err := Load("runners-test")
+	require.NoError(t, err)
+
+	assert.Equal(t, "host=db dbname=runners", config.Database.ConnectionString)
+	assert.Equal(t, 40, config.Database.MaxOpenConnections)
+	assert.Equal(t, 5, config.Database.MaxIdleConnections)
+	assert.Equal(t, time.Minute, config.Cache.TTL)
+	assert.Equal(t, ":9000", config.Metrics.Address)
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	t.Setenv("RUNNERS_DATABASE_MAX_IDLE_CONNECTIONS", "50")
+	t.Setenv("RUNNERS_LOG_LEVEL", "verbose")
+	t.Setenv("RUNNERS_CACHE_BACKEND", "memcached")
+	t.Setenv("RUNNERS_HTTP_TRUSTED_PROXIES", "10.0.0.0/8,balanceador")
+
+	_, err := Load("runners-test")
+	require.Error(t, err)
+
+	message := err.Error()
+	assert.Contains(t, message, "database.connection_string is required")
+	assert.Contains(t, message, "database.max_idle_connections (50) must not exceed database.max_open_connections (20)")
+	assert.Contains(t, message, `log.level "verbose"`)
+	assert.Contains(t, message, `cache.backend "memcached"`)
+	assert.Contains(t, message, `http.trusted_proxies: "balanceador" is not an IP or a CIDR range`)
+	assert.Equal(t, 5, len(strings.Split(message, "\n")))
+}
+
+func TestReloadOnlyAppliesSafeSettings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("RUNNERS_DATABASE_CONNECTION_STRING", "host=db")
+
+	config, err := Load("runners-test")
+	require.NoError(t, err)
+
+	reloader := NewReloader("runners-test", config)
+	var reloaded *Config
+	reloader.OnReload(func(c *Config) {
+		reloaded = c
+	})
+
+	t.Setenv("RUNNERS_LOG_LEVEL", "debug")
+	t.Setenv("RUNNERS_RATE_LIMIT_BURST", "5")
+	t.Setenv("RUNNERS_HTTP_SERVER_ADDRESS", ":8081")
+	require.NoError(t, reloader.Reload())
+
+	assert.Same(t, reloaded, reloader.Current())
+	assert.Equal(t, "debug", reloaded.Log.Level)
+	assert.Equal(t, 5, reloaded.RateLimit.Burst)
+	// la dirección del servidor necesita reiniciar
+	assert.Equal(t, ":8080", reloaded.HTTP.ServerAddress)
+
+	// una configuración no válida no se aplica
+	t.Setenv("RUNNERS_RATE_LIMIT_BURST", "0")
+	require.Error(t, reloader.Reload())
+	assert.Equal(t, 5, reloader.Current().RateLimit.Burst)
+}
+
+func TestValidateTLS(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("RUNNERS_DATABASE_CONNECTION_STRING", "host=db")
+
+	t.Setenv("RUNNERS_HTTP_TLS_CERT_FILE", "/etc/runners/tls.crt")
+	t.Setenv("RUNNERS_HTTP_TLS_CLIENT_AUTH", "require")
+	t.Setenv("RUNNERS_METRICS_TLS_CLIENT_AUTH", "always")
+
+	_, err := Load("runners-test")
+	require.Error(t, err)
+
+	message := err.Error()
+	assert.Contains(t, message, "http.tls.cert_file and key_file must be set together")
+	assert.Contains(t, message, `http.tls.client_ca_file is required with client_auth "require"`)
+	assert.Contains(t, message, `metrics.tls.client_auth "always" is not one of none, optional or require`)
+	assert.Contains(t, message, "metrics.tls.client_auth requires cert_file and key_file")
+}
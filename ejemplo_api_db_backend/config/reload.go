@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Reloader mantiene la configuración en vigor y la recarga al recibir SIGHUP. Solo se aplican los ajustes que se pueden cambiar sin reiniciar (nivel de log y límites de peticiones, salvo su almacenamiento). El resto de cambios se ignoran con un aviso
+type Reloader struct {
+	fileName  string
+	current   atomic.Pointer[Config]
+	mutex     sync.Mutex
+	listeners []func(*Config)
+}
+
+func NewReloader(fileName string, config *Config) *Reloader {
+	reloader := &Reloader{
+		fileName: fileName,
+	}
+	reloader.current.Store(config)
+
+	return reloader
+}
+
+// Current devuelve la configuración en vigor. No hay que modificarla
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registra una función a la que se llama con la nueva configuración cada vez que se recarga
+func (r *Reloader) OnReload(listener func(*Config)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// Reload vuelve a leer la configuración. Si la nueva no es válida se mantiene la que hay
+func (r *Reloader) Reload() error {
+	loaded, err := Load(r.fileName)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// partimos de la configuración en vigor y solo cambiamos los ajustes recargables
+	next := *r.current.Load()
+	next.Log.Level = loaded.Log.Level
+	next.RateLimit = loaded.RateLimit
+	// el almacenamiento de los límites se crea al arrancar
+	next.RateLimit.Store = r.current.Load().RateLimit.Store
+
+	if !reflect.DeepEqual(next, *loaded) {
+		slog.Warn("Configuration changes other than log.level and rate_limit (except rate_limit.store) require a restart and have been ignored")
+	}
+
+	r.current.Store(&next)
+	for _, listener := range r.listeners {
+		listener(&next)
+	}
+
+	return nil
+}
+
+// Watch recarga la configuración cada vez que el proceso recibe SIGHUP, hasta que se cancela el contexto
+func (r *Reloader) Watch(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			slog.Info("SIGHUP received, reloading configuration")
+			err := r.Reload()
+			if err != nil {
+				slog.Error("Configuration not reloaded", "error", err)
+				continue
+			}
+			slog.Info("Configuration reloaded")
+		}
+	}
+}
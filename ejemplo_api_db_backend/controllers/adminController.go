@@ -0,0 +1,420 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"runners-postgresql/admin"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tamaño de las páginas del listado de runners de la consola
+const adminPageSize = 20
+
+// clave del usuario de la sesión en el contexto de Gin
+const adminPrincipalKey = "admin.principal"
+
+// mensajes que se muestran después de cada acción, según su acción del log de auditoría
+var adminMessages = map[string]string{
+	models.AuditRunnerUpdated:   "Runner guardado",
+	models.AuditResultCreated:   "Resultado registrado",
+	models.AuditUserCreated:     "Usuario creado",
+	models.AuditUserRoleChanged: "Rol cambiado",
+}
+
+// Consola de administración. Las páginas requieren una sesión de un usuario con el rol ROLE_ADMIN, que se abre con el mismo login que la API y se guarda en una cookie. Los cambios se registran en el log de auditoría
+type AdminController struct {
+	console        *admin.Console
+	runnersService *services.RunnersService
+	resultsService *services.ResultsService
+	usersService   *services.UsersService
+	clubsService   *services.ClubsService
+	auditService   *services.AuditService
+}
+
+func NewAdminController(console *admin.Console, runnersService *services.RunnersService, resultsService *services.ResultsService, usersService *services.UsersService, clubsService *services.ClubsService, auditService *services.AuditService) *AdminController {
+	return &AdminController{
+		console:        console,
+		runnersService: runnersService,
+		resultsService: resultsService,
+		usersService:   usersService,
+		clubsService:   clubsService,
+		auditService:   auditService,
+	}
+}
+
+// Authenticate es el middleware de las páginas de la consola: el token de acceso de la sesión tiene que ser de un administrador. Sin sesión, o si la sesión se ha cerrado desde la API, redirige al login
+func (ac AdminController) Authenticate(ctx *gin.Context) {
+	accessToken := admin.Session(ctx)
+	if accessToken == "" {
+		ctx.Redirect(http.StatusSeeOther, admin.Prefix+"/login")
+		ctx.Abort()
+		return
+	}
+
+	principal, responseErr := ac.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN})
+	if responseErr != nil && responseErr.Status == http.StatusUnauthorized {
+		admin.ClearSession(ctx)
+		ctx.Redirect(http.StatusSeeOther, admin.Prefix+"/login")
+		ctx.Abort()
+		return
+	}
+	if responseErr != nil {
+		ac.console.RenderError(ctx, nil, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ac.console.RenderError(ctx, nil, &models.ResponseError{
+			Message: "Only administrators can use the admin console",
+			Status:  http.StatusForbidden,
+		})
+		return
+	}
+
+	ctx.Set(adminPrincipalKey, principal)
+	ctx.Next()
+}
+
+func (ac AdminController) LoginForm(ctx *gin.Context) {
+	ac.console.Render(ctx, http.StatusOK, "login", &admin.Page{Title: "Iniciar sesión"})
+}
+
+// Login abre la sesión con el usuario y la contraseña del formulario, con el mismo login que la API. El token de acceso queda en la cookie de la sesión
+func (ac AdminController) Login(ctx *gin.Context) {
+	username := ctx.PostForm("username")
+	renderError := func(responseErr *models.ResponseError) {
+		ac.console.Render(ctx, responseErr.Status, "login", &admin.Page{Title: "Iniciar sesión", Error: responseErr.Message, Data: username})
+	}
+
+	accessToken, responseErr := ac.usersService.Login(ctx.Request.Context(), username, ctx.PostForm("password"))
+	if responseErr != nil {
+		renderError(responseErr)
+		return
+	}
+
+	principal, responseErr := ac.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN})
+	if responseErr == nil && principal == nil {
+		// el usuario existe pero no es administrador: no le dejamos la sesión abierta
+		ac.usersService.Logout(ctx.Request.Context(), accessToken)
+		responseErr = &models.ResponseError{
+			Message: "Only administrators can use the admin console",
+			Status:  http.StatusForbidden,
+		}
+	}
+	if responseErr != nil {
+		renderError(responseErr)
+		return
+	}
+
+	admin.SetSession(ctx, accessToken)
+	ctx.Redirect(http.StatusSeeOther, admin.Prefix+"/runners")
+}
+
+// Logout cierra la sesión, que invalida el token de acceso también en la API
+func (ac AdminController) Logout(ctx *gin.Context) {
+	if accessToken := admin.Session(ctx); accessToken != "" {
+		responseErr := ac.usersService.Logout(ctx.Request.Context(), accessToken)
+		if responseErr != nil {
+			logging.Warn(ctx.Request.Context(), "Failed to close admin session", "error", responseErr.Message)
+		}
+	}
+
+	admin.ClearSession(ctx)
+	ctx.Redirect(http.StatusSeeOther, admin.Prefix+"/login")
+}
+
+func (ac AdminController) Index(ctx *gin.Context) {
+	ctx.Redirect(http.StatusSeeOther, admin.Prefix+"/runners")
+}
+
+type adminRunnersPage struct {
+	Query   string
+	Page    int
+	Total   int
+	HasNext bool
+	Runners []*models.Runner
+}
+
+// GetRunners lista los runners, o los que coinciden con la búsqueda del parámetro q
+func (ac AdminController) GetRunners(ctx *gin.Context) {
+	principal := adminPrincipal(ctx)
+	data := &adminRunnersPage{Query: ctx.Query("q"), Page: queryPage(ctx)}
+	page := &admin.Page{Title: "Runners", Principal: principal, Data: data}
+
+	if data.Query != "" {
+		searchPage, responseErr := ac.runnersService.SearchRunners(ctx.Request.Context(), principal, data.Query, strconv.Itoa(data.Page), strconv.Itoa(adminPageSize))
+		if responseErr != nil {
+			page.Error = responseErr.Message
+			ac.console.Render(ctx, responseErr.Status, "runners", page)
+			return
+		}
+
+		data.Total = searchPage.Total
+		for _, match := range searchPage.Matches {
+			data.Runners = append(data.Runners, match.Runner)
+		}
+	} else {
+		runnerPage, responseErr := ac.runnersService.GetRunnersPage(ctx.Request.Context(), principal, models.RunnersFilter{}, data.Page, adminPageSize)
+		if responseErr != nil {
+			ac.console.RenderError(ctx, principal, responseErr)
+			return
+		}
+
+		data.Total = runnerPage.Total
+		data.Runners = runnerPage.Runners
+	}
+
+	data.HasNext = data.Page*adminPageSize < data.Total
+	ac.console.Render(ctx, http.StatusOK, "runners", page)
+}
+
+type adminRunnerPage struct {
+	Runner *models.Runner
+	Result *models.Result // valores del formulario de un resultado nuevo
+}
+
+// GetRunner muestra el formulario del runner, con sus resultados y el formulario para registrar uno nuevo
+func (ac AdminController) GetRunner(ctx *gin.Context) {
+	ac.renderRunner(ctx, http.StatusOK, nil, nil, "")
+}
+
+// UpdateRunner guarda los datos del formulario del runner
+func (ac AdminController) UpdateRunner(ctx *gin.Context) {
+	principal := adminPrincipal(ctx)
+
+	// los campos numéricos que no son números se convierten en valores no válidos, que rechaza el servicio
+	runner := &models.Runner{
+		ID:        ctx.Param("id"),
+		FirstName: strings.TrimSpace(ctx.PostForm("first_name")),
+		LastName:  strings.TrimSpace(ctx.PostForm("last_name")),
+		Age:       formInt(ctx, "age"),
+		Country:   strings.TrimSpace(ctx.PostForm("country")),
+	}
+
+	responseErr := ac.runnersService.UpdateRunner(ctx.Request.Context(), principal, runner)
+	if responseErr != nil {
+		ac.renderRunner(ctx, responseErr.Status, runner, nil, responseErr.Message)
+		return
+	}
+
+	ac.auditService.Record(ctx.Request.Context(), principal, models.AuditRunnerUpdated, runner.ID,
+		fmt.Sprintf("%s %s, %s, %d", runner.FirstName, runner.LastName, runner.Country, runner.Age))
+	ac.redirectDone(ctx, "/runners/"+url.PathEscape(runner.ID), models.AuditRunnerUpdated)
+}
+
+// CreateResult registra un resultado del runner con los datos del formulario
+func (ac AdminController) CreateResult(ctx *gin.Context) {
+	principal := adminPrincipal(ctx)
+
+	result := &models.Result{
+		RunnerID:   ctx.Param("id"),
+		RaceResult: strings.TrimSpace(ctx.PostForm("race_result")),
+		Location:   strings.TrimSpace(ctx.PostForm("location")),
+		Position:   formInt(ctx, "position"),
+		Year:       formInt(ctx, "year"),
+	}
+	if distance := ctx.PostForm("distance"); distance != "" {
+		value, err := strconv.ParseFloat(distance, 64)
+		if err != nil {
+			value = -1
+		}
+		result.Distance = value
+	}
+
+	created, responseErr := ac.resultsService.CreateResult(ctx.Request.Context(), principal, result)
+	if responseErr != nil {
+		ac.renderRunner(ctx, responseErr.Status, nil, result, responseErr.Message)
+		return
+	}
+
+	ac.auditService.Record(ctx.Request.Context(), principal, models.AuditResultCreated, created.ID,
+		fmt.Sprintf("runner %s: %s en %s %d", created.RunnerID, created.RaceResult, created.Location, created.Year))
+	ac.redirectDone(ctx, "/runners/"+url.PathEscape(result.RunnerID), models.AuditResultCreated)
+}
+
+// renderRunner muestra la página del runner. Si el formulario del runner o el del resultado no se han podido guardar, se muestran con los valores enviados
+func (ac AdminController) renderRunner(ctx *gin.Context, status int, form *models.Runner, result *models.Result, message string) {
+	principal := adminPrincipal(ctx)
+
+	runner, responseErr := ac.runnersService.GetRunner(ctx.Request.Context(), principal, ctx.Param("id"))
+	if responseErr != nil {
+		ac.console.RenderError(ctx, principal, responseErr)
+		return
+	}
+
+	if form != nil {
+		runner.FirstName = form.FirstName
+		runner.LastName = form.LastName
+		runner.Age = form.Age
+		runner.Country = form.Country
+	}
+	if result == nil {
+		result = &models.Result{}
+	}
+
+	ac.console.Render(ctx, status, "runner", &admin.Page{
+		Title:     runner.FirstName + " " + runner.LastName,
+		Principal: principal,
+		Message:   adminMessages[ctx.Query("done")],
+		Error:     message,
+		Data:      &adminRunnerPage{Runner: runner, Result: result},
+	})
+}
+
+type adminUsersPage struct {
+	Users   []*models.User
+	Clubs   []*models.Club
+	NewUser *models.User // valores del formulario de un usuario nuevo
+}
+
+func (ac AdminController) GetUsers(ctx *gin.Context) {
+	ac.renderUsers(ctx, http.StatusOK, nil, "")
+}
+
+// CreateUser da de alta un usuario con contraseña
+func (ac AdminController) CreateUser(ctx *gin.Context) {
+	principal := adminPrincipal(ctx)
+	user := &models.User{
+		Username: strings.TrimSpace(ctx.PostForm("username")),
+		Password: ctx.PostForm("password"),
+		Role:     ctx.PostForm("role"),
+		ClubID:   ctx.PostForm("club_id"),
+	}
+
+	created, responseErr := ac.usersService.CreateUser(ctx.Request.Context(), user)
+	if responseErr != nil {
+		user.Password = ""
+		ac.renderUsers(ctx, responseErr.Status, user, responseErr.Message)
+		return
+	}
+
+	ac.auditService.Record(ctx.Request.Context(), principal, models.AuditUserCreated, created.Username, roleDetails(created.Role, created.ClubID))
+	ac.redirectDone(ctx, "/users", models.AuditUserCreated)
+}
+
+// SetUserRole cambia el rol de un usuario. Un administrador no puede cambiar su propio rol, para que la consola no se quede sin administradores
+func (ac AdminController) SetUserRole(ctx *gin.Context) {
+	principal := adminPrincipal(ctx)
+	username := ctx.Param("username")
+	role := ctx.PostForm("role")
+	// el club solo se guarda para los administradores de club
+	clubId := ""
+	if role == ROLE_CLUB_ADMIN {
+		clubId = ctx.PostForm("club_id")
+	}
+
+	if username == principal.Username {
+		ac.renderUsers(ctx, http.StatusBadRequest, nil, "Cannot change your own role")
+		return
+	}
+
+	responseErr := ac.usersService.SetUserRole(ctx.Request.Context(), username, role, clubId)
+	if responseErr != nil {
+		ac.renderUsers(ctx, responseErr.Status, nil, responseErr.Message)
+		return
+	}
+
+	ac.auditService.Record(ctx.Request.Context(), principal, models.AuditUserRoleChanged, username, roleDetails(role, clubId))
+	ac.redirectDone(ctx, "/users", models.AuditUserRoleChanged)
+}
+
+func (ac AdminController) renderUsers(ctx *gin.Context, status int, newUser *models.User, message string) {
+	principal := adminPrincipal(ctx)
+
+	users, responseErr := ac.usersService.GetAllUsers(ctx.Request.Context())
+	if responseErr != nil {
+		ac.console.RenderError(ctx, principal, responseErr)
+		return
+	}
+
+	clubs, responseErr := ac.clubsService.GetAllClubs(ctx.Request.Context())
+	if responseErr != nil {
+		ac.console.RenderError(ctx, principal, responseErr)
+		return
+	}
+
+	if newUser == nil {
+		newUser = &models.User{Role: models.RoleRunner}
+	}
+
+	ac.console.Render(ctx, status, "users", &admin.Page{
+		Title:     "Usuarios",
+		Principal: principal,
+		Message:   adminMessages[ctx.Query("done")],
+		Error:     message,
+		Data:      &adminUsersPage{Users: users, Clubs: clubs, NewUser: newUser},
+	})
+}
+
+type adminAuditPage struct {
+	*models.AuditPage
+	Username string
+}
+
+// GetAuditLog muestra el log de auditoría, o los cambios de un usuario si se indica en el parámetro username
+func (ac AdminController) GetAuditLog(ctx *gin.Context) {
+	principal := adminPrincipal(ctx)
+	username := ctx.Query("username")
+
+	auditPage, responseErr := ac.auditService.GetEntries(ctx.Request.Context(), username, queryPage(ctx))
+	if responseErr != nil {
+		ac.console.RenderError(ctx, principal, responseErr)
+		return
+	}
+
+	ac.console.Render(ctx, http.StatusOK, "audit", &admin.Page{
+		Title:     "Auditoría",
+		Principal: principal,
+		Data:      &adminAuditPage{AuditPage: auditPage, Username: username},
+	})
+}
+
+// redirectDone redirige a la página después de una acción que ha ido bien, para que recargar la página no la repita
+func (ac AdminController) redirectDone(ctx *gin.Context, path string, action string) {
+	ctx.Redirect(http.StatusSeeOther, admin.Prefix+path+"?done="+action)
+}
+
+func adminPrincipal(ctx *gin.Context) *models.Principal {
+	principal, _ := ctx.Get(adminPrincipalKey)
+	return principal.(*models.Principal)
+}
+
+// queryPage devuelve la página del parámetro page. Las páginas no válidas son la primera, y las que pasan de la última que se puede pedir son esa última
+func queryPage(ctx *gin.Context) int {
+	page, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil || page < 1 {
+		return 1
+	}
+
+	return min(page, services.MaxPage)
+}
+
+// formInt devuelve el número del campo del formulario, 0 si está vacío o -1 si no es un número
+func formInt(ctx *gin.Context, field string) int {
+	value := ctx.PostForm(field)
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+
+	return number
+}
+
+func roleDetails(role string, clubId string) string {
+	if clubId == "" {
+		return role
+	}
+
+	return role + ", club " + clubId
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runners-postgresql/admin"
+	"runners-postgresql/repositories"
+	"runners-postgresql/services"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminConsole(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+
+	console, err := admin.New()
+	require.NoError(t, err)
+
+	usersService := services.NewUsersService(repositories.NewUsersRepository(dbHandler), nil, nil)
+	runnersService := services.NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+	auditService := services.NewAuditService(repositories.NewAuditRepository(dbHandler))
+	adminController := NewAdminController(console, runnersService, nil, usersService, nil, auditService)
+
+	router := gin.New()
+	group := router.Group(admin.Prefix, admin.CSRF())
+	group.GET("/login", adminController.LoginForm)
+	group.POST("/login", adminController.Login)
+	pages := group.Group("", adminController.Authenticate)
+	pages.GET("/runners", adminController.GetRunners)
+	pages.POST("/runners/:id", adminController.UpdateRunner)
+
+	// el navegador guarda las cookies de las respuestas y las envía en las peticiones
+	cookies := make(map[string]*http.Cookie)
+	send := func(method string, path string, form url.Values) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		for _, cookie := range recorder.Result().Cookies() {
+			cookies[cookie.Name] = cookie
+		}
+		return recorder
+	}
+
+	// sin sesión, las páginas redirigen al login
+	recorder := send("GET", "/admin/runners", nil)
+	assert.Equal(t, http.StatusSeeOther, recorder.Code)
+	assert.Equal(t, "/admin/login", recorder.Header().Get("Location"))
+
+	// el formulario de login lleva el token CSRF de la cookie
+	recorder = send("GET", "/admin/login", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	csrfToken := cookies["admin_csrf"].Value
+	assert.Contains(t, recorder.Body.String(), `value="`+csrfToken+`"`)
+
+	// sin el token CSRF no se procesa el formulario
+	recorder = send("POST", "/admin/login", url.Values{"username": {"admin"}, "password": {"admin"}})
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	// un runner no puede usar la consola, y su sesión no queda abierta
+	mock.ExpectQuery("SELECT id").WithArgs("runner", "runner").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+	mock.ExpectExec("UPDATE users SET access_token").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}).
+		AddRow("2", "runner", "runner", nil, nil))
+	mock.ExpectExec("UPDATE users SET access_token = ''").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	recorder = send("POST", "/admin/login", url.Values{"username": {"runner"}, "password": {"runner"}, admin.CSRFField: {csrfToken}})
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Nil(t, cookies["admin_session"])
+
+	// el administrador abre la sesión con el mismo login que la API
+	mock.ExpectQuery("SELECT id").WithArgs("admin", "admin").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	mock.ExpectExec("UPDATE users SET access_token").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}).
+		AddRow("1", "admin", "admin", nil, nil))
+
+	recorder = send("POST", "/admin/login", url.Values{"username": {"admin"}, "password": {"admin"}, admin.CSRFField: {csrfToken}})
+	require.Equal(t, http.StatusSeeOther, recorder.Code)
+	assert.Equal(t, "/admin/runners", recorder.Header().Get("Location"))
+	session := cookies["admin_session"]
+	require.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, session.SameSite)
+
+	// los cambios se registran en el log de auditoría con el usuario de la sesión
+	runnerId := "5f8d0d55-b3c6-4b7f-9d9f-3a5a0c6c2a11"
+	mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}).
+		AddRow("1", "admin", "admin", nil, nil))
+	mock.ExpectExec("UPDATE runners").WithArgs("John", "Smith", 31, "United States", runnerId).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").WithArgs("admin", "runner.updated", runnerId, "John Smith, United States, 31").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	form := url.Values{"first_name": {"John"}, "last_name": {"Smith"}, "age": {"31"}, "country": {"United States"}, admin.CSRFField: {csrfToken}}
+	recorder = send("POST", "/admin/runners/"+runnerId, form)
+	require.Equal(t, http.StatusSeeOther, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "/admin/runners/"+runnerId+"?done=runner.updated", recorder.Header().Get("Location"))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// un formulario enviado desde otra web no se acepta aunque lleve la cookie
+	request := httptest.NewRequest("POST", "/admin/runners/"+runnerId, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Origin", "https://attacker.example.com")
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
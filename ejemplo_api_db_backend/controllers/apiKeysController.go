@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Administración de las claves de API de los sistemas externos. Todos los recursos requieren el rol ROLE_ADMIN, y no se pueden usar con una clave de API
+type APIKeysController struct {
+	apiKeysService *services.APIKeysService
+	usersService   *services.UsersService
+}
+
+func NewAPIKeysController(apiKeysService *services.APIKeysService, usersService *services.UsersService) *APIKeysController {
+	return &APIKeysController{
+		apiKeysService: apiKeysService,
+		usersService:   usersService,
+	}
+}
+
+// CreateAPIKey crea una clave. La respuesta es la única vez que se devuelve la clave completa
+func (ac APIKeysController) CreateAPIKey(ctx *gin.Context) {
+	if !ac.authorizeAdmin(ctx) {
+		return
+	}
+
+	var apiKey models.APIKey
+	if !readBody(ctx, "create API key", &apiKey) {
+		return
+	}
+
+	response, responseErr := ac.apiKeysService.CreateAPIKey(ctx.Request.Context(), &apiKey)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (ac APIKeysController) GetAllAPIKeys(ctx *gin.Context) {
+	if !ac.authorizeAdmin(ctx) {
+		return
+	}
+
+	response, responseErr := ac.apiKeysService.GetAllAPIKeys(ctx.Request.Context())
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ac APIKeysController) RevokeAPIKey(ctx *gin.Context) {
+	if !ac.authorizeAdmin(ctx) {
+		return
+	}
+
+	responseErr := ac.apiKeysService.RevokeAPIKey(ctx.Request.Context(), ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ac APIKeysController) authorizeAdmin(ctx *gin.Context) bool {
+	accessToken := ctx.Request.Header.Get("Token")
+	auth, responseErr := ac.usersService.AuthorizeUser(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return false
+	}
+
+	if !auth {
+		ctx.Status(http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Clubs, pertenencia de los runners a los clubs y clasificaciones. Los administradores de club (ROLE_CLUB_ADMIN) solo pueden administrar su club
+type ClubsController struct {
+	clubsService *services.ClubsService
+	usersService *services.UsersService
+}
+
+func NewClubsController(clubsService *services.ClubsService, usersService *services.UsersService) *ClubsController {
+	return &ClubsController{
+		clubsService: clubsService,
+		usersService: usersService,
+	}
+}
+
+func (cc ClubsController) CreateClub(ctx *gin.Context) {
+	_, ok := cc.authenticate(ctx, ROLE_ADMIN)
+	if !ok {
+		return
+	}
+
+	var club models.Club
+	if !readBody(ctx, "create club", &club) {
+		return
+	}
+
+	response, responseErr := cc.clubsService.CreateClub(ctx.Request.Context(), &club)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (cc ClubsController) UpdateClub(ctx *gin.Context) {
+	principal, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN)
+	if !ok {
+		return
+	}
+
+	var club models.Club
+	if !readBody(ctx, "update club", &club) {
+		return
+	}
+	club.ID = ctx.Param("id")
+
+	responseErr := cc.clubsService.UpdateClub(ctx.Request.Context(), principal, &club)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (cc ClubsController) GetClub(ctx *gin.Context) {
+	_, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER)
+	if !ok {
+		return
+	}
+
+	response, responseErr := cc.clubsService.GetClub(ctx.Request.Context(), ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (cc ClubsController) GetAllClubs(ctx *gin.Context) {
+	_, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER)
+	if !ok {
+		return
+	}
+
+	response, responseErr := cc.clubsService.GetAllClubs(ctx.Request.Context())
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetMembers lista los miembros actuales del club. Con history=true incluye también a los antiguos miembros
+func (cc ClubsController) GetMembers(ctx *gin.Context) {
+	_, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER)
+	if !ok {
+		return
+	}
+
+	response, responseErr := cc.clubsService.GetClubMembers(ctx.Request.Context(), ctx.Param("id"), ctx.Query("history"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (cc ClubsController) AddMember(ctx *gin.Context) {
+	principal, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN)
+	if !ok {
+		return
+	}
+
+	var membership models.ClubMembership
+	if !readBody(ctx, "add club member", &membership) {
+		return
+	}
+
+	response, responseErr := cc.clubsService.AddMember(ctx.Request.Context(), principal, ctx.Param("id"), &membership)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (cc ClubsController) RemoveMember(ctx *gin.Context) {
+	principal, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN)
+	if !ok {
+		return
+	}
+
+	responseErr := cc.clubsService.RemoveMember(ctx.Request.Context(), principal, ctx.Param("id"), ctx.Param("runner"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetLeaderboard devuelve la clasificación del club. Admite los query parameters year y limit
+func (cc ClubsController) GetLeaderboard(ctx *gin.Context) {
+	_, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER)
+	if !ok {
+		return
+	}
+
+	response, responseErr := cc.clubsService.GetLeaderboard(ctx.Request.Context(), ctx.Param("id"), ctx.Query("year"), ctx.Query("limit"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// SetClubAdmin nombra administrador del club a un usuario existente
+func (cc ClubsController) SetClubAdmin(ctx *gin.Context) {
+	_, ok := cc.authenticate(ctx, ROLE_ADMIN)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if !readBody(ctx, "set club admin", &user) {
+		return
+	}
+
+	club, responseErr := cc.clubsService.GetClub(ctx.Request.Context(), ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	responseErr = cc.usersService.SetClubAdmin(ctx.Request.Context(), user.Username, club.ID)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetRunnerClubs devuelve el historial de clubs de un runner
+func (cc ClubsController) GetRunnerClubs(ctx *gin.Context) {
+	_, ok := cc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER)
+	if !ok {
+		return
+	}
+
+	response, responseErr := cc.clubsService.GetRunnerMemberships(ctx.Request.Context(), ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (cc ClubsController) authenticate(ctx *gin.Context, roles ...string) (*models.Principal, bool) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := cc.usersService.Authenticate(ctx.Request.Context(), accessToken, roles)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return nil, false
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return principal, true
+}
+
+// respondError responde con el error de un servicio. Los errores internos se escriben en el log de la petición, porque el cliente solo recibe el mensaje
+func respondError(ctx *gin.Context, responseErr *models.ResponseError) {
+	if responseErr.Status >= http.StatusInternalServerError {
+		logging.Error(ctx.Request.Context(), "Request failed", "status", responseErr.Status, "error", responseErr.Message)
+	} else {
+		logging.Debug(ctx.Request.Context(), "Request rejected", "status", responseErr.Status, "error", responseErr.Message)
+	}
+
+	ctx.AbortWithStatusJSON(responseErr.Status, responseErr)
+}
+
+func readBody(ctx *gin.Context, operation string, dest interface{}) bool {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		logging.Error(ctx.Request.Context(), "Error while reading request body", "operation", operation, "error", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return false
+	}
+
+	err = json.Unmarshal(body, dest)
+	if err != nil {
+		logging.Error(ctx.Request.Context(), "Error while unmarshaling request body", "operation", operation, "error", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return false
+	}
+
+	return true
+}
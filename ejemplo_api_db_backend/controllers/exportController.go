@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"net/http"
+	"runners-postgresql/export"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cada cuantas filas volcamos lo exportado al cliente
+const exportFlushRows = 100
+
+// trailer con el que informamos de un error que se produzca a mitad de la exportación, cuando ya no podemos cambiar el status code
+const exportErrorTrailer = "X-Export-Error"
+
+type ExportController struct {
+	exportService *services.ExportService
+	usersService  *services.UsersService
+}
+
+func NewExportController(exportService *services.ExportService, usersService *services.UsersService) *ExportController {
+	return &ExportController{
+		exportService: exportService,
+		usersService:  usersService,
+	}
+}
+
+func (ec ExportController) ExportRunners(ctx *gin.Context) {
+	principal, format, ok := ec.authorizeExport(ctx)
+	if !ok {
+		return
+	}
+
+	params := ctx.Request.URL.Query()
+	filter, responseErr := ec.exportService.RunnersFilter(principal, params.Get("country"), params.Get("year"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	stream(ctx, format, "runners", export.RunnerSchema, func(encode func([]interface{}) error) *models.ResponseError {
+		return ec.exportService.ExportRunners(ctx.Request.Context(), filter, func(runner *models.Runner) error {
+			return encode(export.RunnerRow(runner))
+		})
+	})
+}
+
+func (ec ExportController) ExportResults(ctx *gin.Context) {
+	principal, format, ok := ec.authorizeExport(ctx)
+	if !ok {
+		return
+	}
+
+	params := ctx.Request.URL.Query()
+	filter, responseErr := ec.exportService.ResultsFilter(principal, params.Get("runner"), params.Get("year"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	stream(ctx, format, "results", export.ResultSchema, func(encode func([]interface{}) error) *models.ResponseError {
+		return ec.exportService.ExportResults(ctx.Request.Context(), filter, func(result *models.Result) error {
+			return encode(export.ResultRow(result))
+		})
+	})
+}
+
+// comprueba el token y elige el formato de la exportación. Si algo falla ya ha respondido al cliente
+func (ec ExportController) authorizeExport(ctx *gin.Context) (*models.Principal, export.Format, bool) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := ec.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return nil, export.Format{}, false
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return nil, export.Format{}, false
+	}
+
+	// el query parameter format tiene prioridad sobre la cabecera Accept
+	format, ok := export.NegotiateFormat(ctx.Query("format"), ctx.Request.Header.Get("Accept"))
+	if !ok {
+		ctx.JSON(http.StatusNotAcceptable, &models.ResponseError{
+			Message: "Unsupported export format",
+			Status:  http.StatusNotAcceptable,
+		})
+		return nil, export.Format{}, false
+	}
+
+	return principal, format, true
+}
+
+// stream escribe la exportación en la respuesta usando chunked encoding. La función rows recibe la función con la que serializar cada fila
+func stream(ctx *gin.Context, format export.Format, name string, schema export.Schema, rows func(encode func([]interface{}) error) *models.ResponseError) {
+	ctx.Header("Content-Type", format.ContentType)
+	ctx.Header("Content-Disposition", "attachment; filename=\""+name+"."+format.Extension+"\"")
+	// anunciamos el trailer en el que informaremos de los errores que se produzcan a mitad de la exportación
+	ctx.Header("Trailer", exportErrorTrailer)
+	ctx.Status(http.StatusOK)
+
+	encoder := format.NewEncoder(ctx.Writer)
+	err := encoder.Begin(schema)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	count := 0
+	responseErr := rows(func(row []interface{}) error {
+		err := encoder.Encode(row)
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushRows == 0 {
+			// volcamos lo serializado y enviamos un chunk al cliente
+			err = encoder.Flush()
+			if err != nil {
+				return err
+			}
+			ctx.Writer.Flush()
+		}
+
+		return nil
+	})
+
+	if responseErr != nil {
+		logging.Error(ctx.Request.Context(), "Error while exporting", "export", name, "error", responseErr.Message)
+		// si todavía no hemos escrito nada podemos responder con el error
+		if !ctx.Writer.Written() {
+			for _, header := range []string{"Content-Type", "Content-Disposition", "Trailer"} {
+				ctx.Writer.Header().Del(header)
+			}
+			respondError(ctx, responseErr)
+			return
+		}
+		ctx.Writer.Header().Set(exportErrorTrailer, responseErr.Message)
+		return
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		logging.Error(ctx.Request.Context(), "Error while closing export", "export", name, "error", err)
+		ctx.Writer.Header().Set(exportErrorTrailer, err.Error())
+		return
+	}
+	ctx.Writer.Flush()
+}
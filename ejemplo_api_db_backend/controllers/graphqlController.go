@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"net/http"
+	"runners-postgresql/gql"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoint de GraphQL. Las consultas se ejecutan con los permisos del usuario del token, igual que en la API REST
+type GraphQLController struct {
+	schema       *gql.Schema
+	usersService *services.UsersService
+}
+
+func NewGraphQLController(schema *gql.Schema, usersService *services.UsersService) *GraphQLController {
+	return &GraphQLController{
+		schema:       schema,
+		usersService: usersService,
+	}
+}
+
+func (gc GraphQLController) Query(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	// las mutaciones comprueban además que el rol sea de administrador o de administrador de club
+	principal, responseErr := gc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var request gql.Request
+	if !readBody(ctx, "graphql", &request) {
+		return
+	}
+
+	if request.Query == "" {
+		respondError(ctx, &models.ResponseError{
+			Message: "Missing query",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	// como en cualquier servidor de GraphQL, los errores de la consulta van en el campo errors de una respuesta 200
+	ctx.JSON(http.StatusOK, gc.schema.Execute(ctx.Request.Context(), principal, request))
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+	"runners-postgresql/health"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Endpoints de salud para Kubernetes (/healthz y /readyz) y el informe detallado para los administradores (/health)
+type HealthController struct {
+	registry     *health.Registry
+	usersService *services.UsersService
+}
+
+func NewHealthController(registry *health.Registry, usersService *services.UsersService) *HealthController {
+	return &HealthController{
+		registry:     registry,
+		usersService: usersService,
+	}
+}
+
+// Liveness responde mientras el proceso es capaz de atender peticiones. No comprueba las dependencias: si la base de datos cae, reiniciar el pod no lo arregla
+func (hc HealthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readiness indica si la aplicación puede recibir tráfico
+func (hc HealthController) Readiness(ctx *gin.Context) {
+	report := hc.registry.Readiness(ctx.Request.Context())
+	ctx.JSON(reportStatus(report), report)
+}
+
+// Health devuelve el resultado y la latencia de todas las comprobaciones. Solo para administradores
+func (hc HealthController) Health(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	auth, responseErr := hc.usersService.AuthorizeUser(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if !auth {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	report := hc.registry.Detailed(ctx.Request.Context())
+	ctx.JSON(reportStatus(report), report)
+}
+
+func reportStatus(report *health.Report) int {
+	if report.Status != health.StatusUp {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
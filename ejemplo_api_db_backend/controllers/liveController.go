@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runners-postgresql/live"
+	"runners-postgresql/logging"
+	"runners-postgresql/metrics"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// número de eventos que se reenvían por defecto a un nuevo suscriptor
+const defaultLiveReplay = 20
+
+// tiempo máximo para escribir un mensaje en un websocket
+const liveWriteTimeout = 10 * time.Second
+
+// Feed de resultados en directo. Un mismo recurso sirve Server-Sent Events y, si la petición pide el upgrade, WebSocket
+type LiveController struct {
+	hub            *live.Hub
+	usersService   *services.UsersService
+	runnersService *services.RunnersService
+	heartbeat      time.Duration
+	upgrader       websocket.Upgrader
+}
+
+func NewLiveController(hub *live.Hub, usersService *services.UsersService, runnersService *services.RunnersService, heartbeat time.Duration) *LiveController {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+
+	return &LiveController{
+		hub:            hub,
+		usersService:   usersService,
+		runnersService: runnersService,
+		heartbeat:      heartbeat,
+		upgrader: websocket.Upgrader{
+			// el feed se consume desde la web del club, que está en otro origen. La autorización la da el token
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (lc LiveController) Results(ctx *gin.Context) {
+	// EventSource y WebSocket no permiten fijar cabeceras desde el navegador, así que el token también se acepta como query parameter
+	accessToken := ctx.Request.Header.Get("Token")
+	if accessToken == "" {
+		accessToken = ctx.Query("token")
+	}
+
+	// necesitamos saber quién se suscribe para aplicar las preferencias de privacidad de los runners a cada evento
+	principal, responseErr := lc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	filter := live.Filter{
+		Race:     ctx.Query("race"),
+		RunnerID: ctx.Query("runner"),
+	}
+
+	// al reconectar, EventSource envía el id del último evento recibido en la cabecera Last-Event-ID
+	lastEventId := ctx.Request.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = ctx.Query("last_event_id")
+	}
+	lastSeq, _ := strconv.ParseUint(lastEventId, 10, 64)
+
+	replay := defaultLiveReplay
+	if value := ctx.Query("replay"); value != "" {
+		replay, _ = strconv.Atoi(value)
+	}
+
+	if websocket.IsWebSocketUpgrade(ctx.Request) {
+		lc.serveWebSocket(ctx, principal, filter, lastSeq, replay)
+		return
+	}
+
+	lc.serveSSE(ctx, principal, filter, lastSeq, replay)
+}
+
+func (lc LiveController) serveSSE(ctx *gin.Context, principal *models.Principal, filter live.Filter, lastSeq uint64, replay int) {
+	subscription := lc.hub.Subscribe(filter, lastSeq, replay)
+	defer subscription.Close()
+
+	metrics.LiveConnections.WithLabelValues("sse").Inc()
+	defer metrics.LiveConnections.WithLabelValues("sse").Dec()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// evitamos que un proxy nginx acumule los eventos
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(lc.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			// los comentarios mantienen viva la conexión a través de proxies
+			fmt.Fprint(ctx.Writer, ": ping\n\n")
+			ctx.Writer.Flush()
+		case message, ok := <-subscription.C():
+			if !ok {
+				if subscription.Err() == live.ErrSlowConsumer {
+					metrics.LiveSlowConsumersCounter.WithLabelValues("sse").Inc()
+					fmt.Fprint(ctx.Writer, "event: error\ndata: {\"message\":\"slow consumer\"}\n\n")
+					ctx.Writer.Flush()
+				}
+				return
+			}
+
+			if !lc.visible(ctx, principal, message) {
+				continue
+			}
+
+			data, err := json.Marshal(message)
+			if err != nil {
+				logging.Error(ctx.Request.Context(), "Error while marshaling live message", "error", err)
+				continue
+			}
+
+			fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", message.Seq, message.Type, data)
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+func (lc LiveController) serveWebSocket(ctx *gin.Context, principal *models.Principal, filter live.Filter, lastSeq uint64, replay int) {
+	conn, err := lc.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// Upgrade ya ha respondido al cliente con el error
+		logging.Warn(ctx.Request.Context(), "Error while upgrading live connection", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// la conexión secuestrada conserva el plazo de lectura del servidor HTTP. El cliente solo envía mensajes de control, así que lo quitamos
+	conn.SetReadDeadline(time.Time{})
+
+	subscription := lc.hub.Subscribe(filter, lastSeq, replay)
+	defer subscription.Close()
+
+	metrics.LiveConnections.WithLabelValues("websocket").Inc()
+	defer metrics.LiveConnections.WithLabelValues("websocket").Dec()
+
+	// el cliente no envía nada, pero hay que leer para procesar los mensajes de control (pong, close)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			_, _, err := conn.NextReader()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(lc.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout))
+			if err != nil {
+				return
+			}
+		case message, ok := <-subscription.C():
+			if !ok {
+				if subscription.Err() == live.ErrSlowConsumer {
+					metrics.LiveSlowConsumersCounter.WithLabelValues("websocket").Inc()
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"),
+						time.Now().Add(liveWriteTimeout))
+				}
+				if subscription.Err() == live.ErrShutdown {
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+						time.Now().Add(liveWriteTimeout))
+				}
+				return
+			}
+
+			if !lc.visible(ctx, principal, message) {
+				continue
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			err := conn.WriteJSON(message)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// visible indica si el evento se puede enviar al suscriptor. Los eventos llevan las marcas del runner, así que los demás runners no reciben los de los runners que ocultan sus resultados. Si no se puede comprobar, el evento no se envía
+func (lc LiveController) visible(ctx *gin.Context, principal *models.Principal, message *live.Message) bool {
+	visible, responseErr := lc.runnersService.CanSeeResults(ctx.Request.Context(), principal, message.RunnerID)
+	if responseErr != nil {
+		logging.Error(ctx.Request.Context(), "Error while checking runner privacy", "runner_id", message.RunnerID, "error", responseErr.Message)
+		return false
+	}
+
+	return visible
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cookie con el state del login, que asocia el callback con el navegador que inició el login
+const oidcStateCookie = "oidc_state"
+
+type OIDCController struct {
+	oidcService *services.OIDCService
+}
+
+// NewOIDCController crea el controlador del login con OpenID Connect. Sin servicio, porque el login no está activado, las rutas responden 404
+func NewOIDCController(oidcService *services.OIDCService) *OIDCController {
+	return &OIDCController{
+		oidcService: oidcService,
+	}
+}
+
+// Login redirige al usuario al proveedor de identidad para que inicie sesión
+func (oc OIDCController) Login(ctx *gin.Context) {
+	if oc.oidcService == nil {
+		respondError(ctx, oidcDisabled())
+		return
+	}
+
+	authURL, state, responseErr := oc.oidcService.StartLogin(ctx.Request.Context())
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, state, 0, "/auth/oidc", "", ctx.Request.TLS != nil, true)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// Callback recibe la respuesta del proveedor de identidad y devuelve el token de acceso, como el login con usuario y contraseña
+func (oc OIDCController) Callback(ctx *gin.Context) {
+	if oc.oidcService == nil {
+		respondError(ctx, oidcDisabled())
+		return
+	}
+
+	// el state solo se usa una vez
+	state, _ := ctx.Cookie(oidcStateCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", ctx.Request.TLS != nil, true)
+
+	// el usuario ha cancelado el login o el proveedor no le deja entrar
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		logging.Warn(ctx.Request.Context(), "Identity provider denied the login", "error", providerErr, "description", ctx.Query("error_description"))
+		respondError(ctx, &models.ResponseError{
+			Message: "Login denied by identity provider: " + providerErr,
+			Status:  http.StatusUnauthorized,
+		})
+		return
+	}
+
+	if state == "" || state != ctx.Query("state") {
+		respondError(ctx, &models.ResponseError{
+			Message: "Invalid state",
+			Status:  http.StatusBadRequest,
+		})
+		return
+	}
+
+	accessToken, responseErr := oc.oidcService.FinishLogin(ctx.Request.Context(), ctx.Query("code"), state)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accessToken)
+}
+
+func oidcDisabled() *models.ResponseError {
+	return &models.ResponseError{
+		Message: "OIDC login is not enabled",
+		Status:  http.StatusNotFound,
+	}
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runners-postgresql/oidc"
+	"runners-postgresql/oidc/oidctest"
+	"runners-postgresql/openapi"
+	"runners-postgresql/repositories"
+	"runners-postgresql/services"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+
+	// proveedor de identidad local, con un cliente confidencial
+	idp := oidctest.NewServer("runners", "secret")
+	defer idp.Close()
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     "runners",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/auth/oidc/callback",
+		Scopes:       []string{"openid", "profile"},
+		Timeout:      time.Second,
+	})
+	usersService := services.NewUsersService(repositories.NewUsersRepository(dbHandler), nil, nil)
+	oidcService := services.NewOIDCService(provider, nil, usersService, services.OIDCConfig{
+		RoleClaim: "groups",
+		Roles:     []services.OIDCRole{{Value: "club-7-admins", Role: "club_admin", ClubID: "7"}},
+		StateTTL:  time.Minute,
+	})
+	router := initOIDCTestRouter(t, NewOIDCController(oidcService))
+
+	// el navegador no sigue las redirecciones para poder comprobarlas
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// login sigue el flujo completo: la aplicación redirige al proveedor, el proveedor redirige al callback con el código, y el callback devuelve el token
+	login := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+		require.Equal(t, http.StatusFound, recorder.Code)
+		cookies := recorder.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.True(t, cookies[0].HttpOnly)
+
+		response, err := browser.Get(recorder.Header().Get("Location"))
+		require.NoError(t, err)
+		response.Body.Close()
+		require.Equal(t, http.StatusFound, response.StatusCode)
+		callback, err := response.Location()
+		require.NoError(t, err)
+		assert.Equal(t, "/auth/oidc/callback", callback.Path)
+
+		request := httptest.NewRequest("GET", callback.RequestURI(), nil)
+		request.AddCookie(cookies[0])
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// el usuario se da de alta con el rol de su grupo y recibe un token de acceso
+	idp.SetUser(map[string]interface{}{"sub": "1234", "preferred_username": "jdoe", "groups": []string{"runners", "club-7-admins"}})
+	mock.ExpectQuery("INSERT INTO users").WithArgs("jdoe", "club_admin", "7", idp.URL, "1234").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "access_token"}).AddRow("1", ""))
+	mock.ExpectExec("UPDATE users SET access_token").WithArgs(sqlmock.AnyArg(), "1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	recorder := login()
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var accessToken string
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &accessToken))
+	assert.NotEmpty(t, accessToken)
+
+	// sin ninguno de los grupos y sin rol por defecto no puede entrar
+	idp.SetUser(map[string]interface{}{"sub": "5678", "groups": []string{"runners"}})
+	recorder = login()
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	// sin la cookie del navegador que inició el login, el callback no se acepta
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/oidc/callback?code=code&state=state", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// el usuario cancela el login en el proveedor
+	idp.SetUser(nil)
+	recorder = login()
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// sin el login activado las rutas no existen
+	recorder = httptest.NewRecorder()
+	initOIDCTestRouter(t, NewOIDCController(nil)).ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func initOIDCTestRouter(t *testing.T, oidcController *OIDCController) *gin.Engine {
+	router := gin.New()
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+	router.Use(openapi.Middleware(doc, openapi.Options{
+		ValidateResponses: true,
+		OnResponseError: func(ctx *gin.Context, err error) {
+			t.Errorf("%s %s: %v", ctx.Request.Method, ctx.FullPath(), err)
+		},
+	}))
+	router.GET("/auth/oidc/login", oidcController.Login)
+	router.GET("/auth/oidc/callback", oidcController.Callback)
+
+	return router
+}
@@ -3,8 +3,8 @@ package controllers
 import (
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
+	"runners-postgresql/logging"
 	"runners-postgresql/models"
 	"runners-postgresql/services"
 
@@ -32,15 +32,15 @@ func (rc ResultsController) CreateResult(ctx *gin.Context) {
 	// recuperamos una cabecera de la petición
 	accessToken := ctx.Request.Header.Get("Token")
 
-	// verificamos que el token tenga asociado el role ROLE_ADMIN
-	auth, responseErr := rc.usersService.AuthorizeUser(accessToken, []string{ROLE_ADMIN})
+	// verificamos que el token tenga asociado el role ROLE_ADMIN o ROLE_CLUB_ADMIN. Con ROLE_CLUB_ADMIN el servicio comprueba además que el runner sea del club del usuario
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
 	if responseErr != nil {
 		// contruye una respuesta con el http status code y el payload
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
-	if !auth {
+	if principal == nil {
 		// responde con el http status code
 		ctx.Status(http.StatusUnauthorized)
 		return
@@ -48,7 +48,7 @@ func (rc ResultsController) CreateResult(ctx *gin.Context) {
 
 	body, err := io.ReadAll(ctx.Request.Body)
 	if err != nil {
-		log.Println("Error while reading create result request body", err)
+		logging.Error(ctx.Request.Context(), "Error while reading create result request body", "error", err)
 		// responde con el http status code y un payload, y detiene la ejecución del handler
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -57,40 +57,160 @@ func (rc ResultsController) CreateResult(ctx *gin.Context) {
 	var result models.Result
 	err = json.Unmarshal(body, &result)
 	if err != nil {
-		log.Println("Error while unmarshaling create result request body", err)
+		logging.Error(ctx.Request.Context(), "Error while unmarshaling create result request body", "error", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	response, responseErr := rc.resultsService.CreateResult(&result)
+	response, responseErr := rc.resultsService.CreateResult(ctx.Request.Context(), principal, &result)
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
+// CreateResults crea varios resultados en una petición. Responde 207 con el estado de cada resultado
+func (rc ResultsController) CreateResults(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var batch models.ResultsBatch
+	if !readBody(ctx, "create results batch", &batch) {
+		return
+	}
+
+	response, responseErr := rc.resultsService.CreateResults(ctx.Request.Context(), principal, &batch)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusMultiStatus, response)
+}
+
 func (rc ResultsController) DeleteResult(ctx *gin.Context) {
 	accessToken := ctx.Request.Header.Get("Token")
-	auth, responseErr := rc.usersService.AuthorizeUser(accessToken, []string{ROLE_ADMIN})
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
-	if !auth {
+	if principal == nil {
 		ctx.Status(http.StatusUnauthorized)
 		return
 	}
 
 	resultId := ctx.Param("id")
 
-	responseErr = rc.resultsService.DeleteResult(resultId)
+	responseErr = rc.resultsService.DeleteResult(ctx.Request.Context(), principal, resultId)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// SubmitResult registra un resultado del propio runner. Queda pendiente hasta que un administrador lo apruebe
+func (rc ResultsController) SubmitResult(ctx *gin.Context) {
+	principal, ok := rc.authenticate(ctx, ROLE_RUNNER)
+	if !ok {
+		return
+	}
+
+	var result models.Result
+	if !readBody(ctx, "submit result", &result) {
+		return
+	}
+
+	response, responseErr := rc.resultsService.SubmitResult(ctx.Request.Context(), principal, &result)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, response)
+}
+
+// GetSubmissions devuelve la cola de resultados enviados por los runners. Admite el query parameter status, por defecto pending
+func (rc ResultsController) GetSubmissions(ctx *gin.Context) {
+	principal, ok := rc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN)
+	if !ok {
+		return
+	}
+
+	response, responseErr := rc.resultsService.GetSubmissions(ctx.Request.Context(), principal, ctx.Query("status"))
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ApproveResult aprueba un resultado pendiente y lo da de alta como un resultado más
+func (rc ResultsController) ApproveResult(ctx *gin.Context) {
+	principal, ok := rc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN)
+	if !ok {
+		return
+	}
+
+	response, responseErr := rc.resultsService.ApproveResult(ctx.Request.Context(), principal, ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// RejectResult rechaza un resultado pendiente. El body con el motivo es opcional
+func (rc ResultsController) RejectResult(ctx *gin.Context) {
+	principal, ok := rc.authenticate(ctx, ROLE_ADMIN, ROLE_CLUB_ADMIN)
+	if !ok {
+		return
+	}
+
+	var review struct {
+		Reason string `json:"reason"`
+	}
+	if ctx.Request.ContentLength != 0 && !readBody(ctx, "reject result", &review) {
+		return
+	}
+
+	responseErr := rc.resultsService.RejectResult(ctx.Request.Context(), principal, ctx.Param("id"), review.Reason)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
 		return
 	}
 
 	ctx.Status(http.StatusNoContent)
 }
+
+func (rc ResultsController) authenticate(ctx *gin.Context, roles ...string) (*models.Principal, bool) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, roles)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return nil, false
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return principal, true
+}
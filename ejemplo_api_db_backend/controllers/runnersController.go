@@ -2,10 +2,9 @@ package controllers
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"runners-postgresql/logging"
 	"runners-postgresql/metrics"
 	"runners-postgresql/models"
 	"runners-postgresql/services"
@@ -17,6 +16,7 @@ import (
 
 const ROLE_ADMIN = "admin"
 const ROLE_RUNNER = "runner"
+const ROLE_CLUB_ADMIN = models.RoleClubAdmin
 
 type RunnersController struct {
 	runnersService *services.RunnersService
@@ -31,24 +31,22 @@ func NewRunnersController(runnersService *services.RunnersService, usersService
 }
 
 func (rc RunnersController) CreateRunner(ctx *gin.Context) {
-	// actualizamos la metrica de contador de peticiones HTTP cada vez que se recibe una solicitud en el endpoint create runner
-	metrics.HttpRequestsCounter.Inc()
-
 	accessToken := ctx.Request.Header.Get("Token")
-	auth, responseErr := rc.usersService.AuthorizeUser(accessToken, []string{ROLE_ADMIN})
+	// los administradores de club solo pueden modificar a los runners de su club, lo comprueba el servicio
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
-	if !auth {
+	if principal == nil {
 		ctx.Status(http.StatusUnauthorized)
 		return
 	}
 
 	body, err := io.ReadAll(ctx.Request.Body)
 	if err != nil {
-		log.Println("Error while reading create runner request body", err)
+		logging.Error(ctx.Request.Context(), "Error while reading create runner request body", "error", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
@@ -56,40 +54,66 @@ func (rc RunnersController) CreateRunner(ctx *gin.Context) {
 	var runner models.Runner
 	err = json.Unmarshal(body, &runner)
 	if err != nil {
-		log.Println("Error while unmarshaling create runner request body", err)
+		logging.Error(ctx.Request.Context(), "Error while unmarshaling create runner request body", "error", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	response, responseErr := rc.runnersService.CreateRunner(&runner)
+	response, responseErr := rc.runnersService.CreateRunner(ctx.Request.Context(), principal, &runner)
 	if responseErr != nil {
 		// responde con el http status code y el payload, y detiene la ejecución del handler
-		ctx.AbortWithStatusJSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
-func (rc RunnersController) UpdateRunner(ctx *gin.Context) {
-	// actualizamos la metrica de contador de peticiones HTTP cada vez que se recibe una solicitud en el endpoint create runner
-	metrics.HttpRequestsCounter.Inc()
+// CreateRunners crea varios runners en una petición. Responde 207 con el estado de cada runner
+func (rc RunnersController) CreateRunners(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var batch models.RunnersBatch
+	if !readBody(ctx, "create runners batch", &batch) {
+		return
+	}
+
+	response, responseErr := rc.runnersService.CreateRunners(ctx.Request.Context(), principal, &batch)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
 
+	ctx.JSON(http.StatusMultiStatus, response)
+}
+
+func (rc RunnersController) UpdateRunner(ctx *gin.Context) {
 	accessToken := ctx.Request.Header.Get("Token")
-	auth, responseErr := rc.usersService.AuthorizeUser(accessToken, []string{ROLE_ADMIN})
+	// los administradores de club solo pueden modificar a los runners de su club, lo comprueba el servicio
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
-	if !auth {
+	if principal == nil {
 		ctx.Status(http.StatusUnauthorized)
 		return
 	}
 
 	body, err := io.ReadAll(ctx.Request.Body)
 	if err != nil {
-		log.Println("Error while reading update runner request body", err)
+		logging.Error(ctx.Request.Context(), "Error while reading update runner request body", "error", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
@@ -97,41 +121,68 @@ func (rc RunnersController) UpdateRunner(ctx *gin.Context) {
 	var runner models.Runner
 	err = json.Unmarshal(body, &runner)
 	if err != nil {
-		log.Println("Error while unmarshaling update runner request body", err)
+		logging.Error(ctx.Request.Context(), "Error while unmarshaling update runner request body", "error", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	responseErr = rc.runnersService.UpdateRunner(&runner)
+	responseErr = rc.runnersService.UpdateRunner(ctx.Request.Context(), principal, &runner)
 	if responseErr != nil {
-		ctx.AbortWithStatusJSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
 	ctx.Status(http.StatusNoContent)
 }
 
-func (rc RunnersController) DeleteRunner(ctx *gin.Context) {
-	// actualizamos la metrica de contador de peticiones HTTP cada vez que se recibe una solicitud en el endpoint create runner
-	metrics.HttpRequestsCounter.Inc()
+// UpdateRunnerV2 actualiza el runner de la ruta (PUT /v2/runner/:id). En v1 el id del runner va en el cuerpo, y en v2 el id del cuerpo se ignora
+func (rc RunnersController) UpdateRunnerV2(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var runner models.Runner
+	if !readBody(ctx, "update runner", &runner) {
+		return
+	}
+	runner.ID = ctx.Param("id")
 
+	responseErr = rc.runnersService.UpdateRunner(ctx.Request.Context(), principal, &runner)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (rc RunnersController) DeleteRunner(ctx *gin.Context) {
 	accessToken := ctx.Request.Header.Get("Token")
-	auth, responseErr := rc.usersService.AuthorizeUser(accessToken, []string{ROLE_ADMIN})
+	// los administradores de club solo pueden modificar a los runners de su club, lo comprueba el servicio
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN})
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
-	if !auth {
+	if principal == nil {
 		ctx.Status(http.StatusUnauthorized)
 		return
 	}
 
 	runnerId := ctx.Param("id")
 
-	responseErr = rc.runnersService.DeleteRunner(runnerId)
+	responseErr = rc.runnersService.DeleteRunner(ctx.Request.Context(), principal, runnerId)
 	if responseErr != nil {
-		ctx.AbortWithStatusJSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
@@ -139,20 +190,18 @@ func (rc RunnersController) DeleteRunner(ctx *gin.Context) {
 }
 
 func (rc RunnersController) GetRunner(ctx *gin.Context) {
-	// actualizamos la metrica de contador de peticiones HTTP cada vez que se recibe una solicitud en el endpoint create runner
-	metrics.HttpRequestsCounter.Inc()
-
 	accessToken := ctx.Request.Header.Get("Token")
-	auth, responseErr := rc.usersService.AuthorizeUser(accessToken, []string{ROLE_ADMIN, ROLE_RUNNER})
+	// necesitamos saber quién consulta el runner para aplicar sus preferencias de privacidad
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
 	if responseErr != nil {
 		// actualizamos la metrica de contador informando también la etiqueta correspondiente al status code
 		metrics.GetRunnerHttpResponsesCounter.WithLabelValues(
 			strconv.Itoa(responseErr.Status)).Inc()
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
-	if !auth {
+	if principal == nil {
 		// actualizamos la metrica de contador informando también la etiqueta correspondiente al status code
 		metrics.GetRunnerHttpResponsesCounter.WithLabelValues("401").Inc()
 		ctx.Status(http.StatusUnauthorized)
@@ -162,12 +211,12 @@ func (rc RunnersController) GetRunner(ctx *gin.Context) {
 	// path parameter
 	runnerId := ctx.Param("id")
 
-	response, responseErr := rc.runnersService.GetRunner(runnerId)
+	response, responseErr := rc.runnersService.GetRunner(ctx.Request.Context(), principal, runnerId)
 	if responseErr != nil {
 		// actualizamos la metrica de contador informando también la etiqueta correspondiente al status code
 		metrics.GetRunnerHttpResponsesCounter.WithLabelValues(
 			strconv.Itoa(responseErr.Status)).Inc()
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
@@ -177,9 +226,6 @@ func (rc RunnersController) GetRunner(ctx *gin.Context) {
 }
 
 func (rc RunnersController) GetRunnersBatch(ctx *gin.Context) {
-	// actualizamos la metrica de contador de peticiones HTTP cada vez que se recibe una solicitud en el endpoint create runner
-	metrics.HttpRequestsCounter.Inc()
-
 	// Medimos la duración de la operación (percentiles, valor medio, desviacion estándar, etc.) utilizando un histograma de Prometheus. Para ello, creamos un timer al inicio del handler y lo detenemos al final del handler utilizando defer. El timer observará la duración de la operación y actualizará el histograma con ese valor.
 	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(f float64) {
 		metrics.GetAllRunnersTimer.Observe(f)
@@ -191,14 +237,14 @@ func (rc RunnersController) GetRunnersBatch(ctx *gin.Context) {
 	}()
 
 	accessToken := ctx.Request.Header.Get("Token")
-	auth, responseErr := rc.usersService.AuthorizeUser(accessToken, []string{ROLE_ADMIN, ROLE_RUNNER})
-	fmt.Println("Response error", responseErr)
+	// necesitamos saber quién consulta el listado para aplicar las preferencias de privacidad de los runners
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
-	if !auth {
+	if principal == nil {
 		ctx.Status(http.StatusUnauthorized)
 		return
 	}
@@ -208,11 +254,138 @@ func (rc RunnersController) GetRunnersBatch(ctx *gin.Context) {
 	country := params.Get("country")
 	year := params.Get("year")
 
-	response, responseErr := rc.runnersService.GetRunnersBatch(country, year)
+	response, responseErr := rc.runnersService.GetRunnersBatch(ctx.Request.Context(), principal, country, year)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (rc RunnersController) SearchRunners(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	// como en el listado, quién busca decide qué datos privados se ven
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
 	if responseErr != nil {
-		ctx.JSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	params := ctx.Request.URL.Query()
+	response, responseErr := rc.runnersService.SearchRunners(ctx.Request.Context(), principal, params.Get("q"), params.Get("page"), params.Get("page_size"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
+
+// GetRunnerStats devuelve las estadísticas de los resultados del runner
+func (rc RunnersController) GetRunnerStats(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	// necesitamos saber quién consulta las estadísticas para aplicar las preferencias de privacidad del runner
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	response, responseErr := rc.runnersService.GetRunnerStats(ctx.Request.Context(), principal, ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetOwnRunner devuelve el perfil del runner asociado a la cuenta del usuario
+func (rc RunnersController) GetOwnRunner(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	response, responseErr := rc.runnersService.GetOwnRunner(ctx.Request.Context(), principal)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// UpdateOwnRunner actualiza el perfil y las preferencias de privacidad del runner asociado a la cuenta del usuario
+func (rc RunnersController) UpdateOwnRunner(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	principal, responseErr := rc.usersService.Authenticate(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN, ROLE_CLUB_ADMIN, ROLE_RUNNER})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if principal == nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var runner models.Runner
+	if !readBody(ctx, "update own runner", &runner) {
+		return
+	}
+
+	responseErr = rc.runnersService.UpdateOwnRunner(ctx.Request.Context(), principal, &runner)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// LinkUser asocia el runner a la cuenta de un usuario, que a partir de entonces puede gestionar su perfil
+func (rc RunnersController) LinkUser(ctx *gin.Context) {
+	accessToken := ctx.Request.Header.Get("Token")
+	auth, responseErr := rc.usersService.AuthorizeUser(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	if !auth {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	if !readBody(ctx, "link runner user", &user) {
+		return
+	}
+
+	responseErr = rc.usersService.LinkRunner(ctx.Request.Context(), user.Username, ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"runners-postgresql/config"
 	"runners-postgresql/models"
+	"runners-postgresql/openapi"
 	"runners-postgresql/repositories"
 	"runners-postgresql/services"
+	"runners-postgresql/versioning"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetRunnersResponse(t *testing.T) {
@@ -22,21 +26,21 @@ func TestGetRunnersResponse(t *testing.T) {
 	defer dbHandler.Close()
 
 	// usamos mock para definir un mock. Indicamos las columnas que queremos que nos devuelva el mock...
-	columnsUsers := []string{"user_role"}
+	columnsUsers := []string{"id", "username", "user_role", "club_id", "runner_id"}
 	//...indicamos que query tiene que se mockeada, que columnas se tienen que devolver, y los valores - una sola final
-	mock.ExpectQuery("SELECT user_role").WillReturnRows(
-		sqlmock.NewRows(columnsUsers).AddRow("runner"),
+	mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(
+		sqlmock.NewRows(columnsUsers).AddRow("1", "runner", "runner", nil, "1"),
 	)
 
-	// definimos otro mock para un select *; Indicamos las columnas que tiene que devolver el mock, y los valores - dos filas
+	// definimos otro mock para el listado, que un runner recibe con las preferencias de privacidad de los demás runners; Indicamos las columnas que tiene que devolver el mock, y los valores - dos filas
 	columns := []string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best"}
-	mock.ExpectQuery("SELECT *").WillReturnRows(
+	mock.ExpectQuery("SELECT *").WithArgs(true, "1").WillReturnRows(
 		sqlmock.NewRows(columns).
 			AddRow("1", "John", "Smith", 30, true, "United States", "02:00:41", "02:13:13").
 			AddRow("2", "Marijana", "Komatinovic", 30, true, "Serbia", "01:18:28", "01:18:28"))
 
 	// definimos el router, usando la conexión a la base de datos mockeada
-	router := initTestRouter(dbHandler)
+	router := initTestRouter(t, dbHandler)
 
 	// crea una request (GET, al recurso /runner, con un payload nulo)
 	request, _ := http.NewRequest("GET", "/runner", nil)
@@ -60,16 +64,83 @@ func TestGetRunnersResponse(t *testing.T) {
 	assert.Equal(t, 2, len(runers))
 }
 
-func initTestRouter(dbHandler *sql.DB) *gin.Engine {
+func TestGetRunnersVersions(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+
+	runnersService := services.NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+	usersServices := services.NewUsersService(repositories.NewUsersRepository(dbHandler), nil, nil)
+	runnersController := NewRunnersController(runnersService, usersServices)
+
+	// la misma ruta en las dos versiones, con la v1 obsoleta
+	versioningConfig := config.VersioningConfig{Default: versioning.V1, Deprecated: versioning.V1, DeprecatedSince: "2024-01-01"}
+	router := gin.New()
+	for _, version := range versioning.Versions {
+		router.Group("/"+version, versioning.Middleware(version, versioningConfig)).GET("/runner", runnersController.GetRunnersBatch)
+	}
+	handler := versioning.Handler(router, versioningConfig.Default)
+
+	get := func(path string, accept string) *httptest.ResponseRecorder {
+		mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}).AddRow("1", "admin", "admin", nil, nil))
+		mock.ExpectQuery("SELECT").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best"}))
+
+		request := httptest.NewRequest("GET", path, nil)
+		request.Header.Set("Token", "token")
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// sin versión en la ruta ni en la cabecera Accept se usa la versión por defecto, que está obsoleta
+	recorder := get("/runner", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+
+	// la cabecera Accept elige la versión
+	recorder = get("/runner", "application/vnd.runners.v2+json")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Deprecation"))
+
+	// y también la ruta
+	recorder = get("/v2/runner", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Deprecation"))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// una versión que no existe no llega a la ruta
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/runner", nil)
+	request.Header.Set("Accept", "application/vnd.runners.v9+json")
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+}
+
+func initTestRouter(t *testing.T, dbHandler *sql.DB) *gin.Engine {
 	// apenas definimos las capas que queremos usar en el test. Estamos usando la base de datos mockeada
 	runnersRepository := repositories.NewRunnersRepository(dbHandler)
 	usersRepository := repositories.NewUsersRepository(dbHandler)
 	// no usamos el repositorio de tokens en este test, por eso le pasamos nil
-	runnersService := services.NewRunnersService(runnersRepository, nil)
-	usersServices := services.NewUsersService(usersRepository)
+	runnersService := services.NewRunnersService(runnersRepository, nil, nil, nil, nil)
+	usersServices := services.NewUsersService(usersRepository, nil, nil)
 	runnersController := NewRunnersController(runnersService, usersServices)
 
 	router := gin.Default()
+	// las peticiones y las respuestas tienen que cumplir la especificación de la API
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+	router.Use(openapi.Middleware(doc, openapi.Options{
+		ValidateResponses: true,
+		OnResponseError: func(ctx *gin.Context, err error) {
+			t.Errorf("%s %s: %v", ctx.Request.Method, ctx.FullPath(), err)
+		},
+	}))
 	// solo incluimos la ruta que queremos testear
 	router.GET("/runner", runnersController.GetRunnersBatch)
 
@@ -1,8 +1,8 @@
 package controllers
 
 import (
-	"log"
 	"net/http"
+	"runners-postgresql/logging"
 	"runners-postgresql/services"
 
 	"github.com/gin-gonic/gin"
@@ -22,14 +22,14 @@ func (uc UsersController) Login(ctx *gin.Context) {
 	// Obtiene las credenciales de autenticación básica
 	username, password, ok := ctx.Request.BasicAuth()
 	if !ok {
-		log.Println("Error while reading credentials")
+		logging.Warn(ctx.Request.Context(), "Login request without basic auth credentials")
 		ctx.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
 	// Valida el usuario y contraseña contra lo que tenemos guardado en la base de datos, y si son correctos genera un token de acceso (que se guarda en la base de datos) y se obtiene aqui
-	accessToken, responseErr := uc.usersService.Login(username, password)
+	accessToken, responseErr := uc.usersService.Login(ctx.Request.Context(), username, password)
 	if responseErr != nil {
-		ctx.AbortWithStatusJSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 	// Devuelve el token de acceso al cliente
@@ -41,9 +41,9 @@ func (uc UsersController) Logout(ctx *gin.Context) {
 	accessToken := ctx.Request.Header.Get("Token")
 
 	// Llama al servicio que elimina el token de acceso de la base de datos
-	responseErr := uc.usersService.Logout(accessToken)
+	responseErr := uc.usersService.Logout(ctx.Request.Context(), accessToken)
 	if responseErr != nil {
-		ctx.AbortWithStatusJSON(responseErr.Status, responseErr)
+		respondError(ctx, responseErr)
 		return
 	}
 
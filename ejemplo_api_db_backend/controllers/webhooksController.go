@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Administración de las suscripciones a los webhooks. Todos los recursos requieren el rol ROLE_ADMIN
+type WebhooksController struct {
+	webhooksService *services.WebhooksService
+	usersService    *services.UsersService
+}
+
+func NewWebhooksController(webhooksService *services.WebhooksService, usersService *services.UsersService) *WebhooksController {
+	return &WebhooksController{
+		webhooksService: webhooksService,
+		usersService:    usersService,
+	}
+}
+
+func (wc WebhooksController) CreateSubscription(ctx *gin.Context) {
+	if !wc.authorizeAdmin(ctx) {
+		return
+	}
+
+	subscription, ok := readSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	response, responseErr := wc.webhooksService.CreateSubscription(ctx.Request.Context(), subscription)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (wc WebhooksController) UpdateSubscription(ctx *gin.Context) {
+	if !wc.authorizeAdmin(ctx) {
+		return
+	}
+
+	subscription, ok := readSubscription(ctx)
+	if !ok {
+		return
+	}
+	subscription.ID = ctx.Param("id")
+
+	responseErr := wc.webhooksService.UpdateSubscription(ctx.Request.Context(), subscription)
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (wc WebhooksController) DeleteSubscription(ctx *gin.Context) {
+	if !wc.authorizeAdmin(ctx) {
+		return
+	}
+
+	responseErr := wc.webhooksService.DeleteSubscription(ctx.Request.Context(), ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (wc WebhooksController) GetSubscription(ctx *gin.Context) {
+	if !wc.authorizeAdmin(ctx) {
+		return
+	}
+
+	response, responseErr := wc.webhooksService.GetSubscription(ctx.Request.Context(), ctx.Param("id"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (wc WebhooksController) GetAllSubscriptions(ctx *gin.Context) {
+	if !wc.authorizeAdmin(ctx) {
+		return
+	}
+
+	response, responseErr := wc.webhooksService.GetAllSubscriptions(ctx.Request.Context())
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetDeliveries lista las entregas de una suscripción. Con status=dead se consulta el dead letter
+func (wc WebhooksController) GetDeliveries(ctx *gin.Context) {
+	if !wc.authorizeAdmin(ctx) {
+		return
+	}
+
+	response, responseErr := wc.webhooksService.GetDeliveries(ctx.Request.Context(), ctx.Param("id"), ctx.Query("status"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// RetryDelivery vuelve a encolar una entrega del dead letter
+func (wc WebhooksController) RetryDelivery(ctx *gin.Context) {
+	if !wc.authorizeAdmin(ctx) {
+		return
+	}
+
+	responseErr := wc.webhooksService.RetryDelivery(ctx.Request.Context(), ctx.Param("id"), ctx.Param("delivery"))
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (wc WebhooksController) authorizeAdmin(ctx *gin.Context) bool {
+	accessToken := ctx.Request.Header.Get("Token")
+	auth, responseErr := wc.usersService.AuthorizeUser(ctx.Request.Context(), accessToken, []string{ROLE_ADMIN})
+	if responseErr != nil {
+		respondError(ctx, responseErr)
+		return false
+	}
+
+	if !auth {
+		ctx.Status(http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+func readSubscription(ctx *gin.Context) (*models.WebhookSubscription, bool) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		logging.Error(ctx.Request.Context(), "Error while reading webhook subscription request body", "error", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	var subscription models.WebhookSubscription
+	err = json.Unmarshal(body, &subscription)
+	if err != nil {
+		logging.Error(ctx.Request.Context(), "Error while unmarshaling webhook subscription request body", "error", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	return &subscription, true
+}
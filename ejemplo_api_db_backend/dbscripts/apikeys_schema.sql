@@ -0,0 +1,16 @@
+-- claves de API de los sistemas externos. La clave no se guarda: solo su hash y el prefijo, en claro, para reconocerla
+CREATE TABLE api_keys (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    name text NOT NULL,
+    prefix text NOT NULL,
+    key_hash text NOT NULL UNIQUE, -- SHA-256 de la clave en hexadecimal
+    user_role text NOT NULL,
+    club_id uuid,
+    scopes text[] NOT NULL DEFAULT '{}',
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz, -- las claves revocadas se conservan para saber quién ha usado la API
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT api_keys_pk PRIMARY KEY (id),
+    CONSTRAINT api_keys_club_fk FOREIGN KEY (club_id) REFERENCES clubs(id)
+);
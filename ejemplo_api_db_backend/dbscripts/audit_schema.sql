@@ -0,0 +1,17 @@
+-- log de auditoría de los cambios hechos desde la consola de administración. Las entradas no se modifican ni se borran
+CREATE TABLE audit_log (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    username text NOT NULL, -- se guarda el nombre y no el id, para que la entrada se pueda leer aunque el usuario cambie o desaparezca
+    action text NOT NULL,
+    resource_id text NOT NULL,
+    details text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT audit_log_pk PRIMARY KEY (id)
+);
+
+-- el log se consulta de lo más reciente a lo más antiguo, y filtrado por usuario
+CREATE INDEX audit_log_created_at
+ON audit_log (created_at DESC);
+
+CREATE INDEX audit_log_username
+ON audit_log (username, created_at DESC);
@@ -0,0 +1,32 @@
+-- clubs
+CREATE TABLE clubs (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    name text NOT NULL UNIQUE,
+    country text NOT NULL,
+    city text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT clubs_pk PRIMARY KEY (id)
+);
+
+-- historial de pertenencia de los runners a los clubs. La pertenencia activa es la que no tiene left_at
+CREATE TABLE club_memberships (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    club_id uuid NOT NULL,
+    runner_id uuid NOT NULL,
+    joined_at date NOT NULL DEFAULT current_date,
+    left_at date,
+    CONSTRAINT club_memberships_pk PRIMARY KEY (id),
+    CONSTRAINT club_memberships_club_fk FOREIGN KEY (club_id) REFERENCES clubs(id),
+    CONSTRAINT club_memberships_runner_fk FOREIGN KEY (runner_id) REFERENCES runners(id),
+    CONSTRAINT club_memberships_dates CHECK (left_at IS NULL OR left_at >= joined_at)
+);
+
+-- un runner solo puede pertenecer a un club a la vez
+CREATE UNIQUE INDEX club_memberships_active_runner
+ON club_memberships (runner_id) WHERE left_at IS NULL;
+
+CREATE INDEX club_memberships_club
+ON club_memberships (club_id);
+
+-- club que administra el usuario. Solo se usa con el rol club_admin
+ALTER TABLE users ADD COLUMN club_id uuid REFERENCES clubs(id);
@@ -0,0 +1,8 @@
+-- usuarios del proveedor de identidad externo (OpenID Connect). Se dan de alta al iniciar sesión y no tienen contraseña, así que no pueden hacer el login con autenticación básica
+ALTER TABLE users ALTER COLUMN user_password DROP NOT NULL;
+ALTER TABLE users ADD COLUMN oidc_issuer text;
+ALTER TABLE users ADD COLUMN oidc_subject text; -- claim sub del ID token, el identificador del usuario en el proveedor
+
+-- cada usuario del proveedor tiene una sola cuenta. Los usuarios locales tienen las dos columnas a NULL, que no entran en conflicto
+CREATE UNIQUE INDEX users_oidc_identity
+ON users (oidc_issuer, oidc_subject);
@@ -0,0 +1,14 @@
+-- búsqueda de runners por nombre. pg_trgm aporta la similitud por trigramas y los índices que la aceleran, y unaccent elimina los acentos
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- nombre normalizado del runner: sin acentos, en minúsculas, y con un único espacio en lugar de cualquier carácter que no sea letra o dígito (igual que search.Normalize)
+-- unaccent no es IMMUTABLE, porque depende del diccionario, así que para poder indexar la expresión la envolvemos indicando explícitamente el diccionario
+CREATE OR REPLACE FUNCTION runner_search_name(first_name text, last_name text)
+RETURNS text AS $$
+    SELECT trim(regexp_replace(lower(public.unaccent('public.unaccent', first_name || ' ' || last_name)), '[^[:alnum:]]+', ' ', 'g'))
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+-- índice GIN de trigramas. Lo usan tanto el operador <% (similitud) como LIKE '%...%' (prefijos)
+CREATE INDEX runners_search_name
+ON runners USING gin (runner_search_name(first_name, last_name) gin_trgm_ops);
@@ -0,0 +1,36 @@
+-- runner asociado a la cuenta del usuario. Cada runner pertenece como mucho a un usuario
+ALTER TABLE users ADD COLUMN runner_id uuid UNIQUE REFERENCES runners(id);
+
+-- preferencias de privacidad del runner. Si no hay fila se muestran todos los datos
+-- están en una tabla aparte, y no en runners, porque varias consultas hacen SELECT * sobre runners
+CREATE TABLE runner_privacy (
+    runner_id uuid NOT NULL,
+    hide_age boolean NOT NULL DEFAULT false,
+    hide_results boolean NOT NULL DEFAULT false,
+    CONSTRAINT runner_privacy_pk PRIMARY KEY (runner_id),
+    CONSTRAINT runner_privacy_runner_fk FOREIGN KEY (runner_id) REFERENCES runners(id)
+);
+
+-- resultados enviados por los propios runners. No cuentan para las marcas ni las clasificaciones hasta que un administrador los aprueba, y entonces se copian a results
+CREATE TABLE result_submissions (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    runner_id uuid NOT NULL,
+    race_result interval NOT NULL,
+    location text NOT NULL,
+    position integer,
+    year integer NOT NULL,
+    status text NOT NULL DEFAULT 'pending', -- pending, approved o rejected
+    submitted_by uuid NOT NULL,
+    submitted_at timestamptz NOT NULL DEFAULT now(),
+    reviewed_by uuid,
+    reviewed_at timestamptz,
+    reject_reason text,
+    result_id uuid, -- resultado creado al aprobar el envío
+    CONSTRAINT result_submissions_pk PRIMARY KEY (id),
+    CONSTRAINT result_submissions_runner_fk FOREIGN KEY (runner_id) REFERENCES runners(id),
+    CONSTRAINT result_submissions_user_fk FOREIGN KEY (submitted_by) REFERENCES users(id)
+);
+
+-- índice parcial con la cola de envíos pendientes de aprobación
+CREATE INDEX result_submissions_pending
+ON result_submissions (submitted_at) WHERE status = 'pending';
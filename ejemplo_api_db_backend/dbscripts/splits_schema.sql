@@ -0,0 +1,9 @@
+-- tiempos de paso de los resultados: distancia desde la salida en kilómetros y tiempo desde la salida. El último es la llegada
+CREATE TABLE result_splits (
+    result_id uuid NOT NULL,
+    distance numeric(7, 3) NOT NULL,
+    elapsed interval NOT NULL,
+    CONSTRAINT result_splits_pk PRIMARY KEY (result_id, distance),
+    -- al borrar un resultado se borran sus tiempos de paso
+    CONSTRAINT result_splits_result_fk FOREIGN KEY (result_id) REFERENCES results(id) ON DELETE CASCADE
+);
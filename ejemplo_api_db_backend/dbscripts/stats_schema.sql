@@ -0,0 +1,7 @@
+-- distancia de los resultados en kilómetros, para las estadísticas por distancia. Los resultados que ya existen son de maratón
+ALTER TABLE results ADD COLUMN distance numeric(7, 3) NOT NULL DEFAULT 42.195;
+ALTER TABLE result_submissions ADD COLUMN distance numeric(7, 3) NOT NULL DEFAULT 42.195;
+
+-- las estadísticas agregan los resultados de un runner por distancia y año
+CREATE INDEX results_runner_distance_year
+ON results (runner_id, distance, year);
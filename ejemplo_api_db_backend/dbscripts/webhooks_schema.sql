@@ -0,0 +1,49 @@
+-- outbox transaccional: los eventos de dominio se insertan en la misma transacción que el cambio que los provoca
+CREATE TABLE outbox_events (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    event_type text NOT NULL,
+    aggregate_id text NOT NULL,
+    payload jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    processed_at timestamptz, -- se informa cuando el dispatcher ha generado las entregas del evento
+    CONSTRAINT outbox_events_pk PRIMARY KEY (id)
+);
+
+-- índice parcial con los eventos pendientes de procesar por el dispatcher
+CREATE INDEX outbox_events_pending
+ON outbox_events (created_at) WHERE processed_at IS NULL;
+
+-- suscripciones a los webhooks. Si event_types está vacío la suscripción recibe todos los eventos
+CREATE TABLE webhook_subscriptions (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    url text NOT NULL,
+    secret text NOT NULL, -- clave con la que se firma el payload (HMAC-SHA256)
+    event_types text[] NOT NULL DEFAULT '{}',
+    is_active boolean NOT NULL DEFAULT TRUE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT webhook_subscriptions_pk PRIMARY KEY (id)
+);
+
+-- cada evento genera una entrega por suscripción. Las entregas que agotan los reintentos quedan en estado 'dead' (dead letter)
+CREATE TABLE webhook_deliveries (
+    id uuid NOT NULL DEFAULT uuid_generate_v1mc(),
+    event_id uuid NOT NULL,
+    subscription_id uuid NOT NULL,
+    status text NOT NULL DEFAULT 'pending', -- pending, delivered o dead
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text,
+    delivered_at timestamptz,
+    CONSTRAINT webhook_deliveries_pk PRIMARY KEY (id),
+    CONSTRAINT fk_webhook_deliveries_event_id FOREIGN KEY (event_id)
+        REFERENCES outbox_events (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT fk_webhook_deliveries_subscription_id FOREIGN KEY (subscription_id)
+        REFERENCES webhook_subscriptions (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_due
+ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
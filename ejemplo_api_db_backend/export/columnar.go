@@ -0,0 +1,313 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Formato columnar inspirado en Parquet. Las filas se agrupan en grupos de filas (row groups) y dentro de cada grupo los valores se guardan columna a columna. Así el consumo de memoria está acotado por el tamaño del grupo, y no por el número total de filas.
+//
+//	magic "RCOL" | versión | esquema | row group* | 0 | magic "RCOL"
+//
+// El esquema es el número de columnas seguido de nombre y tipo de cada columna. Cada row group empieza con el número de filas, y después, por cada columna, la longitud en bytes de la columna y los valores. Los enteros se codifican como varint, los strings con su longitud delante, los booleanos con un byte, y los decimales con los 8 bytes de su float64 en little endian.
+const (
+	columnarMagic   = "RCOL"
+	columnarVersion = 1
+	// número de filas de cada row group
+	RowGroupSize = 1024
+)
+
+type columnarEncoder struct {
+	writer  *bufio.Writer
+	schema  Schema
+	columns []*bytes.Buffer
+	rows    int
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func NewColumnarEncoder(w io.Writer) Encoder {
+	return &columnarEncoder{
+		writer: bufio.NewWriter(w),
+	}
+}
+
+func (e *columnarEncoder) Begin(schema Schema) error {
+	e.schema = schema
+	e.columns = make([]*bytes.Buffer, len(schema))
+	for i := range e.columns {
+		e.columns[i] = &bytes.Buffer{}
+	}
+
+	e.writer.WriteString(columnarMagic)
+	e.writer.WriteByte(columnarVersion)
+	e.writeUvarint(uint64(len(schema)))
+	for _, column := range schema {
+		e.writeUvarint(uint64(len(column.Name)))
+		e.writer.WriteString(column.Name)
+		e.writer.WriteByte(byte(column.Type))
+	}
+
+	return nil
+}
+
+func (e *columnarEncoder) Encode(row []interface{}) error {
+	if len(row) != len(e.schema) {
+		return fmt.Errorf("row has %d values, schema has %d columns", len(row), len(e.schema))
+	}
+
+	for i, value := range row {
+		column := e.columns[i]
+		switch e.schema[i].Type {
+		case TypeString:
+			v, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("column %s expects a string, got %T", e.schema[i].Name, value)
+			}
+			n := binary.PutUvarint(e.scratch[:], uint64(len(v)))
+			column.Write(e.scratch[:n])
+			column.WriteString(v)
+		case TypeInt:
+			v, ok := value.(int)
+			if !ok {
+				return fmt.Errorf("column %s expects an int, got %T", e.schema[i].Name, value)
+			}
+			n := binary.PutVarint(e.scratch[:], int64(v))
+			column.Write(e.scratch[:n])
+		case TypeBool:
+			v, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("column %s expects a bool, got %T", e.schema[i].Name, value)
+			}
+			if v {
+				column.WriteByte(1)
+			} else {
+				column.WriteByte(0)
+			}
+		case TypeFloat:
+			v, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("column %s expects a float64, got %T", e.schema[i].Name, value)
+			}
+			binary.LittleEndian.PutUint64(e.scratch[:8], math.Float64bits(v))
+			column.Write(e.scratch[:8])
+		}
+	}
+
+	e.rows++
+	if e.rows == RowGroupSize {
+		return e.writeRowGroup()
+	}
+
+	return nil
+}
+
+// escribe el row group pendiente y deja los buffers de las columnas listos para reutilizarlos
+func (e *columnarEncoder) writeRowGroup() error {
+	if e.rows == 0 {
+		return nil
+	}
+
+	e.writeUvarint(uint64(e.rows))
+	for _, column := range e.columns {
+		e.writeUvarint(uint64(column.Len()))
+		column.WriteTo(e.writer)
+		column.Reset()
+	}
+	e.rows = 0
+
+	return nil
+}
+
+func (e *columnarEncoder) writeUvarint(v uint64) {
+	n := binary.PutUvarint(e.scratch[:], v)
+	e.writer.Write(e.scratch[:n])
+}
+
+// Flush solo vuelca los row groups completos; el row group en curso se escribe al completarse o al cerrar
+func (e *columnarEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+func (e *columnarEncoder) Close() error {
+	err := e.writeRowGroup()
+	if err != nil {
+		return err
+	}
+
+	e.writeUvarint(0)
+	e.writer.WriteString(columnarMagic)
+
+	return e.writer.Flush()
+}
+
+// ColumnarReader lee un fichero en formato columnar. Se lee un row group cada vez
+type ColumnarReader struct {
+	reader *bufio.Reader
+	schema Schema
+	group  [][]interface{}
+	next   int
+	done   bool
+}
+
+func NewColumnarReader(r io.Reader) (*ColumnarReader, error) {
+	reader := bufio.NewReader(r)
+
+	magic := make([]byte, len(columnarMagic)+1)
+	_, err := io.ReadFull(reader, magic)
+	if err != nil {
+		return nil, err
+	}
+	if string(magic[:len(columnarMagic)]) != columnarMagic || magic[len(columnarMagic)] != columnarVersion {
+		return nil, errors.New("not a columnar export")
+	}
+
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := make(Schema, count)
+	for i := range schema {
+		name, err := readString(reader)
+		if err != nil {
+			return nil, err
+		}
+		columnType, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		schema[i] = Column{Name: name, Type: ColumnType(columnType)}
+	}
+
+	return &ColumnarReader{
+		reader: reader,
+		schema: schema,
+	}, nil
+}
+
+func (cr *ColumnarReader) Schema() Schema {
+	return cr.schema
+}
+
+// Read devuelve la siguiente fila, o io.EOF cuando no quedan más
+func (cr *ColumnarReader) Read() ([]interface{}, error) {
+	if cr.next == len(cr.group) {
+		if cr.done {
+			return nil, io.EOF
+		}
+
+		err := cr.readRowGroup()
+		if err != nil {
+			return nil, err
+		}
+
+		if cr.done {
+			return nil, io.EOF
+		}
+	}
+
+	row := cr.group[cr.next]
+	cr.next++
+
+	return row, nil
+}
+
+func (cr *ColumnarReader) readRowGroup() error {
+	rows, err := binary.ReadUvarint(cr.reader)
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		magic := make([]byte, len(columnarMagic))
+		_, err := io.ReadFull(cr.reader, magic)
+		if err != nil {
+			return err
+		}
+		if string(magic) != columnarMagic {
+			return errors.New("corrupt columnar export footer")
+		}
+		cr.done = true
+		cr.group = nil
+		cr.next = 0
+		return nil
+	}
+
+	group := make([][]interface{}, rows)
+	for i := range group {
+		group[i] = make([]interface{}, len(cr.schema))
+	}
+
+	for c, column := range cr.schema {
+		length, err := binary.ReadUvarint(cr.reader)
+		if err != nil {
+			return err
+		}
+
+		chunk := make([]byte, length)
+		_, err = io.ReadFull(cr.reader, chunk)
+		if err != nil {
+			return err
+		}
+
+		values := bytes.NewReader(chunk)
+		for r := range group {
+			switch column.Type {
+			case TypeString:
+				v, err := readString(values)
+				if err != nil {
+					return err
+				}
+				group[r][c] = v
+			case TypeInt:
+				v, err := binary.ReadVarint(values)
+				if err != nil {
+					return err
+				}
+				group[r][c] = int(v)
+			case TypeBool:
+				v, err := values.ReadByte()
+				if err != nil {
+					return err
+				}
+				group[r][c] = v == 1
+			case TypeFloat:
+				var bits [8]byte
+				_, err := io.ReadFull(values, bits[:])
+				if err != nil {
+					return err
+				}
+				group[r][c] = math.Float64frombits(binary.LittleEndian.Uint64(bits[:]))
+			default:
+				return fmt.Errorf("unknown column type %d", column.Type)
+			}
+		}
+	}
+
+	cr.group = group
+	cr.next = 0
+
+	return nil
+}
+
+func readString(r io.ByteReader) (string, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := make([]byte, length)
+	for i := range buffer {
+		buffer[i], err = r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return string(buffer), nil
+}
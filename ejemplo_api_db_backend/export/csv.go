@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type csvEncoder struct {
+	writer *csv.Writer
+	record []string
+}
+
+func NewCSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{
+		writer: csv.NewWriter(w),
+	}
+}
+
+func (e *csvEncoder) Begin(schema Schema) error {
+	header := make([]string, len(schema))
+	for i, column := range schema {
+		header[i] = column.Name
+	}
+	// reutilizamos el mismo slice para todas las filas
+	e.record = make([]string, len(schema))
+
+	return e.writer.Write(header)
+}
+
+func (e *csvEncoder) Encode(row []interface{}) error {
+	if len(row) != len(e.record) {
+		return fmt.Errorf("row has %d values, schema has %d columns", len(row), len(e.record))
+	}
+
+	for i, value := range row {
+		switch v := value.(type) {
+		case string:
+			e.record[i] = v
+		case int:
+			e.record[i] = strconv.Itoa(v)
+		case bool:
+			e.record[i] = strconv.FormatBool(v)
+		case float64:
+			e.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Errorf("unsupported value type %T", value)
+		}
+	}
+
+	return e.writer.Write(e.record)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	return e.Flush()
+}
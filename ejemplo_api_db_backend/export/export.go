@@ -0,0 +1,166 @@
+package export
+
+import (
+	"io"
+	"mime"
+	"runners-postgresql/models"
+	"strconv"
+	"strings"
+)
+
+// Tipos de dato que admiten las columnas de una exportación
+type ColumnType byte
+
+const (
+	TypeString ColumnType = iota + 1
+	TypeInt
+	TypeBool
+	TypeFloat
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// El esquema describe las columnas de la exportación. Todas las filas tienen que respetar el orden y el tipo de las columnas
+type Schema []Column
+
+// Un encoder serializa filas en un formato concreto. Las filas se escriben de una en una, de modo que el consumo de memoria no depende del número de filas exportadas
+type Encoder interface {
+	// escribe la cabecera del formato (si la tiene)
+	Begin(schema Schema) error
+	// serializa una fila. Los valores tienen que ser string, int, bool o float64 según el esquema
+	Encode(row []interface{}) error
+	// vuelca al writer subyacente lo que el encoder tenga pendiente
+	Flush() error
+	// termina la exportación escribiendo lo que el formato necesite al final
+	Close() error
+}
+
+// Formato de exportación
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	NewEncoder  func(w io.Writer) Encoder
+}
+
+var (
+	CSV = Format{
+		Name:        "csv",
+		ContentType: "text/csv",
+		Extension:   "csv",
+		NewEncoder:  NewCSVEncoder,
+	}
+
+	NDJSON = Format{
+		Name:        "ndjson",
+		ContentType: "application/x-ndjson",
+		Extension:   "ndjson",
+		NewEncoder:  NewNDJSONEncoder,
+	}
+
+	Columnar = Format{
+		Name:        "columnar",
+		ContentType: "application/vnd.runners.columnar",
+		Extension:   "rcol",
+		NewEncoder:  NewColumnarEncoder,
+	}
+)
+
+var formats = []Format{CSV, NDJSON, Columnar}
+
+// NegotiateFormat elige el formato de exportación. El query parameter format tiene prioridad sobre la cabecera Accept. Si no se indica ninguno se usa NDJSON
+func NegotiateFormat(format string, accept string) (Format, bool) {
+	if format != "" {
+		for _, f := range formats {
+			if strings.EqualFold(f.Name, format) {
+				return f, true
+			}
+		}
+		return Format{}, false
+	}
+
+	if accept == "" {
+		return NDJSON, true
+	}
+
+	// la cabecera Accept puede traer varios tipos separados por comas; nos quedamos con el primero que soportemos
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		if mediaType == "*/*" || mediaType == "application/*" {
+			return NDJSON, true
+		}
+
+		for _, f := range formats {
+			if f.ContentType == mediaType {
+				return f, true
+			}
+		}
+	}
+
+	return Format{}, false
+}
+
+var RunnerSchema = Schema{
+	{Name: "id", Type: TypeString},
+	{Name: "first_name", Type: TypeString},
+	{Name: "last_name", Type: TypeString},
+	{Name: "age", Type: TypeInt},
+	{Name: "is_active", Type: TypeBool},
+	{Name: "country", Type: TypeString},
+	{Name: "personal_best", Type: TypeString},
+	{Name: "season_best", Type: TypeString},
+}
+
+func RunnerRow(runner *models.Runner) []interface{} {
+	return []interface{}{
+		runner.ID,
+		runner.FirstName,
+		runner.LastName,
+		runner.Age,
+		runner.IsActive,
+		runner.Country,
+		runner.PersonalBest,
+		runner.SeasonBest,
+	}
+}
+
+var ResultSchema = Schema{
+	{Name: "id", Type: TypeString},
+	{Name: "runner_id", Type: TypeString},
+	{Name: "race_result", Type: TypeString},
+	{Name: "location", Type: TypeString},
+	{Name: "position", Type: TypeInt},
+	{Name: "year", Type: TypeInt},
+	{Name: "distance", Type: TypeFloat},
+	{Name: "splits", Type: TypeString},
+}
+
+func ResultRow(result *models.Result) []interface{} {
+	return []interface{}{
+		result.ID,
+		result.RunnerID,
+		result.RaceResult,
+		result.Location,
+		result.Position,
+		result.Year,
+		result.Distance,
+		formatSplits(result.Splits),
+	}
+}
+
+// formatSplits escribe los tiempos de paso en una sola columna, como pares distancia=tiempo separados por punto y coma: 10=00:30:05;21.0975=01:03:10. Sin tiempos de paso la columna queda vacía
+func formatSplits(splits []*models.Split) string {
+	pairs := make([]string, len(splits))
+	for i, split := range splits {
+		pairs[i] = strconv.FormatFloat(split.Distance, 'f', -1, 64) + "=" + split.Time
+	}
+
+	return strings.Join(pairs, ";")
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bytes"
+	"io"
+	"runners-postgresql/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		accept string
+		want   string
+		ok     bool
+	}{
+		{name: "Default", want: "ndjson", ok: true},
+		{name: "Query_Parameter", format: "CSV", want: "csv", ok: true},
+		{name: "Query_Parameter_Wins", format: "columnar", accept: "text/csv", want: "columnar", ok: true},
+		{name: "Accept_Header", accept: "application/json;q=0.9, text/csv", want: "csv", ok: true},
+		{name: "Accept_Wildcard", accept: "*/*", want: "ndjson", ok: true},
+		{name: "Unknown_Format", format: "xml", ok: false},
+		{name: "Unknown_Accept", accept: "application/xml", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, ok := NegotiateFormat(test.format, test.accept)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.want, format.Name)
+		})
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder := NewCSVEncoder(&buffer)
+
+	assert.NoError(t, encoder.Begin(ResultSchema))
+	assert.NoError(t, encoder.Encode(ResultRow(&models.Result{
+		ID:         "1",
+		RunnerID:   "2",
+		RaceResult: "02:10:00",
+		Location:   "Berlin, Germany",
+		Position:   3,
+		Year:       2022,
+		Distance:   models.MarathonDistance,
+		Splits: []*models.Split{
+			{Distance: 21.0975, Time: "01:04:30"},
+			{Distance: 42.195, Time: "02:10:00"},
+		},
+	})))
+	assert.NoError(t, encoder.Close())
+
+	assert.Equal(t, "id,runner_id,race_result,location,position,year,distance,splits\n1,2,02:10:00,\"Berlin, Germany\",3,2022,42.195,21.0975=01:04:30;42.195=02:10:00\n", buffer.String())
+}
+
+func TestNDJSONEncoder(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder := NewNDJSONEncoder(&buffer)
+
+	assert.NoError(t, encoder.Begin(Schema{{Name: "id", Type: TypeString}, {Name: "age", Type: TypeInt}, {Name: "is_active", Type: TypeBool}}))
+	assert.NoError(t, encoder.Encode([]interface{}{"1", 30, true}))
+	assert.NoError(t, encoder.Encode([]interface{}{"2", 0, false}))
+	assert.NoError(t, encoder.Close())
+
+	assert.Equal(t, "{\"id\":\"1\",\"age\":30,\"is_active\":true}\n{\"id\":\"2\",\"age\":0,\"is_active\":false}\n", buffer.String())
+}
+
+func TestColumnarRoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder := NewColumnarEncoder(&buffer)
+	assert.NoError(t, encoder.Begin(RunnerSchema))
+
+	// escribimos más de un row group para comprobar que se leen todos
+	total := RowGroupSize*2 + 10
+	for i := 0; i < total; i++ {
+		assert.NoError(t, encoder.Encode(RunnerRow(&models.Runner{
+			ID:        strings.Repeat("x", i%7),
+			FirstName: "John",
+			LastName:  "Smith",
+			Age:       i % 100,
+			IsActive:  i%2 == 0,
+			Country:   "United States",
+		})))
+	}
+	assert.NoError(t, encoder.Close())
+
+	reader, err := NewColumnarReader(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, RunnerSchema, reader.Schema())
+
+	count := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("x", count%7), row[0])
+		assert.Equal(t, count%100, row[3])
+		assert.Equal(t, count%2 == 0, row[4])
+		count++
+	}
+
+	assert.Equal(t, total, count)
+}
+
+func TestColumnarResultsKeepDistance(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder := NewColumnarEncoder(&buffer)
+	assert.NoError(t, encoder.Begin(ResultSchema))
+	assert.NoError(t, encoder.Encode(ResultRow(&models.Result{ID: "1", RunnerID: "2", RaceResult: "00:29:10", Year: 2022, Distance: 10})))
+	assert.NoError(t, encoder.Encode(ResultRow(&models.Result{ID: "2", RunnerID: "2", RaceResult: "01:01:30", Year: 2022, Distance: 21.0975})))
+	assert.NoError(t, encoder.Close())
+
+	reader, err := NewColumnarReader(&buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, ResultSchema, reader.Schema())
+
+	// un 10K y una media maratón se distinguen por la distancia
+	row, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, row[6])
+	row, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, 21.0975, row[6])
+}
+
+func TestFormatSplits(t *testing.T) {
+	assert.Equal(t, "", formatSplits(nil))
+	assert.Equal(t, "5=00:15:10;10=00:30:05", formatSplits([]*models.Split{
+		{Distance: 5, Time: "00:15:10"},
+		{Distance: 10, Time: "00:30:05"},
+	}))
+}
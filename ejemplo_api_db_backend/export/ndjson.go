@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Cada fila se serializa como un objeto JSON en una línea independiente
+type ndjsonEncoder struct {
+	writer *bufio.Writer
+	keys   [][]byte
+}
+
+func NewNDJSONEncoder(w io.Writer) Encoder {
+	return &ndjsonEncoder{
+		writer: bufio.NewWriter(w),
+	}
+}
+
+func (e *ndjsonEncoder) Begin(schema Schema) error {
+	// precalculamos las claves de los objetos, ya escapadas
+	e.keys = make([][]byte, len(schema))
+	for i, column := range schema {
+		key, err := json.Marshal(column.Name)
+		if err != nil {
+			return err
+		}
+		e.keys[i] = key
+	}
+
+	return nil
+}
+
+func (e *ndjsonEncoder) Encode(row []interface{}) error {
+	if len(row) != len(e.keys) {
+		return fmt.Errorf("row has %d values, schema has %d columns", len(row), len(e.keys))
+	}
+
+	e.writer.WriteByte('{')
+	for i, value := range row {
+		if i > 0 {
+			e.writer.WriteByte(',')
+		}
+
+		value, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		e.writer.Write(e.keys[i])
+		e.writer.WriteByte(':')
+		e.writer.Write(value)
+	}
+	e.writer.WriteByte('}')
+
+	return e.writer.WriteByte('\n')
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return e.Flush()
+}
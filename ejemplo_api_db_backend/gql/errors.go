@@ -0,0 +1,38 @@
+package gql
+
+import (
+	"net/http"
+	"runners-postgresql/models"
+)
+
+// códigos de error de GraphQL equivalentes a los códigos HTTP de los servicios, que se envían en extensions.code
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "BAD_USER_INPUT",
+	http.StatusUnauthorized:        "UNAUTHENTICATED",
+	http.StatusForbidden:           "FORBIDDEN",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusConflict:            "CONFLICT",
+	http.StatusInternalServerError: "INTERNAL_SERVER_ERROR",
+}
+
+// serviceError es el error de un servicio en la respuesta de GraphQL, con el mismo mensaje que en la API REST y el código en las extensiones
+type serviceError struct {
+	responseErr *models.ResponseError
+}
+
+func newError(responseErr *models.ResponseError) error {
+	return &serviceError{responseErr: responseErr}
+}
+
+func (e *serviceError) Error() string {
+	return e.responseErr.Message
+}
+
+func (e *serviceError) Extensions() map[string]interface{} {
+	code, found := errorCodes[e.responseErr.Status]
+	if !found {
+		code = "INTERNAL_SERVER_ERROR"
+	}
+
+	return map[string]interface{}{"code": code}
+}
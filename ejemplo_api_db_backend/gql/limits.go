@@ -0,0 +1,185 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// número de resultados por runner que se estima al calcular la complejidad de una consulta
+const estimatedResultsPerRunner = 10
+
+// Limits protege la base de datos de las consultas demasiado grandes. La profundidad es el número de campos anidados; la complejidad, el número de campos que puede devolver la consulta, multiplicando los campos de las listas por el número de elementos que pueden tener
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// checkLimits calcula la profundidad y la complejidad de la operación que se va a ejecutar. El documento ya está validado, así que los fragmentos existen y no tienen ciclos. Los campos de introspección (__schema, __type) no cuentan
+func checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}, limits Limits) []gqlerrors.FormattedError {
+	analysis := analysis{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			analysis.fragments[definition.Name.Value] = definition
+		}
+	}
+
+	// sin operación el ejecutor responde con el error
+	if operation == nil {
+		return nil
+	}
+
+	depth := analysis.depth(operation.SelectionSet)
+	if depth > limits.MaxDepth {
+		return []gqlerrors.FormattedError{limitError(fmt.Sprintf("Query depth %d exceeds the maximum of %d", depth, limits.MaxDepth))}
+	}
+
+	complexity := analysis.complexity(operation.SelectionSet)
+	if complexity > limits.MaxComplexity {
+		return []gqlerrors.FormattedError{limitError(fmt.Sprintf("Query complexity %d exceeds the maximum of %d", complexity, limits.MaxComplexity))}
+	}
+
+	return nil
+}
+
+func limitError(message string) gqlerrors.FormattedError {
+	formatted := gqlerrors.NewFormattedError(message)
+	formatted.Extensions = map[string]interface{}{"code": "QUERY_TOO_COMPLEX"}
+
+	return formatted
+}
+
+type analysis struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (a analysis) depth(selectionSet *ast.SelectionSet) int {
+	if selectionSet == nil {
+		return 0
+	}
+
+	max := 0
+	for _, selection := range selectionSet.Selections {
+		depth := 0
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if isIntrospection(selection) {
+				continue
+			}
+			depth = 1 + a.depth(selection.SelectionSet)
+		case *ast.InlineFragment:
+			depth = a.depth(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			depth = a.depth(a.fragments[selection.Name.Value].SelectionSet)
+		}
+
+		if depth > max {
+			max = depth
+		}
+	}
+
+	return max
+}
+
+func (a analysis) complexity(selectionSet *ast.SelectionSet) int {
+	if selectionSet == nil {
+		return 0
+	}
+
+	total := 0
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if isIntrospection(selection) {
+				continue
+			}
+			total += 1 + a.listSize(selection)*a.complexity(selection.SelectionSet)
+		case *ast.InlineFragment:
+			total += a.complexity(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			total += a.complexity(a.fragments[selection.Name.Value].SelectionSet)
+		}
+	}
+
+	return total
+}
+
+// listSize estima cuántos elementos puede devolver un campo. Si el tamaño de la página viene en una variable que no se ha enviado se supone el máximo
+func (a analysis) listSize(field *ast.Field) int {
+	switch field.Name.Value {
+	case "runners":
+		for _, argument := range field.Arguments {
+			if argument.Name.Value == "page" {
+				return a.pageSize(argument.Value)
+			}
+		}
+		return defaultPageSize
+	case "results":
+		return estimatedResultsPerRunner
+	}
+
+	return 1
+}
+
+func (a analysis) pageSize(value ast.Value) int {
+	switch value := value.(type) {
+	case *ast.Variable:
+		page, ok := a.variables[value.Name.Value].(map[string]interface{})
+		if !ok {
+			return maxPageSize
+		}
+		size, found := page["size"]
+		if !found {
+			return defaultPageSize
+		}
+		return a.number(size)
+	case *ast.ObjectValue:
+		for _, field := range value.Fields {
+			if field.Name.Value != "size" {
+				continue
+			}
+			switch size := field.Value.(type) {
+			case *ast.IntValue:
+				return a.number(size.Value)
+			case *ast.Variable:
+				return a.number(a.variables[size.Name.Value])
+			}
+		}
+		return defaultPageSize
+	}
+
+	return maxPageSize
+}
+
+// los números de las variables llegan del JSON como float64, y los de la consulta como texto. Si no son válidos el ejecutor rechazará la consulta
+func (a analysis) number(value interface{}) int {
+	switch value := value.(type) {
+	case float64:
+		return int(value)
+	case string:
+		number, err := strconv.Atoi(value)
+		if err == nil {
+			return number
+		}
+	}
+
+	return maxPageSize
+}
+
+func isIntrospection(field *ast.Field) bool {
+	return strings.HasPrefix(field.Name.Value, "__")
+}
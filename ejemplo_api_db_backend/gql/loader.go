@@ -0,0 +1,80 @@
+package gql
+
+import (
+	"context"
+	"runners-postgresql/models"
+	"sync"
+)
+
+// resultsLoader agrupa en una sola consulta los resultados de todos los runners de una petición. Cada runner pide sus resultados con Load, que no consulta nada y devuelve un thunk; el ejecutor de GraphQL resuelve los thunks cuando ha recorrido todo el nivel de la consulta, y el primero que se resuelve carga los resultados de todos los runners pendientes
+type resultsLoader struct {
+	mutex   sync.Mutex
+	fetch   func(ctx context.Context, runnerIds []string) (map[string][]*models.Result, *models.ResponseError)
+	pending []string
+	loaded  map[string][]*models.Result
+}
+
+func newResultsLoader(fetch func(ctx context.Context, runnerIds []string) (map[string][]*models.Result, *models.ResponseError)) *resultsLoader {
+	return &resultsLoader{
+		fetch:  fetch,
+		loaded: make(map[string][]*models.Result),
+	}
+}
+
+// Load devuelve un thunk con los resultados del runner
+func (l *resultsLoader) Load(ctx context.Context, runnerId string) func() (interface{}, error) {
+	l.mutex.Lock()
+	if _, found := l.loaded[runnerId]; !found {
+		l.pending = append(l.pending, runnerId)
+	}
+	l.mutex.Unlock()
+
+	return func() (interface{}, error) {
+		results, responseErr := l.get(ctx, runnerId)
+		if responseErr != nil {
+			return nil, newError(responseErr)
+		}
+
+		return results, nil
+	}
+}
+
+func (l *resultsLoader) get(ctx context.Context, runnerId string) ([]*models.Result, *models.ResponseError) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if results, found := l.loaded[runnerId]; found {
+		return results, nil
+	}
+
+	runnerIds := unique(l.pending)
+	l.pending = nil
+
+	results, responseErr := l.fetch(ctx, runnerIds)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	// los runners sin resultados también quedan cargados, para no volver a consultarlos
+	for _, id := range runnerIds {
+		l.loaded[id] = results[id]
+		if l.loaded[id] == nil {
+			l.loaded[id] = make([]*models.Result, 0)
+		}
+	}
+
+	return l.loaded[runnerId], nil
+}
+
+func unique(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,421 @@
+package gql
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+	"runners-postgresql/tracing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// tamaño de página por defecto y máximo del listado de runners, los mismos que admite el servicio
+const defaultPageSize = 20
+const maxPageSize = 100
+
+// Request es una petición de GraphQL
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+const roleAdmin = "admin"
+
+type principalKey struct{}
+type loaderKey struct{}
+
+// Schema ejecuta las consultas de GraphQL sobre los servicios de runners y resultados. Los permisos, las validaciones y las preferencias de privacidad son los de la API REST, porque los aplican los servicios
+type Schema struct {
+	schema         graphql.Schema
+	runnersService *services.RunnersService
+	resultsService *services.ResultsService
+	limits         Limits
+}
+
+func NewSchema(runnersService *services.RunnersService, resultsService *services.ResultsService, limits Limits) (*Schema, error) {
+	s := &Schema{
+		runnersService: runnersService,
+		resultsService: resultsService,
+		limits:         limits,
+	}
+
+	splitType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Split",
+		Fields: graphql.Fields{
+			"distance": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"time":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	resultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Result",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"runnerId":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"raceResult": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"location":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"position": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optionalInt(p.Source.(*models.Result).Position), nil
+			}},
+			"year": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			// en kilómetros, para distinguir la maratón de las demás distancias
+			"distance": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"splits": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(splitType))),
+				Description: "Tiempos de paso del resultado, de la salida a la llegada",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					splits := p.Source.(*models.Result).Splits
+					if splits == nil {
+						return []*models.Split{}, nil
+					}
+					return splits, nil
+				},
+			},
+		},
+	})
+
+	runnerType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Runner",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"firstName": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"lastName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"age": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optionalInt(p.Source.(*models.Runner).Age), nil
+			}},
+			"isActive": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"country":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"personalBest": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optionalString(p.Source.(*models.Runner).PersonalBest), nil
+			}},
+			"seasonBest": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return optionalString(p.Source.(*models.Runner).SeasonBest), nil
+			}},
+			"results": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(resultType))),
+				Description: "Resultados del runner, del más reciente al más antiguo",
+				Args: graphql.FieldConfigArgument{
+					"year": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: s.resolveResults,
+			},
+		},
+	})
+
+	runnerPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RunnerPage",
+		Fields: graphql.Fields{
+			"page":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"pageSize": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"total":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"items": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(runnerType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.RunnerPage).Runners, nil
+				},
+			},
+		},
+	})
+
+	runnersFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "RunnersFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"country": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"year":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+
+	pageInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "PageInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"number": &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: 1},
+			"size":   &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+		},
+	})
+
+	runnerInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "RunnerInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"firstName": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"lastName":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"age":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"isActive":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean, DefaultValue: true},
+			"country":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"runner": &graphql.Field{
+				Type: runnerType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.resolveRunner,
+			},
+			"runners": &graphql.Field{
+				Type: graphql.NewNonNull(runnerPageType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: runnersFilterType},
+					"page":   &graphql.ArgumentConfig{Type: pageInputType},
+				},
+				Resolve: s.resolveRunners,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createRunner": &graphql.Field{
+				Type: graphql.NewNonNull(runnerType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(runnerInputType)},
+				},
+				Resolve: s.resolveCreateRunner,
+			},
+			"updateRunner": &graphql.Field{
+				Type: runnerType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(runnerInputType)},
+				},
+				Resolve: s.resolveUpdateRunner,
+			},
+			"deleteRunner": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.resolveDeleteRunner,
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.schema = schema
+
+	return s, nil
+}
+
+// Execute valida y ejecuta una petición en nombre del usuario. Las consultas que superan los límites de profundidad o de complejidad no se ejecutan
+func (s *Schema) Execute(ctx context.Context, principal *models.Principal, request Request) *graphql.Result {
+	ctx, span := tracing.Start(ctx, "GraphQL.Execute")
+	defer span.End()
+
+	doc, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&s.schema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	limitErrors := checkLimits(doc, request.OperationName, request.Variables, s.limits)
+	if limitErrors != nil {
+		return &graphql.Result{Errors: limitErrors}
+	}
+
+	// cada petición tiene su propio loader, así que los resultados solo se agrupan dentro de la petición y con los permisos del usuario
+	loader := newResultsLoader(func(ctx context.Context, runnerIds []string) (map[string][]*models.Result, *models.ResponseError) {
+		return s.resultsService.GetResultsByRunners(ctx, principal, runnerIds)
+	})
+	ctx = context.WithValue(ctx, principalKey{}, principal)
+	ctx = context.WithValue(ctx, loaderKey{}, loader)
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       ctx,
+	})
+}
+
+func principalFrom(ctx context.Context) *models.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return principal
+}
+
+func (s *Schema) resolveRunner(p graphql.ResolveParams) (interface{}, error) {
+	runner, responseErr := s.runnersService.GetRunner(p.Context, principalFrom(p.Context), p.Args["id"].(string))
+	if responseErr != nil {
+		// un runner que no existe es null, como en cualquier API de GraphQL
+		if responseErr.Status == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, newError(responseErr)
+	}
+
+	return runner, nil
+}
+
+func (s *Schema) resolveRunners(p graphql.ResolveParams) (interface{}, error) {
+	var filter models.RunnersFilter
+	if input, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Country, _ = input["country"].(string)
+		filter.Year, _ = input["year"].(int)
+	}
+
+	page, pageSize := 1, defaultPageSize
+	if input, ok := p.Args["page"].(map[string]interface{}); ok {
+		if number, ok := input["number"].(int); ok {
+			page = number
+		}
+		if size, ok := input["size"].(int); ok {
+			pageSize = size
+		}
+	}
+
+	runnerPage, responseErr := s.runnersService.GetRunnersPage(p.Context, principalFrom(p.Context), filter, page, pageSize)
+	if responseErr != nil {
+		return nil, newError(responseErr)
+	}
+
+	return runnerPage, nil
+}
+
+// resolveResults devuelve los resultados que ya tiene el runner (los de runner(id) vienen con sus resultados) o los pide al loader, que los carga para todos los runners de la consulta a la vez
+func (s *Schema) resolveResults(p graphql.ResolveParams) (interface{}, error) {
+	runner := p.Source.(*models.Runner)
+	year, filtered := p.Args["year"].(int)
+
+	if runner.Results != nil {
+		return filterByYear(runner.Results, year, filtered), nil
+	}
+
+	loader := p.Context.Value(loaderKey{}).(*resultsLoader)
+	thunk := loader.Load(p.Context, runner.ID)
+
+	return func() (interface{}, error) {
+		results, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+
+		return filterByYear(results.([]*models.Result), year, filtered), nil
+	}, nil
+}
+
+func filterByYear(results []*models.Result, year int, filtered bool) []*models.Result {
+	if !filtered {
+		return results
+	}
+
+	yearResults := make([]*models.Result, 0)
+	for _, result := range results {
+		if result.Year == year {
+			yearResults = append(yearResults, result)
+		}
+	}
+
+	return yearResults
+}
+
+func (s *Schema) resolveCreateRunner(p graphql.ResolveParams) (interface{}, error) {
+	principal, err := requireStaff(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, responseErr := s.runnersService.CreateRunner(p.Context, principal, runnerFromInput(p.Args["input"]))
+	if responseErr != nil {
+		return nil, newError(responseErr)
+	}
+
+	return runner, nil
+}
+
+func (s *Schema) resolveUpdateRunner(p graphql.ResolveParams) (interface{}, error) {
+	principal, err := requireStaff(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	runner := runnerFromInput(p.Args["input"])
+	runner.ID = p.Args["id"].(string)
+	responseErr := s.runnersService.UpdateRunner(p.Context, principal, runner)
+	if responseErr != nil {
+		return nil, newError(responseErr)
+	}
+
+	// se devuelve el runner como queda guardado, con sus marcas y resultados
+	return s.resolveRunner(p)
+}
+
+func (s *Schema) resolveDeleteRunner(p graphql.ResolveParams) (interface{}, error) {
+	principal, err := requireStaff(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	responseErr := s.runnersService.DeleteRunner(p.Context, principal, p.Args["id"].(string))
+	if responseErr != nil {
+		return nil, newError(responseErr)
+	}
+
+	return true, nil
+}
+
+// requireStaff comprueba que el usuario puede modificar runners: los administradores y los administradores de club, con los mismos permisos que en la API REST. Las claves de API además necesitan el permiso runners:write
+func requireStaff(ctx context.Context) (*models.Principal, error) {
+	principal := principalFrom(ctx)
+	if principal == nil || (principal.Role != roleAdmin && principal.Role != models.RoleClubAdmin) {
+		return nil, newError(&models.ResponseError{
+			Message: "Insufficient role",
+			Status:  http.StatusForbidden,
+		})
+	}
+
+	if !principal.HasScope(models.ScopeRunnersWrite) {
+		return nil, newError(&models.ResponseError{
+			Message: "Missing scope " + models.ScopeRunnersWrite,
+			Status:  http.StatusForbidden,
+		})
+	}
+
+	return principal, nil
+}
+
+func runnerFromInput(value interface{}) *models.Runner {
+	input := value.(map[string]interface{})
+	runner := &models.Runner{}
+	runner.FirstName, _ = input["firstName"].(string)
+	runner.LastName, _ = input["lastName"].(string)
+	runner.Age, _ = input["age"].(int)
+	runner.IsActive, _ = input["isActive"].(bool)
+	runner.Country, _ = input["country"].(string)
+
+	return runner
+}
+
+// optionalInt y optionalString devuelven null en lugar del valor vacío de los campos que la API REST omite cuando están vacíos (por ejemplo, la edad que el runner oculta)
+func optionalInt(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+
+	return value
+}
+
+func optionalString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
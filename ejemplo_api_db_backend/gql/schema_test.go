@@ -0,0 +1,99 @@
+package gql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"runners-postgresql/services"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedResultsUseOneQuery(t *testing.T) {
+	dbHandler, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+
+	schema := newTestSchema(t, dbHandler, Limits{MaxDepth: 5, MaxComplexity: 2000})
+
+	mock.ExpectQuery("SELECT runners.id, runners.first_name, runners.last_name").WithArgs(2, 0, false, "", "Spain").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best", "count"}).
+			AddRow("1", "Juan", "García", 30, true, "Spain", "02:05:00", nil, 3).
+			AddRow("2", "Ana", "López", nil, true, "Spain", nil, nil, 3))
+	// los resultados de los dos runners se leen con una sola consulta
+	mock.ExpectQuery("SELECT id, runner_id, race_result").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "runner_id", "race_result", "location", "position", "year", "distance", "split_distances", "split_times"}).
+			AddRow("10", "1", "02:05:00", "Valencia", 3, 2023, 42.195, "{21.0975,42.195}", "{01:02:00,02:05:00}").
+			AddRow("11", "1", "01:07:00", "Berlin", nil, 2022, 21.0975, "{}", "{}"))
+
+	result := schema.Execute(context.Background(), &models.Principal{Role: "admin"}, Request{
+		Query: `query ($page: PageInput) {
+			runners(filter: {country: "Spain"}, page: $page) {
+				total
+				items { firstName age results { location position distance splits { distance time } } }
+			}
+		}`,
+		Variables: map[string]interface{}{"page": map[string]interface{}{"number": 1.0, "size": 2.0}},
+	})
+	require.Empty(t, result.Errors)
+
+	data, err := json.Marshal(result.Data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"runners": {"total": 3, "items": [
+		{"firstName": "Juan", "age": 30, "results": [
+			{"location": "Valencia", "position": 3, "distance": 42.195, "splits": [{"distance": 21.0975, "time": "01:02:00"}, {"distance": 42.195, "time": "02:05:00"}]},
+			{"location": "Berlin", "position": null, "distance": 21.0975, "splits": []}
+		]},
+		{"firstName": "Ana", "age": null, "results": []}
+	]}}`, string(data))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLimitsRejectQueriesBeforeExecuting(t *testing.T) {
+	dbHandler, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+
+	schema := newTestSchema(t, dbHandler, Limits{MaxDepth: 3, MaxComplexity: 250})
+	principal := &models.Principal{Role: "admin"}
+
+	// runners > items > results > location: profundidad 4
+	result := schema.Execute(context.Background(), principal, Request{
+		Query: `{ runners { items { results { location } } } }`,
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Query depth 4 exceeds the maximum of 3", result.Errors[0].Message)
+	assert.Equal(t, "QUERY_TOO_COMPLEX", result.Errors[0].Extensions["code"])
+
+	// 1 + 50 × (1 + 4): los campos de los runners cuentan una vez por cada runner de la página
+	result = schema.Execute(context.Background(), principal, Request{
+		Query: `fragment names on Runner { firstName lastName } { runners(page: {size: 50}) { items { id country ...names } } }`,
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Query complexity 251 exceeds the maximum of 250", result.Errors[0].Message)
+
+	// los runners no pueden modificar runners
+	result = schema.Execute(context.Background(), &models.Principal{Role: "runner"}, Request{
+		Query: `mutation { deleteRunner(id: "1") }`,
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Insufficient role", result.Errors[0].Message)
+	assert.Equal(t, "FORBIDDEN", result.Errors[0].Extensions["code"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func newTestSchema(t *testing.T, dbHandler *sql.DB, limits Limits) *Schema {
+	t.Helper()
+
+	runnersService := services.NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+	resultsService := services.NewResultsService(repositories.NewResultsRepository(dbHandler), nil, nil, nil, nil, nil)
+	schema, err := NewSchema(runnersService, resultsService, limits)
+	require.NoError(t, err)
+
+	return schema
+}
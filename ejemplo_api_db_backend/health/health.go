@@ -0,0 +1,143 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc comprueba una dependencia. Devuelve nil si está bien
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name      string
+	readiness bool
+	run       CheckFunc
+}
+
+// Resultado de una comprobación
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Readiness bool    `json:"readiness"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Informe con el resultado de todas las comprobaciones. Status es down si falla alguna de las comprobaciones incluidas
+type Report struct {
+	Status string         `json:"status"`
+	Checks []*CheckResult `json:"checks"`
+}
+
+// Registry guarda las comprobaciones de salud de la aplicación. Cada backend registra las de sus dependencias
+type Registry struct {
+	mutex   sync.RWMutex
+	checks  []check
+	timeout time.Duration
+}
+
+// NewRegistry crea el registro. Cada comprobación tiene como mucho timeout para responder
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	return &Registry{
+		timeout: timeout,
+	}
+}
+
+// Register añade una comprobación. Las de readiness deciden si la aplicación puede recibir tráfico; el resto solo aparecen en el informe detallado
+func (r *Registry) Register(name string, readiness bool, run CheckFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.checks = append(r.checks, check{
+		name:      name,
+		readiness: readiness,
+		run:       run,
+	})
+}
+
+// Readiness ejecuta solo las comprobaciones de readiness
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	return r.run(ctx, true)
+}
+
+// Detailed ejecuta todas las comprobaciones
+func (r *Registry) Detailed(ctx context.Context) *Report {
+	return r.run(ctx, false)
+}
+
+// las comprobaciones se ejecutan en paralelo, así que el informe tarda lo que la más lenta
+func (r *Registry) run(ctx context.Context, readinessOnly bool) *Report {
+	r.mutex.RLock()
+	checks := make([]check, 0, len(r.checks))
+	for _, registered := range r.checks {
+		if !readinessOnly || registered.readiness {
+			checks = append(checks, registered)
+		}
+	}
+	r.mutex.RUnlock()
+
+	report := &Report{
+		Status: StatusUp,
+		Checks: make([]*CheckResult, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Checks[i] = r.runCheck(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) runCheck(ctx context.Context, registered check) *CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// si la comprobación no respeta el contexto no esperamos más del timeout
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		done <- registered.run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := &CheckResult{
+		Name:      registered.name,
+		Status:    StatusUp,
+		Readiness: registered.readiness,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
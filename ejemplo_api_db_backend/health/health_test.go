@@ -0,0 +1,62 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryReadiness(t *testing.T) {
+	registry := NewRegistry(50 * time.Millisecond)
+	registry.Register("database", true, func(ctx context.Context) error { return nil })
+	registry.Register("cache", false, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	// la caché no forma parte de readiness
+	report := registry.Readiness(context.Background())
+	assert.Equal(t, StatusUp, report.Status)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "database", report.Checks[0].Name)
+
+	report = registry.Detailed(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusDown, report.Checks[1].Status)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	registry := NewRegistry(20 * time.Millisecond)
+	// una comprobación que no respeta el contexto no bloquea el informe
+	registry.Register("stuck", true, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := registry.Readiness(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT to_regclass\\('runners'\\)").WillReturnRows(sqlmock.NewRows([]string{"applied"}).AddRow(true))
+	mock.ExpectQuery("SELECT to_regclass\\('clubs'\\)").WillReturnRows(sqlmock.NewRows([]string{"applied"}).AddRow(false))
+
+	err = Migrations(db, []Migration{
+		{Script: "public_schema.sql", Query: "SELECT to_regclass('runners') IS NOT NULL"},
+		{Script: "clubs_schema.sql", Query: "SELECT to_regclass('clubs') IS NOT NULL"},
+	})(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "pending migrations: clubs_schema.sql", err.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
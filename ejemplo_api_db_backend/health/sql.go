@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Database comprueba que la base de datos responde
+func Database(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Pool comprueba que el pool de conexiones no está saturado: falla si las conexiones en uso alcanzan la fracción saturation del máximo. Sin máximo de conexiones abiertas el pool no se satura
+func Pool(db *sql.DB, saturation float64) CheckFunc {
+	return func(ctx context.Context) error {
+		stats := db.Stats()
+		if stats.MaxOpenConnections <= 0 {
+			return nil
+		}
+
+		if float64(stats.InUse) >= saturation*float64(stats.MaxOpenConnections) {
+			return fmt.Errorf("connection pool saturated: %d of %d connections in use, %d waits", stats.InUse, stats.MaxOpenConnections, stats.WaitCount)
+		}
+
+		return nil
+	}
+}
+
+// Migration es un script de dbscripts y la consulta que indica si está aplicado. La consulta devuelve un booleano
+type Migration struct {
+	Script string
+	Query  string
+}
+
+// Migrations comprueba que están aplicados todos los scripts de la base de datos
+func Migrations(db *sql.DB, migrations []Migration) CheckFunc {
+	return func(ctx context.Context) error {
+		pending := make([]string, 0)
+		for _, migration := range migrations {
+			var applied bool
+			err := db.QueryRowContext(ctx, migration.Query).Scan(&applied)
+			if err != nil {
+				return fmt.Errorf("%s: %w", migration.Script, err)
+			}
+
+			if !applied {
+				pending = append(pending, migration.Script)
+			}
+		}
+
+		if len(pending) > 0 {
+			return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,237 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Hook es un componente de la aplicación con su arranque y su parada. Los dos son opcionales
+type Hook struct {
+	Name string
+	// Start arranca el componente y vuelve enseguida. Los componentes que se ejecutan en segundo plano informan de sus errores con el fail que reciben
+	Start func(ctx context.Context, fail func(error)) error
+	// Stop detiene el componente. Tiene que terminar antes de que venza el contexto
+	Stop func(ctx context.Context) error
+}
+
+// Manager arranca los componentes en el orden en que se registran y los detiene en el orden inverso. Mientras la aplicación está arrancada y no se está deteniendo se considera lista para recibir tráfico
+type Manager struct {
+	hooks           []Hook
+	ready           atomic.Bool
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	failures        chan error
+}
+
+// New crea el gestor. Al parar, primero deja de estar listo y espera drainDelay para que el balanceador deje de enviar tráfico, y después tiene shutdownTimeout para detener todos los componentes
+func New(shutdownTimeout time.Duration, drainDelay time.Duration) *Manager {
+	return &Manager{
+		shutdownTimeout: shutdownTimeout,
+		drainDelay:      drainDelay,
+		failures:        make(chan error, 1),
+	}
+}
+
+func (m *Manager) Register(hook Hook) {
+	m.hooks = append(m.hooks, hook)
+}
+
+// Ready indica si la aplicación está arrancada y no se está deteniendo
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Run arranca los componentes y espera a SIGTERM o SIGINT, a que se cancele el contexto o a que falle un componente. Después detiene los componentes. Devuelve el error del arranque o del componente que falló, y los errores de la parada
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	started := 0
+	var runErr error
+	for _, hook := range m.hooks {
+		if hook.Start != nil {
+			slog.Info("Starting component", "component", hook.Name)
+			err := hook.Start(ctx, m.fail)
+			if err != nil {
+				runErr = fmt.Errorf("%s: %w", hook.Name, err)
+				break
+			}
+		}
+		started++
+	}
+
+	if runErr == nil {
+		m.ready.Store(true)
+		slog.Info("Application started")
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Shutdown requested")
+		case err := <-m.failures:
+			runErr = err
+			slog.Error("Shutting down after component failure", "error", err)
+		}
+	}
+
+	// dejamos de estar listos y damos tiempo a que el balanceador lo vea antes de dejar de aceptar peticiones
+	m.ready.Store(false)
+	if runErr == nil && m.drainDelay > 0 {
+		time.Sleep(m.drainDelay)
+	}
+
+	return errors.Join(runErr, m.shutdown(m.hooks[:started]))
+}
+
+func (m *Manager) shutdown(hooks []Hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	errs := make([]error, 0)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+
+		slog.Info("Stopping component", "component", hook.Name)
+		err := hook.Stop(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fail informa del error de un componente en segundo plano. Solo el primero provoca la parada
+func (m *Manager) fail(err error) {
+	select {
+	case m.failures <- err:
+	default:
+	}
+}
+
+// Server gestiona un servidor HTTP, o HTTPS si tiene TLSConfig. El puerto se abre al arrancar, de modo que un puerto ocupado es un error de arranque, y al parar se deja de aceptar conexiones y se espera a que terminen las peticiones en curso
+func Server(name string, server *http.Server) Hook {
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context, fail func(error)) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+
+			slog.Info("Server listening", "server", name, "address", listener.Addr().String(), "tls", server.TLSConfig != nil)
+			go func() {
+				var err error
+				if server.TLSConfig != nil {
+					// los certificados están en server.TLSConfig
+					err = server.ServeTLS(listener, "", "")
+				} else {
+					err = server.Serve(listener)
+				}
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			err := server.Shutdown(ctx)
+			if err != nil {
+				// vence el plazo: cerramos las conexiones que quedan
+				server.Close()
+			}
+			return err
+		},
+	}
+}
+
+// GrpcServer gestiona un servidor gRPC igual que Server: el puerto se abre al arrancar y al parar se espera a que terminen las llamadas en curso. Si vence el plazo se cierran las que quedan
+func GrpcServer(name string, server *grpc.Server, address string) Hook {
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context, fail func(error)) error {
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+
+			slog.Info("Server listening", "server", name, "address", listener.Addr().String())
+			go func() {
+				err := server.Serve(listener)
+				if err != nil {
+					fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				server.GracefulStop()
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				server.Stop()
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// Background gestiona una tarea que se ejecuta en segundo plano hasta que se cancela su contexto. Al parar se cancela y se espera a que termine
+func Background(name string, run func(ctx context.Context)) Hook {
+	var cancel context.CancelFunc
+	var done sync.WaitGroup
+
+	return Hook{
+		Name: name,
+		Start: func(_ context.Context, _ func(error)) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+
+			done.Add(1)
+			go func() {
+				defer done.Done()
+				run(ctx)
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+
+			finished := make(chan struct{})
+			go func() {
+				done.Wait()
+				close(finished)
+			}()
+
+			select {
+			case <-finished:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
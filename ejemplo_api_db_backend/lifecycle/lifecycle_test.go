@@ -0,0 +1,141 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerOrder(t *testing.T) {
+	events := make([]string, 0)
+	hook := func(name string) Hook {
+		return Hook{
+			Name: name,
+			Start: func(ctx context.Context, fail func(error)) error {
+				events = append(events, "start "+name)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				events = append(events, "stop "+name)
+				return nil
+			},
+		}
+	}
+
+	manager := New(time.Second, 0)
+	manager.Register(hook("database"))
+	manager.Register(hook("dispatcher"))
+	manager.Register(hook("http"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, manager.Run(ctx))
+	assert.Equal(t, []string{
+		"start database", "start dispatcher", "start http",
+		"stop http", "stop dispatcher", "stop database",
+	}, events)
+	assert.False(t, manager.Ready())
+}
+
+func TestManagerStartFailure(t *testing.T) {
+	stopped := make([]string, 0)
+	manager := New(time.Second, 0)
+	manager.Register(Hook{
+		Name: "database",
+		Stop: func(ctx context.Context) error {
+			stopped = append(stopped, "database")
+			return nil
+		},
+	})
+	manager.Register(Hook{
+		Name: "http",
+		Start: func(ctx context.Context, fail func(error)) error {
+			return errors.New("address already in use")
+		},
+		Stop: func(ctx context.Context) error {
+			stopped = append(stopped, "http")
+			return nil
+		},
+	})
+
+	err := manager.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http: address already in use")
+	// solo se detienen los componentes que llegaron a arrancar
+	assert.Equal(t, []string{"database"}, stopped)
+}
+
+func TestServerDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := &http.Server{
+		Addr: freeAddress(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			io.WriteString(w, "done")
+		}),
+	}
+
+	hook := Server("test", server)
+	failed := make(chan error, 1)
+	require.NoError(t, hook.Start(context.Background(), func(err error) { failed <- err }))
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + server.Addr)
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	<-started
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- hook.Stop(context.Background())
+	}()
+
+	// la parada espera a la petición en curso
+	select {
+	case <-stopped:
+		t.Fatal("server stopped before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, "done", <-response)
+	assert.NoError(t, <-stopped)
+	assert.Empty(t, failed)
+}
+
+func TestBackgroundStop(t *testing.T) {
+	finished := false
+	hook := Background("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		finished = true
+	})
+
+	require.NoError(t, hook.Start(context.Background(), nil))
+	require.NoError(t, hook.Stop(context.Background()))
+	assert.True(t, finished)
+}
+
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
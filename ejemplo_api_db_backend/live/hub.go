@@ -0,0 +1,265 @@
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"runners-postgresql/models"
+	"strings"
+	"sync"
+)
+
+// Motivo por el que se cierra la suscripción de un consumidor que no lee lo bastante rápido
+var ErrSlowConsumer = errors.New("slow consumer")
+
+// Motivo por el que se cierran las suscripciones cuando se detiene la aplicación
+var ErrShutdown = errors.New("server shutting down")
+
+// Mensaje que se envía a los suscriptores. Seq es creciente y se usa como id del evento para poder reconectar sin perder mensajes
+type Message struct {
+	Seq      uint64          `json:"seq"`
+	Type     string          `json:"type"`
+	RunnerID string          `json:"runner_id"`
+	Race     string          `json:"race,omitempty"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Filtro de una suscripción. Los campos vacíos no filtran
+type Filter struct {
+	Race     string
+	RunnerID string
+}
+
+func (f Filter) matches(message *Message) bool {
+	if f.RunnerID != "" && f.RunnerID != message.RunnerID {
+		return false
+	}
+
+	if f.Race != "" && !strings.EqualFold(f.Race, message.Race) {
+		return false
+	}
+
+	return true
+}
+
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	ch     chan *Message
+	err    error
+	closed bool
+}
+
+// C devuelve el canal por el que llegan los mensajes. El canal se cierra cuando termina la suscripción
+func (s *Subscription) C() <-chan *Message {
+	return s.ch
+}
+
+// Err indica por qué se cerró la suscripción, si fue el hub quien la cerró
+func (s *Subscription) Err() error {
+	s.hub.mutex.Lock()
+	defer s.hub.mutex.Unlock()
+
+	return s.err
+}
+
+// Close da de baja la suscripción
+func (s *Subscription) Close() {
+	s.hub.mutex.Lock()
+	defer s.hub.mutex.Unlock()
+
+	s.hub.remove(s, nil)
+}
+
+// El hub reparte los eventos entre los suscriptores, y guarda los últimos eventos para enviarlos a los nuevos suscriptores
+type Hub struct {
+	mutex       sync.Mutex
+	seq         uint64
+	replay      []*Message // buffer circular con los últimos mensajes
+	next        int
+	queueSize   int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub crea un hub que guarda los últimos replaySize mensajes. Cada suscriptor puede tener hasta queueSize mensajes pendientes de leer; si se supera se le desconecta
+func NewHub(replaySize int, queueSize int) *Hub {
+	if replaySize <= 0 {
+		replaySize = 100
+	}
+	if queueSize <= 0 {
+		queueSize = 64
+	}
+
+	return &Hub{
+		replay:      make([]*Message, 0, replaySize),
+		queueSize:   queueSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish convierte los eventos de dominio en mensajes y los reparte. Solo se publican los eventos de resultados y marcas personales
+func (h *Hub) Publish(events ...*models.Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, event := range events {
+		message, ok := newMessage(event)
+		if !ok {
+			continue
+		}
+
+		h.seq++
+		message.Seq = h.seq
+		h.store(message)
+
+		for subscription := range h.subscribers {
+			if !subscription.filter.matches(message) {
+				continue
+			}
+
+			select {
+			case subscription.ch <- message:
+			default:
+				// la cola del suscriptor está llena: en lugar de bloquear al resto lo desconectamos. Podrá reconectar indicando el último Seq recibido
+				h.remove(subscription, ErrSlowConsumer)
+			}
+		}
+	}
+}
+
+// Subscribe da de alta un suscriptor. Si lastSeq es mayor que cero se le reenvían los mensajes guardados posteriores a lastSeq; si no, los últimos replay mensajes que cumplan el filtro
+func (h *Hub) Subscribe(filter Filter, lastSeq uint64, replay int) *Subscription {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if replay > cap(h.replay) {
+		replay = cap(h.replay)
+	}
+
+	pending := make([]*Message, 0)
+	h.each(func(message *Message) {
+		if !filter.matches(message) {
+			return
+		}
+		if lastSeq > 0 && message.Seq <= lastSeq {
+			return
+		}
+		pending = append(pending, message)
+	})
+
+	if lastSeq == 0 {
+		if replay < 0 {
+			replay = 0
+		}
+		if len(pending) > replay {
+			pending = pending[len(pending)-replay:]
+		}
+	}
+
+	// el canal tiene capacidad para el replay además de la cola del suscriptor
+	subscription := &Subscription{
+		hub:    h,
+		filter: filter,
+		ch:     make(chan *Message, len(pending)+h.queueSize),
+	}
+	for _, message := range pending {
+		subscription.ch <- message
+	}
+
+	// con el hub cerrado el suscriptor recibe lo pendiente y la suscripción termina
+	if h.closed {
+		subscription.closed = true
+		subscription.err = ErrShutdown
+		close(subscription.ch)
+		return subscription
+	}
+
+	h.subscribers[subscription] = struct{}{}
+
+	return subscription
+}
+
+// Close cierra todas las suscripciones con ErrShutdown y hace que las nuevas terminen enseguida. Se llama al detener la aplicación para que las conexiones del feed no retrasen la parada
+func (h *Hub) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closed = true
+	for subscription := range h.subscribers {
+		h.remove(subscription, ErrShutdown)
+	}
+}
+
+// Subscribers devuelve el número de suscripciones activas
+func (h *Hub) Subscribers() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.subscribers)
+}
+
+// hay que llamarlo con el mutex cogido
+func (h *Hub) remove(subscription *Subscription, err error) {
+	if subscription.closed {
+		return
+	}
+
+	subscription.closed = true
+	subscription.err = err
+	delete(h.subscribers, subscription)
+	close(subscription.ch)
+}
+
+func (h *Hub) store(message *Message) {
+	if len(h.replay) < cap(h.replay) {
+		h.replay = append(h.replay, message)
+		return
+	}
+
+	h.replay[h.next] = message
+	h.next = (h.next + 1) % cap(h.replay)
+}
+
+// recorre los mensajes guardados del más antiguo al más reciente
+func (h *Hub) each(fn func(*Message)) {
+	if len(h.replay) < cap(h.replay) {
+		for _, message := range h.replay {
+			fn(message)
+		}
+		return
+	}
+
+	for i := 0; i < len(h.replay); i++ {
+		fn(h.replay[(h.next+i)%len(h.replay)])
+	}
+}
+
+func newMessage(event *models.Event) (*Message, bool) {
+	message := &Message{
+		Type: event.Type,
+		Data: event.Payload,
+	}
+
+	switch event.Type {
+	case models.EventResultCreated:
+		var result models.Result
+		err := json.Unmarshal(event.Payload, &result)
+		if err != nil {
+			return nil, false
+		}
+		message.RunnerID = result.RunnerID
+		message.Race = result.Location
+	case models.EventRunnerPersonalBest:
+		var personalBest models.PersonalBestEvent
+		err := json.Unmarshal(event.Payload, &personalBest)
+		if err != nil {
+			return nil, false
+		}
+		message.RunnerID = personalBest.RunnerID
+		message.Race = personalBest.Location
+	default:
+		return nil, false
+	}
+
+	return message, true
+}
@@ -0,0 +1,91 @@
+package live
+
+import (
+	"encoding/json"
+	"runners-postgresql/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resultEvent(runnerId string, location string) *models.Event {
+	payload, _ := json.Marshal(&models.Result{RunnerID: runnerId, Location: location, RaceResult: "02:10:00"})
+	return &models.Event{Type: models.EventResultCreated, Payload: payload}
+}
+
+func TestHubFilter(t *testing.T) {
+	hub := NewHub(10, 10)
+	subscription := hub.Subscribe(Filter{Race: "berlin", RunnerID: "1"}, 0, 0)
+
+	hub.Publish(
+		resultEvent("1", "Berlin"),
+		resultEvent("2", "Berlin"),
+		resultEvent("1", "London"),
+		// los eventos de baja de runners no se publican en el feed
+		&models.Event{Type: models.EventRunnerDeleted, Payload: []byte(`{"runner_id":"1"}`)},
+	)
+
+	assert.Len(t, subscription.C(), 1)
+	message := <-subscription.C()
+	assert.Equal(t, uint64(1), message.Seq)
+	assert.Equal(t, "1", message.RunnerID)
+	assert.Equal(t, "Berlin", message.Race)
+}
+
+func TestHubReplay(t *testing.T) {
+	hub := NewHub(3, 10)
+	for i := 0; i < 5; i++ {
+		hub.Publish(resultEvent("1", "Berlin"))
+	}
+
+	// el buffer solo guarda los tres últimos mensajes
+	subscription := hub.Subscribe(Filter{}, 0, 10)
+	assert.Len(t, subscription.C(), 3)
+	assert.Equal(t, uint64(3), (<-subscription.C()).Seq)
+
+	// al reconectar con Last-Event-ID solo se reenvían los posteriores
+	subscription = hub.Subscribe(Filter{}, 4, 0)
+	assert.Len(t, subscription.C(), 1)
+	assert.Equal(t, uint64(5), (<-subscription.C()).Seq)
+}
+
+func TestHubSlowConsumer(t *testing.T) {
+	hub := NewHub(10, 2)
+	slow := hub.Subscribe(Filter{}, 0, 0)
+	assert.Equal(t, 1, hub.Subscribers())
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(resultEvent("1", "Berlin"))
+	}
+
+	// al llenarse la cola el suscriptor se desconecta, pero conserva los mensajes que ya tenía
+	assert.Equal(t, 0, hub.Subscribers())
+	assert.Equal(t, ErrSlowConsumer, slow.Err())
+	count := 0
+	for range slow.C() {
+		count++
+	}
+	assert.Equal(t, 2, count)
+
+	// cerrar una suscripción ya cerrada no falla
+	slow.Close()
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(10, 2)
+	hub.Publish(resultEvent("1", "Berlin"))
+	subscription := hub.Subscribe(Filter{}, 0, 0)
+
+	hub.Close()
+	assert.Equal(t, 0, hub.Subscribers())
+	assert.Equal(t, ErrShutdown, subscription.Err())
+	_, ok := <-subscription.C()
+	assert.False(t, ok)
+
+	// tras cerrar el hub las nuevas suscripciones reciben lo guardado y terminan
+	late := hub.Subscribe(Filter{}, 0, 10)
+	assert.Equal(t, ErrShutdown, late.Err())
+	assert.Equal(t, uint64(1), (<-late.C()).Seq)
+	_, ok = <-late.C()
+	assert.False(t, ok)
+}
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"runners-postgresql/config"
+	"runners-postgresql/tracing"
+)
+
+// Init configura el logger por defecto con el formato y el nivel de la configuración. Los mensajes del paquete log también pasan por él. Devuelve el nivel, que se puede cambiar en caliente
+func Init(config config.LogConfig) *slog.LevelVar {
+	level := new(slog.LevelVar)
+	level.Set(config.SlogLevel())
+	slog.SetDefault(New(os.Stderr, config.Format, level))
+
+	return level
+}
+
+// New crea un logger que escribe en w en formato "json" o "text". Los valores de las claves con datos sensibles se ocultan, y los mensajes escritos con contexto llevan los identificadores de la traza
+func New(w io.Writer, format string, level slog.Leveler) *slog.Logger {
+	options := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+
+	return slog.New(tracing.LogHandler(handler))
+}
+
+type loggerKey struct{}
+
+// WithLogger guarda en el contexto el logger de la petición
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext devuelve el logger de la petición, con su request_id. Fuera de una petición devuelve el logger por defecto
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// Debug, Info, Warn y Error escriben con el logger de la petición: logging.Error(ctx, "Error while reading cache", "cache", name, "error", err)
+func Debug(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).DebugContext(ctx, msg, args...)
+}
+
+func Info(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).InfoContext(ctx, msg, args...)
+}
+
+func Warn(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).WarnContext(ctx, msg, args...)
+}
+
+func Error(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).ErrorContext(ctx, msg, args...)
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runners-postgresql/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New(&buffer, "json", slog.LevelInfo)
+
+	logger.Info("login",
+		"Token", "abc123",
+		"password", "secret-password",
+		"username", "runner1",
+		slog.Group("config", "redis_password", "redis-secret"),
+		"user", models.User{ID: "1", Username: "runner1", Password: "pw", AccessToken: "tok"})
+
+	line := buffer.String()
+	assert.NotContains(t, line, "abc123")
+	assert.NotContains(t, line, "secret-password")
+	assert.NotContains(t, line, "redis-secret")
+	assert.NotContains(t, line, `"pw"`)
+	assert.NotContains(t, line, `"tok"`)
+	assert.Contains(t, line, `"Token":"[REDACTED]"`)
+	assert.Contains(t, line, `"username":"runner1"`)
+}
+
+func TestMiddlewareRequestId(t *testing.T) {
+	var buffer bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(New(&buffer, "json", slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/runner/:id", func(ctx *gin.Context) {
+		Info(ctx.Request.Context(), "Getting runner")
+		ctx.Status(http.StatusNotFound)
+	})
+
+	// se mantiene el identificador que envía el cliente
+	request := httptest.NewRequest(http.MethodGet, "/runner/1?token=abc", nil)
+	request.Header.Set(RequestIDHeader, "req-1")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, "req-1", recorder.Header().Get(RequestIDHeader))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+	entries := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &entries[i]))
+		assert.Equal(t, "req-1", entries[i]["request_id"])
+	}
+	assert.Equal(t, "Getting runner", entries[0]["msg"])
+	assert.Equal(t, "request", entries[1]["msg"])
+	assert.Equal(t, "WARN", entries[1]["level"])
+	assert.Equal(t, "/runner/:id", entries[1]["route"])
+	assert.Equal(t, float64(http.StatusNotFound), entries[1]["status"])
+	assert.NotContains(t, lines[1], "abc")
+
+	// un identificador no válido se sustituye por uno nuevo
+	request = httptest.NewRequest(http.MethodGet, "/runner/1", nil)
+	request.Header.Set(RequestIDHeader, "bad id\n")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Len(t, recorder.Header().Get(RequestIDHeader), 32)
+}
+
+func TestFromContextWithoutRequest(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cabecera con el identificador de la petición. Si el cliente (o el balanceador) la envía se mantiene, y si no se genera. Siempre se devuelve en la respuesta
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware asigna a cada petición su identificador y un logger con él, que usan todas las capas a través del contexto. Al terminar la petición escribe el log de acceso
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		requestId := RequestID(ctx.GetHeader(RequestIDHeader))
+		ctx.Header(RequestIDHeader, requestId)
+
+		logger := slog.Default().With("request_id", requestId)
+		requestCtx := WithLogger(ctx.Request.Context(), logger)
+		ctx.Request = ctx.Request.WithContext(requestCtx)
+
+		ctx.Next()
+
+		// igual que en las métricas, las peticiones que no coinciden con ninguna ruta se agrupan
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		// no se escribe la query string, que puede llevar datos personales
+		args := []any{
+			"method", ctx.Request.Method,
+			"route", route,
+			"path", ctx.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", ctx.Writer.Size(),
+			"client_ip", ctx.ClientIP(),
+		}
+		if len(ctx.Errors) > 0 {
+			args = append(args, "errors", ctx.Errors.String())
+		}
+
+		logger.Log(requestCtx, level, "request", args...)
+	}
+}
+
+// RequestID devuelve el identificador de la petición que envía el cliente si es válido, o uno nuevo si no lo es o no lo envía
+func RequestID(requestId string) string {
+	if !validRequestId(requestId) {
+		return newRequestId()
+	}
+
+	return requestId
+}
+
+// el identificador que llega del cliente acaba en los logs, así que solo se acepta si es corto y no tiene caracteres de control
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > 128 {
+		return false
+	}
+
+	for _, c := range requestId {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// se oculta el valor de cualquier clave que contenga una de estas palabras, sin distinguir mayúsculas: token, access_token, Token, user_password, redis_password...
+var sensitiveKeys = []string{
+	"token",
+	"password",
+	"secret",
+	"authorization",
+	"cookie",
+	"signature",
+	"connection_string",
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if isSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	return attr
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,35 +1,76 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
 	"runners-postgresql/config"
+	"runners-postgresql/lifecycle"
+	"runners-postgresql/logging"
 	"runners-postgresql/server"
+	"runners-postgresql/tracing"
 
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	log.Println("Starting Runners App")
+	slog.Info("Starting Runners App")
 
-	log.Println("Initializing configuration")
-	// recuperamos la configuración
-	config := config.InitConfig(getConfigFileName())
+	slog.Info("Initializing configuration")
+	// recuperamos la configuración. Si no es válida la aplicación termina informando de todos los errores
+	configFileName := getConfigFileName()
+	appConfig := config.InitConfig(configFileName)
 
-	log.Println("Initializing database")
-	// inicializamos la base de datos
-	dbHandler := server.InitDatabase(config)
+	// el gestor del ciclo de vida arranca los componentes en el orden en que se registran y, al recibir SIGTERM o SIGINT, los detiene en el orden inverso
+	manager := lifecycle.New(appConfig.Shutdown.Timeout, appConfig.Shutdown.DrainDelay)
 
-	log.Println("Initializing Prometheus")
+	// logs estructurados en JSON o texto. Con SIGHUP se recargan el nivel de log y los límites de peticiones
+	logLevel := logging.Init(appConfig.Log)
+	reloader := config.NewReloader(configFileName, appConfig)
+	reloader.OnReload(func(reloaded *config.Config) {
+		logLevel.Set(reloaded.Log.SlogLevel())
+	})
+	manager.Register(lifecycle.Background("configuration reloader", reloader.Watch))
+
+	slog.Info("Initializing tracing")
+	// se detiene después de los servidores y de la base de datos para exportar los spans de las peticiones que terminan durante la parada
+	shutdownTracing, err := tracing.Init(appConfig.Tracing)
+	if err != nil {
+		slog.Error("Error while initializing tracing", "error", err)
+		os.Exit(1)
+	}
+	manager.Register(lifecycle.Hook{
+		Name: "tracing",
+		Stop: shutdownTracing,
+	})
+
+	slog.Info("Initializing database")
+	// inicializamos la base de datos. Se cierra lo último, cuando ya han terminado las peticiones y las transacciones en curso
+	dbHandler := server.InitDatabase(appConfig.Database)
+	manager.Register(lifecycle.Hook{
+		Name: "database",
+		Stop: func(ctx context.Context) error {
+			return dbHandler.Close()
+		},
+	})
+
+	slog.Info("Initializing Prometheus")
 	// inicializamos Prometheus
-	go server.InitPrometheus()
+	manager.Register(lifecycle.Server("Prometheus exporter", server.InitPrometheus(appConfig, manager)))
 
-	log.Println("Initializig HTTP sever")
+	slog.Info("Initializing HTTP server")
 	// inicializamos el servidor HTTP donde exponemos los diferentes recursos y  las apis asociadas a ellos. Pasamos la configuración y la base de datos
-	httpServer := server.InitHttpServer(config, dbHandler)
+	httpServer := server.InitHttpServer(appConfig, dbHandler, manager, reloader)
+	httpServer.Register(manager)
+
+	// arrancamos la aplicación y esperamos a que termine
+	err = manager.Run(context.Background())
+	if err != nil {
+		slog.Error("Application stopped with errors", "error", err)
+		os.Exit(1)
+	}
 
-	// arrancamos el servidor HTTP
-	httpServer.Start()
+	slog.Info("Runners App stopped")
 }
 
 func getConfigFileName() string {
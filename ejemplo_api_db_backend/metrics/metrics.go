@@ -28,4 +28,110 @@ var (
 			Help: "Duración de la operación get all runners en segundos",
 		},
 	)
+
+	LiveConnections = promauto.NewGaugeVec( // un gauge. A diferencia del contador, su valor puede subir y bajar. Lo usamos para saber cuantas conexiones del feed en directo hay abiertas
+		prometheus.GaugeOpts{
+			Name: "runners_app_live_connections",
+			Help: "Número de conexiones abiertas al feed de resultados en directo",
+		},
+		[]string{"transporte"}, // sse, websocket o grpc
+	)
+
+	LiveSlowConsumersCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runners_app_live_slow_consumers",
+			Help: "Número de conexiones al feed en directo cerradas por no consumir los eventos a tiempo",
+		},
+		[]string{"transporte"},
+	)
+
+	CacheRequestsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runners_app_cache_requests",
+			Help: "Número de consultas a la caché, clasificadas en aciertos (hit) y fallos (miss)",
+		},
+		[]string{"cache", "resultado"},
+	)
+
+	CacheInvalidationsCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runners_app_cache_invalidations",
+			Help: "Número de claves invalidadas en la caché",
+		},
+		[]string{"cache"},
+	)
+)
+
+// Métricas RED (rate, errors, duration) de todas las rutas, que registra el middleware de Gin. La ruta es la plantilla (/runner/:id), no la URL, para no crear una serie por cada id
+var (
+	HttpResponsesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runners_app_http_responses",
+			Help: "Número total de respuestas HTTP por ruta, método y código de estado",
+		},
+		[]string{"ruta", "metodo", "estado"},
+	)
+
+	HttpRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "runners_app_http_request_duration_seconds",
+			Help:    "Duración de las peticiones HTTP en segundos por ruta, método y código de estado",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"ruta", "metodo", "estado"},
+	)
+
+	HttpRequestsInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runners_app_http_requests_in_flight",
+			Help: "Número de peticiones HTTP en curso por ruta y método",
+		},
+		[]string{"ruta", "metodo"},
+	)
+
+	DBQueryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "runners_app_db_query_duration_seconds",
+			Help:    "Duración de las operaciones de los repositorios en segundos",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"repositorio", "operacion"},
+	)
+)
+
+// Peticiones rechazadas por los límites de peticiones. El motivo es "rate" (el cliente ha superado su límite, 429) o "concurrency" (demasiadas peticiones en curso, 503)
+var RateLimitedCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "runners_app_rate_limited_requests",
+		Help: "Número total de peticiones rechazadas por los límites de peticiones por grupo y motivo",
+	},
+	[]string{"grupo", "motivo"},
+)
+
+// Fecha de caducidad de los certificados TLS de los servidores, para avisar antes de que caduquen si no se renuevan
+var TLSCertificateExpiry = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "runners_app_tls_certificate_expiry_timestamp_seconds",
+		Help: "Fecha de caducidad del certificado TLS en uso por servidor, en segundos desde epoch",
+	},
+	[]string{"servidor"},
+)
+
+// Duración de las llamadas a la API gRPC por método y código de respuesta. Con _count se obtienen las llamadas y los errores
+var GrpcRequestDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "runners_app_grpc_request_duration_seconds",
+		Help:    "Duración de las llamadas gRPC en segundos por método y código",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"metodo", "codigo"},
+)
+
+// Peticiones a la API REST por versión y por cómo se ha elegido: "path" (/v1/runner), "accept" (cabecera Accept) o "default" (sin versión). Permite saber cuándo se puede retirar una versión obsoleta
+var APIVersionRequests = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "runners_app_api_version_requests",
+		Help: "Número total de peticiones a la API REST por versión y forma de elegir la versión",
+	},
+	[]string{"version", "negociacion"},
 )
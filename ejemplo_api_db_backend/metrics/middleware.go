@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etiqueta de las peticiones que no corresponden a ninguna ruta, para que una URL inventada no cree series nuevas
+const unmatchedRoute = "unmatched"
+
+// Middleware registra las métricas RED de todas las rutas: peticiones, duración y peticiones en curso
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := ctx.Request.Method
+
+		HttpRequestsCounter.Inc()
+		inFlight := HttpRequestsInFlight.WithLabelValues(route, method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		ctx.Next()
+
+		status := strconv.Itoa(ctx.Writer.Status())
+		HttpResponsesCounter.WithLabelValues(route, method, status).Inc()
+		HttpRequestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/runner/:id", func(ctx *gin.Context) {
+		// mientras se atiende la petición cuenta como en curso
+		assert.Equal(t, 1.0, testutil.ToFloat64(HttpRequestsInFlight.WithLabelValues("/runner/:id", http.MethodGet)))
+		ctx.Status(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/runner/1", "/runner/2", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// las dos peticiones a /runner/:id comparten serie
+	assert.Equal(t, 2.0, testutil.ToFloat64(HttpResponsesCounter.WithLabelValues("/runner/:id", http.MethodGet, "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(HttpResponsesCounter.WithLabelValues(unmatchedRoute, http.MethodGet, "404")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(HttpRequestsInFlight.WithLabelValues("/runner/:id", http.MethodGet)))
+	// una serie de duración por ruta, método y estado
+	assert.Equal(t, 2, testutil.CollectAndCount(HttpRequestDuration))
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Permisos de las claves de API, con el formato recurso:acción. Las acciones de lectura son las peticiones GET y las de escritura el resto
+const (
+	ScopeRunnersRead   = "runners:read"
+	ScopeRunnersWrite  = "runners:write"
+	ScopeResultsRead   = "results:read"
+	ScopeResultsWrite  = "results:write"
+	ScopeClubsRead     = "clubs:read"
+	ScopeClubsWrite    = "clubs:write"
+	ScopeExportRead    = "export:read"
+	ScopeWebhooksRead  = "webhooks:read"
+	ScopeWebhooksWrite = "webhooks:write"
+	ScopeHealthRead    = "health:read"
+)
+
+var APIKeyScopes = []string{
+	ScopeRunnersRead, ScopeRunnersWrite,
+	ScopeResultsRead, ScopeResultsWrite,
+	ScopeClubsRead, ScopeClubsWrite,
+	ScopeExportRead,
+	ScopeWebhooksRead, ScopeWebhooksWrite,
+	ScopeHealthRead,
+}
+
+// Clave de API de un sistema externo, que se autentica con la cabecera "Authorization: ApiKey <clave>" en lugar de con un token
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`        // principio de la clave, en claro, para poder reconocerla
+	Key        string     `json:"key,omitempty"` // solo se devuelve al crear la clave. En la base de datos se guarda su hash
+	Role       string     `json:"role"`
+	ClubID     string     `json:"club_id,omitempty"` // club que administra (solo para el rol club_admin)
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Hash       string     `json:"-"`
+}
+
+// Expired indica si la clave ha caducado. Las claves sin fecha de caducidad no caducan
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Principal devuelve el usuario con el que se tratan las peticiones de la clave: tiene su rol y sus permisos
+func (k *APIKey) Principal() *Principal {
+	return &Principal{
+		Username: k.Name,
+		Role:     k.Role,
+		ClubID:   k.ClubID,
+		APIKeyID: k.ID,
+		Scopes:   k.Scopes,
+	}
+}
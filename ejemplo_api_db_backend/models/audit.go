@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Acciones que se registran en el log de auditoría
+const (
+	AuditRunnerUpdated   = "runner.updated"
+	AuditResultCreated   = "result.created"
+	AuditUserCreated     = "user.created"
+	AuditUserRoleChanged = "user.role_changed"
+)
+
+// Una entrada del log de auditoría: quién ha hecho un cambio, sobre qué recurso y cuándo
+type AuditEntry struct {
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	Action     string    `json:"action"`
+	ResourceID string    `json:"resource_id"` // id del runner o del resultado, o nombre del usuario
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Página del log de auditoría
+type AuditPage struct {
+	Page    int           `json:"page"`
+	HasNext bool          `json:"has_next"` // hay entradas más antiguas
+	Entries []*AuditEntry `json:"entries"`
+}
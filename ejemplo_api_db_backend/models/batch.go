@@ -0,0 +1,38 @@
+package models
+
+// Modos de las peticiones por lotes
+const (
+	BatchAtomic     = "atomic"      // todo o nada: si falla un elemento no se guarda ninguno
+	BatchBestEffort = "best_effort" // se guardan los elementos válidos y se informa del error de los demás
+)
+
+// MaxBatchSize es el número máximo de elementos de una petición por lotes
+const MaxBatchSize = 100
+
+// Alta de varios runners en una única petición
+type RunnersBatch struct {
+	Mode  string    `json:"mode"` // atomic si no se indica
+	Items []*Runner `json:"items"`
+}
+
+// Alta de varios resultados en una única petición
+type ResultsBatch struct {
+	Mode  string    `json:"mode"` // atomic si no se indica
+	Items []*Result `json:"items"`
+}
+
+// Respuesta multi-status de una petición por lotes, con el estado de cada elemento en el mismo orden que la petición
+type BatchResponse struct {
+	Mode      string       `json:"mode"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Items     []*BatchItem `json:"items"`
+}
+
+// Estado de un elemento del lote: el http status code que tendría su petición individual, y el elemento creado o el error
+type BatchItem struct {
+	Index  int         `json:"index"`
+	Status int         `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
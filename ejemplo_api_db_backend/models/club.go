@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type Club struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Country   string    `json:"country"`
+	City      string    `json:"city,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Pertenencia de un runner a un club. Las pertenencias terminadas se conservan como historial
+type ClubMembership struct {
+	ID        string     `json:"id"`
+	ClubID    string     `json:"club_id"`
+	ClubName  string     `json:"club_name,omitempty"`
+	RunnerID  string     `json:"runner_id"`
+	FirstName string     `json:"first_name,omitempty"`
+	LastName  string     `json:"last_name,omitempty"`
+	JoinedAt  time.Time  `json:"joined_at"`
+	LeftAt    *time.Time `json:"left_at,omitempty"`  // se incluye solo si la pertenencia ha terminado
+	Transfer  bool       `json:"transfer,omitempty"` // al dar de alta, indica que hay que cerrar la pertenencia del runner a otro club
+}
+
+// Una posición de la clasificación de un club: el mejor resultado de cada miembro
+type LeaderboardEntry struct {
+	Position   int    `json:"position"`
+	RunnerID   string `json:"runner_id"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Country    string `json:"country"`
+	RaceResult string `json:"race_result"`
+	Location   string `json:"location"`
+	Year       int    `json:"year"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tipos de eventos de dominio que se publican a través del outbox
+const (
+	EventResultCreated      = "result.created"
+	EventRunnerPersonalBest = "runner.personal_best"
+	EventRunnerDeleted      = "runner.deleted"
+)
+
+var EventTypes = []string{EventResultCreated, EventRunnerPersonalBest, EventRunnerDeleted}
+
+type Event struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"` // id del runner o resultado al que se refiere el evento
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// payload del evento runner.personal_best
+type PersonalBestEvent struct {
+	RunnerID             string `json:"runner_id"`
+	ResultID             string `json:"result_id"`
+	Location             string `json:"location"` // carrera en la que se consiguió la marca
+	PersonalBest         string `json:"personal_best"`
+	PreviousPersonalBest string `json:"previous_personal_best,omitempty"`
+}
+
+// payload del evento runner.deleted
+type RunnerDeletedEvent struct {
+	RunnerID string `json:"runner_id"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// Filtros que admiten los listados y las exportaciones de runners. Solo uno de los dos puede estar informado
+type RunnersFilter struct {
+	Country string
+	Year    int
+	Public      bool   // aplica las preferencias de privacidad de los runners
+	OwnRunnerID string // el runner que consulta, que sí ve sus propios datos
+}
+
+// Filtros que admiten los listados y las exportaciones de resultados
+type ResultsFilter struct {
+	RunnerID string
+	Year     int
+	Public      bool   // excluye los resultados de los runners que los ocultan
+	OwnRunnerID string // el runner que consulta, que sí ve sus propios resultados
+}
@@ -0,0 +1,49 @@
+package models
+
+// Rol de los usuarios que son runners. Solo pueden modificar su propio perfil
+const RoleRunner = "runner"
+
+// Rol de los administradores de club. Sus permisos de escritura se limitan a los runners de su club
+const RoleClubAdmin = "club_admin"
+
+// El usuario que hace la petición, obtenido a partir de su token
+type Principal struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	ClubID   string `json:"club_id,omitempty"`   // club que administra (solo para el rol club_admin)
+	RunnerID string `json:"runner_id,omitempty"` // runner asociado a la cuenta del usuario
+	// clave de API con la que se autentica un sistema externo, y los permisos que tiene
+	APIKeyID string   `json:"api_key_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// HasScope indica si el usuario tiene el permiso. Solo las claves de API tienen permisos: los usuarios y los servicios con certificado se limitan por su rol
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil || p.APIKeyID == "" {
+		return true
+	}
+
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClubScoped indica si los permisos del usuario se limitan a un club. Un principal nil (llamadas internas, sin usuario) no tiene limitaciones
+func (p *Principal) ClubScoped() bool {
+	return p != nil && p.Role == RoleClubAdmin
+}
+
+// CanSeePrivateData indica si el usuario puede ver los datos que el runner ha decidido ocultar: puede hacerlo el propio runner y el personal (administradores y administradores de club)
+func (p *Principal) CanSeePrivateData(runnerId string) bool {
+	return !p.IsRunner() || (p.RunnerID != "" && p.RunnerID == runnerId)
+}
+
+// IsRunner indica si el usuario es un runner, y no parte del personal
+func (p *Principal) IsRunner() bool {
+	return p != nil && p.Role == RoleRunner
+}
@@ -1,10 +1,18 @@
 package models
 
 type Result struct {
-	ID         string `json:"id"`
-	RunnerID   string `json:"runner_id"`
-	RaceResult string `json:"race_result"`
-	Location   string `json:"location"`
-	Position   int    `json:"position,omitempty"`
-	Year       int    `json:"year"`
+	ID         string   `json:"id"`
+	RunnerID   string   `json:"runner_id"`
+	RaceResult string   `json:"race_result"`
+	Location   string   `json:"location"`
+	Position   int      `json:"position,omitempty"`
+	Year       int      `json:"year"`
+	Distance   float64  `json:"distance,omitempty"` // en kilómetros. Si no se indica es la maratón
+	Splits     []*Split `json:"splits,omitempty"`   // tiempos de paso, opcionales. El último es la llegada
+}
+
+// Tiempo de paso de un resultado
+type Split struct {
+	Distance float64 `json:"distance"` // kilómetros desde la salida
+	Time     string  `json:"time"`     // tiempo desde la salida, hh:mm:ss
 }
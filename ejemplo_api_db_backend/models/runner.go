@@ -1,13 +1,29 @@
 package models
 
 type Runner struct {
-	ID           string    `json:"id"`
-	FirstName    string    `json:"first_name"`
-	LastName     string    `json:"last_name"`
-	Age          int       `json:"age,omitempty"`
-	IsActive     bool      `json:"is_active"`
-	Country      string    `json:"country"`
-	PersonalBest string    `json:"personal_best,omitempty"` // se incluye el campo en el json solo si no es vacío
-	SeasonBest   string    `json:"season_best,omitempty"`   // se incluye el campo en el json solo si no es vacío
-	Results      []*Result `json:"results,omitempty"`       // se incluye el campo en el json solo si no es nulo o vacío
+	ID            string         `json:"id"`
+	FirstName     string         `json:"first_name"`
+	LastName      string         `json:"last_name"`
+	Age           int            `json:"age,omitempty"`
+	IsActive      bool           `json:"is_active"`
+	Country       string         `json:"country"`
+	PersonalBest  string         `json:"personal_best,omitempty"`  // se incluye el campo en el json solo si no es vacío
+	SeasonBest    string         `json:"season_best,omitempty"`    // se incluye el campo en el json solo si no es vacío
+	Results       []*Result      `json:"results,omitempty"`        // se incluye el campo en el json solo si no es nulo o vacío
+	Privacy       *Privacy       `json:"privacy,omitempty"`        // solo se incluye para el propio runner y los administradores
+	SplitAnalysis *SplitAnalysis `json:"split_analysis,omitempty"` // análisis de los resultados con tiempos de paso
+}
+
+// Preferencias de privacidad del runner. Se aplican cuando otros runners consultan su perfil
+type Privacy struct {
+	HideAge     bool `json:"hide_age"`
+	HideResults bool `json:"hide_results"`
+}
+
+// Página de un listado de runners
+type RunnerPage struct {
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+	Total    int       `json:"total"` // número total de runners que cumplen el filtro
+	Runners  []*Runner `json:"runners"`
 }
@@ -0,0 +1,16 @@
+package models
+
+// Un runner encontrado por la búsqueda, con la puntuación de la coincidencia entre 0 y 1
+type RunnerMatch struct {
+	Runner *Runner `json:"runner"`
+	Score  float64 `json:"score"`
+}
+
+// Página de resultados de una búsqueda de runners
+type RunnerSearchPage struct {
+	Query    string         `json:"query"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+	Total    int            `json:"total"`
+	Matches  []*RunnerMatch `json:"matches"`
+}
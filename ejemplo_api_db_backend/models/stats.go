@@ -0,0 +1,95 @@
+package models
+
+// Distancia de la maratón en kilómetros. Es la de los resultados que no indican distancia
+const MarathonDistance = 42.195
+
+// Agregados de los resultados de un runner en una distancia y un año. Los tiempos están en segundos. A partir de ellos se calculan todas las estadísticas, así que los backends que pueden agregar en la base de datos no necesitan leer cada resultado
+type ResultsAggregate struct {
+	Distance      float64
+	Year          int
+	Races         int
+	Best          float64 // mejor marca
+	Sum           float64 // suma de las marcas
+	SumOfSquares  float64 // suma de los cuadrados de las marcas, para la desviación típica
+	BestPosition  int     // 0 si ningún resultado tiene posición
+	WorstPosition int
+}
+
+// Estadísticas de los resultados de un runner
+type RunnerStats struct {
+	RunnerID  string           `json:"runner_id"`
+	Races     int              `json:"races"`
+	Distances []*DistanceStats `json:"distances"`
+}
+
+// Estadísticas de un runner en una distancia. Los ritmos son el ritmo medio en min/km y min/milla, y las mejoras son porcentajes: positivas si la marca baja
+type DistanceStats struct {
+	Distance      float64       `json:"distance"` // en kilómetros
+	Races         int           `json:"races"`
+	Best          string        `json:"best"`
+	Average       string        `json:"average"`
+	PacePerKm     string        `json:"pace_per_km"`
+	PacePerMile   string        `json:"pace_per_mile"`
+	Improvement   *float64      `json:"improvement,omitempty"` // mejor marca del último año respecto de la del primero
+	BestPosition  int           `json:"best_position,omitempty"`
+	WorstPosition int           `json:"worst_position,omitempty"`
+	Consistency   *float64      `json:"consistency,omitempty"` // entre 0 y 100, solo con dos o más carreras
+	Progression   []*YearStats  `json:"progression"`
+	YearOverYear  *YearOverYear `json:"year_over_year,omitempty"` // último año con resultados frente al anterior
+}
+
+// Estadísticas de un runner en una distancia y un año
+type YearStats struct {
+	Year          int      `json:"year"`
+	Races         int      `json:"races"`
+	Best          string   `json:"best"`
+	Average       string   `json:"average"`
+	PacePerKm     string   `json:"pace_per_km"`
+	PacePerMile   string   `json:"pace_per_mile"`
+	Improvement   *float64 `json:"improvement,omitempty"` // mejor marca respecto de la del año anterior con resultados
+	BestPosition  int      `json:"best_position,omitempty"`
+	WorstPosition int      `json:"worst_position,omitempty"`
+}
+
+// Comparación de un año con el anterior con resultados
+type YearOverYear struct {
+	Year               int      `json:"year"`
+	PreviousYear       int      `json:"previous_year"`
+	Best               string   `json:"best"`
+	PreviousBest       string   `json:"previous_best"`
+	BestImprovement    *float64 `json:"best_improvement,omitempty"`
+	Average            string   `json:"average"`
+	PreviousAverage    string   `json:"previous_average"`
+	AverageImprovement *float64 `json:"average_improvement,omitempty"`
+	Races              int      `json:"races"`
+	PreviousRaces      int      `json:"previous_races"`
+}
+
+// Análisis de los tiempos de paso de los resultados de un runner
+type SplitAnalysis struct {
+	Races        []*RaceSplits  `json:"races"`
+	BestSegments []*SegmentBest `json:"best_segments"` // mejor tiempo en cada tramo entre dos puntos de paso
+}
+
+// Reparto del esfuerzo en una carrera. El split es negativo si la segunda mitad es más rápida que la primera
+type RaceSplits struct {
+	ResultID   string  `json:"result_id"`
+	Location   string  `json:"location"`
+	Year       int     `json:"year"`
+	Distance   float64 `json:"distance"`
+	FirstHalf  string  `json:"first_half"`
+	SecondHalf string  `json:"second_half"`
+	Difference string  `json:"difference"` // segunda mitad menos la primera, con signo (+00:01:20)
+	Split      string  `json:"split"`      // negative, positive o even
+}
+
+// Mejor tiempo de un runner en un tramo
+type SegmentBest struct {
+	From      float64 `json:"from"` // kilómetros desde la salida
+	To        float64 `json:"to"`
+	Time      string  `json:"time"`
+	PacePerKm string  `json:"pace_per_km"`
+	ResultID  string  `json:"result_id"`
+	Location  string  `json:"location"`
+	Year      int     `json:"year"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Estados de un resultado enviado por un runner
+const (
+	SubmissionPending  = "pending"
+	SubmissionApproved = "approved"
+	SubmissionRejected = "rejected"
+)
+
+// Resultado enviado por el propio runner, pendiente de que un administrador lo apruebe
+type ResultSubmission struct {
+	ID           string     `json:"id"`
+	RunnerID     string     `json:"runner_id"`
+	RaceResult   string     `json:"race_result"`
+	Location     string     `json:"location"`
+	Position     int        `json:"position,omitempty"`
+	Year         int        `json:"year"`
+	Distance     float64    `json:"distance"` // en kilómetros
+	Status       string     `json:"status"`
+	SubmittedBy  string     `json:"submitted_by"`
+	SubmittedAt  time.Time  `json:"submitted_at"`
+	ReviewedBy   string     `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	RejectReason string     `json:"reject_reason,omitempty"`
+	ResultID     string     `json:"result_id,omitempty"` // resultado creado al aprobar el envío
+}
+
+// Result devuelve el resultado que se crea al aprobar el envío
+func (s *ResultSubmission) Result() *Result {
+	return &Result{
+		RunnerID:   s.RunnerID,
+		RaceResult: s.RaceResult,
+		Location:   s.Location,
+		Position:   s.Position,
+		Year:       s.Year,
+		Distance:   s.Distance,
+	}
+}
@@ -1,9 +1,32 @@
 package models
 
+import "log/slog"
+
 type User struct {
 	ID          string `json:"id"`
 	Username    string `json:"username"`
 	Password    string `json:"user_password"`
 	Role        string `json:"user_role"`
 	AccessToken string `json:"access_token"`
+	ClubID      string `json:"club_id,omitempty"`   // club que administra (solo para el rol club_admin)
+	RunnerID    string `json:"runner_id,omitempty"` // runner asociado a la cuenta
+	External    bool   `json:"external,omitempty"`  // usuario del proveedor de identidad, sin contraseña
+}
+
+// LogValue hace que al escribir un usuario en el log no aparezcan ni la contraseña ni el token
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", u.ID),
+		slog.String("username", u.Username),
+		slog.String("role", u.Role),
+	)
+}
+
+// ExternalIdentity es un usuario autenticado por el proveedor de identidad externo, con el rol que le corresponde según sus claims
+type ExternalIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Role     string
+	ClubID   string
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Estados de una entrega de un webhook
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+type WebhookSubscription struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"` // solo se devuelve al crear la suscripción
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	EventID        string     `json:"event_id"`
+	SubscriptionID string     `json:"subscription_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	// datos necesarios para hacer la entrega. No se devuelven en la api
+	Event  *Event `json:"-"`
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
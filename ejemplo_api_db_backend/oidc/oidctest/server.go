@@ -0,0 +1,225 @@
+// Package oidctest arranca un proveedor de identidad OpenID Connect local, al estilo de httptest. Sirve para probar el login de principio a fin sin un proveedor real
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// authorization es un código de autorización pendiente de cambiar por los tokens
+type authorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]interface{}
+}
+
+// Server implementa el descubrimiento, el JWKS, el authorization endpoint y el token endpoint con PKCE (S256). El authorization endpoint no pide credenciales: inicia la sesión del usuario indicado con SetUser y redirige a la aplicación
+type Server struct {
+	URL          string // issuer del proveedor
+	ClientID     string
+	ClientSecret string // si no está vacío el cliente se tiene que autenticar con client_secret_basic
+	server       *httptest.Server
+	key          *rsa.PrivateKey
+	keyID        string
+	mutex        sync.Mutex
+	user         map[string]interface{}
+	codes        map[string]*authorization
+}
+
+func NewServer(clientID string, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: failed to generate key: %v", err))
+	}
+
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		keyID:        "test-key",
+		codes:        make(map[string]*authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+
+	return s
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetUser indica los claims del usuario que inicia sesión en el proveedor, como sub, preferred_username o groups
+func (s *Server) SetUser(claims map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.user = claims
+}
+
+// Sign firma un ID token con la clave del proveedor. Los claims iss, aud, iat y exp se añaden si no están
+func (s *Server) Sign(claims map[string]interface{}) string {
+	payload := map[string]interface{}{
+		"iss": s.URL,
+		"aud": s.ClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range claims {
+		payload[name] = value
+	}
+
+	signed := encodeSegment(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.keyID}) + "." + encodeSegment(payload)
+	hash := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: failed to sign token: %v", err))
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": s.keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != s.ClientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	user := s.user
+	s.mutex.Unlock()
+
+	response := redirectURI.Query()
+	response.Set("state", query.Get("state"))
+	if user == nil {
+		response.Set("error", "access_denied")
+	} else {
+		code := randomString()
+		s.mutex.Lock()
+		s.codes[code] = &authorization{
+			redirectURI: redirectURI.String(),
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+			claims:      user,
+		}
+		s.mutex.Unlock()
+		response.Set("code", code)
+	}
+
+	redirectURI.RawQuery = response.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, basic := r.BasicAuth()
+	if !basic {
+		clientID = r.PostFormValue("client_id")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// los códigos solo se pueden usar una vez
+	s.mutex.Lock()
+	code := r.PostFormValue("code")
+	authorization, found := s.codes[code]
+	delete(s.codes, code)
+	s.mutex.Unlock()
+
+	if !found || authorization.redirectURI != r.PostFormValue("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	hash := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(hash[:]) != authorization.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	claims := map[string]interface{}{"nonce": authorization.nonce}
+	for name, value := range authorization.claims {
+		claims[name] = value
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     s.Sign(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func encodeSegment(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func randomString() string {
+	value := make([]byte, 16)
+	rand.Read(value)
+	return base64.RawURLEncoding.EncodeToString(value)
+}
@@ -0,0 +1,246 @@
+// Package oidc implementa el cliente de OpenID Connect con el flujo authorization code y PKCE: descubrimiento de la configuración del proveedor, intercambio del código por los tokens y verificación del ID token con las claves públicas del proveedor (JWKS)
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ruta del documento de descubrimiento, relativa al issuer
+const discoveryPath = "/.well-known/openid-configuration"
+
+// tamaño máximo de las respuestas del proveedor
+const maxResponseSize = 1 << 20
+
+// ErrInvalidToken es el error de los ID tokens que no superan la verificación. El resto de errores son del proveedor o de la comunicación con él
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// Config es el cliente registrado en el proveedor
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // vacío para los clientes públicos, que se autentican solo con PKCE
+	RedirectURL  string
+	Scopes       []string
+	Timeout      time.Duration
+}
+
+// Discovery son los datos del documento de descubrimiento que usa el cliente
+type Discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// Provider es un proveedor de identidad. La configuración y las claves se descargan en el primer login, para que la aplicación arranque aunque el proveedor no esté disponible
+type Provider struct {
+	config    Config
+	client    *http.Client
+	mutex     sync.Mutex
+	discovery *Discovery
+	keys      map[string]interface{} // claves públicas por kid
+}
+
+func NewProvider(config Config) *Provider {
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// AuthRequest son los valores aleatorios de un login: state para asociar la respuesta del proveedor con la petición, nonce para asociar el ID token con el login y verifier para PKCE
+type AuthRequest struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func NewAuthRequest() (*AuthRequest, error) {
+	values := make([]string, 3)
+	for i := range values {
+		value, err := randomString()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return &AuthRequest{State: values[0], Nonce: values[1], Verifier: values[2]}, nil
+}
+
+// Challenge es el code_challenge de PKCE con el método S256: el SHA-256 del verifier en base64url
+func (ar *AuthRequest) Challenge() string {
+	hash := sha256.Sum256([]byte(ar.Verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// AuthCodeURL devuelve la URL del proveedor a la que se redirige al usuario para que inicie sesión
+func (p *Provider) AuthCodeURL(ctx context.Context, request *AuthRequest) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {request.State},
+		"nonce":                 {request.Nonce},
+		"code_challenge":        {request.Challenge()},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange cambia el código de autorización por los tokens en el token endpoint, con el verifier de PKCE, y devuelve el ID token sin verificar
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	// los clientes confidenciales se autentican con client_secret_basic, y los públicos solo se identifican
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(request, &tokens)
+	if err != nil {
+		return "", err
+	}
+
+	if status != http.StatusOK || tokens.Error != "" {
+		return "", fmt.Errorf("token endpoint responded %d: %s %s", status, tokens.Error, tokens.ErrorDescription)
+	}
+
+	if tokens.IDToken == "" {
+		return "", errors.New("token endpoint response without id_token")
+	}
+
+	return tokens.IDToken, nil
+}
+
+// Discover devuelve la configuración del proveedor, que se descarga la primera vez. El issuer del documento tiene que ser el de la configuración
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.config.Issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery Discovery
+	status, err := p.do(request, &discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery responded %d", status)
+	}
+
+	if discovery.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", discovery.Issuer, p.config.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document without authorization_endpoint, token_endpoint or jwks_uri")
+	}
+
+	// si el proveedor anuncia los métodos de PKCE tiene que aceptar S256. Si no los anuncia lo intentamos igualmente
+	if len(discovery.CodeChallengeMethods) > 0 && !contains(discovery.CodeChallengeMethods, "S256") {
+		return nil, errors.New("provider does not support PKCE with S256")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// do hace la petición y decodifica la respuesta JSON, sea cual sea su código de estado
+func (p *Provider) do(request *http.Request, dest interface{}) (int, error) {
+	response, err := p.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+
+	err = json.Unmarshal(body, dest)
+	if err != nil && response.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("invalid response from %s: %w", request.URL.Path, err)
+	}
+
+	return response.StatusCode, nil
+}
+
+// randomString devuelve 32 bytes aleatorios en base64url, el tamaño recomendado para el verifier de PKCE
+func randomString() (string, error) {
+	value := make([]byte, 32)
+	_, err := rand.Read(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
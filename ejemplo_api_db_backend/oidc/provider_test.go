@@ -0,0 +1,88 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"runners-postgresql/oidc/oidctest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	idp := oidctest.NewServer("runners", "")
+	defer idp.Close()
+
+	provider := NewProvider(Config{Issuer: idp.URL, ClientID: "runners", Timeout: time.Second})
+	ctx := context.Background()
+
+	claims, err := provider.Verify(ctx, idp.Sign(map[string]interface{}{
+		"sub":    "1234",
+		"nonce":  "nonce",
+		"groups": []string{"club-7-admins", "runners"},
+		"realm_access": map[string]interface{}{
+			"roles": []string{"admin"},
+		},
+	}), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, "1234", claims.Subject)
+	assert.Equal(t, []string{"runners"}, claims.Audience)
+	assert.Equal(t, []string{"club-7-admins", "runners"}, claims.Values("groups"))
+	assert.Equal(t, []string{"admin"}, claims.Values("realm_access.roles"))
+	assert.Nil(t, claims.Values("roles"))
+
+	invalid := map[string]map[string]interface{}{
+		"nonce":    {"sub": "1234", "nonce": "other"},
+		"audience": {"sub": "1234", "nonce": "nonce", "aud": "other-client"},
+		"azp":      {"sub": "1234", "nonce": "nonce", "aud": []string{"runners", "other-client"}},
+		"expired":  {"sub": "1234", "nonce": "nonce", "exp": time.Now().Add(-2 * time.Minute).Unix()},
+		"issuer":   {"sub": "1234", "nonce": "nonce", "iss": "https://idp.example.com"},
+		"subject":  {"nonce": "nonce"},
+	}
+	for name, claims := range invalid {
+		_, err := provider.Verify(ctx, idp.Sign(claims), "nonce")
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+
+	// un token modificado, o sin firma, no es válido
+	parts := strings.Split(idp.Sign(map[string]interface{}{"sub": "1234", "nonce": "nonce"}), ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"` + idp.URL + `","aud":"runners","sub":"admin","nonce":"nonce","exp":9999999999}`))
+	_, err = provider.Verify(ctx, parts[0]+"."+forged+"."+parts[2], "nonce")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	_, err = provider.Verify(ctx, unsigned+"."+parts[1]+".", "nonce")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := oidctest.NewServer("runners", "")
+	defer idp.Close()
+
+	provider := NewProvider(Config{Issuer: idp.URL, ClientID: "runners", RedirectURL: "http://localhost:8080/auth/oidc/callback", Scopes: []string{"openid", "profile"}, Timeout: time.Second})
+	request, err := NewAuthRequest()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), request)
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, idp.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "openid profile", query.Get("scope"))
+	assert.Equal(t, request.State, query.Get("state"))
+	assert.Equal(t, request.Nonce, query.Get("nonce"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	// el verifier no sale de la aplicación: el proveedor solo recibe su hash
+	assert.Equal(t, request.Challenge(), query.Get("code_challenge"))
+	assert.NotContains(t, authURL, request.Verifier)
+
+	// el issuer del descubrimiento tiene que ser el configurado
+	_, err = NewProvider(Config{Issuer: idp.URL + "/", ClientID: "runners", Timeout: time.Second}).Discover(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,281 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// margen con el que se comprueban las fechas del token, por la diferencia entre los relojes de la aplicación y del proveedor
+const clockSkew = time.Minute
+
+// Claims son los claims del ID token. Raw tiene todos, para leer los que dependen del proveedor, como el de los grupos o roles
+type Claims struct {
+	Issuer            string                 `json:"iss"`
+	Subject           string                 `json:"sub"`
+	AuthorizedParty   string                 `json:"azp"`
+	Expiry            int64                  `json:"exp"`
+	IssuedAt          int64                  `json:"iat"`
+	Nonce             string                 `json:"nonce"`
+	Email             string                 `json:"email"`
+	PreferredUsername string                 `json:"preferred_username"`
+	Audience          []string               `json:"-"`
+	Raw               map[string]interface{} `json:"-"`
+}
+
+// Values devuelve los valores de un claim que puede ser una cadena o una lista de cadenas. Los claims anidados se indican con puntos, como realm_access.roles en Keycloak
+func (c *Claims) Values(claim string) []string {
+	var value interface{} = c.Raw
+	for _, name := range strings.Split(claim, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// Verify comprueba la firma del ID token con las claves del proveedor y sus claims: el issuer, la audiencia (nuestro client_id), las fechas y el nonce del login. Devuelve los claims del token
+func (p *Provider) Verify(ctx context.Context, rawIDToken string, nonce string) (*Claims, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	err = decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+
+	// solo aceptamos firmas asimétricas: ni "none" ni HMAC, que se podrían falsificar con datos públicos
+	if header.Algorithm != "RS256" && header.Algorithm != "ES256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+
+	key, err := p.key(ctx, discovery, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	if !verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrInvalidToken)
+	}
+
+	claims, err := decodeClaims(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != discovery.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q does not match", ErrInvalidToken, claims.Issuer)
+	case !contains(claims.Audience, p.config.ClientID):
+		return nil, fmt.Errorf("%w: audience does not include the client", ErrInvalidToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID:
+		return nil, fmt.Errorf("%w: authorized party %q does not match", ErrInvalidToken, claims.AuthorizedParty)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: token without subject", ErrInvalidToken)
+	case !now.Before(time.Unix(claims.Expiry, 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	case now.Add(clockSkew).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, fmt.Errorf("%w: token issued in the future", ErrInvalidToken)
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// key devuelve la clave pública con el kid. Si no la tenemos volvemos a descargar las claves, porque el proveedor puede haberlas rotado
+func (p *Provider) key(ctx context.Context, discovery *Discovery, keyId string) (interface{}, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, found := p.keys[keyId]; found {
+		return key, nil
+	}
+
+	keys, err := p.fetchKeys(ctx, discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+
+	key, found := keys[keyId]
+	if !found {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, keyId)
+	}
+
+	return key, nil
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	status, err := p.do(request, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("JWKS responded %d", status)
+	}
+
+	// las claves que no son de firma o que no sabemos leer se ignoran
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decodeInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+}
+
+func verifySignature(algorithm string, key interface{}, signed string, signature []byte) bool {
+	hash := sha256.Sum256([]byte(signed))
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return algorithm == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// en JWS la firma ECDSA son r y s seguidos, de 32 bytes cada uno con P-256
+		if algorithm != "ES256" || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, hash[:], r, s)
+	}
+
+	return false
+}
+
+// decodeClaims decodifica el payload del token. La audiencia puede ser una cadena o una lista de cadenas
+func decodeClaims(segment string) (*Claims, error) {
+	var claims Claims
+	err := decodeSegment(segment, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	err = decodeSegment(segment, &claims.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	claims.Audience = claims.Values("aud")
+	return &claims, nil
+}
+
+func decodeSegment(segment string, dest interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,63 @@
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+// la especificación se escribe a mano en YAML, que es más fácil de leer y de revisar, y se sirve en JSON
+//
+//go:embed openapi.yaml
+var specification []byte
+
+//go:embed ui/index.html
+var uiIndex []byte
+
+// Load carga la especificación de la API y comprueba que es válida
+func Load() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(specification)
+	if err != nil {
+		return nil, err
+	}
+
+	err = doc.Validate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// SpecHandler sirve la especificación en JSON
+func SpecHandler(doc *openapi3.T) (gin.HandlerFunc, error) {
+	content, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json", content)
+	}, nil
+}
+
+// UIHandler sirve Swagger UI con la especificación de /openapi.json. Se registra en una ruta con comodín (por ejemplo, "/docs/*file"). Los archivos de Swagger UI están incluidos en el binario, así que no depende de ningún CDN
+func UIHandler(prefix string) gin.HandlerFunc {
+	files := http.StripPrefix(prefix, http.FileServer(swaggerFiles.HTTP))
+
+	return func(ctx *gin.Context) {
+		file := strings.TrimPrefix(ctx.Request.URL.Path, prefix)
+		if file == "/" || file == "/index.html" {
+			ctx.Data(http.StatusOK, "text/html; charset=utf-8", uiIndex)
+			return
+		}
+
+		files.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+}
@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareValidatesRequestsAndResponses(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	var responseErrors []error
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(doc, Options{
+		ValidateResponses: true,
+		OnResponseError: func(ctx *gin.Context, err error) {
+			responseErrors = append(responseErrors, err)
+		},
+	}))
+	router.GET("/v1/runner/:id", func(ctx *gin.Context) {
+		// a la respuesta le faltan campos obligatorios del runner
+		ctx.JSON(http.StatusOK, gin.H{"id": ctx.Param("id")})
+	})
+	router.GET("/v1/runner/search", func(ctx *gin.Context) {
+		ctx.Status(http.StatusUnauthorized)
+	})
+	router.GET("/internal", func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	// la búsqueda necesita al menos dos caracteres: la petición no llega al controlador
+	recorder := serve(router, "/v1/runner/search?q=a")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"message":"Invalid request`)
+
+	// una petición válida llega al controlador, y la respuesta sin cuerpo es válida
+	recorder = serve(router, "/v1/runner/search?q=smith")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Empty(t, responseErrors)
+
+	// la respuesta se envía, pero se informa de que no cumple la especificación
+	recorder = serve(router, "/v1/runner/1")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Len(t, responseErrors, 1)
+
+	// las rutas que no están en la especificación no se validan
+	recorder = serve(router, "/internal")
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Len(t, responseErrors, 1)
+}
+
+func TestRoutesUseTheServersOfEachOperation(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	routes := Routes(doc)
+	// las operaciones sin servidores propios están en todas las versiones
+	assert.Contains(t, routes, "GET /v1/runner/:id")
+	assert.Contains(t, routes, "GET /v2/runner/:id")
+	// el contrato que cambia en v2
+	assert.Contains(t, routes, "PUT /v1/runner")
+	assert.NotContains(t, routes, "PUT /v2/runner")
+	assert.Contains(t, routes, "PUT /v2/runner/:id")
+	assert.NotContains(t, routes, "PUT /v1/runner/:id")
+	// las sondas no tienen versión
+	assert.Contains(t, routes, "GET /healthz")
+	assert.NotContains(t, routes, "GET /v1/healthz")
+}
+
+func TestGinPath(t *testing.T) {
+	assert.Equal(t, "/runner/:id", GinPath("/runner/{id}"))
+	assert.Equal(t, "/webhook/:id/deliveries/:delivery/retry", GinPath("/webhook/{id}/deliveries/{delivery}/retry"))
+	assert.Equal(t, "/runner", GinPath("/runner"))
+}
+
+func serve(router *gin.Engine, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	request.Header.Set("Token", "token")
+	router.ServeHTTP(recorder, request)
+
+	return recorder
+}
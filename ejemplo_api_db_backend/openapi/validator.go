@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"mime"
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+// tamaño máximo de las respuestas que se validan. Las mayores (por ejemplo, exportaciones) se envían sin validar
+const maxValidatedResponse = 1 << 20
+
+// Options de la validación
+type Options struct {
+	// ValidateResponses valida también las respuestas. Está pensado para los tests: la respuesta ya se ha enviado cuando se valida, así que los errores solo se pueden informar
+	ValidateResponses bool
+	// OnResponseError recibe los errores de validación de las respuestas. Por defecto se escriben en el log
+	OnResponseError func(ctx *gin.Context, err error)
+}
+
+// Middleware valida las peticiones contra la especificación y responde 400 si no la cumplen. Usa la ruta que ha encontrado Gin para buscar la operación, y no valida las rutas que no están en la especificación. La autenticación no se valida aquí: la hacen los controladores
+func Middleware(doc *openapi3.T, options Options) gin.HandlerFunc {
+	// los errores de validación incluyen el campo y el motivo, pero no todo el esquema
+	openapi3.SchemaErrorDetailsDisabled = true
+
+	if options.OnResponseError == nil {
+		options.OnResponseError = func(ctx *gin.Context, err error) {
+			logging.Error(ctx.Request.Context(), "Response does not match the API specification", "error", err)
+		}
+	}
+
+	operations := Routes(doc)
+	filterOptions := &openapi3filter.Options{
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		IncludeResponseStatus: true,
+	}
+	withoutBody := &openapi3filter.Options{
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		IncludeResponseStatus: true,
+		ExcludeResponseBody:   true,
+	}
+
+	return func(ctx *gin.Context) {
+		route, found := operations[ctx.Request.Method+" "+ctx.FullPath()]
+		if !found {
+			ctx.Next()
+			return
+		}
+
+		pathParams := make(map[string]string, len(ctx.Params))
+		for _, param := range ctx.Params {
+			pathParams[param.Key] = param.Value
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    ctx.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    filterOptions,
+		}
+		err := openapi3filter.ValidateRequest(ctx.Request.Context(), input)
+		if err != nil {
+			logging.Debug(ctx.Request.Context(), "Request does not match the API specification", "error", err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, &models.ResponseError{
+				Message: "Invalid request: " + err.Error(),
+				Status:  http.StatusBadRequest,
+			})
+			return
+		}
+
+		// las conexiones WebSocket no tienen una respuesta que validar
+		if !options.ValidateResponses || ctx.IsWebsocket() {
+			ctx.Next()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 recorder.Status(),
+			Header:                 recorder.Header(),
+			Options:                filterOptions,
+		}
+		// las respuestas sin cuerpo (por ejemplo, los 401 cuando el rol no es suficiente) o que no son JSON solo se validan por el código de estado
+		if len(recorder.body) == 0 || recorder.truncated || !isJSON(recorder.Header().Get("Content-Type")) {
+			responseInput.Options = withoutBody
+		}
+		responseInput.SetBodyBytes(recorder.body)
+
+		err = openapi3filter.ValidateResponse(ctx.Request.Context(), responseInput)
+		if err != nil {
+			options.OnResponseError(ctx, err)
+		}
+	}
+}
+
+// Routes indexa las operaciones de la especificación por método y ruta de Gin, con el prefijo de cada servidor de la operación: "GET /v1/runner/:id" y "GET /v2/runner/:id".
+// Los servidores de la operación, si los tiene, sustituyen a los de la ruta, y estos a los de la especificación
+func Routes(doc *openapi3.T) map[string]*routers.Route {
+	operations := make(map[string]*routers.Route)
+	for path, pathItem := range doc.Paths {
+		for method, operation := range pathItem.Operations() {
+			servers := doc.Servers
+			if operation.Servers != nil {
+				servers = *operation.Servers
+			} else if len(pathItem.Servers) > 0 {
+				servers = pathItem.Servers
+			}
+
+			for _, server := range servers {
+				operations[method+" "+strings.TrimSuffix(server.URL, "/")+GinPath(path)] = &routers.Route{
+					Spec:      doc,
+					Server:    server,
+					Path:      path,
+					PathItem:  pathItem,
+					Method:    method,
+					Operation: operation,
+				}
+			}
+		}
+	}
+
+	return operations
+}
+
+// GinPath convierte una ruta de OpenAPI ("/runner/{id}") en la plantilla de Gin ("/runner/:id")
+func GinPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// responseRecorder guarda una copia de la respuesta mientras se envía, para validarla después
+type responseRecorder struct {
+	gin.ResponseWriter
+	body      []byte
+	truncated bool
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.record(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(data string) (int, error) {
+	r.record([]byte(data))
+	return r.ResponseWriter.WriteString(data)
+}
+
+func (r *responseRecorder) record(data []byte) {
+	if r.truncated {
+		return
+	}
+	if len(r.body)+len(data) > maxValidatedResponse {
+		r.truncated = true
+		r.body = nil
+		return
+	}
+
+	r.body = append(r.body, data...)
+}
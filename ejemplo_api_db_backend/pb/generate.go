@@ -0,0 +1,4 @@
+// Package pb tiene el código generado a partir de proto/runners.proto. No se edita a mano: después de cambiar el .proto se vuelve a generar con go generate ./pb (necesita protoc, protoc-gen-go y protoc-gen-go-grpc) y se incluye en el commit
+package pb
+
+//go:generate protoc --proto_path=../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative runners.proto
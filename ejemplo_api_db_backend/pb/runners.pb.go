@@ -0,0 +1,1073 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: runners.proto
+
+// API gRPC para los servicios internos. Usa los mismos servicios que la API REST, y los mismos roles
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Runner struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	FirstName    string                 `protobuf:"bytes,2,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName     string                 `protobuf:"bytes,3,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Age          int32                  `protobuf:"varint,4,opt,name=age,proto3" json:"age,omitempty"`
+	IsActive     bool                   `protobuf:"varint,5,opt,name=is_active,json=isActive,proto3" json:"is_active,omitempty"`
+	Country      string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	PersonalBest string                 `protobuf:"bytes,7,opt,name=personal_best,json=personalBest,proto3" json:"personal_best,omitempty"`
+	SeasonBest   string                 `protobuf:"bytes,8,opt,name=season_best,json=seasonBest,proto3" json:"season_best,omitempty"`
+	Results      []*Result              `protobuf:"bytes,9,rep,name=results,proto3" json:"results,omitempty"`
+	// solo se informa para el propio runner y el personal
+	Privacy       *Privacy `protobuf:"bytes,10,opt,name=privacy,proto3" json:"privacy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Runner) Reset() {
+	*x = Runner{}
+	mi := &file_runners_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Runner) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Runner) ProtoMessage() {}
+
+func (x *Runner) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Runner.ProtoReflect.Descriptor instead.
+func (*Runner) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Runner) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Runner) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *Runner) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *Runner) GetAge() int32 {
+	if x != nil {
+		return x.Age
+	}
+	return 0
+}
+
+func (x *Runner) GetIsActive() bool {
+	if x != nil {
+		return x.IsActive
+	}
+	return false
+}
+
+func (x *Runner) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *Runner) GetPersonalBest() string {
+	if x != nil {
+		return x.PersonalBest
+	}
+	return ""
+}
+
+func (x *Runner) GetSeasonBest() string {
+	if x != nil {
+		return x.SeasonBest
+	}
+	return ""
+}
+
+func (x *Runner) GetResults() []*Result {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *Runner) GetPrivacy() *Privacy {
+	if x != nil {
+		return x.Privacy
+	}
+	return nil
+}
+
+type Privacy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	HideAge       bool                   `protobuf:"varint,1,opt,name=hide_age,json=hideAge,proto3" json:"hide_age,omitempty"`
+	HideResults   bool                   `protobuf:"varint,2,opt,name=hide_results,json=hideResults,proto3" json:"hide_results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Privacy) Reset() {
+	*x = Privacy{}
+	mi := &file_runners_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Privacy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Privacy) ProtoMessage() {}
+
+func (x *Privacy) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Privacy.ProtoReflect.Descriptor instead.
+func (*Privacy) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Privacy) GetHideAge() bool {
+	if x != nil {
+		return x.HideAge
+	}
+	return false
+}
+
+func (x *Privacy) GetHideResults() bool {
+	if x != nil {
+		return x.HideResults
+	}
+	return false
+}
+
+type Result struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	RunnerId string                 `protobuf:"bytes,2,opt,name=runner_id,json=runnerId,proto3" json:"runner_id,omitempty"`
+	// tiempo en formato "hh:mm:ss"
+	RaceResult    string `protobuf:"bytes,3,opt,name=race_result,json=raceResult,proto3" json:"race_result,omitempty"`
+	Location      string `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	Position      int32  `protobuf:"varint,5,opt,name=position,proto3" json:"position,omitempty"`
+	Year          int32  `protobuf:"varint,6,opt,name=year,proto3" json:"year,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_runners_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Result) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Result) GetRunnerId() string {
+	if x != nil {
+		return x.RunnerId
+	}
+	return ""
+}
+
+func (x *Result) GetRaceResult() string {
+	if x != nil {
+		return x.RaceResult
+	}
+	return ""
+}
+
+func (x *Result) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *Result) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *Result) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+type GetRunnerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunnerRequest) Reset() {
+	*x = GetRunnerRequest{}
+	mi := &file_runners_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunnerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunnerRequest) ProtoMessage() {}
+
+func (x *GetRunnerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunnerRequest.ProtoReflect.Descriptor instead.
+func (*GetRunnerRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRunnerRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListRunnersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// con country devuelve los 10 mejores del país por marca personal
+	Country string `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	// con year devuelve los 10 mejores del año por marca de la temporada
+	Year          int32 `protobuf:"varint,2,opt,name=year,proto3" json:"year,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRunnersRequest) Reset() {
+	*x = ListRunnersRequest{}
+	mi := &file_runners_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRunnersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRunnersRequest) ProtoMessage() {}
+
+func (x *ListRunnersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRunnersRequest.ProtoReflect.Descriptor instead.
+func (*ListRunnersRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListRunnersRequest) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *ListRunnersRequest) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+type ListRunnersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Runners       []*Runner              `protobuf:"bytes,1,rep,name=runners,proto3" json:"runners,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRunnersResponse) Reset() {
+	*x = ListRunnersResponse{}
+	mi := &file_runners_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRunnersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRunnersResponse) ProtoMessage() {}
+
+func (x *ListRunnersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRunnersResponse.ProtoReflect.Descriptor instead.
+func (*ListRunnersResponse) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListRunnersResponse) GetRunners() []*Runner {
+	if x != nil {
+		return x.Runners
+	}
+	return nil
+}
+
+type CreateRunnerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Runner        *Runner                `protobuf:"bytes,1,opt,name=runner,proto3" json:"runner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRunnerRequest) Reset() {
+	*x = CreateRunnerRequest{}
+	mi := &file_runners_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRunnerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRunnerRequest) ProtoMessage() {}
+
+func (x *CreateRunnerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRunnerRequest.ProtoReflect.Descriptor instead.
+func (*CreateRunnerRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateRunnerRequest) GetRunner() *Runner {
+	if x != nil {
+		return x.Runner
+	}
+	return nil
+}
+
+type UpdateRunnerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Runner        *Runner                `protobuf:"bytes,1,opt,name=runner,proto3" json:"runner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRunnerRequest) Reset() {
+	*x = UpdateRunnerRequest{}
+	mi := &file_runners_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRunnerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRunnerRequest) ProtoMessage() {}
+
+func (x *UpdateRunnerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRunnerRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRunnerRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateRunnerRequest) GetRunner() *Runner {
+	if x != nil {
+		return x.Runner
+	}
+	return nil
+}
+
+type DeleteRunnerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRunnerRequest) Reset() {
+	*x = DeleteRunnerRequest{}
+	mi := &file_runners_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRunnerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRunnerRequest) ProtoMessage() {}
+
+func (x *DeleteRunnerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRunnerRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRunnerRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteRunnerRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CreateResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *Result                `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateResultRequest) Reset() {
+	*x = CreateResultRequest{}
+	mi := &file_runners_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateResultRequest) ProtoMessage() {}
+
+func (x *CreateResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateResultRequest.ProtoReflect.Descriptor instead.
+func (*CreateResultRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateResultRequest) GetResult() *Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type DeleteResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResultRequest) Reset() {
+	*x = DeleteResultRequest{}
+	mi := &file_runners_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResultRequest) ProtoMessage() {}
+
+func (x *DeleteResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResultRequest.ProtoReflect.Descriptor instead.
+func (*DeleteResultRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteResultRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type WatchResultsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// filtros; los vacíos no filtran
+	Race     string `protobuf:"bytes,1,opt,name=race,proto3" json:"race,omitempty"`
+	RunnerId string `protobuf:"bytes,2,opt,name=runner_id,json=runnerId,proto3" json:"runner_id,omitempty"`
+	// último evento recibido, para continuar sin perder eventos al volver a suscribirse
+	LastSeq uint64 `protobuf:"varint,3,opt,name=last_seq,json=lastSeq,proto3" json:"last_seq,omitempty"`
+	// eventos recientes que se envían al suscribirse sin last_seq (20 si no se indica)
+	Replay        *int32 `protobuf:"varint,4,opt,name=replay,proto3,oneof" json:"replay,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResultsRequest) Reset() {
+	*x = WatchResultsRequest{}
+	mi := &file_runners_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResultsRequest) ProtoMessage() {}
+
+func (x *WatchResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResultsRequest.ProtoReflect.Descriptor instead.
+func (*WatchResultsRequest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchResultsRequest) GetRace() string {
+	if x != nil {
+		return x.Race
+	}
+	return ""
+}
+
+func (x *WatchResultsRequest) GetRunnerId() string {
+	if x != nil {
+		return x.RunnerId
+	}
+	return ""
+}
+
+func (x *WatchResultsRequest) GetLastSeq() uint64 {
+	if x != nil {
+		return x.LastSeq
+	}
+	return 0
+}
+
+func (x *WatchResultsRequest) GetReplay() int32 {
+	if x != nil && x.Replay != nil {
+		return *x.Replay
+	}
+	return 0
+}
+
+type ResultEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	// "result.created" o "runner.personal_best"
+	Type     string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	RunnerId string `protobuf:"bytes,3,opt,name=runner_id,json=runnerId,proto3" json:"runner_id,omitempty"`
+	Race     string `protobuf:"bytes,4,opt,name=race,proto3" json:"race,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ResultEvent_Result
+	//	*ResultEvent_PersonalBest
+	Payload       isResultEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultEvent) Reset() {
+	*x = ResultEvent{}
+	mi := &file_runners_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultEvent) ProtoMessage() {}
+
+func (x *ResultEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultEvent.ProtoReflect.Descriptor instead.
+func (*ResultEvent) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ResultEvent) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ResultEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ResultEvent) GetRunnerId() string {
+	if x != nil {
+		return x.RunnerId
+	}
+	return ""
+}
+
+func (x *ResultEvent) GetRace() string {
+	if x != nil {
+		return x.Race
+	}
+	return ""
+}
+
+func (x *ResultEvent) GetPayload() isResultEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ResultEvent) GetResult() *Result {
+	if x != nil {
+		if x, ok := x.Payload.(*ResultEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *ResultEvent) GetPersonalBest() *PersonalBest {
+	if x != nil {
+		if x, ok := x.Payload.(*ResultEvent_PersonalBest); ok {
+			return x.PersonalBest
+		}
+	}
+	return nil
+}
+
+type isResultEvent_Payload interface {
+	isResultEvent_Payload()
+}
+
+type ResultEvent_Result struct {
+	Result *Result `protobuf:"bytes,5,opt,name=result,proto3,oneof"`
+}
+
+type ResultEvent_PersonalBest struct {
+	PersonalBest *PersonalBest `protobuf:"bytes,6,opt,name=personal_best,json=personalBest,proto3,oneof"`
+}
+
+func (*ResultEvent_Result) isResultEvent_Payload() {}
+
+func (*ResultEvent_PersonalBest) isResultEvent_Payload() {}
+
+type PersonalBest struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	RunnerId             string                 `protobuf:"bytes,1,opt,name=runner_id,json=runnerId,proto3" json:"runner_id,omitempty"`
+	ResultId             string                 `protobuf:"bytes,2,opt,name=result_id,json=resultId,proto3" json:"result_id,omitempty"`
+	Location             string                 `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	PersonalBest         string                 `protobuf:"bytes,4,opt,name=personal_best,json=personalBest,proto3" json:"personal_best,omitempty"`
+	PreviousPersonalBest string                 `protobuf:"bytes,5,opt,name=previous_personal_best,json=previousPersonalBest,proto3" json:"previous_personal_best,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *PersonalBest) Reset() {
+	*x = PersonalBest{}
+	mi := &file_runners_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PersonalBest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PersonalBest) ProtoMessage() {}
+
+func (x *PersonalBest) ProtoReflect() protoreflect.Message {
+	mi := &file_runners_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PersonalBest.ProtoReflect.Descriptor instead.
+func (*PersonalBest) Descriptor() ([]byte, []int) {
+	return file_runners_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PersonalBest) GetRunnerId() string {
+	if x != nil {
+		return x.RunnerId
+	}
+	return ""
+}
+
+func (x *PersonalBest) GetResultId() string {
+	if x != nil {
+		return x.ResultId
+	}
+	return ""
+}
+
+func (x *PersonalBest) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *PersonalBest) GetPersonalBest() string {
+	if x != nil {
+		return x.PersonalBest
+	}
+	return ""
+}
+
+func (x *PersonalBest) GetPreviousPersonalBest() string {
+	if x != nil {
+		return x.PreviousPersonalBest
+	}
+	return ""
+}
+
+var File_runners_proto protoreflect.FileDescriptor
+
+const file_runners_proto_rawDesc = "" +
+	"\n" +
+	"\rrunners.proto\x12\n" +
+	"runners.v1\x1a\x1bgoogle/protobuf/empty.proto\"\xc0\x02\n" +
+	"\x06Runner\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x02 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x03 \x01(\tR\blastName\x12\x10\n" +
+	"\x03age\x18\x04 \x01(\x05R\x03age\x12\x1b\n" +
+	"\tis_active\x18\x05 \x01(\bR\bisActive\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12#\n" +
+	"\rpersonal_best\x18\a \x01(\tR\fpersonalBest\x12\x1f\n" +
+	"\vseason_best\x18\b \x01(\tR\n" +
+	"seasonBest\x12,\n" +
+	"\aresults\x18\t \x03(\v2\x12.runners.v1.ResultR\aresults\x12-\n" +
+	"\aprivacy\x18\n" +
+	" \x01(\v2\x13.runners.v1.PrivacyR\aprivacy\"G\n" +
+	"\aPrivacy\x12\x19\n" +
+	"\bhide_age\x18\x01 \x01(\bR\ahideAge\x12!\n" +
+	"\fhide_results\x18\x02 \x01(\bR\vhideResults\"\xa2\x01\n" +
+	"\x06Result\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\trunner_id\x18\x02 \x01(\tR\brunnerId\x12\x1f\n" +
+	"\vrace_result\x18\x03 \x01(\tR\n" +
+	"raceResult\x12\x1a\n" +
+	"\blocation\x18\x04 \x01(\tR\blocation\x12\x1a\n" +
+	"\bposition\x18\x05 \x01(\x05R\bposition\x12\x12\n" +
+	"\x04year\x18\x06 \x01(\x05R\x04year\"\"\n" +
+	"\x10GetRunnerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"B\n" +
+	"\x12ListRunnersRequest\x12\x18\n" +
+	"\acountry\x18\x01 \x01(\tR\acountry\x12\x12\n" +
+	"\x04year\x18\x02 \x01(\x05R\x04year\"C\n" +
+	"\x13ListRunnersResponse\x12,\n" +
+	"\arunners\x18\x01 \x03(\v2\x12.runners.v1.RunnerR\arunners\"A\n" +
+	"\x13CreateRunnerRequest\x12*\n" +
+	"\x06runner\x18\x01 \x01(\v2\x12.runners.v1.RunnerR\x06runner\"A\n" +
+	"\x13UpdateRunnerRequest\x12*\n" +
+	"\x06runner\x18\x01 \x01(\v2\x12.runners.v1.RunnerR\x06runner\"%\n" +
+	"\x13DeleteRunnerRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"A\n" +
+	"\x13CreateResultRequest\x12*\n" +
+	"\x06result\x18\x01 \x01(\v2\x12.runners.v1.ResultR\x06result\"%\n" +
+	"\x13DeleteResultRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x89\x01\n" +
+	"\x13WatchResultsRequest\x12\x12\n" +
+	"\x04race\x18\x01 \x01(\tR\x04race\x12\x1b\n" +
+	"\trunner_id\x18\x02 \x01(\tR\brunnerId\x12\x19\n" +
+	"\blast_seq\x18\x03 \x01(\x04R\alastSeq\x12\x1b\n" +
+	"\x06replay\x18\x04 \x01(\x05H\x00R\x06replay\x88\x01\x01B\t\n" +
+	"\a_replay\"\xde\x01\n" +
+	"\vResultEvent\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1b\n" +
+	"\trunner_id\x18\x03 \x01(\tR\brunnerId\x12\x12\n" +
+	"\x04race\x18\x04 \x01(\tR\x04race\x12,\n" +
+	"\x06result\x18\x05 \x01(\v2\x12.runners.v1.ResultH\x00R\x06result\x12?\n" +
+	"\rpersonal_best\x18\x06 \x01(\v2\x18.runners.v1.PersonalBestH\x00R\fpersonalBestB\t\n" +
+	"\apayload\"\xbf\x01\n" +
+	"\fPersonalBest\x12\x1b\n" +
+	"\trunner_id\x18\x01 \x01(\tR\brunnerId\x12\x1b\n" +
+	"\tresult_id\x18\x02 \x01(\tR\bresultId\x12\x1a\n" +
+	"\blocation\x18\x03 \x01(\tR\blocation\x12#\n" +
+	"\rpersonal_best\x18\x04 \x01(\tR\fpersonalBest\x124\n" +
+	"\x16previous_personal_best\x18\x05 \x01(\tR\x14previousPersonalBest2\xf5\x02\n" +
+	"\rRunnerService\x12=\n" +
+	"\tGetRunner\x12\x1c.runners.v1.GetRunnerRequest\x1a\x12.runners.v1.Runner\x12N\n" +
+	"\vListRunners\x12\x1e.runners.v1.ListRunnersRequest\x1a\x1f.runners.v1.ListRunnersResponse\x12C\n" +
+	"\fCreateRunner\x12\x1f.runners.v1.CreateRunnerRequest\x1a\x12.runners.v1.Runner\x12G\n" +
+	"\fUpdateRunner\x12\x1f.runners.v1.UpdateRunnerRequest\x1a\x16.google.protobuf.Empty\x12G\n" +
+	"\fDeleteRunner\x12\x1f.runners.v1.DeleteRunnerRequest\x1a\x16.google.protobuf.Empty2\xe9\x01\n" +
+	"\rResultService\x12C\n" +
+	"\fCreateResult\x12\x1f.runners.v1.CreateResultRequest\x1a\x12.runners.v1.Result\x12G\n" +
+	"\fDeleteResult\x12\x1f.runners.v1.DeleteResultRequest\x1a\x16.google.protobuf.Empty\x12J\n" +
+	"\fWatchResults\x12\x1f.runners.v1.WatchResultsRequest\x1a\x17.runners.v1.ResultEvent0\x01B\x17Z\x15runners-postgresql/pbb\x06proto3"
+
+var (
+	file_runners_proto_rawDescOnce sync.Once
+	file_runners_proto_rawDescData []byte
+)
+
+func file_runners_proto_rawDescGZIP() []byte {
+	file_runners_proto_rawDescOnce.Do(func() {
+		file_runners_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_runners_proto_rawDesc), len(file_runners_proto_rawDesc)))
+	})
+	return file_runners_proto_rawDescData
+}
+
+var file_runners_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_runners_proto_goTypes = []any{
+	(*Runner)(nil),              // 0: runners.v1.Runner
+	(*Privacy)(nil),             // 1: runners.v1.Privacy
+	(*Result)(nil),              // 2: runners.v1.Result
+	(*GetRunnerRequest)(nil),    // 3: runners.v1.GetRunnerRequest
+	(*ListRunnersRequest)(nil),  // 4: runners.v1.ListRunnersRequest
+	(*ListRunnersResponse)(nil), // 5: runners.v1.ListRunnersResponse
+	(*CreateRunnerRequest)(nil), // 6: runners.v1.CreateRunnerRequest
+	(*UpdateRunnerRequest)(nil), // 7: runners.v1.UpdateRunnerRequest
+	(*DeleteRunnerRequest)(nil), // 8: runners.v1.DeleteRunnerRequest
+	(*CreateResultRequest)(nil), // 9: runners.v1.CreateResultRequest
+	(*DeleteResultRequest)(nil), // 10: runners.v1.DeleteResultRequest
+	(*WatchResultsRequest)(nil), // 11: runners.v1.WatchResultsRequest
+	(*ResultEvent)(nil),         // 12: runners.v1.ResultEvent
+	(*PersonalBest)(nil),        // 13: runners.v1.PersonalBest
+	(*emptypb.Empty)(nil),       // 14: google.protobuf.Empty
+}
+var file_runners_proto_depIdxs = []int32{
+	2,  // 0: runners.v1.Runner.results:type_name -> runners.v1.Result
+	1,  // 1: runners.v1.Runner.privacy:type_name -> runners.v1.Privacy
+	0,  // 2: runners.v1.ListRunnersResponse.runners:type_name -> runners.v1.Runner
+	0,  // 3: runners.v1.CreateRunnerRequest.runner:type_name -> runners.v1.Runner
+	0,  // 4: runners.v1.UpdateRunnerRequest.runner:type_name -> runners.v1.Runner
+	2,  // 5: runners.v1.CreateResultRequest.result:type_name -> runners.v1.Result
+	2,  // 6: runners.v1.ResultEvent.result:type_name -> runners.v1.Result
+	13, // 7: runners.v1.ResultEvent.personal_best:type_name -> runners.v1.PersonalBest
+	3,  // 8: runners.v1.RunnerService.GetRunner:input_type -> runners.v1.GetRunnerRequest
+	4,  // 9: runners.v1.RunnerService.ListRunners:input_type -> runners.v1.ListRunnersRequest
+	6,  // 10: runners.v1.RunnerService.CreateRunner:input_type -> runners.v1.CreateRunnerRequest
+	7,  // 11: runners.v1.RunnerService.UpdateRunner:input_type -> runners.v1.UpdateRunnerRequest
+	8,  // 12: runners.v1.RunnerService.DeleteRunner:input_type -> runners.v1.DeleteRunnerRequest
+	9,  // 13: runners.v1.ResultService.CreateResult:input_type -> runners.v1.CreateResultRequest
+	10, // 14: runners.v1.ResultService.DeleteResult:input_type -> runners.v1.DeleteResultRequest
+	11, // 15: runners.v1.ResultService.WatchResults:input_type -> runners.v1.WatchResultsRequest
+	0,  // 16: runners.v1.RunnerService.GetRunner:output_type -> runners.v1.Runner
+	5,  // 17: runners.v1.RunnerService.ListRunners:output_type -> runners.v1.ListRunnersResponse
+	0,  // 18: runners.v1.RunnerService.CreateRunner:output_type -> runners.v1.Runner
+	14, // 19: runners.v1.RunnerService.UpdateRunner:output_type -> google.protobuf.Empty
+	14, // 20: runners.v1.RunnerService.DeleteRunner:output_type -> google.protobuf.Empty
+	2,  // 21: runners.v1.ResultService.CreateResult:output_type -> runners.v1.Result
+	14, // 22: runners.v1.ResultService.DeleteResult:output_type -> google.protobuf.Empty
+	12, // 23: runners.v1.ResultService.WatchResults:output_type -> runners.v1.ResultEvent
+	16, // [16:24] is the sub-list for method output_type
+	8,  // [8:16] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_runners_proto_init() }
+func file_runners_proto_init() {
+	if File_runners_proto != nil {
+		return
+	}
+	file_runners_proto_msgTypes[11].OneofWrappers = []any{}
+	file_runners_proto_msgTypes[12].OneofWrappers = []any{
+		(*ResultEvent_Result)(nil),
+		(*ResultEvent_PersonalBest)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_runners_proto_rawDesc), len(file_runners_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_runners_proto_goTypes,
+		DependencyIndexes: file_runners_proto_depIdxs,
+		MessageInfos:      file_runners_proto_msgTypes,
+	}.Build()
+	File_runners_proto = out.File
+	file_runners_proto_goTypes = nil
+	file_runners_proto_depIdxs = nil
+}
@@ -0,0 +1,482 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: runners.proto
+
+// API gRPC para los servicios internos. Usa los mismos servicios que la API REST, y los mismos roles
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RunnerService_GetRunner_FullMethodName    = "/runners.v1.RunnerService/GetRunner"
+	RunnerService_ListRunners_FullMethodName  = "/runners.v1.RunnerService/ListRunners"
+	RunnerService_CreateRunner_FullMethodName = "/runners.v1.RunnerService/CreateRunner"
+	RunnerService_UpdateRunner_FullMethodName = "/runners.v1.RunnerService/UpdateRunner"
+	RunnerService_DeleteRunner_FullMethodName = "/runners.v1.RunnerService/DeleteRunner"
+)
+
+// RunnerServiceClient is the client API for RunnerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Runners. El token va en la clave "token" de los metadatos
+type RunnerServiceClient interface {
+	// Roles: admin, club_admin, runner
+	GetRunner(ctx context.Context, in *GetRunnerRequest, opts ...grpc.CallOption) (*Runner, error)
+	// Roles: admin, club_admin, runner. Sin filtros devuelve los runners activos
+	ListRunners(ctx context.Context, in *ListRunnersRequest, opts ...grpc.CallOption) (*ListRunnersResponse, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	CreateRunner(ctx context.Context, in *CreateRunnerRequest, opts ...grpc.CallOption) (*Runner, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	UpdateRunner(ctx context.Context, in *UpdateRunnerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	DeleteRunner(ctx context.Context, in *DeleteRunnerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type runnerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRunnerServiceClient(cc grpc.ClientConnInterface) RunnerServiceClient {
+	return &runnerServiceClient{cc}
+}
+
+func (c *runnerServiceClient) GetRunner(ctx context.Context, in *GetRunnerRequest, opts ...grpc.CallOption) (*Runner, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Runner)
+	err := c.cc.Invoke(ctx, RunnerService_GetRunner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runnerServiceClient) ListRunners(ctx context.Context, in *ListRunnersRequest, opts ...grpc.CallOption) (*ListRunnersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRunnersResponse)
+	err := c.cc.Invoke(ctx, RunnerService_ListRunners_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runnerServiceClient) CreateRunner(ctx context.Context, in *CreateRunnerRequest, opts ...grpc.CallOption) (*Runner, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Runner)
+	err := c.cc.Invoke(ctx, RunnerService_CreateRunner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runnerServiceClient) UpdateRunner(ctx context.Context, in *UpdateRunnerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RunnerService_UpdateRunner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runnerServiceClient) DeleteRunner(ctx context.Context, in *DeleteRunnerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RunnerService_DeleteRunner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RunnerServiceServer is the server API for RunnerService service.
+// All implementations must embed UnimplementedRunnerServiceServer
+// for forward compatibility.
+//
+// Runners. El token va en la clave "token" de los metadatos
+type RunnerServiceServer interface {
+	// Roles: admin, club_admin, runner
+	GetRunner(context.Context, *GetRunnerRequest) (*Runner, error)
+	// Roles: admin, club_admin, runner. Sin filtros devuelve los runners activos
+	ListRunners(context.Context, *ListRunnersRequest) (*ListRunnersResponse, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	CreateRunner(context.Context, *CreateRunnerRequest) (*Runner, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	UpdateRunner(context.Context, *UpdateRunnerRequest) (*emptypb.Empty, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	DeleteRunner(context.Context, *DeleteRunnerRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedRunnerServiceServer()
+}
+
+// UnimplementedRunnerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRunnerServiceServer struct{}
+
+func (UnimplementedRunnerServiceServer) GetRunner(context.Context, *GetRunnerRequest) (*Runner, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRunner not implemented")
+}
+func (UnimplementedRunnerServiceServer) ListRunners(context.Context, *ListRunnersRequest) (*ListRunnersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRunners not implemented")
+}
+func (UnimplementedRunnerServiceServer) CreateRunner(context.Context, *CreateRunnerRequest) (*Runner, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRunner not implemented")
+}
+func (UnimplementedRunnerServiceServer) UpdateRunner(context.Context, *UpdateRunnerRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRunner not implemented")
+}
+func (UnimplementedRunnerServiceServer) DeleteRunner(context.Context, *DeleteRunnerRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRunner not implemented")
+}
+func (UnimplementedRunnerServiceServer) mustEmbedUnimplementedRunnerServiceServer() {}
+func (UnimplementedRunnerServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeRunnerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RunnerServiceServer will
+// result in compilation errors.
+type UnsafeRunnerServiceServer interface {
+	mustEmbedUnimplementedRunnerServiceServer()
+}
+
+func RegisterRunnerServiceServer(s grpc.ServiceRegistrar, srv RunnerServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRunnerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RunnerService_ServiceDesc, srv)
+}
+
+func _RunnerService_GetRunner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RunnerServiceServer).GetRunner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RunnerService_GetRunner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RunnerServiceServer).GetRunner(ctx, req.(*GetRunnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RunnerService_ListRunners_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRunnersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RunnerServiceServer).ListRunners(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RunnerService_ListRunners_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RunnerServiceServer).ListRunners(ctx, req.(*ListRunnersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RunnerService_CreateRunner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRunnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RunnerServiceServer).CreateRunner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RunnerService_CreateRunner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RunnerServiceServer).CreateRunner(ctx, req.(*CreateRunnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RunnerService_UpdateRunner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRunnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RunnerServiceServer).UpdateRunner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RunnerService_UpdateRunner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RunnerServiceServer).UpdateRunner(ctx, req.(*UpdateRunnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RunnerService_DeleteRunner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRunnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RunnerServiceServer).DeleteRunner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RunnerService_DeleteRunner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RunnerServiceServer).DeleteRunner(ctx, req.(*DeleteRunnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RunnerService_ServiceDesc is the grpc.ServiceDesc for RunnerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RunnerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "runners.v1.RunnerService",
+	HandlerType: (*RunnerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRunner",
+			Handler:    _RunnerService_GetRunner_Handler,
+		},
+		{
+			MethodName: "ListRunners",
+			Handler:    _RunnerService_ListRunners_Handler,
+		},
+		{
+			MethodName: "CreateRunner",
+			Handler:    _RunnerService_CreateRunner_Handler,
+		},
+		{
+			MethodName: "UpdateRunner",
+			Handler:    _RunnerService_UpdateRunner_Handler,
+		},
+		{
+			MethodName: "DeleteRunner",
+			Handler:    _RunnerService_DeleteRunner_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "runners.proto",
+}
+
+const (
+	ResultService_CreateResult_FullMethodName = "/runners.v1.ResultService/CreateResult"
+	ResultService_DeleteResult_FullMethodName = "/runners.v1.ResultService/DeleteResult"
+	ResultService_WatchResults_FullMethodName = "/runners.v1.ResultService/WatchResults"
+)
+
+// ResultServiceClient is the client API for ResultService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Resultados. El token va en la clave "token" de los metadatos
+type ResultServiceClient interface {
+	// Roles: admin, club_admin (solo runners de su club)
+	CreateResult(ctx context.Context, in *CreateResultRequest, opts ...grpc.CallOption) (*Result, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	DeleteResult(ctx context.Context, in *DeleteResultRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Roles: admin, club_admin, runner. Mismos eventos que el feed en directo (/live/results). El stream termina con UNAVAILABLE si el cliente no lee lo bastante rápido o si se detiene la aplicación; se puede volver a suscribir con last_seq
+	WatchResults(ctx context.Context, in *WatchResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResultEvent], error)
+}
+
+type resultServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewResultServiceClient(cc grpc.ClientConnInterface) ResultServiceClient {
+	return &resultServiceClient{cc}
+}
+
+func (c *resultServiceClient) CreateResult(ctx context.Context, in *CreateResultRequest, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, ResultService_CreateResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *resultServiceClient) DeleteResult(ctx context.Context, in *DeleteResultRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ResultService_DeleteResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *resultServiceClient) WatchResults(ctx context.Context, in *WatchResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResultEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ResultService_ServiceDesc.Streams[0], ResultService_WatchResults_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchResultsRequest, ResultEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ResultService_WatchResultsClient = grpc.ServerStreamingClient[ResultEvent]
+
+// ResultServiceServer is the server API for ResultService service.
+// All implementations must embed UnimplementedResultServiceServer
+// for forward compatibility.
+//
+// Resultados. El token va en la clave "token" de los metadatos
+type ResultServiceServer interface {
+	// Roles: admin, club_admin (solo runners de su club)
+	CreateResult(context.Context, *CreateResultRequest) (*Result, error)
+	// Roles: admin, club_admin (solo runners de su club)
+	DeleteResult(context.Context, *DeleteResultRequest) (*emptypb.Empty, error)
+	// Roles: admin, club_admin, runner. Mismos eventos que el feed en directo (/live/results). El stream termina con UNAVAILABLE si el cliente no lee lo bastante rápido o si se detiene la aplicación; se puede volver a suscribir con last_seq
+	WatchResults(*WatchResultsRequest, grpc.ServerStreamingServer[ResultEvent]) error
+	mustEmbedUnimplementedResultServiceServer()
+}
+
+// UnimplementedResultServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedResultServiceServer struct{}
+
+func (UnimplementedResultServiceServer) CreateResult(context.Context, *CreateResultRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateResult not implemented")
+}
+func (UnimplementedResultServiceServer) DeleteResult(context.Context, *DeleteResultRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteResult not implemented")
+}
+func (UnimplementedResultServiceServer) WatchResults(*WatchResultsRequest, grpc.ServerStreamingServer[ResultEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchResults not implemented")
+}
+func (UnimplementedResultServiceServer) mustEmbedUnimplementedResultServiceServer() {}
+func (UnimplementedResultServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeResultServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ResultServiceServer will
+// result in compilation errors.
+type UnsafeResultServiceServer interface {
+	mustEmbedUnimplementedResultServiceServer()
+}
+
+func RegisterResultServiceServer(s grpc.ServiceRegistrar, srv ResultServiceServer) {
+	// If the following call pancis, it indicates UnimplementedResultServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ResultService_ServiceDesc, srv)
+}
+
+func _ResultService_CreateResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResultServiceServer).CreateResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ResultService_CreateResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResultServiceServer).CreateResult(ctx, req.(*CreateResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ResultService_DeleteResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResultServiceServer).DeleteResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ResultService_DeleteResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResultServiceServer).DeleteResult(ctx, req.(*DeleteResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ResultService_WatchResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ResultServiceServer).WatchResults(m, &grpc.GenericServerStream[WatchResultsRequest, ResultEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ResultService_WatchResultsServer = grpc.ServerStreamingServer[ResultEvent]
+
+// ResultService_ServiceDesc is the grpc.ServiceDesc for ResultService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ResultService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "runners.v1.ResultService",
+	HandlerType: (*ResultServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateResult",
+			Handler:    _ResultService_CreateResult_Handler,
+		},
+		{
+			MethodName: "DeleteResult",
+			Handler:    _ResultService_DeleteResult_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchResults",
+			Handler:       _ResultService_WatchResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "runners.proto",
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import "sync/atomic"
+
+// Concurrency limita las peticiones en curso en la réplica. Es la idea de MiThrotle (Learning Go, ch12 concurrencia/backpressure): si no hay capacidad la petición se rechaza enseguida en lugar de esperar. En lugar de un canal con buffer usamos un contador, para poder cambiar el máximo en caliente
+type Concurrency struct {
+	inFlight atomic.Int64
+	max      atomic.Int64
+}
+
+// SetMax cambia el máximo de peticiones en curso. Con 0 no hay límite
+func (c *Concurrency) SetMax(max int) {
+	c.max.Store(int64(max))
+}
+
+// Acquire reserva un hueco. Si devuelve true hay que liberarlo con Release al terminar
+func (c *Concurrency) Acquire() bool {
+	inFlight := c.inFlight.Add(1)
+	max := c.max.Load()
+	if max > 0 && inFlight > max {
+		c.inFlight.Add(-1)
+		return false
+	}
+
+	return true
+}
+
+func (c *Concurrency) Release() {
+	c.inFlight.Add(-1)
+}
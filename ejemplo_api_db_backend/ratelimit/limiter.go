@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"runners-postgresql/certs"
+	"runners-postgresql/config"
+	"runners-postgresql/logging"
+	"runners-postgresql/metrics"
+	"runners-postgresql/models"
+	"runners-postgresql/versioning"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// grupo de las rutas que no están en ningún grupo de la configuración
+const defaultGroup = "default"
+
+// Identify devuelve el usuario autenticado que hace la petición, o nil si la petición no trae credenciales válidas
+type Identify func(ctx *gin.Context) *models.Principal
+
+// Limiter aplica los límites de peticiones por cliente y el límite global de peticiones en curso. La configuración se puede cambiar en caliente con Update
+type Limiter struct {
+	store       Store
+	identify    Identify
+	concurrency Concurrency
+	settings    atomic.Pointer[settings]
+}
+
+// la configuración preparada para buscar el grupo de cada ruta
+type settings struct {
+	enabled bool
+	rule    Rule
+	groups  map[string]group // por "MÉTODO /ruta"
+}
+
+type group struct {
+	name string
+	rule Rule
+}
+
+// NewLimiter crea el limitador. identify resuelve el usuario de cada petición para llevar su cuenta, y si es nil los clientes solo se identifican por su IP o su certificado
+func NewLimiter(store Store, config config.RateLimitConfig, identify Identify) *Limiter {
+	limiter := &Limiter{
+		store:    store,
+		identify: identify,
+	}
+	limiter.Update(config)
+
+	return limiter
+}
+
+// Update aplica una nueva configuración. Los clientes conservan lo que llevan consumido
+func (l *Limiter) Update(config config.RateLimitConfig) {
+	next := &settings{
+		enabled: config.Enabled,
+		rule:    Rule{RequestsPerSecond: config.RequestsPerSecond, Burst: config.Burst},
+		groups:  make(map[string]group),
+	}
+	for name, groupConfig := range config.Groups {
+		for _, route := range groupConfig.Routes {
+			next.groups[route] = group{
+				name: name,
+				rule: Rule{RequestsPerSecond: groupConfig.RequestsPerSecond, Burst: groupConfig.Burst},
+			}
+		}
+	}
+
+	l.concurrency.SetMax(config.MaxConcurrent)
+	l.settings.Store(next)
+}
+
+// Reload aplica los límites de una configuración recargada: reloader.OnReload(limiter.Reload)
+func (l *Limiter) Reload(reloaded *config.Config) {
+	l.Update(reloaded.RateLimit)
+}
+
+// Middleware limita todas las rutas salvo las indicadas en exempt (por ejemplo, las sondas de Kubernetes), sin la versión de la API. Si el cliente supera su límite responde 429, y si la réplica tiene demasiadas peticiones en curso responde 503
+func (l *Limiter) Middleware(exempt ...string) gin.HandlerFunc {
+	exemptRoutes := make(map[string]bool)
+	for _, route := range exempt {
+		exemptRoutes[route] = true
+	}
+
+	return func(ctx *gin.Context) {
+		current := l.settings.Load()
+		// los límites de una ruta son los mismos en todas las versiones de la API
+		route := versioning.Unversioned(ctx.FullPath())
+		if !current.enabled || exemptRoutes[route] {
+			ctx.Next()
+			return
+		}
+
+		name, rule := defaultGroup, current.rule
+		if routeGroup, ok := current.groups[ctx.Request.Method+" "+route]; ok {
+			name, rule = routeGroup.name, routeGroup.rule
+		}
+
+		decision, err := l.store.Allow(ctx.Request.Context(), name+":"+l.clientKey(ctx), rule)
+		if err != nil {
+			// si el almacenamiento compartido falla preferimos atender la petición a rechazar todas
+			logging.Error(ctx.Request.Context(), "Error while checking rate limit, request allowed", "group", name, "error", err)
+		} else {
+			setHeaders(ctx, decision)
+			if !decision.Allowed {
+				metrics.RateLimitedCounter.WithLabelValues(name, "rate").Inc()
+				ctx.Header("Retry-After", ceilSeconds(decision.RetryAfter))
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, &models.ResponseError{
+					Message: "Too many requests",
+					Status:  http.StatusTooManyRequests,
+				})
+				return
+			}
+		}
+
+		if !l.concurrency.Acquire() {
+			metrics.RateLimitedCounter.WithLabelValues(name, "concurrency").Inc()
+			ctx.Header("Retry-After", "1")
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, &models.ResponseError{
+				Message: "Server overloaded",
+				Status:  http.StatusServiceUnavailable,
+			})
+			return
+		}
+		defer l.concurrency.Release()
+
+		ctx.Next()
+	}
+}
+
+// clientKey identifica al cliente: los usuarios y las claves de API autenticados tienen su propia cuenta, vengan de la IP que vengan, y los servicios con certificado de cliente la suya. Las peticiones sin credenciales válidas se cuentan por IP, de modo que inventar un token distinto en cada petición no da una cuenta nueva
+func (l *Limiter) clientKey(ctx *gin.Context) string {
+	if principal := certs.PrincipalFromContext(ctx.Request.Context()); principal != nil {
+		return "cert:" + principal.Username
+	}
+
+	if l.identify != nil {
+		if principal := l.identify(ctx); principal != nil {
+			if principal.APIKeyID != "" {
+				return "apikey:" + principal.APIKeyID
+			}
+			return "user:" + principal.UserID
+		}
+	}
+
+	return "ip:" + ctx.ClientIP()
+}
+
+// cabeceras del borrador de IETF "RateLimit header fields for HTTP"
+func setHeaders(ctx *gin.Context, decision Decision) {
+	ctx.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	ctx.Header("RateLimit-Reset", ceilSeconds(decision.Reset))
+}
+
+// las cabeceras van en segundos enteros. Redondeamos hacia arriba para que el cliente no reintente antes de tiempo
+func ceilSeconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
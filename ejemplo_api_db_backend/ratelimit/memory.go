@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // a partir de este momento el bucket vuelve a estar lleno y es igual que uno nuevo
+}
+
+// Memory es un token bucket en memoria. Es exacto, pero cada réplica lleva su propia cuenta
+type Memory struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (m *Memory) Allow(_ context.Context, key string, rule Rule) (Decision, error) {
+	now := m.now()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		m.buckets[key] = b
+	}
+
+	// recargamos los tokens del tiempo transcurrido desde la última petición
+	burst := float64(rule.Burst)
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rule.RequestsPerSecond)
+	b.last = now
+
+	decision := Decision{Limit: rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - b.tokens) / rule.RequestsPerSecond)
+	}
+
+	decision.Remaining = int(b.tokens)
+	decision.Reset = seconds((burst - b.tokens) / rule.RequestsPerSecond)
+	b.full = now.Add(decision.Reset)
+
+	return decision, nil
+}
+
+// sweep borra, como mucho una vez por minuto, los buckets que ya están llenos. Así la memoria depende de los clientes activos y no de todos los que han llegado a conectarse
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
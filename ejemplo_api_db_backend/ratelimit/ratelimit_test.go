@@ -0,0 +1,195 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runners-postgresql/cache"
+	"runners-postgresql/cache/redistest"
+	"runners-postgresql/certs"
+	"runners-postgresql/config"
+	"runners-postgresql/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryTokenBucket(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemory()
+	store.now = func() time.Time { return now }
+	rule := Rule{RequestsPerSecond: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		decision, err := store.Allow(context.Background(), "client", rule)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 1-i, decision.Remaining)
+	}
+
+	decision, _ := store.Allow(context.Background(), "client", rule)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+	assert.Equal(t, 2*time.Second, decision.Reset)
+
+	// otro cliente tiene su propio bucket
+	decision, _ = store.Allow(context.Background(), "other", rule)
+	assert.True(t, decision.Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	decision, _ = store.Allow(context.Background(), "client", rule)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 500*time.Millisecond, decision.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	decision, _ = store.Allow(context.Background(), "client", rule)
+	assert.True(t, decision.Allowed)
+}
+
+func TestRedisSharedBetweenReplicas(t *testing.T) {
+	server := redistest.NewServer()
+	defer server.Close()
+
+	// comienzo de una ventana de un segundo
+	now := time.Unix(1700000000, 0)
+	replicas := []*Redis{
+		NewRedis(cache.NewRedis(server.Addr, "", 0, 2, time.Second)),
+		NewRedis(cache.NewRedis(server.Addr, "", 0, 2, time.Second)),
+	}
+	for _, replica := range replicas {
+		replica.now = func() time.Time { return now }
+	}
+	rule := Rule{RequestsPerSecond: 2, Burst: 2}
+
+	for _, replica := range replicas {
+		decision, err := replica.Allow(context.Background(), "default:ip:10.0.0.1", rule)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+
+	decision, err := replicas[0].Allow(context.Background(), "default:ip:10.0.0.1", rule)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+
+	// en la siguiente ventana vuelve a tener todas las peticiones
+	now = now.Add(time.Second)
+	decision, err = replicas[1].Allow(context.Background(), "default:ip:10.0.0.1", rule)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 1, decision.Remaining)
+}
+
+func TestMiddlewareGroupsAndHeaders(t *testing.T) {
+	limiter := NewLimiter(NewMemory(), config.RateLimitConfig{
+		Enabled:           true,
+		RequestsPerSecond: 100,
+		Burst:             100,
+		Groups: map[string]config.RateLimitGroup{
+			"login": {Routes: []string{"POST /login"}, RequestsPerSecond: 0.1, Burst: 1},
+		},
+	}, identifyTokens)
+	router := newRouter(limiter, nil)
+
+	response := request(router, http.MethodPost, "/login", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "1", response.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", response.Header().Get("RateLimit-Reset"))
+
+	response = request(router, http.MethodPost, "/login", "")
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "10", response.Header().Get("Retry-After"))
+
+	// el resto de rutas tienen su propia cuenta
+	assert.Equal(t, http.StatusOK, request(router, http.MethodGet, "/runner", "").Code)
+	// inventar un token no da una cuenta nueva
+	assert.Equal(t, http.StatusTooManyRequests, request(router, http.MethodPost, "/login", "made-up").Code)
+	// pero un usuario autenticado tiene su propia cuenta, desde cualquier IP
+	assert.Equal(t, http.StatusOK, request(router, http.MethodPost, "/login", "token-1").Code)
+	fromOtherIP := httptest.NewRequest(http.MethodPost, "/login", nil)
+	fromOtherIP.RemoteAddr = "198.51.100.7:4321"
+	fromOtherIP.Header.Set("Token", "token-1")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, fromOtherIP)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	// los servicios autenticados con su certificado tienen su propia cuenta
+	withCert := httptest.NewRequest(http.MethodPost, "/login", nil)
+	withCert = withCert.WithContext(certs.WithPrincipal(withCert.Context(), &models.Principal{Username: "CN=results-importer", Role: "admin"}))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, withCert)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	// las rutas exentas no tienen límite
+	assert.Empty(t, request(router, http.MethodGet, "/healthz", "").Header().Get("RateLimit-Limit"))
+
+	// se puede desactivar en caliente
+	limiter.Update(config.RateLimitConfig{Enabled: false})
+	assert.Equal(t, http.StatusOK, request(router, http.MethodPost, "/login", "").Code)
+}
+
+func TestMiddlewareConcurrency(t *testing.T) {
+	limiter := NewLimiter(NewMemory(), config.RateLimitConfig{
+		Enabled:           true,
+		RequestsPerSecond: 100,
+		Burst:             100,
+		MaxConcurrent:     1,
+	}, nil)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newRouter(limiter, func() {
+		close(started)
+		<-release
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- request(router, http.MethodGet, "/runner", "").Code
+	}()
+	<-started
+
+	response := request(router, http.MethodGet, "/runner", "")
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Equal(t, "1", response.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+}
+
+// solo token-1 es un token válido
+func identifyTokens(ctx *gin.Context) *models.Principal {
+	if ctx.GetHeader("Token") == "token-1" {
+		return &models.Principal{UserID: "1", Username: "runner", Role: models.RoleRunner}
+	}
+
+	return nil
+}
+
+func newRouter(limiter *Limiter, slowRunners func()) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(limiter.Middleware("/healthz"))
+	router.POST("/login", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.GET("/healthz", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.GET("/runner", func(ctx *gin.Context) {
+		if slowRunners != nil {
+			slowRunners()
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	return router
+}
+
+func request(router *gin.Engine, method string, path string, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		request.Header.Set("Token", token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	return recorder
+}
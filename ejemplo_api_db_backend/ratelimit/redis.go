@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"runners-postgresql/cache"
+	"strconv"
+	"time"
+)
+
+// Redis comparte la cuenta entre réplicas en un servidor compatible con Redis. Para no depender de scripts Lua, que no todos los servidores compatibles admiten, aproxima el token bucket con una ventana fija de Burst/RequestsPerSecond segundos en la que se permiten Burst peticiones: la media es la misma, pero en el cambio de ventana un cliente puede llegar a hacer hasta 2*Burst peticiones seguidas
+type Redis struct {
+	client *cache.Redis
+	now    func() time.Time
+}
+
+func NewRedis(client *cache.Redis) *Redis {
+	return &Redis{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, rule Rule) (Decision, error) {
+	window := seconds(float64(rule.Burst) / rule.RequestsPerSecond)
+	if window < time.Millisecond {
+		window = time.Millisecond
+	}
+
+	// cada ventana usa su propia clave, de modo que un contador que se quede sin caducidad no bloquea al cliente
+	now := r.now().UnixNano()
+	slot := now / int64(window)
+	reset := time.Duration(int64(window) - now%int64(window))
+
+	count, err := r.client.Incr(ctx, "ratelimit:"+key+":"+strconv.FormatInt(slot, 10), 2*window)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	decision := Decision{
+		Limit: rule.Burst,
+		Reset: reset,
+	}
+	if count <= int64(rule.Burst) {
+		decision.Allowed = true
+		decision.Remaining = rule.Burst - int(count)
+	} else {
+		decision.RetryAfter = reset
+	}
+
+	return decision, nil
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Rule es un límite de tipo token bucket: el cliente puede hacer Burst peticiones seguidas, y recupera RequestsPerSecond peticiones por segundo
+type Rule struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// Decision es el resultado de consultar el límite de un cliente
+type Decision struct {
+	Allowed    bool
+	Limit      int           // peticiones seguidas que se permiten
+	Remaining  int           // peticiones que le quedan al cliente
+	Reset      time.Duration // tiempo hasta que el cliente recupera todas las peticiones
+	RetryAfter time.Duration // si se rechaza, tiempo hasta que puede volver a intentarlo
+}
+
+// Store lleva la cuenta de las peticiones de cada cliente
+type Store interface {
+	Allow(ctx context.Context, key string, rule Rule) (Decision, error)
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runners-postgresql/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type APIKeysRepository struct {
+	dbHandler *sql.DB
+}
+
+func NewAPIKeysRepository(dbHandler *sql.DB) *APIKeysRepository {
+	return &APIKeysRepository{
+		dbHandler: dbHandler,
+	}
+}
+
+func (ar APIKeysRepository) CreateAPIKey(ctx context.Context, apiKey *models.APIKey) (*models.APIKey, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "api_keys", "CreateAPIKey")
+	defer done()
+
+	query := `
+		INSERT INTO api_keys(name, prefix, key_hash, user_role, club_id, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7)
+		RETURNING id, created_at`
+
+	row := ar.dbHandler.QueryRowContext(ctx, query, apiKey.Name, apiKey.Prefix, apiKey.Hash, apiKey.Role, apiKey.ClubID, pq.Array(apiKey.Scopes), apiKey.ExpiresAt)
+
+	response := *apiKey
+	err := row.Scan(&response.ID, &response.CreatedAt)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &response, nil
+}
+
+// RevokeAPIKey revoca la clave. Devuelve su hash, para poder invalidar la caché de roles
+func (ar APIKeysRepository) RevokeAPIKey(ctx context.Context, apiKeyId string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "api_keys", "RevokeAPIKey")
+	defer done()
+
+	query := `
+		UPDATE api_keys
+		SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING key_hash`
+
+	var hash string
+	err := ar.dbHandler.QueryRowContext(ctx, query, apiKeyId).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", &models.ResponseError{
+			Message: "API key not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return hash, nil
+}
+
+func (ar APIKeysRepository) GetAllAPIKeys(ctx context.Context) ([]*models.APIKey, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "api_keys", "GetAllAPIKeys")
+	defer done()
+
+	query := `
+		SELECT id, name, prefix, user_role, club_id, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		ORDER BY created_at`
+
+	return ar.queryAPIKeys(ctx, query)
+}
+
+// GetAPIKeyByHash devuelve la clave con el hash, o nil si no existe o está revocada. Las claves caducadas se devuelven, y es el servicio quien las rechaza
+func (ar APIKeysRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "api_keys", "GetAPIKeyByHash")
+	defer done()
+
+	query := `
+		SELECT id, name, prefix, user_role, club_id, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	apiKeys, responseErr := ar.queryAPIKeys(ctx, query, hash)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if len(apiKeys) == 0 {
+		return nil, nil
+	}
+
+	return apiKeys[0], nil
+}
+
+// TouchAPIKey guarda cuándo se ha usado la clave por última vez
+func (ar APIKeysRepository) TouchAPIKey(ctx context.Context, apiKeyId string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "api_keys", "TouchAPIKey")
+	defer done()
+
+	query := `UPDATE api_keys SET last_used_at = now() WHERE id = $1`
+
+	_, err := ar.dbHandler.ExecContext(ctx, query, apiKeyId)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (ar APIKeysRepository) queryAPIKeys(ctx context.Context, query string, args ...interface{}) ([]*models.APIKey, *models.ResponseError) {
+	rows, err := ar.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	apiKeys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		apiKey := &models.APIKey{}
+		var clubId sql.NullString
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+		err := rows.Scan(&apiKey.ID, &apiKey.Name, &apiKey.Prefix, &apiKey.Role, &clubId, pq.Array(&apiKey.Scopes), &expiresAt, &lastUsedAt, &revokedAt, &apiKey.CreatedAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		apiKey.ClubID = clubId.String
+		apiKey.ExpiresAt = nullTime(expiresAt)
+		apiKey.LastUsedAt = nullTime(lastUsedAt)
+		apiKey.RevokedAt = nullTime(revokedAt)
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return apiKeys, nil
+}
+
+// nullTime convierte una fecha que puede ser NULL en un puntero
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
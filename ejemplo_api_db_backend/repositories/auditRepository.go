@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runners-postgresql/models"
+)
+
+type AuditRepository struct {
+	dbHandler *sql.DB
+}
+
+func NewAuditRepository(dbHandler *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		dbHandler: dbHandler,
+	}
+}
+
+func (ar AuditRepository) InsertEntry(ctx context.Context, entry *models.AuditEntry) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "audit_log", "InsertEntry")
+	defer done()
+
+	query := `
+		INSERT INTO audit_log(username, action, resource_id, details)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err := ar.dbHandler.ExecContext(ctx, query, entry.Username, entry.Action, entry.ResourceID, entry.Details)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// GetEntries devuelve las entradas más recientes, primero la última. Si se indica el usuario, solo las suyas
+func (ar AuditRepository) GetEntries(ctx context.Context, username string, limit int, offset int) ([]*models.AuditEntry, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "audit_log", "GetEntries")
+	defer done()
+
+	query := `
+		SELECT id, username, action, resource_id, details, created_at
+		FROM audit_log
+		WHERE $1 = '' OR username = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := ar.dbHandler.QueryContext(ctx, query, username, limit, offset)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	entries := make([]*models.AuditEntry, 0)
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		err := rows.Scan(&entry.ID, &entry.Username, &entry.Action, &entry.ResourceID, &entry.Details, &entry.CreatedAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,470 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"runners-postgresql/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// códigos de error de Postgres que traducimos a errores de la api
+const (
+	pqForeignKeyViolation = "23503"
+	pqUniqueViolation     = "23505"
+)
+
+type ClubsRepository struct {
+	dbHandler *sql.DB
+}
+
+func NewClubsRepository(dbHandler *sql.DB) *ClubsRepository {
+	return &ClubsRepository{
+		dbHandler: dbHandler,
+	}
+}
+
+func (cr ClubsRepository) CreateClub(ctx context.Context, club *models.Club) (*models.Club, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "CreateClub")
+	defer done()
+
+	query := `
+		INSERT INTO clubs(name, country, city)
+		VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id, created_at`
+
+	response := *club
+	err := cr.dbHandler.QueryRowContext(ctx, query, club.Name, club.Country, club.City).Scan(&response.ID, &response.CreatedAt)
+	if err != nil {
+		if pqErrorCode(err) == pqUniqueViolation {
+			return nil, &models.ResponseError{
+				Message: "Club name already exists",
+				Status:  http.StatusConflict,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &response, nil
+}
+
+func (cr ClubsRepository) UpdateClub(ctx context.Context, club *models.Club) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "clubs", "UpdateClub")
+	defer done()
+
+	query := `
+		UPDATE clubs
+		SET
+			name = $1,
+			country = $2,
+			city = NULLIF($3, '')
+		WHERE id = $4`
+
+	res, err := cr.dbHandler.ExecContext(ctx, query, club.Name, club.Country, club.City, club.ID)
+	if err != nil {
+		if pqErrorCode(err) == pqUniqueViolation {
+			return &models.ResponseError{
+				Message: "Club name already exists",
+				Status:  http.StatusConflict,
+			}
+		}
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Club not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+func (cr ClubsRepository) GetClub(ctx context.Context, clubId string) (*models.Club, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "GetClub")
+	defer done()
+
+	query := `
+		SELECT id, name, country, city, created_at
+		FROM clubs
+		WHERE id = $1`
+
+	club := &models.Club{}
+	var city sql.NullString
+	err := cr.dbHandler.QueryRowContext(ctx, query, clubId).Scan(&club.ID, &club.Name, &club.Country, &city, &club.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, &models.ResponseError{
+			Message: "Club not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	club.City = city.String
+
+	return club, nil
+}
+
+func (cr ClubsRepository) GetAllClubs(ctx context.Context) ([]*models.Club, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "GetAllClubs")
+	defer done()
+
+	query := `
+		SELECT id, name, country, city, created_at
+		FROM clubs
+		ORDER BY name`
+
+	rows, err := cr.dbHandler.QueryContext(ctx, query)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	clubs := make([]*models.Club, 0)
+	for rows.Next() {
+		club := &models.Club{}
+		var city sql.NullString
+		err := rows.Scan(&club.ID, &club.Name, &club.Country, &city, &club.CreatedAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		club.City = city.String
+		clubs = append(clubs, club)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return clubs, nil
+}
+
+// AddMember da de alta al runner en el club. Si transfer es true antes se cierra, en la misma transacción, la pertenencia activa del runner a otro club. Si no, un runner que ya pertenece a un club no se puede dar de alta
+func (cr ClubsRepository) AddMember(ctx context.Context, clubId string, runnerId string, joinedAt time.Time, transfer bool) (*models.ClubMembership, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "AddMember")
+	defer done()
+
+	transaction, err := cr.dbHandler.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer transaction.Rollback()
+
+	if transfer {
+		query := `
+			UPDATE club_memberships
+			SET left_at = $2
+			WHERE runner_id = $1 AND left_at IS NULL AND club_id <> $3`
+
+		_, err = transaction.ExecContext(ctx, query, runnerId, joinedAt, clubId)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	query := `
+		INSERT INTO club_memberships(club_id, runner_id, joined_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, joined_at`
+
+	membership := &models.ClubMembership{
+		ClubID:   clubId,
+		RunnerID: runnerId,
+	}
+	err = transaction.QueryRowContext(ctx, query, clubId, runnerId, joinedAt).Scan(&membership.ID, &membership.JoinedAt)
+	if err != nil {
+		switch pqErrorCode(err) {
+		case pqUniqueViolation:
+			return nil, &models.ResponseError{
+				Message: "Runner is already a member of a club",
+				Status:  http.StatusConflict,
+			}
+		case pqForeignKeyViolation:
+			return nil, &models.ResponseError{
+				Message: "Club or runner not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return membership, nil
+}
+
+// EndMembership cierra la pertenencia activa del runner al club. La pertenencia se conserva en el historial
+func (cr ClubsRepository) EndMembership(ctx context.Context, clubId string, runnerId string, leftAt time.Time) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "clubs", "EndMembership")
+	defer done()
+
+	query := `
+		UPDATE club_memberships
+		SET left_at = $3
+		WHERE club_id = $1 AND runner_id = $2 AND left_at IS NULL`
+
+	res, err := cr.dbHandler.ExecContext(ctx, query, clubId, runnerId, leftAt)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Runner is not a member of the club",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+// IsActiveMember indica si el runner pertenece actualmente al club
+func (cr ClubsRepository) IsActiveMember(ctx context.Context, clubId string, runnerId string) (bool, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "IsActiveMember")
+	defer done()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM club_memberships
+			WHERE club_id = $1 AND runner_id = $2 AND left_at IS NULL)`
+
+	var member bool
+	err := cr.dbHandler.QueryRowContext(ctx, query, clubId, runnerId).Scan(&member)
+	if err != nil {
+		return false, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return member, nil
+}
+
+// WasMember indica si el runner ha pertenecido alguna vez al club, aunque ya no sea miembro
+func (cr ClubsRepository) WasMember(ctx context.Context, clubId string, runnerId string) (bool, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "WasMember")
+	defer done()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM club_memberships
+			WHERE club_id = $1 AND runner_id = $2)`
+
+	var member bool
+	err := cr.dbHandler.QueryRowContext(ctx, query, clubId, runnerId).Scan(&member)
+	if err != nil {
+		return false, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return member, nil
+}
+
+// GetClubMembers devuelve los miembros activos del club o, si history es true, también las pertenencias terminadas
+func (cr ClubsRepository) GetClubMembers(ctx context.Context, clubId string, history bool) ([]*models.ClubMembership, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "GetClubMembers")
+	defer done()
+
+	query := `
+		SELECT members.id, members.club_id, clubs.name, members.runner_id, runners.first_name, runners.last_name, members.joined_at, members.left_at
+		FROM club_memberships members
+		INNER JOIN clubs ON clubs.id = members.club_id
+		INNER JOIN runners ON runners.id = members.runner_id
+		WHERE members.club_id = $1 AND ($2 OR members.left_at IS NULL)
+		ORDER BY members.joined_at DESC, runners.last_name, runners.first_name`
+
+	return cr.queryMemberships(ctx, query, clubId, history)
+}
+
+// GetRunnerMemberships devuelve el historial de clubs del runner, empezando por el más reciente
+func (cr ClubsRepository) GetRunnerMemberships(ctx context.Context, runnerId string) ([]*models.ClubMembership, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "GetRunnerMemberships")
+	defer done()
+
+	query := `
+		SELECT members.id, members.club_id, clubs.name, members.runner_id, runners.first_name, runners.last_name, members.joined_at, members.left_at
+		FROM club_memberships members
+		INNER JOIN clubs ON clubs.id = members.club_id
+		INNER JOIN runners ON runners.id = members.runner_id
+		WHERE members.runner_id = $1
+		ORDER BY members.joined_at DESC`
+
+	return cr.queryMemberships(ctx, query, runnerId)
+}
+
+func (cr ClubsRepository) queryMemberships(ctx context.Context, query string, args ...interface{}) ([]*models.ClubMembership, *models.ResponseError) {
+	rows, err := cr.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	memberships := make([]*models.ClubMembership, 0)
+	for rows.Next() {
+		membership := &models.ClubMembership{}
+		var leftAt sql.NullTime
+		err := rows.Scan(&membership.ID, &membership.ClubID, &membership.ClubName, &membership.RunnerID,
+			&membership.FirstName, &membership.LastName, &membership.JoinedAt, &leftAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		if leftAt.Valid {
+			membership.LeftAt = &leftAt.Time
+		}
+		memberships = append(memberships, membership)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return memberships, nil
+}
+
+// GetLeaderboard clasifica a los miembros del club por su mejor resultado. Sin año se usan los miembros actuales y todos sus resultados. Con año, los runners que fueron miembros en algún momento de ese año y sus resultados de ese año
+func (cr ClubsRepository) GetLeaderboard(ctx context.Context, clubId string, year int, limit int) ([]*models.LeaderboardEntry, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "clubs", "GetLeaderboard")
+	defer done()
+
+	query := `
+		SELECT runners.id, runners.first_name, runners.last_name, runners.country, best.race_result, best.location, best.year
+		FROM runners
+		INNER JOIN LATERAL (
+			SELECT race_result, location, year
+			FROM results
+			WHERE results.runner_id = runners.id AND ($2 = 0 OR results.year = $2)
+			ORDER BY race_result
+			LIMIT 1) best ON true
+		WHERE runners.is_active = 'true'
+			AND runners.id IN (
+				SELECT runner_id
+				FROM club_memberships
+				WHERE club_id = $1
+					AND CASE WHEN $2 = 0
+						THEN left_at IS NULL
+						ELSE joined_at < make_date($2 + 1, 1, 1) AND (left_at IS NULL OR left_at >= make_date($2, 1, 1))
+					END)
+		ORDER BY best.race_result, runners.last_name, runners.first_name
+		LIMIT $3`
+
+	rows, err := cr.dbHandler.QueryContext(ctx, query, clubId, year, limit)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	leaderboard := make([]*models.LeaderboardEntry, 0)
+	for rows.Next() {
+		entry := &models.LeaderboardEntry{
+			Position: len(leaderboard) + 1,
+		}
+		err := rows.Scan(&entry.RunnerID, &entry.FirstName, &entry.LastName, &entry.Country, &entry.RaceResult, &entry.Location, &entry.Year)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		leaderboard = append(leaderboard, entry)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return leaderboard, nil
+}
+
+// pqErrorCode devuelve el código SQLSTATE si el error es de Postgres
+func pqErrorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+
+	return ""
+}
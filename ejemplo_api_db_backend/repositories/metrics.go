@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"runners-postgresql/metrics"
+	"runners-postgresql/tracing"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// observeQuery mide la duración de una operación del repositorio y crea su span, del que cuelgan los de las consultas. Se usa al principio del método:
+//
+//	ctx, done := observeQuery(ctx, "runners", "GetRunner")
+//	defer done()
+func observeQuery(ctx context.Context, repository string, operation string) (context.Context, func()) {
+	timer := prometheus.NewTimer(metrics.DBQueryDuration.WithLabelValues(repository, operation))
+	ctx, span := tracing.Start(ctx, strings.ToUpper(repository[:1])+repository[1:]+"Repository."+operation)
+
+	return ctx, func() {
+		span.End()
+		timer.ObserveDuration()
+	}
+}
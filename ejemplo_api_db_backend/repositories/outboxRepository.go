@@ -0,0 +1,299 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"runners-postgresql/models"
+	"time"
+)
+
+type OutboxRepository struct {
+	dbHandler *sql.DB
+}
+
+func NewOutboxRepository(dbHandler *sql.DB) *OutboxRepository {
+	return &OutboxRepository{
+		dbHandler: dbHandler,
+	}
+}
+
+// InsertEvent guarda un evento de dominio en el outbox, dentro de la transacción del cambio que lo provoca
+func (obr OutboxRepository) InsertEvent(ctx context.Context, transaction *sql.Tx, eventType string, aggregateId string, payload interface{}) (*models.Event, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "outbox", "InsertEvent")
+	defer done()
+
+	query := `
+		INSERT INTO outbox_events(event_type, aggregate_id, payload)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	event := &models.Event{
+		Type:        eventType,
+		AggregateID: aggregateId,
+		Payload:     body,
+	}
+
+	err = transaction.QueryRowContext(ctx, query, eventType, aggregateId, string(body)).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return event, nil
+}
+
+// FanOutPendingEvents crea una entrega por cada suscripción interesada en los eventos pendientes, y marca los eventos como procesados. Devuelve el número de eventos procesados
+func (obr OutboxRepository) FanOutPendingEvents(ctx context.Context, limit int) (int64, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "outbox", "FanOutPendingEvents")
+	defer done()
+
+	// con FOR UPDATE SKIP LOCKED varias réplicas pueden procesar el outbox a la vez sin repartir dos veces el mismo evento
+	query := `
+		WITH pending AS (
+			SELECT id, event_type
+			FROM outbox_events
+			WHERE processed_at IS NULL
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), fanout AS (
+			INSERT INTO webhook_deliveries(event_id, subscription_id)
+			SELECT pending.id, subscriptions.id
+			FROM pending
+			INNER JOIN webhook_subscriptions subscriptions
+			ON subscriptions.is_active
+				AND (cardinality(subscriptions.event_types) = 0 OR pending.event_type = ANY(subscriptions.event_types))
+		)
+		UPDATE outbox_events
+		SET processed_at = now()
+		WHERE id IN (SELECT id FROM pending)`
+
+	res, err := obr.dbHandler.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return rowsAffected, nil
+}
+
+// ClaimDueDeliveries reserva las entregas pendientes cuyo siguiente intento ya ha vencido. La reserva incrementa el número de intentos y aplaza el siguiente intento el tiempo indicado en lease, de modo que si la réplica cae la entrega se reintentará más tarde
+func (obr OutboxRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "outbox", "ClaimDueDeliveries")
+	defer done()
+
+	query := `
+		UPDATE webhook_deliveries deliveries
+		SET attempts = deliveries.attempts + 1,
+			next_attempt_at = now() + make_interval(secs => $2)
+		FROM outbox_events events, webhook_subscriptions subscriptions
+		WHERE deliveries.id IN (
+				SELECT id
+				FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= now()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED)
+			AND events.id = deliveries.event_id
+			AND subscriptions.id = deliveries.subscription_id
+		RETURNING deliveries.id, deliveries.event_id, deliveries.subscription_id, deliveries.attempts,
+			events.event_type, events.aggregate_id, events.payload, events.created_at,
+			subscriptions.url, subscriptions.secret`
+
+	rows, err := obr.dbHandler.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{
+			Status: models.DeliveryPending,
+			Event:  &models.Event{},
+		}
+		var payload []byte
+		err := rows.Scan(&delivery.ID, &delivery.EventID, &delivery.SubscriptionID, &delivery.Attempts,
+			&delivery.Event.Type, &delivery.Event.AggregateID, &payload, &delivery.Event.CreatedAt,
+			&delivery.URL, &delivery.Secret)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		delivery.Event.ID = delivery.EventID
+		delivery.Event.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (obr OutboxRepository) MarkDelivered(ctx context.Context, deliveryId string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "outbox", "MarkDelivered")
+	defer done()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', delivered_at = now(), last_error = NULL
+		WHERE id = $1`
+
+	_, err := obr.dbHandler.ExecContext(ctx, query, deliveryId)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// MarkFailed registra un intento fallido. Si dead es true la entrega pasa al dead letter y no se vuelve a intentar
+func (obr OutboxRepository) MarkFailed(ctx context.Context, deliveryId string, lastError string, nextAttemptAt time.Time, dead bool) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "outbox", "MarkFailed")
+	defer done()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, last_error = $3, next_attempt_at = $4
+		WHERE id = $1`
+
+	status := models.DeliveryPending
+	if dead {
+		status = models.DeliveryDead
+	}
+
+	_, err := obr.dbHandler.ExecContext(ctx, query, deliveryId, status, lastError, nextAttemptAt)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (obr OutboxRepository) GetDeliveries(ctx context.Context, subscriptionId string, status string) ([]*models.WebhookDelivery, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "outbox", "GetDeliveries")
+	defer done()
+
+	query := `
+		SELECT id, event_id, subscription_id, status, attempts, next_attempt_at, last_error, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY next_attempt_at DESC
+		LIMIT 100`
+
+	rows, err := obr.dbHandler.QueryContext(ctx, query, subscriptionId, status)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		var lastError sql.NullString
+		var deliveredAt sql.NullTime
+		err := rows.Scan(&delivery.ID, &delivery.EventID, &delivery.SubscriptionID, &delivery.Status,
+			&delivery.Attempts, &delivery.NextAttemptAt, &lastError, &deliveredAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		delivery.LastError = lastError.String
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery saca una entrega del dead letter para que el dispatcher la vuelva a intentar
+func (obr OutboxRepository) RetryDelivery(ctx context.Context, subscriptionId string, deliveryId string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "outbox", "RetryDelivery")
+	defer done()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND subscription_id = $2 AND status = 'dead'`
+
+	res, err := obr.dbHandler.ExecContext(ctx, query, deliveryId, subscriptionId)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Dead delivery not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
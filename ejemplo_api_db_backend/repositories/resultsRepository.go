@@ -1,14 +1,18 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
 	"runners-postgresql/models"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 type ResultsRepository struct {
-	dbHandler   *sql.DB
-	transaction *sql.Tx // Se usa para las operaciones de actualización que requieren ejecutarse dentro de una transacción
+	dbHandler *sql.DB
 }
 
 func NewResultsRepository(dbHAndler *sql.DB) *ResultsRepository {
@@ -17,14 +21,17 @@ func NewResultsRepository(dbHAndler *sql.DB) *ResultsRepository {
 	}
 }
 
-func (rr ResultsRepository) CreateResult(result *models.Result) (*models.Result, *models.ResponseError) {
+func (rr ResultsRepository) CreateResult(ctx context.Context, transaction *sql.Tx, result *models.Result) (*models.Result, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "CreateResult")
+	defer done()
+
 	query := `
-		INSERT INTO results(runner_id, race_result, location, position, year)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO results(runner_id, race_result, location, position, year, distance)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id`
 
 	// ejecutamos la query dentro de una transaccion (estamos cambiando datos)
-	rows, err := rr.transaction.Query(query, result.RunnerID, result.RaceResult, result.Location, result.Position, result.Year)
+	rows, err := transaction.QueryContext(ctx, query, result.RunnerID, result.RaceResult, result.Location, result.Position, result.Year, result.Distance)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -56,6 +63,14 @@ func (rr ResultsRepository) CreateResult(result *models.Result) (*models.Result,
 		}
 	}
 
+	// los tiempos de paso se guardan en la misma transacción que el resultado
+	if len(result.Splits) > 0 {
+		responseErr := rr.createSplits(ctx, transaction, resultId, result.Splits)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+	}
+
 	return &models.Result{
 		ID:         resultId,
 		RunnerID:   result.RunnerID,
@@ -63,16 +78,46 @@ func (rr ResultsRepository) CreateResult(result *models.Result) (*models.Result,
 		Location:   result.Location,
 		Position:   result.Position,
 		Year:       result.Year,
+		Distance:   result.Distance,
+		Splits:     result.Splits,
 	}, nil
 }
 
-func (rr ResultsRepository) DeleteResult(resultId string) (*models.Result, *models.ResponseError) {
+// createSplits guarda los tiempos de paso del resultado con una sola sentencia
+func (rr ResultsRepository) createSplits(ctx context.Context, transaction *sql.Tx, resultId string, splits []*models.Split) *models.ResponseError {
+	distances := make([]float64, len(splits))
+	times := make([]string, len(splits))
+	for i, split := range splits {
+		distances[i] = split.Distance
+		times[i] = split.Time
+	}
+
+	// unnest recorre los dos arrays a la vez, y devuelve una fila por tiempo de paso
+	query := `
+		INSERT INTO result_splits(result_id, distance, elapsed)
+		SELECT $1, unnest($2::numeric[]), unnest($3::interval[])`
+
+	_, err := transaction.ExecContext(ctx, query, resultId, pq.Array(distances), pq.Array(times))
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (rr ResultsRepository) DeleteResult(ctx context.Context, transaction *sql.Tx, resultId string) (*models.Result, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "DeleteResult")
+	defer done()
+
 	query := `
 		DELETE FROM results
 		WHERE id = $1
-		RETURNING runner_id, race_result, year`
+		RETURNING runner_id, race_result, year, distance`
 
-	rows, err := rr.transaction.Query(query, resultId)
+	rows, err := transaction.QueryContext(ctx, query, resultId)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -84,8 +129,9 @@ func (rr ResultsRepository) DeleteResult(resultId string) (*models.Result, *mode
 
 	var runnerId, raceResult string
 	var year int
+	var distance float64
 	for rows.Next() {
-		err := rows.Scan(&runnerId, &raceResult, &year)
+		err := rows.Scan(&runnerId, &raceResult, &year, &distance)
 		if err != nil {
 			return nil, &models.ResponseError{
 				Message: err.Error(),
@@ -106,17 +152,49 @@ func (rr ResultsRepository) DeleteResult(resultId string) (*models.Result, *mode
 		RunnerID:   runnerId,
 		RaceResult: raceResult,
 		Year:       year,
+		Distance:   distance,
 	}, nil
 }
 
-func (rr ResultsRepository) GetAllRunnersResults(runnerId string) ([]*models.Result, *models.ResponseError) {
+// GetResultRunnerId devuelve el runner del resultado, para comprobar los permisos antes de modificarlo
+func (rr ResultsRepository) GetResultRunnerId(ctx context.Context, resultId string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetResultRunnerId")
+	defer done()
+
 	query := `
-	SELECT id, race_result, location, position, year
+		SELECT runner_id
+		FROM results
+		WHERE id = $1`
+
+	var runnerId string
+	err := rr.dbHandler.QueryRowContext(ctx, query, resultId).Scan(&runnerId)
+	if err == sql.ErrNoRows {
+		return "", &models.ResponseError{
+			Message: "Result not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return runnerId, nil
+}
+
+func (rr ResultsRepository) GetAllRunnersResults(ctx context.Context, runnerId string) ([]*models.Result, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetAllRunnersResults")
+	defer done()
+
+	query := `
+	SELECT id, race_result, location, position, year, distance
 	FROM results
 	WHERE runner_id = $1`
 
 	// ejecutamos la query (consulta)
-	rows, err := rr.dbHandler.Query(query, runnerId)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, runnerId)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -130,11 +208,12 @@ func (rr ResultsRepository) GetAllRunnersResults(runnerId string) ([]*models.Res
 	results := make([]*models.Result, 0)
 	var id, raceResult, location string
 	var position, year int
+	var distance float64
 
 	// iteramos sobre el cursor
 	for rows.Next() {
 		// capturamos los datos recuperados con el cursor
-		err := rows.Scan(&id, &raceResult, &location, &position, &year)
+		err := rows.Scan(&id, &raceResult, &location, &position, &year, &distance)
 		if err != nil {
 			return nil, &models.ResponseError{
 				Message: err.Error(),
@@ -149,6 +228,7 @@ func (rr ResultsRepository) GetAllRunnersResults(runnerId string) ([]*models.Res
 			Location:   location,
 			Position:   position,
 			Year:       year,
+			Distance:   distance,
 		}
 
 		results = append(results, result)
@@ -164,13 +244,216 @@ func (rr ResultsRepository) GetAllRunnersResults(runnerId string) ([]*models.Res
 	return results, nil
 }
 
-func (rr ResultsRepository) GetPersonalBestResults(runnerId string) (string, *models.ResponseError) {
+// GetRunnerSplits devuelve los tiempos de paso de los resultados del runner, agrupados por resultado y ordenados por distancia
+func (rr ResultsRepository) GetRunnerSplits(ctx context.Context, runnerId string) (map[string][]*models.Split, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetRunnerSplits")
+	defer done()
+
+	query := `
+	SELECT splits.result_id, splits.distance, splits.elapsed
+	FROM result_splits splits
+	JOIN results ON results.id = splits.result_id
+	WHERE results.runner_id = $1
+	ORDER BY splits.result_id, splits.distance`
+
+	rows, err := rr.dbHandler.QueryContext(ctx, query, runnerId)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	splits := make(map[string][]*models.Split)
+	var resultId, elapsed string
+	var distance float64
+
+	for rows.Next() {
+		err := rows.Scan(&resultId, &distance, &elapsed)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		splits[resultId] = append(splits[resultId], &models.Split{
+			Distance: distance,
+			Time:     elapsed,
+		})
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return splits, nil
+}
+
+// GetResultsAggregates devuelve los agregados de los resultados del runner por distancia y año, ordenados por distancia y por año. Las marcas se agregan en segundos en la propia consulta, de modo que no hace falta leer cada resultado
+func (rr ResultsRepository) GetResultsAggregates(ctx context.Context, runnerId string) ([]*models.ResultsAggregate, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetResultsAggregates")
+	defer done()
+
+	// los resultados sin posición se guardan con posición 0
+	query := `
+	SELECT distance, year, count(*),
+		min(EXTRACT(EPOCH FROM race_result)),
+		sum(EXTRACT(EPOCH FROM race_result)),
+		sum(EXTRACT(EPOCH FROM race_result) ^ 2),
+		min(NULLIF(position, 0)),
+		max(NULLIF(position, 0))
+	FROM results
+	WHERE runner_id = $1
+	GROUP BY distance, year
+	ORDER BY distance, year`
+
+	rows, err := rr.dbHandler.QueryContext(ctx, query, runnerId)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	aggregates := make([]*models.ResultsAggregate, 0)
+	var bestPosition, worstPosition sql.NullInt64
+
+	for rows.Next() {
+		aggregate := &models.ResultsAggregate{}
+		err := rows.Scan(&aggregate.Distance, &aggregate.Year, &aggregate.Races, &aggregate.Best, &aggregate.Sum, &aggregate.SumOfSquares, &bestPosition, &worstPosition)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		aggregate.BestPosition = int(bestPosition.Int64)
+		aggregate.WorstPosition = int(worstPosition.Int64)
+		aggregates = append(aggregates, aggregate)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return aggregates, nil
+}
+
+// splitsColumns lee los tiempos de paso de cada resultado en la misma consulta, como dos arrays ordenados por distancia, para no hacer una consulta más por resultado
+const splitsColumns = `
+	ARRAY(SELECT splits.distance FROM result_splits splits WHERE splits.result_id = results.id ORDER BY splits.distance),
+	ARRAY(SELECT splits.elapsed::text FROM result_splits splits WHERE splits.result_id = results.id ORDER BY splits.distance)`
+
+// newSplits junta los arrays de splitsColumns. Los resultados sin tiempos de paso no tienen splits
+func newSplits(distances pq.Float64Array, times pq.StringArray) []*models.Split {
+	if len(distances) == 0 {
+		return nil
+	}
+
+	splits := make([]*models.Split, len(distances))
+	for i := range distances {
+		splits[i] = &models.Split{
+			Distance: distances[i],
+			Time:     times[i],
+		}
+	}
+
+	return splits
+}
+
+// GetResultsByRunners devuelve en una sola consulta los resultados de varios runners, agrupados por runner. Con public se excluyen los resultados de los runners que los ocultan, salvo los de ownRunnerId (el runner que hace la consulta)
+func (rr ResultsRepository) GetResultsByRunners(ctx context.Context, runnerIds []string, public bool, ownRunnerId string) (map[string][]*models.Result, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetResultsByRunners")
+	defer done()
+
+	query := `
+	SELECT id, runner_id, race_result, location, position, year, distance,` + splitsColumns + `
+	FROM results
+	WHERE runner_id = ANY($1::uuid[])`
+	// pq.Array convierte el slice en un array de Postgres
+	args := []interface{}{pq.Array(runnerIds)}
+
+	if public {
+		query += `
+		AND (runner_id = NULLIF($2, '')::uuid OR runner_id NOT IN (SELECT runner_id FROM runner_privacy WHERE hide_results))`
+		args = append(args, ownRunnerId)
+	}
+
+	query += `
+	ORDER BY year DESC, id`
+
+	rows, err := rr.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	results := make(map[string][]*models.Result, len(runnerIds))
+	var id, runnerId, raceResult, location string
+	var position sql.NullInt64
+	var year int
+	var distance float64
+	var splitDistances pq.Float64Array
+	var splitTimes pq.StringArray
+
+	for rows.Next() {
+		err := rows.Scan(&id, &runnerId, &raceResult, &location, &position, &year, &distance, &splitDistances, &splitTimes)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		results[runnerId] = append(results[runnerId], &models.Result{
+			ID:         id,
+			RunnerID:   runnerId,
+			RaceResult: raceResult,
+			Location:   location,
+			Position:   int(position.Int64),
+			Year:       year,
+			Distance:   distance,
+			Splits:     newSplits(splitDistances, splitTimes),
+		})
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return results, nil
+}
+
+// GetPersonalBestResults devuelve la mejor marca del runner en maratón, o una cadena vacía si no tiene ninguna
+func (rr ResultsRepository) GetPersonalBestResults(ctx context.Context, runnerId string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetPersonalBestResults")
+	defer done()
+
 	query := `
 	SELECT MIN(race_result)
 	FROM results
-	WHERE runner_id = $1`
+	WHERE runner_id = $1 AND distance = $2`
 
-	rows, err := rr.dbHandler.Query(query, runnerId)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, runnerId, models.MarathonDistance)
 	if err != nil {
 		return "", &models.ResponseError{
 			Message: err.Error(),
@@ -180,7 +463,7 @@ func (rr ResultsRepository) GetPersonalBestResults(runnerId string) (string, *mo
 
 	defer rows.Close()
 
-	var raceResult string
+	var raceResult sql.NullString
 
 	for rows.Next() {
 		err := rows.Scan(&raceResult)
@@ -199,16 +482,20 @@ func (rr ResultsRepository) GetPersonalBestResults(runnerId string) (string, *mo
 		}
 	}
 
-	return raceResult, nil
+	return raceResult.String, nil
 }
 
-func (rr ResultsRepository) GetSeasonBestResults(runnerId string, year int) (string, *models.ResponseError) {
+// GetSeasonBestResults devuelve la mejor marca del runner en maratón en el año, o una cadena vacía si no tiene ninguna
+func (rr ResultsRepository) GetSeasonBestResults(ctx context.Context, runnerId string, year int) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetSeasonBestResults")
+	defer done()
+
 	query := `
 	SELECT MIN(race_result)
 	FROM results
-	WHERE runner_id = $1 AND year = $2`
+	WHERE runner_id = $1 AND year = $2 AND distance = $3`
 
-	rows, err := rr.dbHandler.Query(query, runnerId, year)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, runnerId, year, models.MarathonDistance)
 	if err != nil {
 		return "", &models.ResponseError{
 			Message: err.Error(),
@@ -218,7 +505,7 @@ func (rr ResultsRepository) GetSeasonBestResults(runnerId string, year int) (str
 
 	defer rows.Close()
 
-	var raceResult string
+	var raceResult sql.NullString
 
 	for rows.Next() {
 		err := rows.Scan(&raceResult)
@@ -237,5 +524,256 @@ func (rr ResultsRepository) GetSeasonBestResults(runnerId string, year int) (str
 		}
 	}
 
-	return raceResult, nil
+	return raceResult.String, nil
+}
+
+// StreamResults recorre el cursor de la base de datos y entrega los resultados de uno en uno a la función fn, sin materializar la tabla en memoria. Si fn devuelve un error se detiene la iteración
+func (rr ResultsRepository) StreamResults(ctx context.Context, filter models.ResultsFilter, fn func(*models.Result) error) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "results", "StreamResults")
+	defer done()
+
+	query := `
+	SELECT id, runner_id, race_result, location, position, year, distance,` + splitsColumns + `
+	FROM results`
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if filter.RunnerID != "" {
+		args = append(args, filter.RunnerID)
+		conditions = append(conditions, fmt.Sprintf("runner_id = $%d", len(args)))
+	}
+
+	if filter.Year != 0 {
+		args = append(args, filter.Year)
+		conditions = append(conditions, fmt.Sprintf("year = $%d", len(args)))
+	}
+
+	if filter.Public {
+		// como en GetResultsByRunners, el runner que exporta sí ve sus propios resultados
+		args = append(args, filter.OwnRunnerID)
+		conditions = append(conditions, fmt.Sprintf("(runner_id = NULLIF($%d, '')::uuid OR runner_id NOT IN (SELECT runner_id FROM runner_privacy WHERE hide_results))", len(args)))
+	}
+
+	if len(conditions) > 0 {
+		query += `
+	WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	query += `
+	ORDER BY id`
+
+	// usamos el contexto de la petición para que si el cliente se desconecta se cancele la consulta
+	rows, err := rr.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	var id, runnerId, raceResult, location string
+	var position sql.NullInt64
+	var year int
+	var distance float64
+	var splitDistances pq.Float64Array
+	var splitTimes pq.StringArray
+
+	for rows.Next() {
+		err := rows.Scan(&id, &runnerId, &raceResult, &location, &position, &year, &distance, &splitDistances, &splitTimes)
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		err = fn(&models.Result{
+			ID:         id,
+			RunnerID:   runnerId,
+			RaceResult: raceResult,
+			Location:   location,
+			Position:   int(position.Int64),
+			Year:       year,
+			Distance:   distance,
+			Splits:     newSplits(splitDistances, splitTimes),
+		})
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	if rows.Err() != nil {
+		return &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// CreateSubmission guarda un resultado enviado por un runner, pendiente de aprobación
+func (rr ResultsRepository) CreateSubmission(ctx context.Context, submission *models.ResultSubmission) (*models.ResultSubmission, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "CreateSubmission")
+	defer done()
+
+	query := `
+		INSERT INTO result_submissions(runner_id, race_result, location, position, year, distance, submitted_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, status, submitted_at`
+
+	response := *submission
+	err := rr.dbHandler.QueryRowContext(ctx, query, submission.RunnerID, submission.RaceResult, submission.Location, submission.Position,
+		submission.Year, submission.Distance, submission.SubmittedBy).Scan(&response.ID, &response.Status, &response.SubmittedAt)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &response, nil
+}
+
+func (rr ResultsRepository) GetSubmission(ctx context.Context, submissionId string) (*models.ResultSubmission, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetSubmission")
+	defer done()
+
+	submissions, responseErr := rr.querySubmissions(ctx, `
+		SELECT id, runner_id, race_result, location, position, year, distance, status, submitted_by, submitted_at, reviewed_by, reviewed_at, reject_reason, result_id
+		FROM result_submissions
+		WHERE id = $1`, submissionId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if len(submissions) == 0 {
+		return nil, &models.ResponseError{
+			Message: "Result submission not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return submissions[0], nil
+}
+
+// GetSubmissions devuelve los envíos en el estado indicado, empezando por los más antiguos. Con clubId solo los de los miembros actuales del club
+func (rr ResultsRepository) GetSubmissions(ctx context.Context, status string, clubId string) ([]*models.ResultSubmission, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "results", "GetSubmissions")
+	defer done()
+
+	return rr.querySubmissions(ctx, `
+		SELECT id, runner_id, race_result, location, position, year, distance, status, submitted_by, submitted_at, reviewed_by, reviewed_at, reject_reason, result_id
+		FROM result_submissions
+		WHERE status = $1
+			AND ($2 = '' OR runner_id IN (
+				SELECT runner_id
+				FROM club_memberships
+				WHERE club_id::text = $2 AND left_at IS NULL))
+		ORDER BY submitted_at
+		LIMIT 500`, status, clubId)
+}
+
+// ApproveSubmission marca el envío como aprobado y lo enlaza con el resultado creado. Se ejecuta dentro de la transacción que crea el resultado, y si el envío ya no está pendiente (por ejemplo, porque otro administrador lo ha aprobado a la vez) devuelve un conflicto. Si lo revisa un servicio autenticado por certificado, que no es un usuario, reviewed_by queda vacío
+func (rr ResultsRepository) ApproveSubmission(ctx context.Context, transaction *sql.Tx, submissionId string, reviewerId string, resultId string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "results", "ApproveSubmission")
+	defer done()
+
+	query := `
+		UPDATE result_submissions
+		SET status = 'approved', reviewed_by = NULLIF($2, '')::uuid, reviewed_at = now(), result_id = $3
+		WHERE id = $1 AND status = 'pending'`
+
+	return rr.reviewSubmission(ctx, transaction.ExecContext, query, submissionId, reviewerId, resultId)
+}
+
+func (rr ResultsRepository) RejectSubmission(ctx context.Context, submissionId string, reviewerId string, reason string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "results", "RejectSubmission")
+	defer done()
+
+	query := `
+		UPDATE result_submissions
+		SET status = 'rejected', reviewed_by = NULLIF($2, '')::uuid, reviewed_at = now(), reject_reason = NULLIF($3, '')
+		WHERE id = $1 AND status = 'pending'`
+
+	return rr.reviewSubmission(ctx, rr.dbHandler.ExecContext, query, submissionId, reviewerId, reason)
+}
+
+func (rr ResultsRepository) reviewSubmission(ctx context.Context, exec func(context.Context, string, ...interface{}) (sql.Result, error), query string, args ...interface{}) *models.ResponseError {
+	res, err := exec(ctx, query, args...)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Result submission is not pending",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	return nil
+}
+
+func (rr ResultsRepository) querySubmissions(ctx context.Context, query string, args ...interface{}) ([]*models.ResultSubmission, *models.ResponseError) {
+	rows, err := rr.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	submissions := make([]*models.ResultSubmission, 0)
+	for rows.Next() {
+		submission := &models.ResultSubmission{}
+		var position sql.NullInt64
+		var reviewedBy, rejectReason, resultId sql.NullString
+		var reviewedAt sql.NullTime
+		err := rows.Scan(&submission.ID, &submission.RunnerID, &submission.RaceResult, &submission.Location, &position,
+			&submission.Year, &submission.Distance, &submission.Status, &submission.SubmittedBy, &submission.SubmittedAt,
+			&reviewedBy, &reviewedAt, &rejectReason, &resultId)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		submission.Position = int(position.Int64)
+		submission.ReviewedBy = reviewedBy.String
+		submission.RejectReason = rejectReason.String
+		submission.ResultID = resultId.String
+		if reviewedAt.Valid {
+			submission.ReviewedAt = &reviewedAt.Time
+		}
+		submissions = append(submissions, submission)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return submissions, nil
 }
@@ -1,14 +1,16 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"runners-postgresql/models"
+	"runners-postgresql/search"
+	"strconv"
 )
 
 type RunnersRepository struct {
-	dbHandler   *sql.DB
-	transaction *sql.Tx // Se usa para las operaciones de actualización que requieren ejecutarse dentro de una transacción
+	dbHandler *sql.DB
 }
 
 func NewRunnersRepository(dbHandler *sql.DB) *RunnersRepository {
@@ -17,14 +19,16 @@ func NewRunnersRepository(dbHandler *sql.DB) *RunnersRepository {
 	}
 }
 
-func (rr RunnersRepository) CreateRunner(runner *models.Runner) (*models.Runner, *models.ResponseError) {
+func (rr RunnersRepository) CreateRunner(ctx context.Context, runner *models.Runner) (*models.Runner, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "CreateRunner")
+	defer done()
 
 	query := `
 		INSERT INTO runners(first_name, last_name, age, country)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id`
 
-	rows, err := rr.dbHandler.Query(query, runner.FirstName, runner.LastName, runner.Age, runner.Country)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, runner.FirstName, runner.LastName, runner.Age, runner.Country)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -62,7 +66,85 @@ func (rr RunnersRepository) CreateRunner(runner *models.Runner) (*models.Runner,
 	}, nil
 }
 
-func (rr RunnersRepository) UpdateRunner(runner *models.Runner) *models.ResponseError {
+// CreateRunnerInClub crea el runner y lo da de alta en el club en una única sentencia, de modo que no puede quedar un runner sin club
+func (rr RunnersRepository) CreateRunnerInClub(ctx context.Context, runner *models.Runner, clubId string) (*models.Runner, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "CreateRunnerInClub")
+	defer done()
+
+	query := `
+		WITH runner AS (
+			INSERT INTO runners(first_name, last_name, age, country)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id)
+		INSERT INTO club_memberships(club_id, runner_id)
+		SELECT $5, id FROM runner
+		RETURNING runner_id`
+
+	var runnerId string
+	err := rr.dbHandler.QueryRowContext(ctx, query, runner.FirstName, runner.LastName, runner.Age, runner.Country, clubId).Scan(&runnerId)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &models.Runner{
+		ID:        runnerId,
+		FirstName: runner.FirstName,
+		LastName:  runner.LastName,
+		Age:       runner.Age,
+		IsActive:  true,
+		Country:   runner.Country,
+	}, nil
+}
+
+// CreateRunnerInTransaction crea el runner dentro de la transacción, para las altas por lotes. Si se indica el club lo da de alta en él en la misma sentencia, como CreateRunnerInClub
+func (rr RunnersRepository) CreateRunnerInTransaction(ctx context.Context, transaction *sql.Tx, runner *models.Runner, clubId string) (*models.Runner, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "CreateRunnerInTransaction")
+	defer done()
+
+	query := `
+		INSERT INTO runners(first_name, last_name, age, country)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
+	args := []interface{}{runner.FirstName, runner.LastName, runner.Age, runner.Country}
+
+	if clubId != "" {
+		query = `
+		WITH runner AS (
+			INSERT INTO runners(first_name, last_name, age, country)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id)
+		INSERT INTO club_memberships(club_id, runner_id)
+		SELECT $5, id FROM runner
+		RETURNING runner_id`
+		args = append(args, clubId)
+	}
+
+	var runnerId string
+	err := transaction.QueryRowContext(ctx, query, args...).Scan(&runnerId)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &models.Runner{
+		ID:        runnerId,
+		FirstName: runner.FirstName,
+		LastName:  runner.LastName,
+		Age:       runner.Age,
+		IsActive:  true,
+		Country:   runner.Country,
+	}, nil
+}
+
+func (rr RunnersRepository) UpdateRunner(ctx context.Context, runner *models.Runner) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "runners", "UpdateRunner")
+	defer done()
+
 	query := `
 		UPDATE runners
 		SET
@@ -73,7 +155,7 @@ func (rr RunnersRepository) UpdateRunner(runner *models.Runner) *models.Response
 		WHERE id = $5`
 
 	//ejecutamos la query
-	res, err := rr.dbHandler.Exec(query, runner.FirstName, runner.LastName, runner.Age, runner.Country, runner.ID)
+	res, err := rr.dbHandler.ExecContext(ctx, query, runner.FirstName, runner.LastName, runner.Age, runner.Country, runner.ID)
 	if err != nil {
 		return &models.ResponseError{
 			Message: err.Error(),
@@ -100,16 +182,19 @@ func (rr RunnersRepository) UpdateRunner(runner *models.Runner) *models.Response
 	return nil
 }
 
-func (rr RunnersRepository) UpdateRunnerResults(runner *models.Runner) *models.ResponseError {
+func (rr RunnersRepository) UpdateRunnerResults(ctx context.Context, transaction *sql.Tx, runner *models.Runner) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "runners", "UpdateRunnerResults")
+	defer done()
+
 	query := `
 		UPDATE runners
 		SET
-			personal_best = $1,
-			season_best = $2
+			personal_best = NULLIF($1, '')::interval,
+			season_best = NULLIF($2, '')::interval
 		WHERE id = $3`
 
 	//ejecutamos la query
-	res, err := rr.transaction.Exec(query, runner.PersonalBest, runner.SeasonBest, runner.ID)
+	res, err := transaction.ExecContext(ctx, query, runner.PersonalBest, runner.SeasonBest, runner.ID)
 	if err != nil {
 		return &models.ResponseError{
 			Message: err.Error(),
@@ -136,10 +221,14 @@ func (rr RunnersRepository) UpdateRunnerResults(runner *models.Runner) *models.R
 	return nil
 }
 
-func (rr RunnersRepository) DeleteRunner(runnerId string) *models.ResponseError {
+func (rr RunnersRepository) DeleteRunner(ctx context.Context, transaction *sql.Tx, runnerId string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "runners", "DeleteRunner")
+	defer done()
+
 	query := `UPDATE runners SET is_active = 'false' WHERE id = $1`
 
-	res, err := rr.dbHandler.Exec(query, runnerId)
+	// se ejecuta dentro de una transacción para publicar el evento runner.deleted en el outbox
+	res, err := transaction.ExecContext(ctx, query, runnerId)
 	if err != nil {
 		return &models.ResponseError{
 			Message: err.Error(),
@@ -165,13 +254,16 @@ func (rr RunnersRepository) DeleteRunner(runnerId string) *models.ResponseError
 	return nil
 }
 
-func (rr RunnersRepository) GetRunner(runnerId string) (*models.Runner, *models.ResponseError) {
+func (rr RunnersRepository) GetRunner(ctx context.Context, runnerId string) (*models.Runner, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "GetRunner")
+	defer done()
+
 	query := `
 		SELECT *
 		FROM runners
 		WHERE id = $1`
 
-	rows, err := rr.dbHandler.Query(query, runnerId)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, runnerId)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -214,12 +306,89 @@ func (rr RunnersRepository) GetRunner(runnerId string) (*models.Runner, *models.
 	}, nil
 }
 
-func (rr RunnersRepository) GetAllRunners() ([]*models.Runner, *models.ResponseError) {
+// GetRunnerPrivacy devuelve las preferencias de privacidad del runner. Si no las ha configurado se muestran todos sus datos
+func (rr RunnersRepository) GetRunnerPrivacy(ctx context.Context, runnerId string) (*models.Privacy, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "GetRunnerPrivacy")
+	defer done()
+
+	query := `
+		SELECT hide_age, hide_results
+		FROM runner_privacy
+		WHERE runner_id = $1`
+
+	privacy := &models.Privacy{}
+	err := rr.dbHandler.QueryRowContext(ctx, query, runnerId).Scan(&privacy.HideAge, &privacy.HideResults)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return privacy, nil
+}
+
+// UpdateRunnerProfile actualiza los datos del runner y sus preferencias de privacidad en una única sentencia
+func (rr RunnersRepository) UpdateRunnerProfile(ctx context.Context, runner *models.Runner) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "runners", "UpdateRunnerProfile")
+	defer done()
+
+	query := `
+		WITH privacy AS (
+			INSERT INTO runner_privacy(runner_id, hide_age, hide_results)
+			SELECT id, $6, $7 FROM runners WHERE id = $5
+			ON CONFLICT (runner_id) DO UPDATE
+			SET hide_age = EXCLUDED.hide_age, hide_results = EXCLUDED.hide_results)
+		UPDATE runners
+		SET
+			first_name = $1,
+			last_name = $2,
+			age = $3,
+			country = $4
+		WHERE id = $5`
+
+	res, err := rr.dbHandler.ExecContext(ctx, query, runner.FirstName, runner.LastName, runner.Age, runner.Country, runner.ID,
+		runner.Privacy.HideAge, runner.Privacy.HideResults)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Runner not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+// GetAllRunners devuelve todos los runners. Si public es true no se muestran la edad ni las marcas de los runners que las ocultan, salvo las del propio runner que consulta (ownRunnerId)
+func (rr RunnersRepository) GetAllRunners(ctx context.Context, public bool, ownRunnerId string) ([]*models.Runner, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "GetAllRunners")
+	defer done()
+
 	query := `
-	SELECT *
-	FROM runners`
+	SELECT runners.id, runners.first_name, runners.last_name,
+		` + privateColumn("runners.age", "hide_age", "0", "runners.id", 1) + `,
+		runners.is_active, runners.country,
+		` + privateColumn("runners.personal_best", "hide_results", "NULL", "runners.id", 1) + `,
+		` + privateColumn("runners.season_best", "hide_results", "NULL", "runners.id", 1) + `
+	FROM runners
+	LEFT JOIN runner_privacy privacy ON privacy.runner_id = runners.id`
 
-	rows, err := rr.dbHandler.Query(query)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, public, ownRunnerId)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -268,15 +437,23 @@ func (rr RunnersRepository) GetAllRunners() ([]*models.Runner, *models.ResponseE
 	return runners, nil
 }
 
-func (rr RunnersRepository) GetRunnersByCountry(country string) ([]*models.Runner, *models.ResponseError) {
+// GetRunnersByCountry devuelve los 10 mejores runners del país, con las mismas preferencias de privacidad que GetAllRunners. Se ordena por las marcas que se muestran, de modo que los runners que las ocultan van al final, ordenados por nombre, y su posición no revela su marca
+func (rr RunnersRepository) GetRunnersByCountry(ctx context.Context, country string, public bool, ownRunnerId string) ([]*models.Runner, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "GetRunnersByCountry")
+	defer done()
+
 	query := `
-	SELECT id, first_name, last_name, age, personal_best, season_best
+	SELECT runners.id, runners.first_name, runners.last_name,
+		` + privateColumn("runners.age", "hide_age", "0", "runners.id", 2) + `,
+		` + privateColumn("runners.personal_best", "hide_results", "NULL", "runners.id", 2) + ` AS personal_best,
+		` + privateColumn("runners.season_best", "hide_results", "NULL", "runners.id", 2) + `
 	FROM runners
-	WHERE country = $1 AND is_active = 'true'
-	ORDER BY personal_best
+	LEFT JOIN runner_privacy privacy ON privacy.runner_id = runners.id
+	WHERE runners.country = $1 AND runners.is_active = 'true'
+	ORDER BY personal_best, runners.last_name, runners.first_name
 	LIMIT 10`
 
-	rows, err := rr.dbHandler.Query(query, country)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, country, public, ownRunnerId)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -324,9 +501,17 @@ func (rr RunnersRepository) GetRunnersByCountry(country string) ([]*models.Runne
 	return runners, nil
 }
 
-func (rr RunnersRepository) GetRunnersByYear(year int) ([]*models.Runner, *models.ResponseError) {
+// GetRunnersByYear devuelve los 10 runners con mejor marca en el año, con las mismas preferencias de privacidad y el mismo orden que GetRunnersByCountry
+func (rr RunnersRepository) GetRunnersByYear(ctx context.Context, year int, public bool, ownRunnerId string) ([]*models.Runner, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "GetRunnersByYear")
+	defer done()
+
 	query := `
-	SELECT runners.id, runners.first_name, runners.last_name, runners.age, runners.is_active, runners.country, runners.personal_best, results.race_result
+	SELECT runners.id, runners.first_name, runners.last_name,
+		` + privateColumn("runners.age", "hide_age", "0", "runners.id", 2) + `,
+		runners.is_active, runners.country,
+		` + privateColumn("runners.personal_best", "hide_results", "NULL", "runners.id", 2) + `,
+		` + privateColumn("results.race_result", "hide_results", "NULL", "runners.id", 2) + ` AS race_result
 	FROM runners
 	INNER JOIN (
 		SELECT runner_id, MIN(race_result) as race_result
@@ -334,10 +519,11 @@ func (rr RunnersRepository) GetRunnersByYear(year int) ([]*models.Runner, *model
 		WHERE year = $1
 		GROUP BY runner_id) results
 	ON runners.id = results.runner_id
-	ORDER BY results.race_result
+	LEFT JOIN runner_privacy privacy ON privacy.runner_id = runners.id
+	ORDER BY race_result, runners.last_name, runners.first_name
 	LIMIT 10`
 
-	rows, err := rr.dbHandler.Query(query, year)
+	rows, err := rr.dbHandler.QueryContext(ctx, query, year, public, ownRunnerId)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: err.Error(),
@@ -385,3 +571,302 @@ func (rr RunnersRepository) GetRunnersByYear(year int) ([]*models.Runner, *model
 
 	return runners, nil
 }
+
+// StreamRunners recorre el cursor de la base de datos y entrega los runners de uno en uno a la función fn, sin materializar la tabla en memoria. Si fn devuelve un error se detiene la iteración
+func (rr RunnersRepository) StreamRunners(ctx context.Context, filter models.RunnersFilter, fn func(*models.Runner) error) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "runners", "StreamRunners")
+	defer done()
+
+	query := `
+	SELECT runners.id, runners.first_name, runners.last_name,
+		` + privateColumn("runners.age", "hide_age", "0", "runners.id", 1) + `,
+		runners.is_active, runners.country,
+		` + privateColumn("runners.personal_best", "hide_results", "NULL", "runners.id", 1) + `,
+		` + privateColumn("runners.season_best", "hide_results", "NULL", "runners.id", 1) + `
+	FROM runners
+	LEFT JOIN runner_privacy privacy ON privacy.runner_id = runners.id`
+	// se exportan los mismos runners que lista el endpoint del que viene el filtro
+	filterClause, filterArgs := runnersFilterClause(filter, 3)
+	args := append([]interface{}{filter.Public, filter.OwnRunnerID}, filterArgs...)
+	query += filterClause + `
+	ORDER BY runners.id`
+
+	// usamos el contexto de la petición para que si el cliente se desconecta se cancele la consulta
+	rows, err := rr.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	var id, firstName, lastName, country string
+	var personalBest, seasonBest sql.NullString
+	var age sql.NullInt64
+	var isActive sql.NullBool
+
+	for rows.Next() {
+		err := rows.Scan(&id, &firstName, &lastName, &age, &isActive, &country, &personalBest, &seasonBest)
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		err = fn(&models.Runner{
+			ID:           id,
+			FirstName:    firstName,
+			LastName:     lastName,
+			Age:          int(age.Int64),
+			IsActive:     isActive.Bool,
+			Country:      country,
+			PersonalBest: personalBest.String,
+			SeasonBest:   seasonBest.String,
+		})
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	if rows.Err() != nil {
+		return &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// GetRunnersPage devuelve una página de runners ordenados por nombre, junto con el número total de runners que cumplen el filtro. Con una página posterior a la última devuelve una página vacía, pero con el total
+func (rr RunnersRepository) GetRunnersPage(ctx context.Context, filter models.RunnersFilter, limit int, offset int) ([]*models.Runner, int, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "GetRunnersPage")
+	defer done()
+
+	query := `
+	SELECT runners.id, runners.first_name, runners.last_name,
+		` + privateColumn("runners.age", "hide_age", "0", "runners.id", 3) + `,
+		runners.is_active, runners.country,
+		` + privateColumn("runners.personal_best", "hide_results", "NULL", "runners.id", 3) + `,
+		` + privateColumn("runners.season_best", "hide_results", "NULL", "runners.id", 3) + `,
+		count(*) OVER ()
+	FROM runners
+	LEFT JOIN runner_privacy privacy ON privacy.runner_id = runners.id`
+	filterClause, filterArgs := runnersFilterClause(filter, 5)
+	args := append([]interface{}{limit, offset, filter.Public, filter.OwnRunnerID}, filterArgs...)
+
+	// el id deshace los empates para que las páginas no se solapen
+	query += filterClause + `
+	ORDER BY runners.last_name, runners.first_name, runners.id
+	LIMIT $1 OFFSET $2`
+
+	rows, err := rr.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	runners := make([]*models.Runner, 0)
+	total := 0
+	var id, firstName, lastName, country string
+	var personalBest, seasonBest sql.NullString
+	var age sql.NullInt64
+	var isActive sql.NullBool
+
+	for rows.Next() {
+		err := rows.Scan(&id, &firstName, &lastName, &age, &isActive, &country, &personalBest, &seasonBest, &total)
+		if err != nil {
+			return nil, 0, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		runners = append(runners, &models.Runner{
+			ID:           id,
+			FirstName:    firstName,
+			LastName:     lastName,
+			Age:          int(age.Int64),
+			IsActive:     isActive.Bool,
+			Country:      country,
+			PersonalBest: personalBest.String,
+			SeasonBest:   seasonBest.String,
+		})
+	}
+
+	if rows.Err() != nil {
+		return nil, 0, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// si la página está más allá del final no hay filas de las que leer el total, así que lo contamos aparte
+	if len(runners) == 0 && offset > 0 {
+		filterClause, filterArgs = runnersFilterClause(filter, 1)
+		err = rr.dbHandler.QueryRowContext(ctx, `
+	SELECT count(*)
+	FROM runners`+filterClause, filterArgs...).Scan(&total)
+		if err != nil {
+			return nil, 0, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	return runners, total, nil
+}
+
+// runnersFilterClause devuelve el JOIN y el WHERE con los que los listados filtran los runners, con el valor del filtro como parámetro número param. Por país solo se listan los runners activos, como en GetRunnersByCountry, y por año los que tienen algún resultado ese año, como en GetRunnersByYear
+func runnersFilterClause(filter models.RunnersFilter, param int) (string, []interface{}) {
+	if filter.Country != "" {
+		return `
+	WHERE runners.country = $` + strconv.Itoa(param) + ` AND runners.is_active = 'true'`, []interface{}{filter.Country}
+	}
+
+	if filter.Year != 0 {
+		return `
+	INNER JOIN (
+		SELECT runner_id
+		FROM results
+		WHERE year = $` + strconv.Itoa(param) + `
+		GROUP BY runner_id) results
+	ON runners.id = results.runner_id`, []interface{}{filter.Year}
+	}
+
+	return "", nil
+}
+
+// privateColumn aplica a una columna la regla de privacidad de todas las consultas de runners: si la consulta es pública (el parámetro número publicParam), el runner tiene activada la preferencia setting y no es el runner que consulta (el parámetro siguiente, vacío si no lo es ningún runner), la columna vale hidden. La consulta tiene que unir runner_privacy como privacy
+func privateColumn(column string, setting string, hidden string, idColumn string, publicParam int) string {
+	return `CASE WHEN $` + strconv.Itoa(publicParam) + ` AND privacy.` + setting + ` AND ` + idColumn + ` IS DISTINCT FROM NULLIF($` + strconv.Itoa(publicParam+1) + `, '')::uuid THEN ` + hidden + ` ELSE ` + column + ` END`
+}
+
+// umbral de similitud por trigramas (pg_trgm.word_similarity_threshold) a partir del cual un nombre es candidato en la búsqueda. Las coincidencias aproximadas puntúan search.PrefixScore por la similitud, así que por debajo de este umbral no llegarían a search.MinScore
+const trigramThreshold = search.MinScore / search.PrefixScore
+
+// runners activos que coinciden con la búsqueda ($1), con su puntuación. Los prefijos de palabra puntúan entre 0.8 y 1 y las coincidencias aproximadas por debajo de 0.8, igual que en search.Score, y solo son coincidencias las que llegan a search.MinScore ($2). El prefijo de palabra se comprueba también como LIKE sobre el nombre, que es lo que puede resolver el índice de trigramas. La usan la búsqueda y el recuento de coincidencias, para que los dos cuenten lo mismo
+const searchMatchesQuery = `
+	SELECT * FROM (
+		SELECT runners.*,
+			CASE WHEN ' ' || runner_search_name(first_name, last_name) LIKE '% ' || $1 || '%'
+				THEN 0.8 + 0.2 * word_similarity($1, runner_search_name(first_name, last_name))
+				ELSE 0.8 * word_similarity($1, runner_search_name(first_name, last_name))
+			END AS score
+		FROM runners
+		WHERE is_active = 'true'
+			AND ((runner_search_name(first_name, last_name) LIKE '%' || $1 || '%'
+					AND ' ' || runner_search_name(first_name, last_name) LIKE '% ' || $1 || '%')
+				OR $1 <% runner_search_name(first_name, last_name))) scored
+	WHERE score >= $2`
+
+// SearchRunners busca runners activos por nombre usando el índice de trigramas. La búsqueda (text) tiene que estar normalizada con search.Normalize, y devuelve la página pedida junto con el número total de coincidencias. Con public se ocultan los datos que los runners no quieren mostrar, salvo los de ownRunnerId (el runner que hace la búsqueda)
+func (rr RunnersRepository) SearchRunners(ctx context.Context, text string, public bool, ownRunnerId string, limit int, offset int) ([]*models.RunnerMatch, int, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "runners", "SearchRunners")
+	defer done()
+
+	query := `
+	SELECT matches.id, first_name, last_name,
+		` + privateColumn("age", "hide_age", "0", "matches.id", 3) + `,
+		is_active, country,
+		` + privateColumn("personal_best", "hide_results", "NULL", "matches.id", 3) + `,
+		` + privateColumn("season_best", "hide_results", "NULL", "matches.id", 3) + `,
+		score, count(*) OVER() AS total
+	FROM (` + searchMatchesQuery + `) matches
+	LEFT JOIN runner_privacy privacy ON privacy.runner_id = matches.id
+	ORDER BY score DESC, last_name, first_name, matches.id
+	LIMIT $5 OFFSET $6`
+
+	// el umbral del operador <% es un parámetro de la sesión, así que lo fijamos solo para esta transacción
+	tx, err := rr.dbHandler.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, strconv.FormatFloat(trigramThreshold, 'f', -1, 64))
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// la búsqueda normalizada solo contiene letras, dígitos y espacios, así que no hay comodines de LIKE que escapar
+	rows, err := tx.QueryContext(ctx, query, text, search.MinScore, public, ownRunnerId, limit, offset)
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	matches := make([]*models.RunnerMatch, 0)
+	total := 0
+	var id, firstName, lastName, country string
+	var personalBest, seasonBest sql.NullString
+	var age sql.NullInt64
+	var isActive sql.NullBool
+	var score float64
+
+	for rows.Next() {
+		err := rows.Scan(&id, &firstName, &lastName, &age, &isActive, &country, &personalBest, &seasonBest, &score, &total)
+		if err != nil {
+			return nil, 0, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		matches = append(matches, &models.RunnerMatch{
+			Runner: &models.Runner{
+				ID:           id,
+				FirstName:    firstName,
+				LastName:     lastName,
+				Age:          int(age.Int64),
+				IsActive:     isActive.Bool,
+				Country:      country,
+				PersonalBest: personalBest.String,
+				SeasonBest:   seasonBest.String,
+			},
+			Score: score,
+		})
+	}
+
+	if rows.Err() != nil {
+		return nil, 0, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// si la página está más allá del final no hay filas de las que leer el total, así que lo contamos aparte
+	if len(matches) == 0 && offset > 0 {
+		err = tx.QueryRowContext(ctx, `SELECT count(*) FROM (`+searchMatchesQuery+`) matches`, text, search.MinScore).Scan(&total)
+		if err != nil {
+			return nil, 0, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	return matches, total, nil
+}
@@ -5,39 +5,34 @@ import (
 	"database/sql"
 )
 
-func BeginTransaction(runnersRepository *RunnersRepository, resultsRepository *ResultsRepository) error {
-	// creamos un contexto para la transacción
-	ctx := context.Background()
-	// iniciamos la transacción
-	transaction, err := resultsRepository.dbHandler.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return err
-	}
-	// asignamos la transacción a ambos repositorios
-	runnersRepository.transaction = transaction
-	resultsRepository.transaction = transaction
-
-	return nil
+// BeginTransaction inicia una transacción para la petición. Los repositorios los comparten todas las peticiones, así que no guardan la transacción: se pasa a cada método que tiene que ejecutarse dentro de ella.
+// El outbox participa en la transacción para que los eventos solo se publiquen si el cambio se confirma
+func BeginTransaction(ctx context.Context, runnersRepository *RunnersRepository) (*sql.Tx, error) {
+	return runnersRepository.dbHandler.BeginTx(ctx, &sql.TxOptions{})
 }
 
-func RollbackTransaction(runnersRepository *RunnersRepository, resultsRepository *ResultsRepository) error {
-	// toma la transacción de uno de los repositorios (los dos tienen la misma transacción así que da igual cual utilicemos)
-	transaction := runnersRepository.transaction
-	// limpiamos la transacción en ambos repositorios
-	runnersRepository.transaction = nil
-	resultsRepository.transaction = nil
-	// hacemos el rollback
+func RollbackTransaction(transaction *sql.Tx) error {
 	return transaction.Rollback()
 }
 
-func CommitTransaction(runnersRepository *RunnersRepository, resultsRepository *ResultsRepository) error {
-	// toma la transacción de uno de los repositorios (los dos tienen la misma transacción así que da igual cual utilicemos)
-	transaction := runnersRepository.transaction
+func CommitTransaction(transaction *sql.Tx) error {
+	return transaction.Commit()
+}
 
-	// limpiamos la transacción en ambos repositorios
-	runnersRepository.transaction = nil
-	resultsRepository.transaction = nil
+// SavepointTransaction marca un punto de la transacción al que se puede volver con RollbackToSavepoint sin perder lo anterior. Las peticiones por lotes marcan uno por elemento
+func SavepointTransaction(ctx context.Context, transaction *sql.Tx, name string) error {
+	_, err := transaction.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
 
-	// hacemos el commit
-	return transaction.Commit()
+// RollbackToSavepoint deshace los cambios hechos desde el savepoint. La transacción sigue abierta
+func RollbackToSavepoint(ctx context.Context, transaction *sql.Tx, name string) error {
+	_, err := transaction.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+// ReleaseSavepoint confirma los cambios hechos desde el savepoint dentro de la transacción, que sigue abierta
+func ReleaseSavepoint(ctx context.Context, transaction *sql.Tx, name string) error {
+	_, err := transaction.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
 }
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"runners-postgresql/models"
@@ -16,13 +17,16 @@ func NewUsersRepository(dbHAndler *sql.DB) *UsersRepository {
 	}
 }
 
-func (ur UsersRepository) LoginUser(username string, password string) (string, *models.ResponseError) {
+func (ur UsersRepository) LoginUser(ctx context.Context, username string, password string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "LoginUser")
+	defer done()
+
 	query := `
 		SELECT id
 		FROM users
 		WHERE username = $1 and user_password = crypt($2, user_password)`
 
-	rows, err := ur.dbHandler.Query(query, username, password)
+	rows, err := ur.dbHandler.QueryContext(ctx, query, username, password)
 	if err != nil {
 		return "", &models.ResponseError{
 			Message: err.Error(),
@@ -53,13 +57,16 @@ func (ur UsersRepository) LoginUser(username string, password string) (string, *
 	return id, nil
 }
 
-func (ur UsersRepository) GetUserRole(accessToken string) (string, *models.ResponseError) {
+func (ur UsersRepository) GetUserRole(ctx context.Context, accessToken string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "GetUserRole")
+	defer done()
+
 	query := `
 		SELECT user_role
 		FROM users
 		WHERE access_token = $1`
 
-	rows, err := ur.dbHandler.Query(query, accessToken)
+	rows, err := ur.dbHandler.QueryContext(ctx, query, accessToken)
 	if err != nil {
 		return "", &models.ResponseError{
 			Message: err.Error(),
@@ -90,11 +97,113 @@ func (ur UsersRepository) GetUserRole(accessToken string) (string, *models.Respo
 	return role, nil
 }
 
-func (ur UsersRepository) SetAccessToken(accessToken string, id string) *models.ResponseError {
+// GetPrincipal devuelve el usuario asociado al token, con su rol, el club que administra y su runner. Si el token no es válido devuelve nil
+func (ur UsersRepository) GetPrincipal(ctx context.Context, accessToken string) (*models.Principal, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "GetPrincipal")
+	defer done()
+
+	query := `
+		SELECT id, username, user_role, club_id, runner_id
+		FROM users
+		WHERE access_token = $1`
+
+	principal := &models.Principal{}
+	var clubId, runnerId sql.NullString
+	err := ur.dbHandler.QueryRowContext(ctx, query, accessToken).Scan(&principal.UserID, &principal.Username, &principal.Role, &clubId, &runnerId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	principal.ClubID = clubId.String
+	principal.RunnerID = runnerId.String
+
+	return principal, nil
+}
+
+// SetClubAdmin convierte al usuario en administrador del club. Devuelve su token de acceso, para poder invalidar la caché de roles
+func (ur UsersRepository) SetClubAdmin(ctx context.Context, username string, clubId string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "SetClubAdmin")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET user_role = $2, club_id = $3
+		WHERE username = $1
+		RETURNING COALESCE(access_token, '')`
+
+	var accessToken string
+	err := ur.dbHandler.QueryRowContext(ctx, query, username, models.RoleClubAdmin, clubId).Scan(&accessToken)
+	if err == sql.ErrNoRows {
+		return "", &models.ResponseError{
+			Message: "User not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return accessToken, nil
+}
+
+// LinkRunner asocia el runner a la cuenta del usuario. Devuelve su token de acceso, para poder invalidar la caché de roles
+func (ur UsersRepository) LinkRunner(ctx context.Context, username string, runnerId string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "LinkRunner")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET runner_id = $2
+		WHERE username = $1
+		RETURNING COALESCE(access_token, '')`
+
+	var accessToken string
+	err := ur.dbHandler.QueryRowContext(ctx, query, username, runnerId).Scan(&accessToken)
+	if err == sql.ErrNoRows {
+		return "", &models.ResponseError{
+			Message: "User not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		switch pqErrorCode(err) {
+		case pqUniqueViolation:
+			return "", &models.ResponseError{
+				Message: "Runner is already linked to another user",
+				Status:  http.StatusConflict,
+			}
+		case pqForeignKeyViolation:
+			return "", &models.ResponseError{
+				Message: "Runner not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return accessToken, nil
+}
+
+func (ur UsersRepository) SetAccessToken(ctx context.Context, accessToken string, id string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "users", "SetAccessToken")
+	defer done()
+
 	// guarda el token en la base de datos para el usuario
 	query := `UPDATE users SET access_token = $1 WHERE id = $2`
 
-	_, err := ur.dbHandler.Exec(query, accessToken, id)
+	_, err := ur.dbHandler.ExecContext(ctx, query, accessToken, id)
 	if err != nil {
 		return &models.ResponseError{
 			Message: err.Error(),
@@ -105,10 +214,13 @@ func (ur UsersRepository) SetAccessToken(accessToken string, id string) *models.
 	return nil
 }
 
-func (ur UsersRepository) RemoveAccessToken(accessToken string) *models.ResponseError {
+func (ur UsersRepository) RemoveAccessToken(ctx context.Context, accessToken string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "users", "RemoveAccessToken")
+	defer done()
+
 	query := `UPDATE users SET access_token = '' WHERE access_token = $1`
 
-	_, err := ur.dbHandler.Exec(query, accessToken)
+	_, err := ur.dbHandler.ExecContext(ctx, query, accessToken)
 	if err != nil {
 		return &models.ResponseError{
 			Message: err.Error(),
@@ -118,3 +230,154 @@ func (ur UsersRepository) RemoveAccessToken(accessToken string) *models.Response
 
 	return nil
 }
+
+// UpsertExternalUser da de alta al usuario del proveedor de identidad la primera vez que inicia sesión, y las siguientes actualiza su rol y su club, que manda el proveedor. Devuelve su id y su token de acceso anterior, para poder invalidar la caché de roles
+func (ur UsersRepository) UpsertExternalUser(ctx context.Context, identity *models.ExternalIdentity) (string, string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "UpsertExternalUser")
+	defer done()
+
+	query := `
+		INSERT INTO users(username, user_role, club_id, oidc_issuer, oidc_subject)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5)
+		ON CONFLICT (oidc_issuer, oidc_subject) DO UPDATE
+		SET user_role = EXCLUDED.user_role, club_id = EXCLUDED.club_id
+		RETURNING id, COALESCE(access_token, '')`
+
+	var id, accessToken string
+	err := ur.dbHandler.QueryRowContext(ctx, query, identity.Username, identity.Role, identity.ClubID, identity.Issuer, identity.Subject).Scan(&id, &accessToken)
+	if err != nil {
+		// el nombre de usuario ya lo tiene otra cuenta, local o de otro usuario del proveedor
+		if pqErrorCode(err) == pqUniqueViolation {
+			return "", "", &models.ResponseError{
+				Message: "Username already in use",
+				Status:  http.StatusConflict,
+			}
+		}
+		return "", "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return id, accessToken, nil
+}
+
+// GetAllUsers devuelve los usuarios ordenados por nombre, sin contraseña ni token
+func (ur UsersRepository) GetAllUsers(ctx context.Context) ([]*models.User, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "GetAllUsers")
+	defer done()
+
+	query := `
+		SELECT id, username, user_role, club_id, runner_id, oidc_subject IS NOT NULL
+		FROM users
+		ORDER BY username`
+
+	rows, err := ur.dbHandler.QueryContext(ctx, query)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	users := make([]*models.User, 0)
+	for rows.Next() {
+		user := &models.User{}
+		var clubId, runnerId sql.NullString
+		err := rows.Scan(&user.ID, &user.Username, &user.Role, &clubId, &runnerId, &user.External)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		user.ClubID = clubId.String
+		user.RunnerID = runnerId.String
+		users = append(users, user)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return users, nil
+}
+
+// CreateUser da de alta un usuario con contraseña. La contraseña se guarda hasheada con crypt(), como la de los usuarios iniciales
+func (ur UsersRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "CreateUser")
+	defer done()
+
+	query := `
+		INSERT INTO users(username, user_password, user_role, club_id)
+		VALUES ($1, crypt($2, gen_salt('bf')), $3, NULLIF($4, '')::uuid)
+		RETURNING id`
+
+	var id string
+	err := ur.dbHandler.QueryRowContext(ctx, query, user.Username, user.Password, user.Role, user.ClubID).Scan(&id)
+	if err != nil {
+		switch pqErrorCode(err) {
+		case pqUniqueViolation:
+			return nil, &models.ResponseError{
+				Message: "Username already in use",
+				Status:  http.StatusConflict,
+			}
+		case pqForeignKeyViolation:
+			return nil, &models.ResponseError{
+				Message: "Club not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &models.User{
+		ID:       id,
+		Username: user.Username,
+		Role:     user.Role,
+		ClubID:   user.ClubID,
+	}, nil
+}
+
+// SetUserRole cambia el rol del usuario, y su club si es administrador de club. Devuelve su token de acceso, para poder invalidar la caché de roles
+func (ur UsersRepository) SetUserRole(ctx context.Context, username string, role string, clubId string) (string, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "users", "SetUserRole")
+	defer done()
+
+	query := `
+		UPDATE users
+		SET user_role = $2, club_id = NULLIF($3, '')::uuid
+		WHERE username = $1
+		RETURNING COALESCE(access_token, '')`
+
+	var accessToken string
+	err := ur.dbHandler.QueryRowContext(ctx, query, username, role, clubId).Scan(&accessToken)
+	if err == sql.ErrNoRows {
+		return "", &models.ResponseError{
+			Message: "User not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		if pqErrorCode(err) == pqForeignKeyViolation {
+			return "", &models.ResponseError{
+				Message: "Club not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return accessToken, nil
+}
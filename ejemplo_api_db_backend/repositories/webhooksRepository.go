@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runners-postgresql/models"
+
+	"github.com/lib/pq"
+)
+
+type WebhooksRepository struct {
+	dbHandler *sql.DB
+}
+
+func NewWebhooksRepository(dbHandler *sql.DB) *WebhooksRepository {
+	return &WebhooksRepository{
+		dbHandler: dbHandler,
+	}
+}
+
+func (wr WebhooksRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "webhooks", "CreateSubscription")
+	defer done()
+
+	query := `
+		INSERT INTO webhook_subscriptions(url, secret, event_types, is_active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	// pq.Array convierte el slice en un array de Postgres
+	row := wr.dbHandler.QueryRowContext(ctx, query, subscription.URL, subscription.Secret, pq.Array(subscription.EventTypes), subscription.IsActive)
+
+	response := *subscription
+	err := row.Scan(&response.ID, &response.CreatedAt)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &response, nil
+}
+
+func (wr WebhooksRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "webhooks", "UpdateSubscription")
+	defer done()
+
+	query := `
+		UPDATE webhook_subscriptions
+		SET
+			url = $1,
+			event_types = $2,
+			is_active = $3
+		WHERE id = $4`
+
+	res, err := wr.dbHandler.ExecContext(ctx, query, subscription.URL, pq.Array(subscription.EventTypes), subscription.IsActive, subscription.ID)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Webhook subscription not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+func (wr WebhooksRepository) DeleteSubscription(ctx context.Context, subscriptionId string) *models.ResponseError {
+	ctx, done := observeQuery(ctx, "webhooks", "DeleteSubscription")
+	defer done()
+
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+	res, err := wr.dbHandler.ExecContext(ctx, query, subscriptionId)
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Webhook subscription not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+func (wr WebhooksRepository) GetSubscription(ctx context.Context, subscriptionId string) (*models.WebhookSubscription, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "webhooks", "GetSubscription")
+	defer done()
+
+	query := `
+		SELECT id, url, event_types, is_active, created_at
+		FROM webhook_subscriptions
+		WHERE id = $1`
+
+	subscriptions, responseErr := wr.querySubscriptions(ctx, query, subscriptionId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if len(subscriptions) == 0 {
+		return nil, &models.ResponseError{
+			Message: "Webhook subscription not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return subscriptions[0], nil
+}
+
+func (wr WebhooksRepository) GetAllSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, *models.ResponseError) {
+	ctx, done := observeQuery(ctx, "webhooks", "GetAllSubscriptions")
+	defer done()
+
+	query := `
+		SELECT id, url, event_types, is_active, created_at
+		FROM webhook_subscriptions
+		ORDER BY created_at`
+
+	return wr.querySubscriptions(ctx, query)
+}
+
+func (wr WebhooksRepository) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]*models.WebhookSubscription, *models.ResponseError) {
+	rows, err := wr.dbHandler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	subscriptions := make([]*models.WebhookSubscription, 0)
+	for rows.Next() {
+		subscription := &models.WebhookSubscription{}
+		err := rows.Scan(&subscription.ID, &subscription.URL, pq.Array(&subscription.EventTypes), &subscription.IsActive, &subscription.CreatedAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if rows.Err() != nil {
+		return nil, &models.ResponseError{
+			Message: rows.Err().Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return subscriptions, nil
+}
@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+	"runners-postgresql/models"
+	"runners-postgresql/services"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const roleAdmin = "admin"
+const roleRunner = models.RoleRunner
+const roleClubAdmin = models.RoleClubAdmin
+
+// clave de los metadatos con el token de acceso, equivalente a la cabecera Token de la API REST
+const tokenKey = "token"
+
+// authenticate devuelve el usuario del token de la llamada, o del certificado de cliente si no hay token. Aplica las mismas reglas que los controladores, pero un usuario sin el rol necesario recibe PermissionDenied en lugar de un 401 sin cuerpo
+func authenticate(ctx context.Context, usersService *services.UsersService, roles ...string) (*models.Principal, error) {
+	principal, responseErr := usersService.Authenticate(ctx, accessToken(ctx), roles)
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	if principal == nil {
+		return nil, status.Error(codes.PermissionDenied, "Insufficient role")
+	}
+
+	return principal, nil
+}
+
+// authorize comprueba que el usuario de la llamada tiene uno de los roles, cuando no hace falta saber quién es
+func authorize(ctx context.Context, usersService *services.UsersService, roles ...string) error {
+	auth, responseErr := usersService.AuthorizeUser(ctx, accessToken(ctx), roles)
+	if responseErr != nil {
+		return toStatus(ctx, responseErr)
+	}
+
+	if !auth {
+		return status.Error(codes.PermissionDenied, "Insufficient role")
+	}
+
+	return nil
+}
+
+func accessToken(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, tokenKey)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,87 @@
+package rpc
+
+import (
+	"runners-postgresql/models"
+	"runners-postgresql/pb"
+)
+
+// conversiones entre los modelos de la aplicación y los mensajes de protobuf
+
+func toRunner(runner *models.Runner) *pb.Runner {
+	message := &pb.Runner{
+		Id:           runner.ID,
+		FirstName:    runner.FirstName,
+		LastName:     runner.LastName,
+		Age:          int32(runner.Age),
+		IsActive:     runner.IsActive,
+		Country:      runner.Country,
+		PersonalBest: runner.PersonalBest,
+		SeasonBest:   runner.SeasonBest,
+	}
+
+	for _, result := range runner.Results {
+		message.Results = append(message.Results, toResult(result))
+	}
+
+	if runner.Privacy != nil {
+		message.Privacy = &pb.Privacy{
+			HideAge:     runner.Privacy.HideAge,
+			HideResults: runner.Privacy.HideResults,
+		}
+	}
+
+	return message
+}
+
+// fromRunner solo convierte los campos que se pueden escribir: las marcas y los resultados los calcula la aplicación
+func fromRunner(message *pb.Runner) *models.Runner {
+	runner := &models.Runner{
+		ID:        message.GetId(),
+		FirstName: message.GetFirstName(),
+		LastName:  message.GetLastName(),
+		Age:       int(message.GetAge()),
+		IsActive:  message.GetIsActive(),
+		Country:   message.GetCountry(),
+	}
+
+	if message.GetPrivacy() != nil {
+		runner.Privacy = &models.Privacy{
+			HideAge:     message.GetPrivacy().GetHideAge(),
+			HideResults: message.GetPrivacy().GetHideResults(),
+		}
+	}
+
+	return runner
+}
+
+func toResult(result *models.Result) *pb.Result {
+	return &pb.Result{
+		Id:         result.ID,
+		RunnerId:   result.RunnerID,
+		RaceResult: result.RaceResult,
+		Location:   result.Location,
+		Position:   int32(result.Position),
+		Year:       int32(result.Year),
+	}
+}
+
+func fromResult(message *pb.Result) *models.Result {
+	return &models.Result{
+		ID:         message.GetId(),
+		RunnerID:   message.GetRunnerId(),
+		RaceResult: message.GetRaceResult(),
+		Location:   message.GetLocation(),
+		Position:   int(message.GetPosition()),
+		Year:       int(message.GetYear()),
+	}
+}
+
+func toPersonalBest(personalBest *models.PersonalBestEvent) *pb.PersonalBest {
+	return &pb.PersonalBest{
+		RunnerId:             personalBest.RunnerID,
+		ResultId:             personalBest.ResultID,
+		Location:             personalBest.Location,
+		PersonalBest:         personalBest.PersonalBest,
+		PreviousPersonalBest: personalBest.PreviousPersonalBest,
+	}
+}
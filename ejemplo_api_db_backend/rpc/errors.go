@@ -0,0 +1,39 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// códigos gRPC equivalentes a los códigos HTTP de los servicios. Los que no están se devuelven como Unknown
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInternalServerError: codes.Internal,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+}
+
+// toStatus convierte el error de un servicio en un error gRPC con el mismo mensaje que la API REST
+func toStatus(ctx context.Context, responseErr *models.ResponseError) error {
+	if responseErr.Status >= http.StatusInternalServerError {
+		logging.Error(ctx, "Call failed", "status", responseErr.Status, "error", responseErr.Message)
+	} else {
+		logging.Debug(ctx, "Call rejected", "status", responseErr.Status, "error", responseErr.Message)
+	}
+
+	code, found := statusCodes[responseErr.Status]
+	if !found {
+		code = codes.Unknown
+	}
+
+	return status.Error(code, responseErr.Message)
+}
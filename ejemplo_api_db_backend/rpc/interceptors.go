@@ -0,0 +1,162 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"runners-postgresql/certs"
+	"runners-postgresql/logging"
+	"runners-postgresql/metrics"
+	"runners-postgresql/tracing"
+	"runtime/debug"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// clave de los metadatos con el identificador de la petición, como la cabecera X-Request-ID de la API REST
+const requestIdKey = "x-request-id"
+
+// códigos que indican un fallo del servidor, y no de la llamada
+var serverErrorCodes = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.DeadlineExceeded: true,
+	codes.Unimplemented:    true,
+	codes.Internal:         true,
+	codes.Unavailable:      true,
+	codes.DataLoss:         true,
+}
+
+// UnaryInterceptor hace con cada llamada lo mismo que los middlewares de Gin con cada petición: traza, logger con el identificador de la petición, usuario del certificado de cliente, métricas, log de acceso y recuperación de panics
+func UnaryInterceptor(identities certs.Identities) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var response any
+		err := observe(ctx, info.FullMethod, identities, func(ctx context.Context) error {
+			var err error
+			response, err = handler(ctx, request)
+			return err
+		})
+
+		return response, err
+	}
+}
+
+// StreamInterceptor es el equivalente de UnaryInterceptor para las llamadas con streams
+func StreamInterceptor(identities certs.Identities) grpc.StreamServerInterceptor {
+	return func(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return observe(stream.Context(), info.FullMethod, identities, func(ctx context.Context) error {
+			return handler(server, &contextStream{ServerStream: stream, ctx: ctx})
+		})
+	}
+}
+
+func observe(ctx context.Context, method string, identities certs.Identities, call func(ctx context.Context) error) (err error) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	// el span continúa la traza de la clave traceparent de los metadatos
+	parent := otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	ctx, span := tracing.Start(parent, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+		))
+	defer span.End()
+
+	requestId := logging.RequestID(first(md, requestIdKey))
+	grpc.SetHeader(ctx, metadata.Pairs(requestIdKey, requestId))
+	logger := slog.Default().With("request_id", requestId)
+	ctx = logging.WithLogger(ctx, logger)
+
+	// los servicios internos que se autentican con un certificado de cliente tienen el rol asociado a su sujeto
+	if tlsInfo, ok := peerTLS(ctx); ok {
+		if principal := identities.Principal(ctx, &tlsInfo.State); principal != nil {
+			ctx = certs.WithPrincipal(ctx, principal)
+		}
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.Error(ctx, "Panic while handling call", "method", method, "panic", recovered, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "Internal error")
+		}
+
+		code := status.Code(err)
+		metrics.GrpcRequestDuration.WithLabelValues(method, code.String()).Observe(time.Since(start).Seconds())
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+
+		level := slog.LevelInfo
+		if serverErrorCodes[code] {
+			level = slog.LevelError
+			span.SetStatus(otelcodes.Error, code.String())
+		} else if code != codes.OK {
+			level = slog.LevelWarn
+		}
+
+		logger.Log(ctx, level, "call",
+			"method", method,
+			"code", code.String(),
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+		)
+	}()
+
+	return call(ctx)
+}
+
+func peerTLS(ctx context.Context) (credentials.TLSInfo, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return credentials.TLSInfo{}, false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	return tlsInfo, ok
+}
+
+func first(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// contextStream sustituye el contexto del stream por el del interceptor, que tiene el span, el logger y el usuario
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier permite leer la traza de los metadatos gRPC con el propagador de OpenTelemetry
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return first(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
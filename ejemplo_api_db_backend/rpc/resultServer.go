@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"runners-postgresql/live"
+	"runners-postgresql/logging"
+	"runners-postgresql/metrics"
+	"runners-postgresql/models"
+	"runners-postgresql/pb"
+	"runners-postgresql/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// número de eventos que se reenvían por defecto a un nuevo suscriptor, igual que en el feed en directo
+const defaultWatchReplay = 20
+
+// ResultServer implementa ResultService con los mismos servicios que el controlador de resultados, y el mismo hub que el feed en directo
+type ResultServer struct {
+	pb.UnimplementedResultServiceServer
+	resultsService *services.ResultsService
+	runnersService *services.RunnersService
+	usersService   *services.UsersService
+	hub            *live.Hub
+}
+
+func NewResultServer(resultsService *services.ResultsService, runnersService *services.RunnersService, usersService *services.UsersService, hub *live.Hub) *ResultServer {
+	return &ResultServer{
+		resultsService: resultsService,
+		runnersService: runnersService,
+		usersService:   usersService,
+		hub:            hub,
+	}
+}
+
+func (rs *ResultServer) CreateResult(ctx context.Context, request *pb.CreateResultRequest) (*pb.Result, error) {
+	// los administradores de club solo pueden añadir resultados a los runners de su club, lo comprueba el servicio
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	result, responseErr := rs.resultsService.CreateResult(ctx, principal, fromResult(request.GetResult()))
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	return toResult(result), nil
+}
+
+func (rs *ResultServer) DeleteResult(ctx context.Context, request *pb.DeleteResultRequest) (*emptypb.Empty, error) {
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	responseErr := rs.resultsService.DeleteResult(ctx, principal, request.GetId())
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// WatchResults envía los eventos del feed en directo hasta que el cliente cancela la llamada. Si el cliente no lee lo bastante rápido, o se detiene la aplicación, la llamada termina con Unavailable y el cliente puede volver a suscribirse con last_seq
+func (rs *ResultServer) WatchResults(request *pb.WatchResultsRequest, stream grpc.ServerStreamingServer[pb.ResultEvent]) error {
+	ctx := stream.Context()
+	// los runners no reciben los eventos de los runners que ocultan sus resultados
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin, roleRunner)
+	if err != nil {
+		return err
+	}
+
+	replay := defaultWatchReplay
+	if request.Replay != nil {
+		replay = int(request.GetReplay())
+	}
+
+	filter := live.Filter{
+		Race:     request.GetRace(),
+		RunnerID: request.GetRunnerId(),
+	}
+	subscription := rs.hub.Subscribe(filter, request.GetLastSeq(), replay)
+	defer subscription.Close()
+
+	metrics.LiveConnections.WithLabelValues("grpc").Inc()
+	defer metrics.LiveConnections.WithLabelValues("grpc").Dec()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-subscription.C():
+			if !ok {
+				switch subscription.Err() {
+				case live.ErrSlowConsumer:
+					metrics.LiveSlowConsumersCounter.WithLabelValues("grpc").Inc()
+					return status.Error(codes.Unavailable, "slow consumer")
+				case live.ErrShutdown:
+					return status.Error(codes.Unavailable, "server shutting down")
+				}
+				return nil
+			}
+
+			visible, responseErr := rs.runnersService.CanSeeResults(ctx, principal, message.RunnerID)
+			if responseErr != nil {
+				logging.Error(ctx, "Error while checking runner privacy", "runner_id", message.RunnerID, "error", responseErr.Message)
+				continue
+			}
+			if !visible {
+				continue
+			}
+
+			event, err := toResultEvent(message)
+			if err != nil {
+				logging.Error(ctx, "Error while converting live message", "error", err)
+				continue
+			}
+
+			err = stream.Send(event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toResultEvent(message *live.Message) (*pb.ResultEvent, error) {
+	event := &pb.ResultEvent{
+		Seq:      message.Seq,
+		Type:     message.Type,
+		RunnerId: message.RunnerID,
+		Race:     message.Race,
+	}
+
+	switch message.Type {
+	case models.EventResultCreated:
+		var result models.Result
+		err := json.Unmarshal(message.Data, &result)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = &pb.ResultEvent_Result{Result: toResult(&result)}
+	case models.EventRunnerPersonalBest:
+		var personalBest models.PersonalBestEvent
+		err := json.Unmarshal(message.Data, &personalBest)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = &pb.ResultEvent_PersonalBest{PersonalBest: toPersonalBest(&personalBest)}
+	}
+
+	return event, nil
+}
@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"runners-postgresql/live"
+	"runners-postgresql/models"
+	"runners-postgresql/pb"
+	"runners-postgresql/repositories"
+	"runners-postgresql/services"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestAuthErrorsMapToGrpcCodes(t *testing.T) {
+	dbHandler, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+
+	conn := startServer(t, dbHandler, live.NewHub(10, 10))
+	client := pb.NewRunnerServiceClient(conn)
+
+	// sin token: el mismo 400 que la API REST
+	_, err = client.DeleteRunner(context.Background(), &pb.DeleteRunnerRequest{Id: "1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "Invalid access token", status.Convert(err).Message())
+
+	// un runner no puede borrar runners
+	mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}).AddRow("1", "runner", "runner", nil, nil))
+	_, err = client.DeleteRunner(withToken("token"), &pb.DeleteRunnerRequest{Id: "1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// un token que no existe
+	mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}))
+	_, err = client.DeleteRunner(withToken("unknown"), &pb.DeleteRunnerRequest{Id: "1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWatchResultsStreamsLiveEvents(t *testing.T) {
+	dbHandler, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+	mock.ExpectQuery("SELECT id, username, user_role").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}).AddRow("1", "runner", "runner", nil, "5"))
+
+	hub := live.NewHub(10, 10)
+	hub.Publish(resultCreated(t, "1", "Valencia"), resultCreated(t, "2", "Berlin"))
+
+	conn := startServer(t, dbHandler, hub)
+	stream, err := pb.NewResultServiceClient(conn).WatchResults(withToken("token"), &pb.WatchResultsRequest{Race: "valencia"})
+	require.NoError(t, err)
+
+	// el evento anterior a la suscripción llega por el replay, y los de otras carreras no
+	mock.ExpectQuery("SELECT hide_age, hide_results").WithArgs("1").WillReturnRows(
+		sqlmock.NewRows([]string{"hide_age", "hide_results"}).AddRow(false, false))
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), event.GetSeq())
+	assert.Equal(t, models.EventResultCreated, event.GetType())
+	assert.Equal(t, "02:05:00", event.GetResult().GetRaceResult())
+
+	// los runners no reciben los eventos de los runners que ocultan sus resultados
+	mock.ExpectQuery("SELECT hide_age, hide_results").WithArgs("3").WillReturnRows(
+		sqlmock.NewRows([]string{"hide_age", "hide_results"}).AddRow(false, true))
+	mock.ExpectQuery("SELECT hide_age, hide_results").WithArgs("4").WillReturnRows(
+		sqlmock.NewRows([]string{"hide_age", "hide_results"}).AddRow(false, false))
+	hub.Publish(resultCreated(t, "3", "Valencia"), resultCreated(t, "4", "Valencia"))
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), event.GetSeq())
+	assert.Equal(t, "4", event.GetRunnerId())
+
+	// el runner siempre recibe sus propios eventos, sin consultar la privacidad
+	hub.Publish(resultCreated(t, "5", "Valencia"))
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "5", event.GetRunnerId())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// al detener la aplicación el stream termina y el cliente puede volver a suscribirse
+	hub.Close()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func startServer(t *testing.T, dbHandler *sql.DB, hub *live.Hub) *grpc.ClientConn {
+	t.Helper()
+
+	usersService := services.NewUsersService(repositories.NewUsersRepository(dbHandler), nil, nil)
+	runnersService := services.NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+	resultsService := services.NewResultsService(nil, nil, nil, nil, hub, nil)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryInterceptor(nil)),
+		grpc.ChainStreamInterceptor(StreamInterceptor(nil)),
+	)
+	pb.RegisterRunnerServiceServer(server, NewRunnerServer(runnersService, usersService))
+	pb.RegisterResultServiceServer(server, NewResultServer(resultsService, runnersService, usersService, hub))
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "token", token)
+}
+
+func resultCreated(t *testing.T, runnerId string, location string) *models.Event {
+	payload, err := json.Marshal(&models.Result{ID: "r" + runnerId, RunnerID: runnerId, RaceResult: "02:05:00", Location: location, Year: 2024})
+	require.NoError(t, err)
+
+	return &models.Event{Type: models.EventResultCreated, AggregateID: runnerId, Payload: payload}
+}
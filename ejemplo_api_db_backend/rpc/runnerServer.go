@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"context"
+	"runners-postgresql/pb"
+	"runners-postgresql/services"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// RunnerServer implementa RunnerService con los mismos servicios que el controlador de runners
+type RunnerServer struct {
+	pb.UnimplementedRunnerServiceServer
+	runnersService *services.RunnersService
+	usersService   *services.UsersService
+}
+
+func NewRunnerServer(runnersService *services.RunnersService, usersService *services.UsersService) *RunnerServer {
+	return &RunnerServer{
+		runnersService: runnersService,
+		usersService:   usersService,
+	}
+}
+
+func (rs *RunnerServer) GetRunner(ctx context.Context, request *pb.GetRunnerRequest) (*pb.Runner, error) {
+	// necesitamos saber quién consulta el runner para aplicar sus preferencias de privacidad
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin, roleRunner)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, responseErr := rs.runnersService.GetRunner(ctx, principal, request.GetId())
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	return toRunner(runner), nil
+}
+
+func (rs *RunnerServer) ListRunners(ctx context.Context, request *pb.ListRunnersRequest) (*pb.ListRunnersResponse, error) {
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin, roleRunner)
+	if err != nil {
+		return nil, err
+	}
+
+	// el servicio recibe los filtros como los query parameters de la API REST
+	year := ""
+	if request.GetYear() != 0 {
+		year = strconv.Itoa(int(request.GetYear()))
+	}
+
+	runners, responseErr := rs.runnersService.GetRunnersBatch(ctx, principal, request.GetCountry(), year)
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	response := &pb.ListRunnersResponse{}
+	for _, runner := range runners {
+		response.Runners = append(response.Runners, toRunner(runner))
+	}
+
+	return response, nil
+}
+
+func (rs *RunnerServer) CreateRunner(ctx context.Context, request *pb.CreateRunnerRequest) (*pb.Runner, error) {
+	// los administradores de club solo pueden modificar a los runners de su club, lo comprueba el servicio
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, responseErr := rs.runnersService.CreateRunner(ctx, principal, fromRunner(request.GetRunner()))
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	return toRunner(runner), nil
+}
+
+func (rs *RunnerServer) UpdateRunner(ctx context.Context, request *pb.UpdateRunnerRequest) (*emptypb.Empty, error) {
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	responseErr := rs.runnersService.UpdateRunner(ctx, principal, fromRunner(request.GetRunner()))
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (rs *RunnerServer) DeleteRunner(ctx context.Context, request *pb.DeleteRunnerRequest) (*emptypb.Empty, error) {
+	principal, err := authenticate(ctx, rs.usersService, roleAdmin, roleClubAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	responseErr := rs.runnersService.DeleteRunner(ctx, principal, request.GetId())
+	if responseErr != nil {
+		return nil, toStatus(ctx, responseErr)
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"runners-postgresql/models"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Puntuación mínima para que un runner se considere una coincidencia
+const MinScore = 0.4
+
+// Las coincidencias por prefijo puntúan al menos esto, por encima de cualquier coincidencia aproximada con erratas
+const PrefixScore = 0.8
+
+// letras que no se descomponen en letra base más diacrítico
+var foldings = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ı': "i",
+}
+
+// Normalize pasa el texto a minúsculas, le quita los acentos y sustituye todo lo que no sean letras o dígitos por un único espacio. Así "José-Luis  Pérez" queda como "jose luis perez"
+func Normalize(text string) string {
+	// NFD separa las letras acentuadas en la letra base y el diacrítico (categoría Mn), que después eliminamos
+	stripper := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(stripper, text)
+	if err != nil {
+		stripped = text
+	}
+
+	var builder strings.Builder
+	space := false
+	for _, r := range strings.ToLower(stripped) {
+		if folding, ok := foldings[r]; ok {
+			builder.WriteString(folding)
+			space = false
+			continue
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+			space = false
+			continue
+		}
+
+		if !space && builder.Len() > 0 {
+			builder.WriteByte(' ')
+			space = true
+		}
+	}
+
+	return strings.TrimSpace(builder.String())
+}
+
+// Score puntúa entre 0 y 1 cómo de bien encaja la búsqueda con el nombre del runner. Cada palabra de la búsqueda se compara con la palabra del nombre que mejor encaje:
+//   - si es el prefijo de la palabra puntúa entre 0.8 y 1, más cuanto más completa esté la palabra
+//   - si no, se usa la mayor de la similitud por trigramas y la similitud por distancia de edición, que tolera erratas y letras traspuestas, escalada para quedar por debajo de 0.8
+//
+// La puntuación del runner es la media de la de las palabras de la búsqueda. La búsqueda tiene que estar normalizada
+func Score(query string, runner *models.Runner) float64 {
+	queryWords := strings.Fields(query)
+	nameWords := strings.Fields(Normalize(runner.FirstName + " " + runner.LastName))
+	if len(queryWords) == 0 || len(nameWords) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, queryWord := range queryWords {
+		best := 0.0
+		for _, nameWord := range nameWords {
+			score := wordScore(queryWord, nameWord)
+			if score > best {
+				best = score
+			}
+		}
+		total += best
+	}
+
+	return total / float64(len(queryWords))
+}
+
+// Rank puntúa los runners activos, descarta los que no llegan a MinScore y devuelve la página pedida ordenada de mayor a menor puntuación, junto con el número total de coincidencias. Es el buscador de los backends que no tienen índices de trigramas: les basta con pasarle todos los runners
+func Rank(query string, runners []*models.Runner, limit int, offset int) ([]*models.RunnerMatch, int) {
+	matches := make([]*models.RunnerMatch, 0)
+	for _, runner := range runners {
+		if !runner.IsActive {
+			continue
+		}
+
+		score := Score(query, runner)
+		if score >= MinScore {
+			matches = append(matches, &models.RunnerMatch{Runner: runner, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return Less(matches[i], matches[j])
+	})
+
+	total := len(matches)
+	if offset >= total {
+		return make([]*models.RunnerMatch, 0), total
+	}
+
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	return matches[offset:end], total
+}
+
+// Less es el orden de los resultados de la búsqueda: por puntuación, y a igual puntuación por apellido, nombre e id
+func Less(a *models.RunnerMatch, b *models.RunnerMatch) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Runner.LastName != b.Runner.LastName {
+		return a.Runner.LastName < b.Runner.LastName
+	}
+	if a.Runner.FirstName != b.Runner.FirstName {
+		return a.Runner.FirstName < b.Runner.FirstName
+	}
+	return a.Runner.ID < b.Runner.ID
+}
+
+func wordScore(queryWord string, nameWord string) float64 {
+	query := []rune(queryWord)
+	name := []rune(nameWord)
+
+	if strings.HasPrefix(nameWord, queryWord) {
+		return PrefixScore + (1-PrefixScore)*float64(len(query))/float64(len(name))
+	}
+
+	score := trigramSimilarity(queryWord, nameWord)
+	if edit := editSimilarity(query, name); edit > score {
+		score = edit
+	}
+
+	// una errata en una palabra a medio escribir: comparamos también con el principio de la palabra del nombre
+	if len(name) > len(query) {
+		if edit := editSimilarity(query, name[:len(query)]); edit > score {
+			score = edit
+		}
+	}
+
+	// las coincidencias aproximadas nunca llegan a la puntuación de un prefijo exacto
+	return score * PrefixScore
+}
+
+// trigramSimilarity calcula la similitud como pg_trgm: la proporción de trigramas comunes entre los trigramas de las dos palabras, rellenadas con dos espacios al principio y uno al final
+func trigramSimilarity(a string, b string) float64 {
+	trigramsA := trigrams(a)
+	trigramsB := trigrams(b)
+
+	common := 0
+	for trigram := range trigramsA {
+		if _, ok := trigramsB[trigram]; ok {
+			common++
+		}
+	}
+
+	union := len(trigramsA) + len(trigramsB) - common
+	if union == 0 {
+		return 0
+	}
+
+	return float64(common) / float64(union)
+}
+
+func trigrams(word string) map[string]struct{} {
+	padded := []rune("  " + word + " ")
+	trigrams := make(map[string]struct{}, len(padded))
+	for i := 0; i+3 <= len(padded); i++ {
+		trigrams[string(padded[i:i+3])] = struct{}{}
+	}
+
+	return trigrams
+}
+
+// editSimilarity es 1 menos la distancia de edición (con trasposiciones) dividida por la longitud de la palabra más larga
+func editSimilarity(a []rune, b []rune) float64 {
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 0
+	}
+
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// editDistance es la distancia de Damerau-Levenshtein restringida: inserciones, borrados, sustituciones y trasposiciones de letras adyacentes
+func editDistance(a []rune, b []rune) int {
+	previous2 := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return previous[len(b)]
+}
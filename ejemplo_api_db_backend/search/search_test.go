@@ -0,0 +1,60 @@
+package search
+
+import (
+	"runners-postgresql/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "jose luis perez", Normalize("  José-Luis  PÉREZ "))
+	assert.Equal(t, "muller strasse", Normalize("Müller Straße"))
+	assert.Equal(t, "o brien", Normalize("O'Brien"))
+	assert.Equal(t, "lukasz", Normalize("Łukasz"))
+}
+
+func TestScore(t *testing.T) {
+	runner := &models.Runner{FirstName: "Eliud", LastName: "Kipchoge"}
+
+	// la palabra completa puntúa 1, y un prefijo más largo puntúa más que uno corto
+	assert.Equal(t, 1.0, Score("kipchoge", runner))
+	assert.Greater(t, Score("kipch", runner), Score("ki", runner))
+	assert.GreaterOrEqual(t, Score("ki", runner), PrefixScore)
+
+	// las erratas encajan, pero por debajo de los prefijos
+	typo := Score("kipchgoe", runner)
+	assert.GreaterOrEqual(t, typo, MinScore)
+	assert.Less(t, typo, PrefixScore)
+	assert.GreaterOrEqual(t, Score("eliu kpch", runner), MinScore)
+
+	assert.Less(t, Score("bekele", runner), MinScore)
+	assert.Equal(t, 0.0, Score("", runner))
+}
+
+func TestRank(t *testing.T) {
+	runners := []*models.Runner{
+		{ID: "1", FirstName: "Kenenisa", LastName: "Bekele", IsActive: true},
+		{ID: "2", FirstName: "José", LastName: "Pérez", IsActive: true},
+		{ID: "3", FirstName: "Pedro", LastName: "Pérez", IsActive: true},
+		{ID: "4", FirstName: "Ana", LastName: "Perea", IsActive: true},
+		{ID: "5", FirstName: "Pere", LastName: "Gil", IsActive: true},
+		// los runners dados de baja no aparecen en la búsqueda
+		{ID: "6", FirstName: "Luis", LastName: "Pérez"},
+	}
+
+	matches, total := Rank(Normalize("Pérez"), runners, 10, 0)
+	assert.Equal(t, 4, total)
+	// primero las coincidencias exactas, ordenadas por nombre, y después las aproximadas
+	assert.Equal(t, "2", matches[0].Runner.ID)
+	assert.Equal(t, "3", matches[1].Runner.ID)
+	assert.Equal(t, 1.0, matches[0].Score)
+	assert.Less(t, matches[2].Score, 1.0)
+
+	// paginación
+	matches, total = Rank("perez", runners, 2, 2)
+	assert.Equal(t, 4, total)
+	assert.Len(t, matches, 2)
+	matches, _ = Rank("perez", runners, 2, 10)
+	assert.Empty(t, matches)
+}
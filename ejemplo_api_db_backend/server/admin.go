@@ -0,0 +1,18 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"runners-postgresql/admin"
+)
+
+// InitAdmin carga las plantillas de la consola de administración. Están incluidas en el binario, así que un error es un fallo de la compilación
+func InitAdmin() *admin.Console {
+	console, err := admin.New()
+	if err != nil {
+		slog.Error("Error while loading admin console templates", "error", err)
+		os.Exit(1)
+	}
+
+	return console
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"log"
+	"runners-postgresql/cache"
+	"runners-postgresql/config"
+)
+
+// InitCache crea el almacenamiento de la caché según la configuración: "memory" (LRU en memoria), "redis" o "none"
+func InitCache(config config.CacheConfig) cache.Store {
+	backend := config.Backend
+
+	switch backend {
+	case "", "none":
+		return nil
+	case "memory":
+		return cache.NewLRU(config.Size)
+	case "redis":
+		return cache.NewRedis(
+			config.RedisAddress,
+			config.RedisPassword,
+			config.RedisDB,
+			config.RedisPoolSize,
+			config.RedisTimeout,
+		)
+	}
+
+	log.Fatalf("Unknown cache backend %s", backend)
+	return nil
+}
@@ -2,29 +2,30 @@ package server
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
+	"os"
+	"runners-postgresql/config"
+	"runners-postgresql/tracing"
 
-	"github.com/spf13/viper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
-func InitDatabase(config *viper.Viper) *sql.DB {
-	// cadena de conexión a la base de datos
-	connectionString := config.GetString("database.connection_string")
+func InitDatabase(config config.DatabaseConfig) *sql.DB {
+	// cadena de conexión a la base de datos. La configuración ya está validada, así que no puede faltar
+	connectionString := config.ConnectionString
 	// configuramos las conexones a mantener abiertas, máximas y el tiempo máximo de vida de una conexión
-	maxIdleConnections := config.GetInt("database.max_idle_connections")
-	maxOpenConnections := config.GetInt("database.max_open_connections")
-	connectionMaxLifetime := config.GetDuration("database.connection_max_lifetime")
+	maxIdleConnections := config.MaxIdleConnections
+	maxOpenConnections := config.MaxOpenConnections
+	connectionMaxLifetime := config.ConnectionMaxLifetime
 	// obtenemos el nombre del driver de base de datos
-	driverName := config.GetString("database.driver_name")
+	driverName := config.DriverName
 
-	if connectionString == "" {
-		log.Fatalf("Database connectin string is missing")
-	}
-
-	// creamos la conexión a la base de datos
-	dbHandler, err := sql.Open(driverName, connectionString)
+	// creamos la conexión a la base de datos. Cada consulta de una petición crea su span en la traza de la petición
+	dbHandler, err := tracing.OpenDB(driverName, connectionString)
 	if err != nil {
-		log.Fatalf("Error while initializing database: %v", err)
+		slog.Error("Error while initializing database", "error", err)
+		os.Exit(1)
 	}
 
 	dbHandler.SetMaxIdleConns(maxIdleConnections)
@@ -35,8 +36,12 @@ func InitDatabase(config *viper.Viper) *sql.DB {
 	err = dbHandler.Ping()
 	if err != nil {
 		dbHandler.Close()
-		log.Fatalf("Error while validating database: %v", err)
+		slog.Error("Error while validating database", "error", err)
+		os.Exit(1)
 	}
 
+	// exportamos las estadísticas del pool de conexiones (go_sql_open_connections, go_sql_idle_connections, go_sql_wait_count_total, go_sql_wait_duration_seconds_total...)
+	prometheus.MustRegister(collectors.NewDBStatsCollector(dbHandler, "runners"))
+
 	return dbHandler
 }
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"runners-postgresql/config"
+	"runners-postgresql/gql"
+	"runners-postgresql/services"
+)
+
+// InitGraphQL crea el esquema de GraphQL sobre los servicios de runners y resultados
+func InitGraphQL(config config.GraphQLConfig, runnersService *services.RunnersService, resultsService *services.ResultsService) *gql.Schema {
+	schema, err := gql.NewSchema(runnersService, resultsService, gql.Limits{
+		MaxDepth:      config.MaxDepth,
+		MaxComplexity: config.MaxComplexity,
+	})
+	if err != nil {
+		slog.Error("Error while creating GraphQL schema", "error", err)
+		os.Exit(1)
+	}
+
+	return schema
+}
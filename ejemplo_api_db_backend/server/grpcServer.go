@@ -0,0 +1,39 @@
+package server
+
+import (
+	"runners-postgresql/certs"
+	"runners-postgresql/config"
+	"runners-postgresql/lifecycle"
+	"runners-postgresql/live"
+	"runners-postgresql/pb"
+	"runners-postgresql/rpc"
+	"runners-postgresql/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+)
+
+// InitGrpcServer crea el servidor gRPC con los mismos servicios que la API REST, o devuelve nil si no está activado. Tiene su propia configuración TLS (grpc.tls), con roles para los certificados de cliente como en http.tls
+func InitGrpcServer(config *config.Config, runnersService *services.RunnersService, resultsService *services.ResultsService, usersService *services.UsersService, liveHub *live.Hub, manager *lifecycle.Manager) *grpc.Server {
+	if !config.GRPC.Enabled {
+		return nil
+	}
+
+	identities := certs.NewIdentities(config.GRPC.TLS.ClientRoles)
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(rpc.UnaryInterceptor(identities)),
+		grpc.ChainStreamInterceptor(rpc.StreamInterceptor(identities)),
+	}
+	if tlsConfig := InitTLS("gRPC server", config.GRPC.TLS, manager); tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(options...)
+	pb.RegisterRunnerServiceServer(server, rpc.NewRunnerServer(runnersService, usersService))
+	pb.RegisterResultServiceServer(server, rpc.NewResultServer(resultsService, runnersService, usersService, liveHub))
+	// la reflexión permite usar clientes genéricos como grpcurl sin el archivo .proto
+	reflection.Register(server)
+
+	return server
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runners-postgresql/cache"
+	"runners-postgresql/config"
+	"runners-postgresql/health"
+	"runners-postgresql/lifecycle"
+)
+
+// scripts de dbscripts que tiene que tener aplicados la base de datos, y cómo saber si lo están
+var postgresMigrations = []health.Migration{
+	{Script: "public_schema.sql", Query: "SELECT to_regclass('runners') IS NOT NULL AND to_regclass('results') IS NOT NULL"},
+	{Script: "update_schema.sql", Query: "SELECT to_regclass('users') IS NOT NULL"},
+	{Script: "webhooks_schema.sql", Query: "SELECT to_regclass('outbox_events') IS NOT NULL AND to_regclass('webhook_deliveries') IS NOT NULL"},
+	{Script: "search_schema.sql", Query: "SELECT to_regprocedure('runner_search_name(text, text)') IS NOT NULL"},
+	{Script: "clubs_schema.sql", Query: columnExists("users", "club_id")},
+	{Script: "self_service_schema.sql", Query: columnExists("users", "runner_id")},
+	{Script: "stats_schema.sql", Query: columnExists("results", "distance")},
+	{Script: "splits_schema.sql", Query: "SELECT to_regclass('result_splits') IS NOT NULL"},
+	{Script: "apikeys_schema.sql", Query: "SELECT to_regclass('api_keys') IS NOT NULL"},
+	{Script: "oidc_schema.sql", Query: columnExists("users", "oidc_subject")},
+	{Script: "audit_schema.sql", Query: "SELECT to_regclass('audit_log') IS NOT NULL"},
+}
+
+func columnExists(table string, column string) string {
+	return "SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = '" + table + "' AND column_name = '" + column + "')"
+}
+
+// InitHealth registra las comprobaciones de salud de la aplicación con Postgres. Las variantes con otras bases de datos registran las suyas
+func InitHealth(config config.HealthConfig, manager *lifecycle.Manager, dbHandler *sql.DB, cacheStore cache.Store) *health.Registry {
+	registry := health.NewRegistry(config.Timeout)
+
+	// mientras arranca o se detiene la aplicación no tiene que recibir tráfico
+	registry.Register("lifecycle", true, func(ctx context.Context) error {
+		if !manager.Ready() {
+			return errors.New("application is starting or shutting down")
+		}
+		return nil
+	})
+	registry.Register("database", true, health.Database(dbHandler))
+	registry.Register("database_pool", true, health.Pool(dbHandler, config.PoolSaturation))
+	registry.Register("migrations", true, health.Migrations(dbHandler, postgresMigrations))
+
+	// sin la caché compartida la aplicación funciona, más lenta, así que no afecta a readiness
+	if redis, ok := cacheStore.(*cache.Redis); ok {
+		registry.Register("cache", false, redis.Ping)
+	}
+
+	return registry
+}
@@ -3,70 +3,303 @@ package server
 import (
 	"database/sql"
 	"log"
+	"net/http"
+	"runners-postgresql/admin"
+	"runners-postgresql/apikeys"
+	"runners-postgresql/cache"
+	"runners-postgresql/certs"
+	"runners-postgresql/config"
 	"runners-postgresql/controllers"
+	"runners-postgresql/lifecycle"
+	"runners-postgresql/live"
+	"runners-postgresql/logging"
+	"runners-postgresql/metrics"
+	"runners-postgresql/openapi"
 	"runners-postgresql/repositories"
 	"runners-postgresql/services"
+	"runners-postgresql/tracing"
+	"runners-postgresql/versioning"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 )
 
 // Servidor HTTP que maneja las solicitudes entrantes
 type HttpServer struct {
-	config            *viper.Viper
-	router            *gin.Engine
-	runnersController *controllers.RunnersController
-	resultsController *controllers.ResultsController
-	usersController   *controllers.UsersController
+	config             *config.Config
+	router             *gin.Engine
+	runnersController  *controllers.RunnersController
+	resultsController  *controllers.ResultsController
+	usersController    *controllers.UsersController
+	exportController   *controllers.ExportController
+	webhooksController *controllers.WebhooksController
+	webhookDispatcher  *services.WebhookDispatcher
+	liveHub            *live.Hub
+	liveController     *controllers.LiveController
+	clubsController    *controllers.ClubsController
+	healthController   *controllers.HealthController
+	graphqlController  *controllers.GraphQLController
+	apiKeysController  *controllers.APIKeysController
+	oidcController     *controllers.OIDCController
+	adminController    *controllers.AdminController
+	grpcServer         *grpc.Server
 }
 
-func InitHttpServer(config *viper.Viper, dbHandler *sql.DB) HttpServer {
+func InitHttpServer(config *config.Config, dbHandler *sql.DB, manager *lifecycle.Manager, reloader *config.Reloader) HttpServer {
 	// Crea el repositorio
 	runnersRepository := repositories.NewRunnersRepository(dbHandler)
 	resultRepository := repositories.NewResultsRepository(dbHandler)
 	usersRepository := repositories.NewUsersRepository(dbHandler)
+	outboxRepository := repositories.NewOutboxRepository(dbHandler)
+	webhooksRepository := repositories.NewWebhooksRepository(dbHandler)
+	clubsRepository := repositories.NewClubsRepository(dbHandler)
+	apiKeysRepository := repositories.NewAPIKeysRepository(dbHandler)
+	auditRepository := repositories.NewAuditRepository(dbHandler)
+
+	// el hub reparte entre los suscriptores del feed en directo los eventos confirmados
+	liveHub := live.NewHub(config.Live.ReplaySize, config.Live.QueueSize)
+
+	// cachés de lectura delante de los repositorios. Comparten el almacenamiento, y cada una usa su prefijo en las claves
+	cacheStore := InitCache(config.Cache)
+	runnersCache := cache.New("runners", cacheStore, config.Cache.TTL)
+	rolesCache := cache.New("roles", cacheStore, config.Cache.RolesTTL)
 
 	// Crea los servicios
-	runnersService := services.NewRunnersService(runnersRepository, resultRepository)
-	resultsService := services.NewResultsService(resultRepository, runnersRepository)
-	usersService := services.NewUsersService(usersRepository)
+	runnersService := services.NewRunnersService(runnersRepository, resultRepository, outboxRepository, clubsRepository, runnersCache)
+	resultsService := services.NewResultsService(resultRepository, runnersRepository, outboxRepository, clubsRepository, liveHub, runnersCache)
+	usersService := services.NewUsersService(usersRepository, apiKeysRepository, rolesCache)
+	exportService := services.NewExportService(runnersRepository, resultRepository)
+	webhooksService := services.NewWebhooksService(webhooksRepository, outboxRepository)
+	clubsService := services.NewClubsService(clubsRepository)
+	apiKeysService := services.NewAPIKeysService(apiKeysRepository, clubsRepository, rolesCache)
+	oidcService := InitOIDC(config.OIDC, cacheStore, usersService)
+	auditService := services.NewAuditService(auditRepository)
+
+	// el dispatcher entrega a los webhooks los eventos publicados en el outbox
+	webhookDispatcher := services.NewWebhookDispatcher(outboxRepository, services.WebhookDispatcherConfig{
+		PollInterval:   config.Webhooks.PollInterval,
+		BatchSize:      config.Webhooks.BatchSize,
+		MaxAttempts:    config.Webhooks.MaxAttempts,
+		RetryBaseDelay: config.Webhooks.RetryBaseDelay,
+		RetryMaxDelay:  config.Webhooks.RetryMaxDelay,
+		RequestTimeout: config.Webhooks.RequestTimeout,
+	})
 
 	// Crea el controller
 	runnersController := controllers.NewRunnersController(runnersService, usersService)
 	resultsController := controllers.NewResultsController(resultsService, usersService)
 	usersController := controllers.NewUsersController(usersService)
+	exportController := controllers.NewExportController(exportService, usersService)
+	webhooksController := controllers.NewWebhooksController(webhooksService, usersService)
+	liveController := controllers.NewLiveController(liveHub, usersService, runnersService, config.Live.Heartbeat)
+	clubsController := controllers.NewClubsController(clubsService, usersService)
+	healthController := controllers.NewHealthController(InitHealth(config.Health, manager, dbHandler, cacheStore), usersService)
+	graphqlController := controllers.NewGraphQLController(InitGraphQL(config.GraphQL, runnersService, resultsService), usersService)
+	apiKeysController := controllers.NewAPIKeysController(apiKeysService, usersService)
+	oidcController := controllers.NewOIDCController(oidcService)
+	adminController := controllers.NewAdminController(InitAdmin(), runnersService, resultsService, usersService, clubsService, auditService)
+
+	// la API gRPC usa los mismos servicios que los controladores
+	grpcServer := InitGrpcServer(config, runnersService, resultsService, usersService, liveHub, manager)
 
 	// instancia el router de Gin...
-	router := gin.Default()
+	router := gin.New()
+	// Gin toma la IP del cliente de X-Forwarded-For solo si la petición llega de uno de los proxies de confianza. Si no, un cliente podría cambiar de IP en cada petición y saltarse los límites
+	err := router.SetTrustedProxies(config.HTTP.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	// un span por petición, que continúa la traza de la cabecera traceparent, y un logger por petición con su X-Request-ID, que también escribe el log de acceso
+	router.Use(tracing.Middleware(), logging.Middleware(), gin.Recovery())
+	// métricas RED de todas las rutas
+	router.Use(metrics.Middleware())
+	// los servicios internos que se autentican con un certificado de cliente tienen el rol asociado a su sujeto
+	if config.HTTP.TLS.ClientAuth != "none" {
+		router.Use(certs.Middleware(config.HTTP.TLS.ClientRoles))
+	}
+	// los sistemas externos se autentican con una clave de API en la cabecera Authorization, con el permiso que necesita cada ruta
+	router.Use(apikeys.Middleware())
+	// límites de peticiones por cliente y de peticiones en curso, que se recargan con SIGHUP. Las sondas y el feed en directo, que mantiene la conexión abierta, no cuentan
+	limiter := InitRateLimit(config, usersService)
+	reloader.OnReload(limiter.Reload)
+	router.Use(limiter.Middleware("/healthz", "/readyz", "/live/results"))
+	// las peticiones que no cumplen la especificación de la API se rechazan antes de llegar a los controladores
+	doc, specHandler := InitOpenAPI()
+	if config.OpenAPI.ValidateRequests {
+		router.Use(openapi.Middleware(doc, openapi.Options{}))
+	}
+
+	// ...y define las rutas y los controladores asociados. Las sondas, la especificación, GraphQL y el login con el proveedor de identidad no tienen versión
+	router.GET("/healthz", healthController.Liveness)
+	router.GET("/readyz", healthController.Readiness)
+	router.GET("/health", healthController.Health)
 
-	// ...y define las rutas y los controladores asociados
-	router.POST("/runner", runnersController.CreateRunner)
-	router.PUT("/runner", runnersController.UpdateRunner)
-	router.DELETE("/runner/:id", runnersController.DeleteRunner)
-	router.GET("/runner/:id", runnersController.GetRunner)
-	router.GET("/runner", runnersController.GetRunnersBatch)
+	// especificación de la API y Swagger UI para consultarla
+	router.GET("/openapi.json", specHandler)
+	router.GET("/docs/*file", openapi.UIHandler("/docs"))
 
-	router.POST("/result", resultsController.CreateResult)
-	router.DELETE("/result/:id", resultsController.DeleteResult)
+	router.POST("/graphql", graphqlController.Query)
 
-	router.POST("/login", usersController.Login)
-	router.POST("/logout", usersController.Logout)
+	// login con el proveedor de identidad externo. La URL del callback está registrada en el proveedor, así que no cambia con la versión
+	router.GET("/auth/oidc/login", oidcController.Login)
+	router.GET("/auth/oidc/callback", oidcController.Callback)
+
+	hs := HttpServer{
+		config:             config,
+		router:             router,
+		runnersController:  runnersController,
+		resultsController:  resultsController,
+		usersController:    usersController,
+		exportController:   exportController,
+		webhooksController: webhooksController,
+		webhookDispatcher:  webhookDispatcher,
+		liveHub:            liveHub,
+		liveController:     liveController,
+		clubsController:    clubsController,
+		healthController:   healthController,
+		graphqlController:  graphqlController,
+		apiKeysController:  apiKeysController,
+		oidcController:     oidcController,
+		adminController:    adminController,
+		grpcServer:         grpcServer,
+	}
+
+	// el resto de la API tiene un grupo de rutas por versión
+	for _, version := range versioning.Versions {
+		hs.registerAPI(router.Group("/"+version, versioning.Middleware(version, config.Versioning)), version)
+	}
+
+	// la consola de administración no forma parte de la API: no tiene versión ni está en la especificación
+	hs.registerAdmin(router.Group(admin.Prefix, admin.CSRF()))
 
 	// devuelve el servidor HTTP configurado
-	return HttpServer{
-		config:            config,
-		router:            router,
-		runnersController: runnersController,
-		resultsController: resultsController,
-		usersController:   usersController,
+	return hs
+}
+
+// registerAPI define las rutas de una versión de la API. Las versiones solo se diferencian en los contratos que han cambiado
+func (hs HttpServer) registerAPI(router *gin.RouterGroup, version string) {
+	router.POST("/runner", hs.runnersController.CreateRunner)
+	router.POST("/runner/batch", hs.runnersController.CreateRunners)
+	if version == versioning.V1 {
+		// en v1 el id del runner va en el cuerpo
+		router.PUT("/runner", hs.runnersController.UpdateRunner)
+	} else {
+		router.PUT("/runner/:id", hs.runnersController.UpdateRunnerV2)
+	}
+	router.DELETE("/runner/:id", hs.runnersController.DeleteRunner)
+	router.GET("/runner/search", hs.runnersController.SearchRunners)
+	router.GET("/runner/:id", hs.runnersController.GetRunner)
+	router.GET("/runner/:id/clubs", hs.clubsController.GetRunnerClubs)
+	router.GET("/runner/:id/stats", hs.runnersController.GetRunnerStats)
+	router.GET("/runner", hs.runnersController.GetRunnersBatch)
+	router.PUT("/runner/:id/user", hs.runnersController.LinkUser)
+
+	router.POST("/result", hs.resultsController.CreateResult)
+	router.POST("/result/batch", hs.resultsController.CreateResults)
+	router.DELETE("/result/:id", hs.resultsController.DeleteResult)
+	router.GET("/result", hs.resultsController.GetSubmissions)
+	router.POST("/result/:id/approve", hs.resultsController.ApproveResult)
+	router.POST("/result/:id/reject", hs.resultsController.RejectResult)
+
+	// autoservicio de los runners con cuenta de usuario asociada
+	router.GET("/me/runner", hs.runnersController.GetOwnRunner)
+	router.PUT("/me/runner", hs.runnersController.UpdateOwnRunner)
+	router.POST("/me/result", hs.resultsController.SubmitResult)
+
+	router.POST("/login", hs.usersController.Login)
+	router.POST("/logout", hs.usersController.Logout)
+
+	router.GET("/export/runners", hs.exportController.ExportRunners)
+	router.GET("/export/results", hs.exportController.ExportResults)
+
+	router.POST("/webhook", hs.webhooksController.CreateSubscription)
+	router.GET("/webhook", hs.webhooksController.GetAllSubscriptions)
+	router.GET("/webhook/:id", hs.webhooksController.GetSubscription)
+	router.PUT("/webhook/:id", hs.webhooksController.UpdateSubscription)
+	router.DELETE("/webhook/:id", hs.webhooksController.DeleteSubscription)
+	router.GET("/webhook/:id/deliveries", hs.webhooksController.GetDeliveries)
+	router.POST("/webhook/:id/deliveries/:delivery/retry", hs.webhooksController.RetryDelivery)
+
+	router.GET("/live/results", hs.liveController.Results)
+
+	router.POST("/club", hs.clubsController.CreateClub)
+	router.GET("/club", hs.clubsController.GetAllClubs)
+	router.GET("/club/:id", hs.clubsController.GetClub)
+	router.PUT("/club/:id", hs.clubsController.UpdateClub)
+	router.PUT("/club/:id/admin", hs.clubsController.SetClubAdmin)
+	router.GET("/club/:id/members", hs.clubsController.GetMembers)
+	router.POST("/club/:id/members", hs.clubsController.AddMember)
+	router.DELETE("/club/:id/members/:runner", hs.clubsController.RemoveMember)
+	router.GET("/club/:id/leaderboard", hs.clubsController.GetLeaderboard)
+
+	router.POST("/apikey", hs.apiKeysController.CreateAPIKey)
+	router.GET("/apikey", hs.apiKeysController.GetAllAPIKeys)
+	router.DELETE("/apikey/:id", hs.apiKeysController.RevokeAPIKey)
+}
+
+// registerAdmin define las rutas de la consola de administración. Todas tienen protección CSRF, y todas menos el login y los archivos estáticos requieren la sesión de un administrador
+func (hs HttpServer) registerAdmin(router *gin.RouterGroup) {
+	router.GET("/static/*file", admin.StaticHandler())
+	router.GET("/login", hs.adminController.LoginForm)
+	router.POST("/login", hs.adminController.Login)
+	router.POST("/logout", hs.adminController.Logout)
+
+	pages := router.Group("", hs.adminController.Authenticate)
+	pages.GET("", hs.adminController.Index)
+	pages.GET("/runners", hs.adminController.GetRunners)
+	pages.GET("/runners/:id", hs.adminController.GetRunner)
+	pages.POST("/runners/:id", hs.adminController.UpdateRunner)
+	pages.POST("/runners/:id/results", hs.adminController.CreateResult)
+	pages.GET("/users", hs.adminController.GetUsers)
+	pages.POST("/users", hs.adminController.CreateUser)
+	pages.POST("/users/:username/role", hs.adminController.SetUserRole)
+	pages.GET("/audit", hs.adminController.GetAuditLog)
+}
+
+// Register da de alta en el gestor del ciclo de vida el dispatcher de los webhooks, el servidor gRPC si está activado y el servidor HTTP. Al parar, primero se drena el servidor HTTP, después el gRPC y por último se detiene el dispatcher
+func (hs HttpServer) Register(manager *lifecycle.Manager) {
+	manager.Register(lifecycle.Background("webhook dispatcher", hs.webhookDispatcher.Run))
+
+	// al parar el servidor HTTP se cierra el feed en directo, así que cuando se para el servidor gRPC las llamadas a WatchResults ya han terminado
+	if hs.grpcServer != nil {
+		manager.Register(lifecycle.GrpcServer("gRPC server", hs.grpcServer, hs.config.GRPC.Address))
 	}
+
+	// si solo especificamos el puerto en la configuración (por ejemplo, ":8080"), el servidor escuchará en todas las interfaces de red disponibles
+	// las peticiones sin versión en la ruta se llevan a la versión que piden en la cabecera Accept, o a la de la configuración
+	handler := versioning.Handler(streamingHandler(hs.router, "/live/", "/export/"), hs.config.Versioning.Default, "/healthz", "/readyz", "/health", "/openapi.json", "/docs/", "/graphql", "/auth/", "/admin", "/admin/")
+	server := newServer(hs.config.HTTP.ServerAddress, handler, hs.config.HTTP)
+	// con HTTPS el gestor del ciclo de vida arranca el servidor con ServeTLS
+	server.TLSConfig = InitTLS("HTTP server", hs.config.HTTP.TLS, manager)
+	// Shutdown no espera a las conexiones secuestradas (WebSocket), y las de SSE no terminan solas: cerramos el feed en directo para que terminen
+	server.RegisterOnShutdown(hs.liveHub.Close)
+	manager.Register(lifecycle.Server("HTTP server", server))
 }
 
-// implementa el método Start para el apigateway HTTP (router de Gin)
-func (hs HttpServer) Start() {
-	// arrancar significa arrancar el router en la dirección indicada en la configuración. Si en la configuración solo especificamos el puerto (por ejemplo, ":8080"), el servidor escuchará en todas las interfaces de red disponibles.
-	err := hs.router.Run(hs.config.GetString("http.server_address"))
-	if err != nil {
-		log.Fatalf("Error while starting HTTP server: %v", err)
+// newServer crea un servidor HTTP con los tiempos máximos de la configuración
+func newServer(address string, handler http.Handler, config config.HTTPConfig) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
 	}
 }
+
+// streamingHandler quita el tiempo máximo de escritura a las respuestas en streaming (feed en directo y exportaciones), que duran lo que haga falta. Los prefijos no llevan la versión
+func streamingHandler(handler http.Handler, prefixes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(versioning.Unversioned(r.URL.Path), prefix) {
+				http.NewResponseController(w).SetWriteDeadline(time.Time{})
+				break
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
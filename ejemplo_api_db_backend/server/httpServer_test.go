@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runners-postgresql/admin"
+	"runners-postgresql/config"
+	"runners-postgresql/lifecycle"
+	"runners-postgresql/openapi"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// las rutas del router y las operaciones de la especificación tienen que ser las mismas
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	// usa la configuración de ejemplo del repositorio
+	t.Chdir("..")
+	t.Setenv("RUNNERS_OPENAPI_VALIDATE_REQUESTS", "true")
+	runnersConfig, err := config.Load("runners")
+	require.NoError(t, err)
+
+	dbHandler, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+
+	manager := lifecycle.New(time.Second, 0)
+	hs := InitHttpServer(runnersConfig, dbHandler, manager, config.NewReloader("runners", runnersConfig))
+
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+	// cada operación de la especificación con el prefijo de sus servidores: "/v1", "/v2" o ninguno
+	specified := make(map[string]bool)
+	for route := range openapi.Routes(doc) {
+		specified[route] = true
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range hs.router.Routes() {
+		// Swagger UI y la consola de administración no forman parte de la API
+		if route.Path == "/docs/*file" || strings.HasPrefix(route.Path, admin.Prefix) {
+			continue
+		}
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for route := range registered {
+		assert.True(t, specified[route], "route %s is not in the API specification", route)
+	}
+	for route := range specified {
+		assert.True(t, registered[route], "operation %s is not registered in the router", route)
+	}
+}
+
+// la IP del cliente solo se toma de X-Forwarded-For si la petición llega de un proxy de confianza
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	t.Chdir("..")
+	t.Setenv("RUNNERS_RATE_LIMIT_ENABLED", "true")
+	t.Setenv("RUNNERS_RATE_LIMIT_BURST", "1")
+
+	dbHandler, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+
+	newRouter := func() http.Handler {
+		runnersConfig, err := config.Load("runners")
+		require.NoError(t, err)
+		return InitHttpServer(runnersConfig, dbHandler, lifecycle.New(time.Second, 0), config.NewReloader("runners", runnersConfig)).router
+	}
+	get := func(router http.Handler, forwardedFor string) int {
+		request := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// sin proxies de confianza cambiar la cabecera no da una cuenta nueva
+	router := newRouter()
+	assert.Equal(t, http.StatusOK, get(router, "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, get(router, "198.51.100.2"))
+
+	// detrás de un proxy de confianza cada cliente tiene su cuenta
+	t.Setenv("RUNNERS_HTTP_TRUSTED_PROXIES", "192.0.2.0/24")
+	router = newRouter()
+	assert.Equal(t, http.StatusOK, get(router, "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, get(router, "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, get(router, "198.51.100.2"))
+}
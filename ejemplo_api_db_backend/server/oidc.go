@@ -0,0 +1,36 @@
+package server
+
+import (
+	"runners-postgresql/cache"
+	"runners-postgresql/config"
+	"runners-postgresql/oidc"
+	"runners-postgresql/services"
+)
+
+// InitOIDC crea el servicio del login con OpenID Connect, o nil si no está activado. Los logins pendientes se guardan en la caché
+func InitOIDC(config config.OIDCConfig, store cache.Store, usersService *services.UsersService) *services.OIDCService {
+	if !config.Enabled {
+		return nil
+	}
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       config.Issuer,
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  config.RedirectURL,
+		Scopes:       config.Scopes,
+		Timeout:      config.Timeout,
+	})
+
+	roles := make([]services.OIDCRole, 0, len(config.RoleMappings))
+	for _, mapping := range config.RoleMappings {
+		roles = append(roles, services.OIDCRole{Value: mapping.Value, Role: mapping.Role, ClubID: mapping.ClubID})
+	}
+
+	return services.NewOIDCService(provider, store, usersService, services.OIDCConfig{
+		RoleClaim:   config.RoleClaim,
+		DefaultRole: config.DefaultRole,
+		Roles:       roles,
+		StateTTL:    config.StateTTL,
+	})
+}
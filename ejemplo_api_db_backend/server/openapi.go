@@ -0,0 +1,27 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"runners-postgresql/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// InitOpenAPI carga la especificación de la API y devuelve el handler que la sirve. La especificación va dentro del binario, así que si no es válida es un error de compilación más que de configuración
+func InitOpenAPI() (*openapi3.T, gin.HandlerFunc) {
+	doc, err := openapi.Load()
+	if err != nil {
+		slog.Error("Error while loading API specification", "error", err)
+		os.Exit(1)
+	}
+
+	specHandler, err := openapi.SpecHandler(doc)
+	if err != nil {
+		slog.Error("Error while serializing API specification", "error", err)
+		os.Exit(1)
+	}
+
+	return doc, specHandler
+}
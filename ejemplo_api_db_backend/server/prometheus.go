@@ -2,12 +2,19 @@ package server
 
 import (
 	"net/http"
+	"runners-postgresql/config"
+	"runners-postgresql/lifecycle"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// definimos el exporter de métricas de Prometheus. En este caso, exponemos las métricas en el endpoint /metrics del puerto 9000
-func InitPrometheus() {
-	http.Handle("/metrics", promhttp.Handler()) // endpoint en el que expondremos las métricas
-	http.ListenAndServe(":9000", nil)
+// definimos el exporter de métricas de Prometheus. Exponemos las métricas en el endpoint /metrics de la dirección indicada en la configuración (metrics.address). El servidor lo arranca y lo detiene el gestor del ciclo de vida. Tiene su propia configuración TLS (metrics.tls), de modo que Prometheus puede autenticarse con un certificado distinto al de los clientes de la API
+func InitPrometheus(config *config.Config, manager *lifecycle.Manager) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler()) // endpoint en el que expondremos las métricas
+
+	server := newServer(config.Metrics.Address, mux, config.HTTP)
+	server.TLSConfig = InitTLS("Prometheus exporter", config.Metrics.TLS, manager)
+
+	return server
 }
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"runners-postgresql/admin"
+	"runners-postgresql/cache"
+	"runners-postgresql/config"
+	"runners-postgresql/models"
+	"runners-postgresql/ratelimit"
+	"runners-postgresql/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitRateLimit crea el limitador de peticiones con el almacenamiento de la configuración. Con "redis" todas las réplicas comparten la cuenta de cada cliente, usando el servidor de la sección cache. Los clientes autenticados se identifican con usersService
+func InitRateLimit(config *config.Config, usersService *services.UsersService) *ratelimit.Limiter {
+	var store ratelimit.Store = ratelimit.NewMemory()
+	if config.RateLimit.Store == "redis" {
+		store = ratelimit.NewRedis(cache.NewRedis(
+			config.Cache.RedisAddress,
+			config.Cache.RedisPassword,
+			config.Cache.RedisDB,
+			config.Cache.RedisPoolSize,
+			config.Cache.RedisTimeout,
+		))
+	}
+
+	// los usuarios se identifican con el token de la cabecera o, en la consola de administración, con el de la sesión
+	return ratelimit.NewLimiter(store, config.RateLimit, func(ctx *gin.Context) *models.Principal {
+		accessToken := ctx.GetHeader("Token")
+		if accessToken == "" {
+			accessToken = admin.Session(ctx)
+		}
+		return usersService.Identify(ctx.Request.Context(), accessToken)
+	})
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"runners-postgresql/certs"
+	"runners-postgresql/config"
+	"runners-postgresql/lifecycle"
+)
+
+// InitTLS devuelve la configuración TLS de un servidor, o nil si el servidor no usa HTTPS. Los certificados se vuelven a leer periódicamente hasta que se detiene la aplicación
+func InitTLS(name string, config config.TLSConfig, manager *lifecycle.Manager) *tls.Config {
+	if !config.Enabled() {
+		return nil
+	}
+
+	reloader, err := certs.NewReloader(name, config)
+	if err != nil {
+		slog.Error("Error while loading TLS certificate", "server", name, "error", err)
+		os.Exit(1)
+	}
+	manager.Register(lifecycle.Background(name+" certificate reloader", reloader.Watch))
+
+	return reloader.TLSConfig()
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/apikeys"
+	"runners-postgresql/cache"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"runners-postgresql/tracing"
+	"time"
+)
+
+type APIKeysService struct {
+	apiKeysRepository *repositories.APIKeysRepository
+	clubsRepository   *repositories.ClubsRepository
+	rolesCache        *cache.ReadThrough
+}
+
+func NewAPIKeysService(apiKeysRepository *repositories.APIKeysRepository, clubsRepository *repositories.ClubsRepository, rolesCache *cache.ReadThrough) *APIKeysService {
+	return &APIKeysService{
+		apiKeysRepository: apiKeysRepository,
+		clubsRepository:   clubsRepository,
+		rolesCache:        rolesCache,
+	}
+}
+
+// CreateAPIKey crea una clave de API. La clave solo se devuelve en la respuesta: en la base de datos se guarda su hash
+func (as APIKeysService) CreateAPIKey(ctx context.Context, apiKey *models.APIKey) (*models.APIKey, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "APIKeysService.CreateAPIKey")
+	defer span.End()
+
+	responseErr := validateAPIKey(apiKey, time.Now())
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if apiKey.ClubID != "" {
+		_, responseErr := as.clubsRepository.GetClub(ctx, apiKey.ClubID)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+	}
+
+	key, prefix, err := apikeys.Generate()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: "Failed to generate API key",
+			Status:  http.StatusInternalServerError,
+		}
+	}
+	apiKey.Key = key
+	apiKey.Prefix = prefix
+	apiKey.Hash = apikeys.Hash(key)
+
+	response, responseErr := as.apiKeysRepository.CreateAPIKey(ctx, apiKey)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	logging.Info(ctx, "API key created", "name", response.Name, "prefix", response.Prefix, "role", response.Role)
+	return response, nil
+}
+
+// RevokeAPIKey revoca la clave, que deja de funcionar de inmediato
+func (as APIKeysService) RevokeAPIKey(ctx context.Context, apiKeyId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "APIKeysService.RevokeAPIKey")
+	defer span.End()
+
+	if apiKeyId == "" {
+		return &models.ResponseError{
+			Message: "Invalid API key ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	hash, responseErr := as.apiKeysRepository.RevokeAPIKey(ctx, apiKeyId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	as.rolesCache.Invalidate(ctx, apiKeyCacheKey(hash))
+	logging.Info(ctx, "API key revoked", "id", apiKeyId)
+
+	return nil
+}
+
+func (as APIKeysService) GetAllAPIKeys(ctx context.Context) ([]*models.APIKey, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "APIKeysService.GetAllAPIKeys")
+	defer span.End()
+
+	return as.apiKeysRepository.GetAllAPIKeys(ctx)
+}
+
+func validateAPIKey(apiKey *models.APIKey, now time.Time) *models.ResponseError {
+	if apiKey.Name == "" {
+		return &models.ResponseError{
+			Message: "Invalid name",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// las claves de API pueden tener los mismos roles que los certificados de cliente
+	if apiKey.Role != "admin" && apiKey.Role != models.RoleClubAdmin {
+		return &models.ResponseError{
+			Message: "Invalid role",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// los administradores de club necesitan su club, y el resto de roles no tienen
+	if (apiKey.Role == models.RoleClubAdmin) != (apiKey.ClubID != "") {
+		return &models.ResponseError{
+			Message: "Invalid club ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if len(apiKey.Scopes) == 0 {
+		return &models.ResponseError{
+			Message: "Invalid scopes",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	for _, scope := range apiKey.Scopes {
+		valid := false
+		for _, knownScope := range models.APIKeyScopes {
+			if scope == knownScope {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return &models.ResponseError{
+				Message: "Invalid scope " + scope,
+				Status:  http.StatusBadRequest,
+			}
+		}
+	}
+
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		return &models.ResponseError{
+			Message: "Invalid expiration date",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return nil
+}
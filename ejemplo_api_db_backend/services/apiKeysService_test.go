@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/apikeys"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+
+	usersService := NewUsersService(repositories.NewUsersRepository(dbHandler), repositories.NewAPIKeysRepository(dbHandler), nil)
+
+	apiKeyColumns := []string{"id", "name", "prefix", "user_role", "club_id", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"}
+	expectAPIKey := func(key string, expiresAt interface{}) {
+		mock.ExpectQuery("FROM api_keys").WithArgs(apikeys.Hash(key)).WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+			AddRow("1", "timing", key[:11], "club_admin", "7", "{results:write,runners:read}", expiresAt, nil, nil, time.Now()))
+	}
+	authenticate := func(key string, scope string, roles ...string) (*models.Principal, *models.ResponseError) {
+		ctx := apikeys.WithCredentials(context.Background(), &apikeys.Credentials{Key: key, Scope: scope})
+		principal, responseErr := usersService.Authenticate(ctx, "", roles)
+		// el último uso se guarda en segundo plano
+		usersService.apiKeysUsage.pending.Wait()
+		return principal, responseErr
+	}
+
+	// la clave se trata como un usuario con su rol y su club, y se guarda cuándo se ha usado
+	expectAPIKey("rk_12345678abcd", nil)
+	mock.ExpectExec("UPDATE api_keys SET last_used_at").WithArgs("1").WillReturnResult(sqlmock.NewResult(0, 1))
+	principal, responseErr := authenticate("rk_12345678abcd", models.ScopeResultsWrite, "admin", models.RoleClubAdmin)
+	require.Nil(t, responseErr)
+	assert.Equal(t, &models.Principal{Username: "timing", Role: models.RoleClubAdmin, ClubID: "7", APIKeyID: "1",
+		Scopes: []string{models.ScopeResultsWrite, models.ScopeRunnersRead}}, principal)
+
+	// aunque la clave no esté en la caché de roles, el uso se guarda como mucho una vez por minuto
+	expectAPIKey("rk_12345678abcd", nil)
+	_, responseErr = authenticate("rk_12345678abcd", models.ScopeResultsWrite, "admin", models.RoleClubAdmin)
+	require.Nil(t, responseErr)
+
+	usersService.apiKeysUsage.touched["1"] = time.Now().Add(-2 * time.Minute)
+	expectAPIKey("rk_12345678abcd", nil)
+	mock.ExpectExec("UPDATE api_keys SET last_used_at").WithArgs("1").WillReturnResult(sqlmock.NewResult(0, 1))
+	_, responseErr = authenticate("rk_12345678abcd", models.ScopeResultsWrite, "admin", models.RoleClubAdmin)
+	require.Nil(t, responseErr)
+
+	// sin el permiso de la ruta la respuesta es 403, aunque el rol sea suficiente. Las peticiones rechazadas no cuentan como uso
+	usersService.apiKeysUsage.touched["1"] = time.Time{}
+	expectAPIKey("rk_12345678abcd", nil)
+	_, responseErr = authenticate("rk_12345678abcd", models.ScopeRunnersWrite, "admin", models.RoleClubAdmin)
+	require.NotNil(t, responseErr)
+	assert.Equal(t, http.StatusForbidden, responseErr.Status)
+	assert.Equal(t, "Missing scope runners:write", responseErr.Message)
+
+	// con el permiso pero sin el rol, como con los tokens, no hay usuario
+	expectAPIKey("rk_12345678abcd", nil)
+	principal, responseErr = authenticate("rk_12345678abcd", models.ScopeRunnersRead, "admin")
+	assert.Nil(t, responseErr)
+	assert.Nil(t, principal)
+
+	expectAPIKey("rk_87654321dcba", time.Now().Add(-time.Hour))
+	_, responseErr = authenticate("rk_87654321dcba", models.ScopeResultsWrite, "admin", models.RoleClubAdmin)
+	require.NotNil(t, responseErr)
+	assert.Equal(t, &models.ResponseError{Message: "API key expired", Status: http.StatusUnauthorized}, responseErr)
+
+	// las claves revocadas no se encuentran
+	mock.ExpectQuery("FROM api_keys").WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+	_, responseErr = authenticate("rk_revoked", models.ScopeResultsWrite, "admin")
+	require.NotNil(t, responseErr)
+	assert.Equal(t, http.StatusUnauthorized, responseErr.Status)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdentify(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+
+	usersService := NewUsersService(repositories.NewUsersRepository(dbHandler), repositories.NewAPIKeysRepository(dbHandler), nil)
+	apiKeyColumns := []string{"id", "name", "prefix", "user_role", "club_id", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"}
+
+	// una clave válida identifica al sistema aunque la ruta necesite otro permiso. Identificar no es usar la clave, así que no se guarda el último uso
+	mock.ExpectQuery("FROM api_keys").WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+		AddRow("1", "timing", "rk_12345678", "admin", nil, "{results:write}", nil, nil, nil, time.Now()))
+	ctx := apikeys.WithCredentials(context.Background(), &apikeys.Credentials{Key: "rk_12345678abcd", Scope: models.ScopeRunnersRead})
+	assert.Equal(t, "1", usersService.Identify(ctx, "").APIKeyID)
+
+	// una clave caducada o un token inventado no identifican a nadie
+	mock.ExpectQuery("FROM api_keys").WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+		AddRow("2", "timing", "rk_87654321", "admin", nil, "{results:write}", time.Now().Add(-time.Hour), nil, nil, time.Now()))
+	ctx = apikeys.WithCredentials(context.Background(), &apikeys.Credentials{Key: "rk_87654321dcba"})
+	assert.Nil(t, usersService.Identify(ctx, ""))
+
+	mock.ExpectQuery("SELECT id, username, user_role").WithArgs("made-up").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "username", "user_role", "club_id", "runner_id"}))
+	assert.Nil(t, usersService.Identify(context.Background(), "made-up"))
+	assert.Nil(t, usersService.Identify(context.Background(), ""))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		apiKey  *models.APIKey
+		message string
+	}{
+		{"valid", &models.APIKey{Name: "timing", Role: "admin", Scopes: []string{models.ScopeResultsWrite}}, ""},
+		{"club admin", &models.APIKey{Name: "timing", Role: models.RoleClubAdmin, ClubID: "7", Scopes: []string{models.ScopeResultsWrite}}, ""},
+		{"without name", &models.APIKey{Role: "admin", Scopes: []string{models.ScopeResultsWrite}}, "Invalid name"},
+		{"runner role", &models.APIKey{Name: "timing", Role: models.RoleRunner, Scopes: []string{models.ScopeResultsWrite}}, "Invalid role"},
+		{"club admin without club", &models.APIKey{Name: "timing", Role: models.RoleClubAdmin, Scopes: []string{models.ScopeResultsWrite}}, "Invalid club ID"},
+		{"without scopes", &models.APIKey{Name: "timing", Role: "admin"}, "Invalid scopes"},
+		{"unknown scope", &models.APIKey{Name: "timing", Role: "admin", Scopes: []string{"results:delete"}}, "Invalid scope results:delete"},
+		{"expired", &models.APIKey{Name: "timing", Role: "admin", Scopes: []string{models.ScopeResultsWrite}, ExpiresAt: &yesterday}, "Invalid expiration date"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responseErr := validateAPIKey(test.apiKey, now)
+			if test.message == "" {
+				assert.Nil(t, responseErr)
+				return
+			}
+
+			require.NotNil(t, responseErr)
+			assert.Equal(t, test.message, responseErr.Message)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"runners-postgresql/tracing"
+)
+
+// tamaño de las páginas del log de auditoría
+const auditPageSize = 50
+
+type AuditService struct {
+	auditRepository *repositories.AuditRepository
+}
+
+func NewAuditService(auditRepository *repositories.AuditRepository) *AuditService {
+	return &AuditService{
+		auditRepository: auditRepository,
+	}
+}
+
+// Record añade al log de auditoría el cambio que ha hecho el usuario. El cambio ya está hecho, así que si no se puede guardar la entrada solo se escribe en el log
+func (as AuditService) Record(ctx context.Context, principal *models.Principal, action string, resourceId string, details string) {
+	ctx, span := tracing.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	responseErr := as.auditRepository.InsertEntry(ctx, &models.AuditEntry{
+		Username:   principal.Username,
+		Action:     action,
+		ResourceID: resourceId,
+		Details:    details,
+	})
+	if responseErr != nil {
+		logging.Error(ctx, "Failed to save audit log entry", "username", principal.Username, "action", action, "resource", resourceId, "error", responseErr.Message)
+	}
+}
+
+// GetEntries devuelve una página del log de auditoría, primero lo más reciente. Si se indica el usuario, solo sus cambios
+func (as AuditService) GetEntries(ctx context.Context, username string, page int) (*models.AuditPage, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "AuditService.GetEntries")
+	defer span.End()
+
+	if page < 1 || page > MaxPage {
+		return nil, &models.ResponseError{
+			Message: "Invalid page",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// pedimos una entrada más para saber si hay otra página
+	entries, responseErr := as.auditRepository.GetEntries(ctx, username, auditPageSize+1, (page-1)*auditPageSize)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	hasNext := len(entries) > auditPageSize
+	if hasNext {
+		entries = entries[:auditPageSize]
+	}
+
+	return &models.AuditPage{
+		Page:    page,
+		HasNext: hasNext,
+		Entries: entries,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+)
+
+// savepoint que marca cada elemento del lote dentro de la transacción
+const batchSavepoint = "batch_item"
+
+// newBatchResponse comprueba el modo y el tamaño del lote y prepara la respuesta, con un elemento pendiente por cada elemento de la petición
+func newBatchResponse(mode string, size int) (*models.BatchResponse, *models.ResponseError) {
+	if mode == "" {
+		mode = models.BatchAtomic
+	}
+
+	if mode != models.BatchAtomic && mode != models.BatchBestEffort {
+		return nil, &models.ResponseError{
+			Message: "Invalid batch mode",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if size == 0 {
+		return nil, &models.ResponseError{
+			Message: "Empty batch",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if size > models.MaxBatchSize {
+		return nil, &models.ResponseError{
+			Message: "Too many items in batch",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	response := &models.BatchResponse{
+		Mode:  mode,
+		Items: make([]*models.BatchItem, size),
+	}
+	for i := range response.Items {
+		response.Items[i] = &models.BatchItem{Index: i}
+	}
+
+	return response, nil
+}
+
+// failBatchItem marca el elemento como fallido con el error que habría devuelto su petición individual
+func failBatchItem(item *models.BatchItem, responseErr *models.ResponseError) {
+	item.Status = responseErr.Status
+	item.Data = nil
+	item.Error = responseErr.Message
+}
+
+// batchFailed indica si algún elemento del lote ha fallado
+func batchFailed(response *models.BatchResponse) bool {
+	for _, item := range response.Items {
+		if item.Status >= http.StatusBadRequest {
+			return true
+		}
+	}
+
+	return false
+}
+
+// abortBatch marca como no guardados los elementos que no han fallado, cuando un lote atomic se deshace entero
+func abortBatch(response *models.BatchResponse) {
+	for _, item := range response.Items {
+		if item.Status < http.StatusBadRequest {
+			failBatchItem(item, &models.ResponseError{
+				Message: "Not saved, another item of the batch failed",
+				Status:  http.StatusFailedDependency,
+			})
+		}
+	}
+}
+
+// finishBatch cuenta los elementos guardados y los fallidos
+func finishBatch(response *models.BatchResponse) *models.BatchResponse {
+	response.Succeeded, response.Failed = 0, 0
+	for _, item := range response.Items {
+		if item.Status >= http.StatusBadRequest {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	return response
+}
+
+// writeBatch guarda los elementos que han pasado la validación dentro de la transacción de la petición, cada uno tras un savepoint. Si la escritura de un elemento falla,
+// en un lote best_effort se vuelve a su savepoint y se sigue con el siguiente, y en uno atomic se para: quien llama deshace la transacción entera.
+// Solo devuelve error si falla la propia transacción
+func writeBatch(ctx context.Context, transaction *sql.Tx, response *models.BatchResponse, write func(item *models.BatchItem) *models.ResponseError) *models.ResponseError {
+	for _, item := range response.Items {
+		// rechazado al validar
+		if item.Status != 0 {
+			continue
+		}
+
+		err := repositories.SavepointTransaction(ctx, transaction, batchSavepoint)
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		responseErr := write(item)
+		if responseErr != nil {
+			failBatchItem(item, responseErr)
+			if response.Mode == models.BatchAtomic {
+				return nil
+			}
+
+			// tras un error Postgres no admite más sentencias en la transacción hasta volver al savepoint
+			err = repositories.RollbackToSavepoint(ctx, transaction, batchSavepoint)
+		} else {
+			err = repositories.ReleaseSavepoint(ctx, transaction, batchSavepoint)
+		}
+
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateResultsBestEffort(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+
+	runnersRepository := repositories.NewRunnersRepository(dbHandler)
+	resultsService := NewResultsService(repositories.NewResultsRepository(dbHandler), runnersRepository, repositories.NewOutboxRepository(dbHandler), nil, nil, nil)
+
+	// cada runner se lee una sola vez, aunque tenga varios resultados en el lote
+	runnerColumns := []string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best"}
+	mock.ExpectQuery("FROM runners").WithArgs("1").WillReturnRows(
+		sqlmock.NewRows(runnerColumns).AddRow("1", "John", "Smith", 30, true, "United States", "02:08:00", nil))
+	mock.ExpectQuery("FROM runners").WithArgs("2").WillReturnRows(
+		sqlmock.NewRows(runnerColumns).AddRow("2", "Marijana", "Komatinovic", 30, true, "Serbia", "02:30:00", nil))
+
+	mock.ExpectBegin()
+	for _, resultId := range []string{"10", "11"} {
+		mock.ExpectExec("^SAVEPOINT batch_item$").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO results").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(resultId))
+		mock.ExpectQuery("INSERT INTO outbox_events").WithArgs(models.EventResultCreated, resultId, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("1", time.Now()))
+		mock.ExpectExec("^RELEASE SAVEPOINT batch_item$").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	// el resultado que falla al guardarse se deshace sin perder los anteriores
+	mock.ExpectExec("^SAVEPOINT batch_item$").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO results").WillReturnError(errors.New("insert failed"))
+	mock.ExpectExec("^ROLLBACK TO SAVEPOINT batch_item$").WillReturnResult(sqlmock.NewResult(0, 0))
+	// las mejores marcas del runner se actualizan una vez, con la mejor de sus resultados del lote
+	mock.ExpectExec("UPDATE runners").WithArgs("02:05:00", "", "1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO outbox_events").WithArgs(models.EventRunnerPersonalBest, "1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("2", time.Now()))
+	mock.ExpectCommit()
+
+	response, responseErr := resultsService.CreateResults(context.Background(), nil, &models.ResultsBatch{
+		Mode: models.BatchBestEffort,
+		Items: []*models.Result{
+			{RunnerID: "1", RaceResult: "02:10:00", Location: "Valencia", Year: 2020},
+			{RunnerID: "1", RaceResult: "02:05:00", Location: "Berlin", Year: 2021},
+			{RunnerID: "1", RaceResult: "02:07:00", Year: 2021},
+			{RunnerID: "2", RaceResult: "02:20:00", Location: "Sevilla", Year: 2021},
+		},
+	})
+	require.Nil(t, responseErr)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, 2, response.Failed)
+	assert.Equal(t, http.StatusOK, response.Items[0].Status)
+	assert.Equal(t, "11", response.Items[1].Data.(*models.Result).ID)
+	assert.Equal(t, &models.BatchItem{Index: 2, Status: http.StatusBadRequest, Error: "Invalid location"}, response.Items[2])
+	assert.Equal(t, http.StatusInternalServerError, response.Items[3].Status)
+}
+
+func TestCreateRunnersAtomicRejectsInvalidBatch(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+
+	runnersService := NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+
+	// un runner no válido en un lote atomic: no se crea ninguno y no se llega a abrir la transacción
+	response, responseErr := runnersService.CreateRunners(context.Background(), nil, &models.RunnersBatch{
+		Items: []*models.Runner{
+			{FirstName: "John", LastName: "Smith", Age: 30, Country: "United States"},
+			{FirstName: "Marijana", Age: 30, Country: "Serbia"},
+		},
+	})
+	require.Nil(t, responseErr)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, models.BatchAtomic, response.Mode)
+	assert.Equal(t, 0, response.Succeeded)
+	assert.Equal(t, http.StatusFailedDependency, response.Items[0].Status)
+	assert.Equal(t, &models.BatchItem{Index: 1, Status: http.StatusBadRequest, Error: "Invalid last name"}, response.Items[1])
+}
+
+// los repositorios los comparten todas las peticiones, así que cada petición tiene que usar su propia transacción aunque otra abra y confirme la suya mientras tanto. Conviene ejecutarlo con -race
+func TestConcurrentRequestsUseTheirOwnTransaction(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	// las sentencias de las dos peticiones se intercalan
+	mock.MatchExpectationsInOrder(false)
+
+	runnersService := NewRunnersService(repositories.NewRunnersRepository(dbHandler), repositories.NewResultsRepository(dbHandler), repositories.NewOutboxRepository(dbHandler), nil, nil)
+
+	// el lote tarda en guardar su runner...
+	mock.ExpectBegin()
+	mock.ExpectExec("^SAVEPOINT batch_item$").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO runners").WillDelayFor(200 * time.Millisecond).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	mock.ExpectExec("^RELEASE SAVEPOINT batch_item$").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	// ...y mientras tanto otra petición borra un runner y publica el evento en su transacción
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE runners SET is_active").WithArgs("2").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO outbox_events").WithArgs(models.EventRunnerDeleted, "2", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("1", time.Now()))
+	mock.ExpectCommit()
+
+	batchDone := make(chan *models.BatchResponse)
+	go func() {
+		response, responseErr := runnersService.CreateRunners(context.Background(), nil, &models.RunnersBatch{
+			Mode:  models.BatchBestEffort,
+			Items: []*models.Runner{{FirstName: "John", LastName: "Smith", Age: 30, Country: "United States"}},
+		})
+		assert.Nil(t, responseErr)
+		batchDone <- response
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, runnersService.DeleteRunner(context.Background(), nil, "2"))
+
+	response := <-batchDone
+	require.NotNil(t, response)
+	assert.Equal(t, 1, response.Succeeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"runners-postgresql/tracing"
+	"time"
+)
+
+// número de posiciones que devuelve por defecto la clasificación de un club
+const defaultLeaderboardLimit = 10
+const maxLeaderboardLimit = 100
+
+type ClubsService struct {
+	clubsRepository *repositories.ClubsRepository
+}
+
+func NewClubsService(clubsRepository *repositories.ClubsRepository) *ClubsService {
+	return &ClubsService{
+		clubsRepository: clubsRepository,
+	}
+}
+
+func (cs ClubsService) CreateClub(ctx context.Context, club *models.Club) (*models.Club, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ClubsService.CreateClub")
+	defer span.End()
+
+	responseErr := validateClub(club)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return cs.clubsRepository.CreateClub(ctx, club)
+}
+
+func (cs ClubsService) UpdateClub(ctx context.Context, principal *models.Principal, club *models.Club) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "ClubsService.UpdateClub")
+	defer span.End()
+
+	responseErr := authorizeClub(principal, club.ID)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	responseErr = validateClub(club)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return cs.clubsRepository.UpdateClub(ctx, club)
+}
+
+func (cs ClubsService) GetClub(ctx context.Context, clubId string) (*models.Club, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ClubsService.GetClub")
+	defer span.End()
+
+	responseErr := validateClubId(clubId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return cs.clubsRepository.GetClub(ctx, clubId)
+}
+
+func (cs ClubsService) GetAllClubs(ctx context.Context) ([]*models.Club, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ClubsService.GetAllClubs")
+	defer span.End()
+
+	return cs.clubsRepository.GetAllClubs(ctx)
+}
+
+// AddMember da de alta a un runner en el club. Solo un administrador global puede traspasar a un runner desde otro club o dar de alta a un runner que nunca ha sido del club
+func (cs ClubsService) AddMember(ctx context.Context, principal *models.Principal, clubId string, membership *models.ClubMembership) (*models.ClubMembership, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ClubsService.AddMember")
+	defer span.End()
+
+	responseErr := authorizeClub(principal, clubId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	responseErr = validateRunnerId(membership.RunnerID)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if membership.Transfer && principal.ClubScoped() {
+		return nil, &models.ResponseError{
+			Message: "Only administrators can transfer runners between clubs",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	// un administrador de club solo puede volver a dar de alta a los runners que ya han sido de su club. Si no, podría hacerse con cualquier runner sin club y modificarlo o borrarlo
+	if principal.ClubScoped() {
+		member, responseErr := cs.clubsRepository.WasMember(ctx, clubId, membership.RunnerID)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		if !member {
+			return nil, &models.ResponseError{
+				Message: "Only administrators can add runners from outside the club",
+				Status:  http.StatusForbidden,
+			}
+		}
+	}
+
+	joinedAt := membership.JoinedAt
+	if joinedAt.IsZero() {
+		joinedAt = time.Now()
+	}
+
+	return cs.clubsRepository.AddMember(ctx, clubId, membership.RunnerID, joinedAt, membership.Transfer)
+}
+
+// RemoveMember da de baja al runner del club. La pertenencia se conserva en el historial
+func (cs ClubsService) RemoveMember(ctx context.Context, principal *models.Principal, clubId string, runnerId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "ClubsService.RemoveMember")
+	defer span.End()
+
+	responseErr := authorizeClub(principal, clubId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	responseErr = validateRunnerId(runnerId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return cs.clubsRepository.EndMembership(ctx, clubId, runnerId, time.Now())
+}
+
+func (cs ClubsService) GetClubMembers(ctx context.Context, clubId string, history string) ([]*models.ClubMembership, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ClubsService.GetClubMembers")
+	defer span.End()
+
+	responseErr := validateClubId(clubId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return cs.clubsRepository.GetClubMembers(ctx, clubId, history == "true")
+}
+
+func (cs ClubsService) GetRunnerMemberships(ctx context.Context, runnerId string) ([]*models.ClubMembership, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ClubsService.GetRunnerMemberships")
+	defer span.End()
+
+	responseErr := validateRunnerId(runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return cs.clubsRepository.GetRunnerMemberships(ctx, runnerId)
+}
+
+func (cs ClubsService) GetLeaderboard(ctx context.Context, clubId string, year string, limit string) ([]*models.LeaderboardEntry, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ClubsService.GetLeaderboard")
+	defer span.End()
+
+	responseErr := validateClubId(clubId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	intYear, responseErr := parseYear(year)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	intLimit, responseErr := parsePageParam(limit, defaultLeaderboardLimit, maxLeaderboardLimit, "Invalid limit")
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return cs.clubsRepository.GetLeaderboard(ctx, clubId, intYear, intLimit)
+}
+
+// authorizeClub comprueba que el usuario puede administrar el club. Los administradores de club solo pueden administrar el suyo
+func authorizeClub(principal *models.Principal, clubId string) *models.ResponseError {
+	responseErr := validateClubId(clubId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if principal.ClubScoped() && principal.ClubID != clubId {
+		return &models.ResponseError{
+			Message: "Not allowed to manage this club",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	return nil
+}
+
+// authorizeRunnerWrite comprueba que el usuario puede modificar el runner o sus resultados. Los administradores de club solo pueden modificar a los miembros actuales de su club
+func authorizeRunnerWrite(ctx context.Context, clubsRepository *repositories.ClubsRepository, principal *models.Principal, runnerId string) *models.ResponseError {
+	if !principal.ClubScoped() {
+		return nil
+	}
+
+	if principal.ClubID == "" {
+		return &models.ResponseError{
+			Message: "Club administrator without club",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	member, responseErr := clubsRepository.IsActiveMember(ctx, principal.ClubID, runnerId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if !member {
+		return &models.ResponseError{
+			Message: "Runner is not a member of your club",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	return nil
+}
+
+func validateClub(club *models.Club) *models.ResponseError {
+	if club.Name == "" {
+		return &models.ResponseError{
+			Message: "Invalid club name",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if club.Country == "" {
+		return &models.ResponseError{
+			Message: "Invalid country",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return nil
+}
+
+func validateClubId(clubId string) *models.ResponseError {
+	if clubId == "" {
+		return &models.ResponseError{
+			Message: "Invalid club ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return nil
+}
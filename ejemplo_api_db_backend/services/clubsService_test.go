@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizeRunnerWrite(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	clubsRepository := repositories.NewClubsRepository(dbHandler)
+
+	admin := &models.Principal{Role: "admin"}
+	clubAdmin := &models.Principal{Role: models.RoleClubAdmin, ClubID: "club-1"}
+
+	// los administradores globales no consultan la pertenencia al club
+	assert.Nil(t, authorizeRunnerWrite(context.Background(), clubsRepository, admin, "runner-1"))
+	assert.Nil(t, authorizeRunnerWrite(context.Background(), clubsRepository, nil, "runner-1"))
+
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("club-1", "runner-1").WillReturnRows(
+		sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	assert.Nil(t, authorizeRunnerWrite(context.Background(), clubsRepository, clubAdmin, "runner-1"))
+
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("club-1", "runner-2").WillReturnRows(
+		sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	responseErr := authorizeRunnerWrite(context.Background(), clubsRepository, clubAdmin, "runner-2")
+	assert.Equal(t, http.StatusForbidden, responseErr.Status)
+
+	// un administrador de club sin club no puede modificar a nadie
+	responseErr = authorizeRunnerWrite(context.Background(), clubsRepository, &models.Principal{Role: models.RoleClubAdmin}, "runner-1")
+	assert.Equal(t, http.StatusForbidden, responseErr.Status)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthorizeClub(t *testing.T) {
+	clubAdmin := &models.Principal{Role: models.RoleClubAdmin, ClubID: "club-1"}
+
+	assert.Nil(t, authorizeClub(clubAdmin, "club-1"))
+	assert.Equal(t, http.StatusForbidden, authorizeClub(clubAdmin, "club-2").Status)
+	assert.Nil(t, authorizeClub(&models.Principal{Role: "admin"}, "club-2"))
+	assert.Equal(t, http.StatusBadRequest, authorizeClub(clubAdmin, "").Status)
+}
+
+func TestClubAdminCannotTransferRunners(t *testing.T) {
+	clubsService := NewClubsService(nil)
+	clubAdmin := &models.Principal{Role: models.RoleClubAdmin, ClubID: "club-1"}
+
+	_, responseErr := clubsService.AddMember(context.Background(), clubAdmin, "club-1", &models.ClubMembership{RunnerID: "runner-1", Transfer: true})
+	assert.Equal(t, http.StatusForbidden, responseErr.Status)
+}
+
+func TestClubAdminCannotClaimRunners(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	clubsService := NewClubsService(repositories.NewClubsRepository(dbHandler))
+	clubAdmin := &models.Principal{Role: models.RoleClubAdmin, ClubID: "club-1"}
+
+	// un runner que nunca ha sido del club solo lo puede dar de alta un administrador
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("club-1", "runner-1").WillReturnRows(
+		sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	_, responseErr := clubsService.AddMember(context.Background(), clubAdmin, "club-1", &models.ClubMembership{RunnerID: "runner-1"})
+	assert.Equal(t, http.StatusForbidden, responseErr.Status)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
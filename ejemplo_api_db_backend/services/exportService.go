@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"runners-postgresql/tracing"
+)
+
+type ExportService struct {
+	runnersRepository *repositories.RunnersRepository
+	resultsRepository *repositories.ResultsRepository
+}
+
+func NewExportService(runnersRepository *repositories.RunnersRepository, resultsRepository *repositories.ResultsRepository) *ExportService {
+	return &ExportService{
+		runnersRepository: runnersRepository,
+		resultsRepository: resultsRepository,
+	}
+}
+
+// RunnersFilter valida los filtros de la exportación de runners. Son los mismos que admite el listado de runners. Si quien exporta es un runner se aplican las preferencias de privacidad, salvo a sus propios datos
+func (es ExportService) RunnersFilter(principal *models.Principal, country string, year string) (models.RunnersFilter, *models.ResponseError) {
+	if country != "" && year != "" {
+		return models.RunnersFilter{}, &models.ResponseError{
+			Message: "Only one parameter, country or year, can be passed",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	intYear, responseErr := parseYear(year)
+	if responseErr != nil {
+		return models.RunnersFilter{}, responseErr
+	}
+
+	public, ownRunnerId := privacyScope(principal)
+	return models.RunnersFilter{
+		Country:     country,
+		Year:        intYear,
+		Public:      public,
+		OwnRunnerID: ownRunnerId,
+	}, nil
+}
+
+// ResultsFilter valida los filtros de la exportación de resultados. Si quien exporta es un runner se excluyen los resultados de los demás runners que los ocultan
+func (es ExportService) ResultsFilter(principal *models.Principal, runnerId string, year string) (models.ResultsFilter, *models.ResponseError) {
+	intYear, responseErr := parseYear(year)
+	if responseErr != nil {
+		return models.ResultsFilter{}, responseErr
+	}
+
+	public, ownRunnerId := privacyScope(principal)
+	return models.ResultsFilter{
+		RunnerID:    runnerId,
+		Year:        intYear,
+		Public:      public,
+		OwnRunnerID: ownRunnerId,
+	}, nil
+}
+
+// ExportRunners entrega los runners a fn según se van leyendo de la base de datos
+func (es ExportService) ExportRunners(ctx context.Context, filter models.RunnersFilter, fn func(*models.Runner) error) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "ExportService.ExportRunners")
+	defer span.End()
+
+	return es.runnersRepository.StreamRunners(ctx, filter, fn)
+}
+
+// ExportResults entrega los resultados a fn según se van leyendo de la base de datos
+func (es ExportService) ExportResults(ctx context.Context, filter models.ResultsFilter, fn func(*models.Result) error) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "ExportService.ExportResults")
+	defer span.End()
+
+	return es.resultsRepository.StreamResults(ctx, filter, fn)
+}
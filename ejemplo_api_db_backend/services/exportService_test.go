@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportResultsIncludeDistanceAndSplits(t *testing.T) {
+	dbHandler, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+	exportService := NewExportService(repositories.NewRunnersRepository(dbHandler), repositories.NewResultsRepository(dbHandler))
+
+	mock.ExpectQuery("SELECT id, runner_id, race_result, location, position, year, distance").WithArgs("1").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "runner_id", "race_result", "location", "position", "year", "distance", "split_distances", "split_times"}).
+			AddRow("10", "1", "02:05:00", "Valencia", 3, 2023, 42.195, "{21.0975,42.195}", "{01:02:00,02:05:00}").
+			AddRow("11", "1", "01:07:00", "Berlin", nil, 2022, 21.0975, "{}", "{}"))
+
+	var results []*models.Result
+	responseErr := exportService.ExportResults(context.Background(), models.ResultsFilter{RunnerID: "1"}, func(result *models.Result) error {
+		results = append(results, result)
+		return nil
+	})
+	require.Nil(t, responseErr)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, 42.195, results[0].Distance)
+	assert.Equal(t, 21.0975, results[1].Distance)
+	// los tiempos de paso se leen en la misma consulta
+	assert.Equal(t, []*models.Split{{Distance: 21.0975, Time: "01:02:00"}, {Distance: 42.195, Time: "02:05:00"}}, results[0].Splits)
+	assert.Nil(t, results[1].Splits)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportResultsKeepRunnerOwnResults(t *testing.T) {
+	dbHandler, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer dbHandler.Close()
+	exportService := NewExportService(nil, repositories.NewResultsRepository(dbHandler))
+
+	// un runner no exporta los resultados que ocultan los demás, pero sí los suyos
+	mock.ExpectQuery(`runner_id = NULLIF\(\$2, ''\)::uuid OR runner_id NOT IN`).WithArgs(2023, "1").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "runner_id", "race_result", "location", "position", "year", "distance", "split_distances", "split_times"}))
+
+	filter, responseErr := exportService.ResultsFilter(&models.Principal{Role: models.RoleRunner, RunnerID: "1"}, "", "2023")
+	require.Nil(t, responseErr)
+	assert.Nil(t, exportService.ExportResults(context.Background(), filter, func(*models.Result) error { return nil }))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runners-postgresql/cache"
+	"runners-postgresql/logging"
+	"runners-postgresql/models"
+	"runners-postgresql/oidc"
+	"runners-postgresql/tracing"
+	"time"
+)
+
+// OIDCRole asocia un valor del claim de roles a un rol de la aplicación
+type OIDCRole struct {
+	Value  string
+	Role   string
+	ClubID string
+}
+
+// OIDCConfig es la asignación de roles a los usuarios del proveedor y el tiempo máximo para iniciar sesión en él
+type OIDCConfig struct {
+	RoleClaim   string
+	DefaultRole string // rol de los usuarios sin ninguno de Roles, o vacío para no dejarles entrar
+	Roles       []OIDCRole
+	StateTTL    time.Duration
+}
+
+// OIDCService implementa el login con el proveedor de identidad externo. Los logins pendientes se guardan en la caché, de modo que el callback puede llegar a cualquier réplica si la caché es Redis
+type OIDCService struct {
+	provider     *oidc.Provider
+	store        cache.Store
+	usersService *UsersService
+	config       OIDCConfig
+}
+
+func NewOIDCService(provider *oidc.Provider, store cache.Store, usersService *UsersService, config OIDCConfig) *OIDCService {
+	// sin caché configurada los logins pendientes se guardan en memoria
+	if store == nil {
+		store = cache.NewLRU(10000)
+	}
+
+	return &OIDCService{
+		provider:     provider,
+		store:        store,
+		usersService: usersService,
+		config:       config,
+	}
+}
+
+// StartLogin crea un login pendiente y devuelve la URL del proveedor a la que se redirige al usuario y el state del login
+func (oidcs OIDCService) StartLogin(ctx context.Context) (string, string, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "OIDCService.StartLogin")
+	defer span.End()
+
+	request, err := oidc.NewAuthRequest()
+	if err != nil {
+		return "", "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	authURL, err := oidcs.provider.AuthCodeURL(ctx, request)
+	if err != nil {
+		logging.Error(ctx, "Error while contacting the identity provider", "error", err)
+		return "", "", &models.ResponseError{
+			Message: "Identity provider unavailable",
+			Status:  http.StatusBadGateway,
+		}
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	err = oidcs.store.Set(ctx, stateCacheKey(request.State), data, oidcs.config.StateTTL)
+	if err != nil {
+		return "", "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return authURL, request.State, nil
+}
+
+// FinishLogin completa el login con el código que envía el proveedor al callback: cambia el código por el ID token, lo verifica, asigna el rol al usuario según sus claims y le da un token de acceso
+func (oidcs OIDCService) FinishLogin(ctx context.Context, code string, state string) (string, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "OIDCService.FinishLogin")
+	defer span.End()
+
+	if code == "" || state == "" {
+		return "", &models.ResponseError{
+			Message: "Invalid code or state",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// cada login pendiente solo se puede completar una vez
+	key := stateCacheKey(state)
+	data, found, err := oidcs.store.Get(ctx, key)
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if !found {
+		return "", &models.ResponseError{
+			Message: "Login expired or already completed",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	err = oidcs.store.Delete(ctx, key)
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	var request oidc.AuthRequest
+	err = json.Unmarshal(data, &request)
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rawIDToken, err := oidcs.provider.Exchange(ctx, code, request.Verifier)
+	if err != nil {
+		logging.Error(ctx, "Error while exchanging the authorization code", "error", err)
+		return "", &models.ResponseError{
+			Message: "Identity provider rejected the login",
+			Status:  http.StatusBadGateway,
+		}
+	}
+
+	claims, err := oidcs.provider.Verify(ctx, rawIDToken, request.Nonce)
+	if errors.Is(err, oidc.ErrInvalidToken) {
+		logging.Warn(ctx, "Invalid ID token", "error", err)
+		return "", &models.ResponseError{
+			Message: "Invalid ID token",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+	if err != nil {
+		logging.Error(ctx, "Error while verifying the ID token", "error", err)
+		return "", &models.ResponseError{
+			Message: "Identity provider unavailable",
+			Status:  http.StatusBadGateway,
+		}
+	}
+
+	role, clubId := oidcs.role(claims)
+	if role == "" {
+		logging.Warn(ctx, "External user without role", "subject", claims.Subject)
+		return "", &models.ResponseError{
+			Message: "User has no role in the application",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	return oidcs.usersService.LoginExternal(ctx, &models.ExternalIdentity{
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Username: externalUsername(claims),
+		Role:     role,
+		ClubID:   clubId,
+	})
+}
+
+// role devuelve el rol del usuario: el de la primera asignación cuyo valor está en el claim de roles, o el rol por defecto
+func (oidcs OIDCService) role(claims *oidc.Claims) (string, string) {
+	values := claims.Values(oidcs.config.RoleClaim)
+	for _, mapping := range oidcs.config.Roles {
+		for _, value := range values {
+			if value == mapping.Value {
+				return mapping.Role, mapping.ClubID
+			}
+		}
+	}
+
+	return oidcs.config.DefaultRole, ""
+}
+
+// externalUsername elige el nombre de usuario entre los claims del token. El sub siempre está, pero no suele ser legible
+func externalUsername(claims *oidc.Claims) string {
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+
+	if claims.Email != "" {
+		return claims.Email
+	}
+
+	return claims.Subject
+}
+
+func stateCacheKey(state string) string {
+	return "oidc:state:" + state
+}
@@ -1,75 +1,232 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"runners-postgresql/cache"
 	"runners-postgresql/models"
 	"runners-postgresql/repositories"
+	"runners-postgresql/stats"
+	"runners-postgresql/tracing"
 	"time"
 )
 
+// EventPublisher recibe los eventos de dominio una vez confirmada la transacción que los generó
+type EventPublisher interface {
+	Publish(events ...*models.Event)
+}
+
 type ResultsService struct {
 	resultsRepository *repositories.ResultsRepository
 	runnersRepository *repositories.RunnersRepository
+	outboxRepository  *repositories.OutboxRepository
+	clubsRepository   *repositories.ClubsRepository
+	publisher         EventPublisher
+	runnersCache      *cache.ReadThrough // caché de runners que hay que invalidar al cambiar sus resultados
 }
 
 // factoria que crea el servicio
 func NewResultsService(resultsRepository *repositories.ResultsRepository,
-	runnersRepository *repositories.RunnersRepository) *ResultsService {
+	runnersRepository *repositories.RunnersRepository,
+	outboxRepository *repositories.OutboxRepository,
+	clubsRepository *repositories.ClubsRepository,
+	publisher EventPublisher,
+	runnersCache *cache.ReadThrough) *ResultsService {
 
 	return &ResultsService{
 		resultsRepository: resultsRepository,
 		runnersRepository: runnersRepository,
+		outboxRepository:  outboxRepository,
+		clubsRepository:   clubsRepository,
+		publisher:         publisher,
+		runnersCache:      runnersCache,
 	}
 }
 
-func (rs ResultsService) CreateResult(result *models.Result) (*models.Result, *models.ResponseError) {
-	// Validation
-	if result.RunnerID == "" {
-		return nil, &models.ResponseError{
-			Message: "Invalid runner ID",
-			Status:  http.StatusBadRequest,
-		}
+func (rs ResultsService) CreateResult(ctx context.Context, principal *models.Principal, result *models.Result) (*models.Result, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ResultsService.CreateResult")
+	defer span.End()
+
+	raceResult, responseErr := validateResult(result)
+	if responseErr != nil {
+		return nil, responseErr
 	}
 
-	if result.RaceResult == "" {
+	// un administrador de club solo puede registrar resultados de los miembros de su club
+	responseErr = authorizeRunnerWrite(ctx, rs.clubsRepository, principal, result.RunnerID)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return rs.saveResult(ctx, result, raceResult, nil)
+}
+
+// saveResult guarda el resultado, actualiza las mejores marcas del runner y publica los eventos en una transacción. Si se indica, inTransaction se ejecuta con el resultado creado justo antes del commit, dentro de la misma transacción
+func (rs ResultsService) saveResult(ctx context.Context, result *models.Result, raceResult time.Duration, inTransaction func(*sql.Tx, *models.Result) *models.ResponseError) (*models.Result, *models.ResponseError) {
+	currentYear := time.Now().Year()
+
+	// Inicia una trasacción
+	transaction, err := repositories.BeginTransaction(ctx, rs.runnersRepository)
+	if err != nil {
 		return nil, &models.ResponseError{
-			Message: "Invalid race result",
+			Message: "Failed to start transaction",
 			Status:  http.StatusBadRequest,
 		}
 	}
 
-	if result.Location == "" {
+	// Crear el resultado
+	response, responseErr := rs.resultsRepository.CreateResult(ctx, transaction, result)
+	// Si hay un error, hacemos rollback y retornamos el error
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return nil, responseErr
+	}
+
+	runner, responseErr := rs.runnersRepository.GetRunner(ctx, result.RunnerID)
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return nil, responseErr
+	}
+
+	if runner == nil {
+		repositories.RollbackTransaction(transaction)
 		return nil, &models.ResponseError{
-			Message: "Invalid location",
-			Status:  http.StatusBadRequest,
+			Message: "Runner not found",
+			Status:  http.StatusNotFound,
 		}
 	}
 
-	if result.Position < 0 {
-		return nil, &models.ResponseError{
-			Message: "Invalid position",
-			Status:  http.StatusBadRequest,
+	previousPersonalBest := runner.PersonalBest
+	_, responseErr = updateBests(runner, result, raceResult, currentYear)
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return nil, responseErr
+	}
+
+	responseErr = rs.runnersRepository.UpdateRunnerResults(ctx, transaction, runner)
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return nil, responseErr
+	}
+
+	// publicamos los eventos en el outbox, dentro de la misma transacción
+	events := make([]*models.Event, 0, 2)
+	event, responseErr := rs.outboxRepository.InsertEvent(ctx, transaction, models.EventResultCreated, response.ID, response)
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return nil, responseErr
+	}
+	events = append(events, event)
+
+	if runner.PersonalBest != previousPersonalBest {
+		event, responseErr = rs.outboxRepository.InsertEvent(ctx, transaction, models.EventRunnerPersonalBest, runner.ID, &models.PersonalBestEvent{
+			RunnerID:             runner.ID,
+			ResultID:             response.ID,
+			Location:             response.Location,
+			PersonalBest:         runner.PersonalBest,
+			PreviousPersonalBest: previousPersonalBest,
+		})
+		if responseErr != nil {
+			repositories.RollbackTransaction(transaction)
+			return nil, responseErr
 		}
+		events = append(events, event)
 	}
 
-	currentYear := time.Now().Year()
-	if result.Year < 0 || result.Year > currentYear {
-		return nil, &models.ResponseError{
-			Message: "Invalid year",
-			Status:  http.StatusBadRequest,
+	if inTransaction != nil {
+		responseErr = inTransaction(transaction, response)
+		if responseErr != nil {
+			repositories.RollbackTransaction(transaction)
+			return nil, responseErr
 		}
 	}
 
-	raceResult, err := parseRaceResult(result.RaceResult)
+	// Si hemos llegado hasta aquí, todo ha ido bien y hacemos commit
+	err = repositories.CommitTransaction(transaction)
 	if err != nil {
 		return nil, &models.ResponseError{
-			Message: "Invalid race result",
-			Status:  http.StatusBadRequest,
+			Message: "Failed to commit transaction",
+			Status:  http.StatusInternalServerError,
 		}
 	}
 
-	// Inicia una trasacción
-	err = repositories.BeginTransaction(rs.runnersRepository, rs.resultsRepository)
+	// solo una vez confirmada la transacción invalidamos la caché y notificamos los eventos (por ejemplo al feed en directo)
+	rs.runnersCache.Invalidate(ctx, result.RunnerID)
+	if rs.publisher != nil {
+		rs.publisher.Publish(events...)
+	}
+
+	return response, nil
+}
+
+// CreateResults crea varios resultados en una única transacción y devuelve el estado de cada uno. En modo atomic, si falla alguno no se crea ninguno.
+// Las mejores marcas de cada runner se recalculan una sola vez con todos sus resultados del lote, y se publica como mucho un evento runner.personal_best por runner
+func (rs ResultsService) CreateResults(ctx context.Context, principal *models.Principal, batch *models.ResultsBatch) (*models.BatchResponse, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ResultsService.CreateResults")
+	defer span.End()
+
+	response, responseErr := newBatchResponse(batch.Mode, len(batch.Items))
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	// runners de los resultados del lote, leídos una vez por runner. Un runner que no existe se guarda como nil
+	runners := make(map[string]*models.Runner)
+	raceResults := make([]time.Duration, len(batch.Items))
+
+	for i, result := range batch.Items {
+		if result == nil {
+			failBatchItem(response.Items[i], &models.ResponseError{
+				Message: "Invalid result",
+				Status:  http.StatusBadRequest,
+			})
+			continue
+		}
+
+		raceResult, responseErr := validateResult(result)
+		if responseErr != nil {
+			failBatchItem(response.Items[i], responseErr)
+			continue
+		}
+		raceResults[i] = raceResult
+
+		responseErr = authorizeRunnerWrite(ctx, rs.clubsRepository, principal, result.RunnerID)
+		if responseErr != nil {
+			failBatchItem(response.Items[i], responseErr)
+			continue
+		}
+
+		runner, found := runners[result.RunnerID]
+		if !found {
+			runner, responseErr = rs.runnersRepository.GetRunner(ctx, result.RunnerID)
+			if responseErr != nil {
+				failBatchItem(response.Items[i], responseErr)
+				continue
+			}
+
+			if runner.ID == "" {
+				runner = nil
+			}
+			runners[result.RunnerID] = runner
+		}
+
+		if runner == nil {
+			failBatchItem(response.Items[i], &models.ResponseError{
+				Message: "Runner not found",
+				Status:  http.StatusNotFound,
+			})
+		}
+	}
+
+	// un lote atomic con algún resultado no válido no llega a la base de datos
+	if response.Mode == models.BatchAtomic && batchFailed(response) {
+		abortBatch(response)
+		return finishBatch(response), nil
+	}
+
+	transaction, err := repositories.BeginTransaction(ctx, rs.runnersRepository)
 	if err != nil {
 		return nil, &models.ResponseError{
 			Message: "Failed to start transaction",
@@ -77,36 +234,123 @@ func (rs ResultsService) CreateResult(result *models.Result) (*models.Result, *m
 		}
 	}
 
-	// Crear el resultado
-	response, responseErr := rs.resultsRepository.CreateResult(result)
-	// Si hay un error, hacemos rollback y retornamos el error
+	events := make([]*models.Event, 0, len(batch.Items))
+	responseErr = writeBatch(ctx, transaction, response, func(item *models.BatchItem) *models.ResponseError {
+		result, responseErr := rs.resultsRepository.CreateResult(ctx, transaction, batch.Items[item.Index])
+		if responseErr != nil {
+			return responseErr
+		}
+
+		event, responseErr := rs.outboxRepository.InsertEvent(ctx, transaction, models.EventResultCreated, result.ID, result)
+		if responseErr != nil {
+			return responseErr
+		}
+		events = append(events, event)
+
+		item.Status = http.StatusOK
+		item.Data = result
+		return nil
+	})
 	if responseErr != nil {
-		repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
+		repositories.RollbackTransaction(transaction)
 		return nil, responseErr
 	}
 
-	runner, responseErr := rs.runnersRepository.GetRunner(result.RunnerID)
-	if responseErr != nil {
-		repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
-		return nil, responseErr
+	if response.Mode == models.BatchAtomic && batchFailed(response) {
+		repositories.RollbackTransaction(transaction)
+		abortBatch(response)
+		return finishBatch(response), nil
 	}
 
-	if runner == nil {
-		repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
+	// resultados creados de cada runner, con los runners en el orden en que aparecen en el lote
+	runnerIds := make([]string, 0, len(runners))
+	created := make(map[string][]*models.BatchItem)
+	for _, item := range response.Items {
+		if item.Status != http.StatusOK {
+			continue
+		}
+
+		runnerId := batch.Items[item.Index].RunnerID
+		if _, found := created[runnerId]; !found {
+			runnerIds = append(runnerIds, runnerId)
+		}
+		created[runnerId] = append(created[runnerId], item)
+	}
+
+	currentYear := time.Now().Year()
+	for _, runnerId := range runnerIds {
+		runner := runners[runnerId]
+		previousPersonalBest := runner.PersonalBest
+
+		var personalBest *models.Result
+		for _, item := range created[runnerId] {
+			improved, responseErr := updateBests(runner, batch.Items[item.Index], raceResults[item.Index], currentYear)
+			if responseErr != nil {
+				repositories.RollbackTransaction(transaction)
+				return nil, responseErr
+			}
+
+			if improved {
+				personalBest = item.Data.(*models.Result)
+			}
+		}
+
+		responseErr = rs.runnersRepository.UpdateRunnerResults(ctx, transaction, runner)
+		if responseErr != nil {
+			repositories.RollbackTransaction(transaction)
+			return nil, responseErr
+		}
+
+		if runner.PersonalBest != previousPersonalBest {
+			event, responseErr := rs.outboxRepository.InsertEvent(ctx, transaction, models.EventRunnerPersonalBest, runner.ID, &models.PersonalBestEvent{
+				RunnerID:             runner.ID,
+				ResultID:             personalBest.ID,
+				Location:             personalBest.Location,
+				PersonalBest:         runner.PersonalBest,
+				PreviousPersonalBest: previousPersonalBest,
+			})
+			if responseErr != nil {
+				repositories.RollbackTransaction(transaction)
+				return nil, responseErr
+			}
+			events = append(events, event)
+		}
+	}
+
+	err = repositories.CommitTransaction(transaction)
+	if err != nil {
 		return nil, &models.ResponseError{
-			Message: "Runner not found",
-			Status:  http.StatusNotFound,
+			Message: "Failed to commit transaction",
+			Status:  http.StatusInternalServerError,
 		}
 	}
 
+	for _, runnerId := range runnerIds {
+		rs.runnersCache.Invalidate(ctx, runnerId)
+	}
+	if rs.publisher != nil {
+		rs.publisher.Publish(events...)
+	}
+
+	return finishBatch(response), nil
+}
+
+// updateBests actualiza la mejor marca personal y la de la temporada del runner con el resultado. Las marcas son de maratón, así que los resultados de otras distancias no cuentan. Devuelve si el resultado es su nueva mejor marca personal
+func updateBests(runner *models.Runner, result *models.Result, raceResult time.Duration, currentYear int) (bool, *models.ResponseError) {
+	if result.Distance != models.MarathonDistance {
+		return false, nil
+	}
+
+	improved := false
+
 	// update runners personal best
 	if runner.PersonalBest == "" {
 		runner.PersonalBest = result.RaceResult
+		improved = true
 	} else {
 		personalBest, err := parseRaceResult(runner.PersonalBest)
 		if err != nil {
-			repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
-			return nil, &models.ResponseError{
+			return false, &models.ResponseError{
 				Message: "Failed to parse personal best",
 				Status:  http.StatusInternalServerError,
 			}
@@ -114,6 +358,7 @@ func (rs ResultsService) CreateResult(result *models.Result) (*models.Result, *m
 
 		if raceResult < personalBest {
 			runner.PersonalBest = result.RaceResult
+			improved = true
 		}
 	}
 
@@ -124,8 +369,7 @@ func (rs ResultsService) CreateResult(result *models.Result) (*models.Result, *m
 		} else {
 			seasonBest, err := parseRaceResult(runner.SeasonBest)
 			if err != nil {
-				repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
-				return nil, &models.ResponseError{
+				return false, &models.ResponseError{
 					Message: "Failed to parse season best",
 					Status:  http.StatusInternalServerError,
 				}
@@ -137,18 +381,13 @@ func (rs ResultsService) CreateResult(result *models.Result) (*models.Result, *m
 		}
 	}
 
-	responseErr = rs.runnersRepository.UpdateRunnerResults(runner)
-	if responseErr != nil {
-		repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
-		return nil, responseErr
-	}
-
-	// Si hemos llegado hasta aquí, todo ha ido bien y hacemos commit
-	repositories.CommitTransaction(rs.runnersRepository, rs.resultsRepository)
-	return response, nil
+	return improved, nil
 }
 
-func (rs ResultsService) DeleteResult(resultId string) *models.ResponseError {
+func (rs ResultsService) DeleteResult(ctx context.Context, principal *models.Principal, resultId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "ResultsService.DeleteResult")
+	defer span.End()
+
 	if resultId == "" {
 		return &models.ResponseError{
 			Message: "Invalid result ID",
@@ -156,7 +395,18 @@ func (rs ResultsService) DeleteResult(resultId string) *models.ResponseError {
 		}
 	}
 
-	err := repositories.BeginTransaction(rs.runnersRepository, rs.resultsRepository)
+	// comprobamos los permisos sobre el runner del resultado antes de borrarlo
+	runnerId, responseErr := rs.resultsRepository.GetResultRunnerId(ctx, resultId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	responseErr = authorizeRunnerWrite(ctx, rs.clubsRepository, principal, runnerId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	transaction, err := repositories.BeginTransaction(ctx, rs.runnersRepository)
 	if err != nil {
 		return &models.ResponseError{
 			Message: "Failed to start transaction",
@@ -164,22 +414,24 @@ func (rs ResultsService) DeleteResult(resultId string) *models.ResponseError {
 		}
 	}
 
-	result, responseErr := rs.resultsRepository.DeleteResult(resultId)
+	result, responseErr := rs.resultsRepository.DeleteResult(ctx, transaction, resultId)
 	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
 		return responseErr
 	}
 
-	runner, responseErr := rs.runnersRepository.GetRunner(result.RunnerID)
+	runner, responseErr := rs.runnersRepository.GetRunner(ctx, result.RunnerID)
 	if responseErr != nil {
-		repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
+		repositories.RollbackTransaction(transaction)
 		return responseErr
 	}
 
 	// Checking if the deleted result is personal best for the runner
-	if runner.PersonalBest == result.RaceResult {
-		personalBest, responseErr := rs.resultsRepository.GetPersonalBestResults(result.RunnerID)
+	marathon := result.Distance == models.MarathonDistance
+	if marathon && runner.PersonalBest == result.RaceResult {
+		personalBest, responseErr := rs.resultsRepository.GetPersonalBestResults(ctx, result.RunnerID)
 		if responseErr != nil {
-			repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
+			repositories.RollbackTransaction(transaction)
 			return responseErr
 		}
 		runner.PersonalBest = personalBest
@@ -187,27 +439,289 @@ func (rs ResultsService) DeleteResult(resultId string) *models.ResponseError {
 
 	// Checking if the deleted result is season best for the runner
 	currentYear := time.Now().Year()
-	if runner.SeasonBest == result.RaceResult && result.Year == currentYear {
-		seasonBest, responseErr := rs.resultsRepository.GetSeasonBestResults(result.RunnerID, result.Year)
+	if marathon && runner.SeasonBest == result.RaceResult && result.Year == currentYear {
+		seasonBest, responseErr := rs.resultsRepository.GetSeasonBestResults(ctx, result.RunnerID, result.Year)
 		if responseErr != nil {
-			repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
+			repositories.RollbackTransaction(transaction)
 			return responseErr
 		}
 		runner.SeasonBest = seasonBest
 	}
 
-	responseErr = rs.runnersRepository.UpdateRunnerResults(runner)
+	responseErr = rs.runnersRepository.UpdateRunnerResults(ctx, transaction, runner)
 	if responseErr != nil {
-		repositories.RollbackTransaction(rs.runnersRepository, rs.resultsRepository)
+		repositories.RollbackTransaction(transaction)
 		return responseErr
 	}
 
-	repositories.CommitTransaction(rs.runnersRepository, rs.resultsRepository)
+	err = repositories.CommitTransaction(transaction)
+	if err != nil {
+		return &models.ResponseError{
+			Message: "Failed to commit transaction",
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rs.runnersCache.Invalidate(ctx, result.RunnerID)
+
+	return nil
+}
+
+// SubmitResult registra un resultado enviado por el propio runner. El resultado queda pendiente hasta que un administrador lo aprueba
+func (rs ResultsService) SubmitResult(ctx context.Context, principal *models.Principal, result *models.Result) (*models.ResultSubmission, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ResultsService.SubmitResult")
+	defer span.End()
+
+	if principal == nil || principal.RunnerID == "" {
+		return nil, &models.ResponseError{
+			Message: "User is not linked to a runner",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	// los envíos no guardan parciales: los registra el administrador al crear el resultado
+	if len(result.Splits) > 0 {
+		return nil, &models.ResponseError{
+			Message: "Splits are not supported in submissions",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// un runner solo puede enviar sus propios resultados
+	result.RunnerID = principal.RunnerID
+	_, responseErr := validateResult(result)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return rs.resultsRepository.CreateSubmission(ctx, &models.ResultSubmission{
+		RunnerID:    result.RunnerID,
+		RaceResult:  result.RaceResult,
+		Location:    result.Location,
+		Position:    result.Position,
+		Year:        result.Year,
+		Distance:    result.Distance,
+		SubmittedBy: principal.UserID,
+	})
+}
+
+// GetSubmissions devuelve la cola de envíos en el estado indicado (por defecto los pendientes). Un administrador de club solo ve los de su club
+func (rs ResultsService) GetSubmissions(ctx context.Context, principal *models.Principal, status string) ([]*models.ResultSubmission, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ResultsService.GetSubmissions")
+	defer span.End()
+
+	if status == "" {
+		status = models.SubmissionPending
+	}
+
+	if status != models.SubmissionPending && status != models.SubmissionApproved && status != models.SubmissionRejected {
+		return nil, &models.ResponseError{
+			Message: "Invalid status",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	clubId := ""
+	if principal.ClubScoped() {
+		clubId = principal.ClubID
+		if clubId == "" {
+			return nil, &models.ResponseError{
+				Message: "Club administrator without club",
+				Status:  http.StatusForbidden,
+			}
+		}
+	}
+
+	return rs.resultsRepository.GetSubmissions(ctx, status, clubId)
+}
+
+// ApproveResult aprueba un envío pendiente: crea el resultado igual que CreateResult, y marca el envío como aprobado en la misma transacción
+func (rs ResultsService) ApproveResult(ctx context.Context, principal *models.Principal, submissionId string) (*models.Result, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ResultsService.ApproveResult")
+	defer span.End()
+
+	submission, responseErr := rs.getPendingSubmission(ctx, principal, submissionId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	result := submission.Result()
+	raceResult, responseErr := validateResult(result)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return rs.saveResult(ctx, result, raceResult, func(transaction *sql.Tx, created *models.Result) *models.ResponseError {
+		return rs.resultsRepository.ApproveSubmission(ctx, transaction, submission.ID, principal.UserID, created.ID)
+	})
+}
+
+func (rs ResultsService) RejectResult(ctx context.Context, principal *models.Principal, submissionId string, reason string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "ResultsService.RejectResult")
+	defer span.End()
+
+	submission, responseErr := rs.getPendingSubmission(ctx, principal, submissionId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return rs.resultsRepository.RejectSubmission(ctx, submission.ID, principal.UserID, reason)
+}
+
+// getPendingSubmission lee el envío y comprueba que está pendiente y que el usuario puede revisarlo
+func (rs ResultsService) getPendingSubmission(ctx context.Context, principal *models.Principal, submissionId string) (*models.ResultSubmission, *models.ResponseError) {
+	if submissionId == "" {
+		return nil, &models.ResponseError{
+			Message: "Invalid result submission ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	submission, responseErr := rs.resultsRepository.GetSubmission(ctx, submissionId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if submission.Status != models.SubmissionPending {
+		return nil, &models.ResponseError{
+			Message: "Result submission is not pending",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	responseErr = authorizeRunnerWrite(ctx, rs.clubsRepository, principal, submission.RunnerID)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return submission, nil
+}
+
+// GetResultsByRunners devuelve los resultados de varios runners con una sola consulta, agrupados por runner. Los runners no ven los resultados de los demás runners que los ocultan
+func (rs ResultsService) GetResultsByRunners(ctx context.Context, principal *models.Principal, runnerIds []string) (map[string][]*models.Result, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "ResultsService.GetResultsByRunners")
+	defer span.End()
+
+	if len(runnerIds) == 0 {
+		return map[string][]*models.Result{}, nil
+	}
+
+	public, ownRunnerId := privacyScope(principal)
+	return rs.resultsRepository.GetResultsByRunners(ctx, runnerIds, public, ownRunnerId)
+}
+
+// distancia máxima de un resultado en kilómetros
+const maxResultDistance = 1000
+
+// validateResult valida el resultado y devuelve la marca como duración
+func validateResult(result *models.Result) (time.Duration, *models.ResponseError) {
+	if result.RunnerID == "" {
+		return 0, &models.ResponseError{
+			Message: "Invalid runner ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if result.RaceResult == "" {
+		return 0, &models.ResponseError{
+			Message: "Invalid race result",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if result.Location == "" {
+		return 0, &models.ResponseError{
+			Message: "Invalid location",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if result.Position < 0 {
+		return 0, &models.ResponseError{
+			Message: "Invalid position",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	// los resultados que no indican la distancia son de maratón
+	if result.Distance == 0 {
+		result.Distance = models.MarathonDistance
+	}
+
+	if result.Distance < 0 || result.Distance > maxResultDistance {
+		return 0, &models.ResponseError{
+			Message: "Invalid distance",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	currentYear := time.Now().Year()
+	if result.Year < 0 || result.Year > currentYear {
+		return 0, &models.ResponseError{
+			Message: "Invalid year",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	raceResult, err := parseRaceResult(result.RaceResult)
+	if err != nil {
+		return 0, &models.ResponseError{
+			Message: "Invalid race result",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr := validateSplits(result)
+	if responseErr != nil {
+		return 0, responseErr
+	}
+
+	return raceResult, nil
+}
+
+// validateSplits comprueba que los parciales del resultado, si los tiene, avanzan en distancia y en tiempo, y que el último es la llegada: la distancia del resultado con la marca final
+func validateSplits(result *models.Result) *models.ResponseError {
+	if len(result.Splits) == 0 {
+		return nil
+	}
+
+	previousDistance, previousTime := 0.0, 0.0
+	for _, split := range result.Splits {
+		if split.Distance <= previousDistance || split.Distance > result.Distance {
+			return &models.ResponseError{
+				Message: "Invalid split distance",
+				Status:  http.StatusBadRequest,
+			}
+		}
+
+		elapsed, err := stats.ParseRaceTime(split.Time)
+		if err != nil || elapsed <= previousTime {
+			return &models.ResponseError{
+				Message: "Invalid split time",
+				Status:  http.StatusBadRequest,
+			}
+		}
+
+		previousDistance, previousTime = split.Distance, elapsed
+	}
+
+	finalTime, err := stats.ParseRaceTime(result.RaceResult)
+	if err != nil || previousDistance != result.Distance || previousTime != finalTime {
+		return &models.ResponseError{
+			Message: "The last split must be the finish, with the race distance and result",
+			Status:  http.StatusBadRequest,
+		}
+	}
 
 	return nil
 }
 
 func parseRaceResult(timeString string) (time.Duration, error) {
+	// el formato es hh:mm:ss. Comprobamos la longitud antes de recortar la cadena
+	if len(timeString) < 8 {
+		return 0, errors.New("invalid race result " + timeString)
+	}
+
 	return time.ParseDuration(
 		timeString[0:2] + "h" +
 			timeString[3:5] + "m" +
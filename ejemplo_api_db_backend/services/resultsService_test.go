@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSplits(t *testing.T) {
+	tests := []struct {
+		name    string
+		splits  []*models.Split
+		message string // mensaje de error esperado, vacío si los tiempos de paso son válidos
+	}{
+		{
+			name:   "Without_Splits",
+			splits: nil,
+		},
+		{
+			name: "Valid",
+			splits: []*models.Split{
+				{Distance: 10, Time: "00:30:05"},
+				{Distance: 21.0975, Time: "01:03:10"},
+				{Distance: 42.195, Time: "02:05:00"},
+			},
+		},
+		{
+			name: "Distance_Not_Increasing",
+			splits: []*models.Split{
+				{Distance: 21.0975, Time: "01:03:10"},
+				{Distance: 10, Time: "01:30:05"},
+				{Distance: 42.195, Time: "02:05:00"},
+			},
+			message: "Invalid split distance",
+		},
+		{
+			name: "Beyond_Finish",
+			splits: []*models.Split{
+				{Distance: 50, Time: "02:05:00"},
+			},
+			message: "Invalid split distance",
+		},
+		{
+			name: "Time_Not_Increasing",
+			splits: []*models.Split{
+				{Distance: 10, Time: "00:30:05"},
+				{Distance: 21.0975, Time: "00:30:05"},
+				{Distance: 42.195, Time: "02:05:00"},
+			},
+			message: "Invalid split time",
+		},
+		{
+			name: "Invalid_Time",
+			splits: []*models.Split{
+				{Distance: 10, Time: "30 min"},
+				{Distance: 42.195, Time: "02:05:00"},
+			},
+			message: "Invalid split time",
+		},
+		{
+			name: "Not_At_Finish",
+			splits: []*models.Split{
+				{Distance: 10, Time: "00:30:05"},
+				{Distance: 40, Time: "01:58:30"},
+			},
+			message: "The last split must be the finish, with the race distance and result",
+		},
+		{
+			name: "Finish_Time_Differs",
+			splits: []*models.Split{
+				{Distance: 10, Time: "00:30:05"},
+				{Distance: 42.195, Time: "02:06:00"},
+			},
+			message: "The last split must be the finish, with the race distance and result",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responseErr := validateSplits(&models.Result{
+				RaceResult: "02:05:00",
+				Distance:   models.MarathonDistance,
+				Splits:     test.splits,
+			})
+
+			if test.message == "" {
+				assert.Nil(t, responseErr)
+				return
+			}
+
+			assert.Equal(t, &models.ResponseError{Message: test.message, Status: http.StatusBadRequest}, responseErr)
+		})
+	}
+}
+
+func TestUpdateBestsOnlyCountsMarathons(t *testing.T) {
+	runner := &models.Runner{PersonalBest: "03:00:00", SeasonBest: "03:00:00"}
+
+	// una media maratón más rápida que la mejor marca de maratón no la cambia
+	improved, responseErr := updateBests(runner, &models.Result{RaceResult: "01:30:00", Year: 2024, Distance: 21.0975}, 90*time.Minute, 2024)
+	assert.Nil(t, responseErr)
+	assert.False(t, improved)
+	assert.Equal(t, "03:00:00", runner.PersonalBest)
+	assert.Equal(t, "03:00:00", runner.SeasonBest)
+
+	improved, responseErr = updateBests(runner, &models.Result{RaceResult: "02:55:00", Year: 2024, Distance: models.MarathonDistance}, 175*time.Minute, 2024)
+	assert.Nil(t, responseErr)
+	assert.True(t, improved)
+	assert.Equal(t, "02:55:00", runner.PersonalBest)
+	assert.Equal(t, "02:55:00", runner.SeasonBest)
+}
+
+func TestDeleteResultCommitError(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	resultsService := NewResultsService(repositories.NewResultsRepository(dbHandler), repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+
+	mock.ExpectQuery("SELECT runner_id").WithArgs("10").WillReturnRows(sqlmock.NewRows([]string{"runner_id"}).AddRow("1"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM results").WithArgs("10").WillReturnRows(
+		sqlmock.NewRows([]string{"runner_id", "race_result", "year", "distance"}).AddRow("1", "00:30:00", 2021, 10000))
+	mock.ExpectQuery("FROM runners").WithArgs("1").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best"}).
+			AddRow("1", "John", "Smith", 30, true, "United States", "02:08:00", nil))
+	mock.ExpectExec("UPDATE runners").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(errors.New("connection lost"))
+
+	// si el commit falla el resultado no se ha borrado, y no se informa de que sí
+	responseErr := resultsService.DeleteResult(context.Background(), nil, "10")
+	assert.Equal(t, &models.ResponseError{Message: "Failed to commit transaction", Status: http.StatusInternalServerError}, responseErr)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,10 +1,15 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"runners-postgresql/models"
+	"runners-postgresql/repositories"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -97,3 +102,159 @@ func TestValidateRunner(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyPrivacy(t *testing.T) {
+	runner := &models.Runner{
+		ID:           "1",
+		FirstName:    "John",
+		LastName:     "Smith",
+		Age:          30,
+		PersonalBest: "02:10:00",
+		SeasonBest:   "02:12:00",
+		Results:      []*models.Result{{ID: "1", RaceResult: "02:10:00"}},
+		Privacy:      &models.Privacy{HideAge: true, HideResults: true},
+	}
+
+	// el propio runner y los administradores ven todos los datos
+	assert.True(t, (&models.Principal{Role: models.RoleRunner, RunnerID: "1"}).CanSeePrivateData("1"))
+	assert.True(t, (&models.Principal{Role: "admin"}).CanSeePrivateData("1"))
+	// un runner sin perfil asociado no es el dueño de ningún runner
+	assert.False(t, (&models.Principal{Role: models.RoleRunner}).CanSeePrivateData(""))
+	assert.False(t, (&models.Principal{Role: models.RoleRunner, RunnerID: "2"}).CanSeePrivateData("1"))
+
+	applyPrivacy(runner)
+	assert.Equal(t, 0, runner.Age)
+	assert.Empty(t, runner.PersonalBest)
+	assert.Empty(t, runner.SeasonBest)
+	assert.Empty(t, runner.Results)
+	assert.Nil(t, runner.Privacy)
+	assert.Equal(t, "John", runner.FirstName)
+}
+
+func TestSearchRunnersPageLimit(t *testing.T) {
+	runnersService := NewRunnersService(nil, nil, nil, nil, nil)
+
+	// una página enorme desbordaría el desplazamiento, así que se rechaza antes de llegar al repositorio
+	_, responseErr := runnersService.SearchRunners(context.Background(), nil, "smith", "922337203685477580", "100")
+	assert.Equal(t, http.StatusBadRequest, responseErr.Status)
+	assert.Equal(t, "Invalid page", responseErr.Message)
+}
+
+func TestSearchRunnersPrivacy(t *testing.T) {
+	tests := []struct {
+		name        string
+		principal   *models.Principal
+		public      bool
+		ownRunnerId string
+	}{
+		{name: "Admin", principal: &models.Principal{Role: "admin"}, public: false, ownRunnerId: ""},
+		{name: "Runner", principal: &models.Principal{Role: models.RoleRunner, RunnerID: "1"}, public: true, ownRunnerId: "1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbHandler, mock, _ := sqlmock.New()
+			defer dbHandler.Close()
+			runnersService := NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+
+			// los administradores ven los datos ocultos, y los runners los suyos
+			mock.ExpectBegin()
+			mock.ExpectExec("set_config").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("LEFT JOIN runner_privacy").WithArgs("smith", sqlmock.AnyArg(), test.public, test.ownRunnerId, 20, 0).WillReturnRows(
+				sqlmock.NewRows([]string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best", "score", "total"}).
+					AddRow("1", "John", "Smith", 30, true, "United States", "02:08:00", nil, 1.0, 1))
+			mock.ExpectRollback()
+
+			page, responseErr := runnersService.SearchRunners(context.Background(), test.principal, "smith", "", "")
+			assert.Nil(t, responseErr)
+			assert.Equal(t, 1, page.Total)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetRunnersPagePastLastPage(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	runnersService := NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, nil, nil, nil)
+
+	// como en el listado por país, solo cuentan los runners activos
+	mock.ExpectQuery(`SELECT runners.id, runners.first_name, runners.last_name(.|\n)*is_active = 'true'`).WithArgs(20, 40, false, "", "Spain").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best", "count"}))
+	// la página está vacía, así que el total se cuenta aparte
+	mock.ExpectQuery(`SELECT count\(\*\)(.|\n)*is_active = 'true'`).WithArgs("Spain").WillReturnRows(
+		sqlmock.NewRows([]string{"count"}).AddRow(25))
+
+	runnerPage, responseErr := runnersService.GetRunnersPage(context.Background(), &models.Principal{Role: "admin"}, models.RunnersFilter{Country: "Spain"}, 3, 20)
+	assert.Nil(t, responseErr)
+	assert.Empty(t, runnerPage.Runners)
+	assert.Equal(t, 25, runnerPage.Total)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportRunnersUsesListFilters(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	exportService := NewExportService(repositories.NewRunnersRepository(dbHandler), nil)
+	columns := []string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best"}
+
+	// la exportación por país, como el listado, deja fuera a los runners dados de baja
+	mock.ExpectQuery(`FROM runners(.|\n)*WHERE runners.country = \$3 AND runners.is_active = 'true'`).WithArgs(false, "", "Spain").WillReturnRows(
+		sqlmock.NewRows(columns).AddRow("1", "Juan", "García", 30, true, "Spain", "02:05:00", nil))
+	// y por año, como la clasificación del año, se cruza con los resultados de ese año
+	mock.ExpectQuery(`FROM runners(.|\n)*INNER JOIN \((.|\n)*WHERE year = \$3`).WithArgs(false, "", 2023).WillReturnRows(
+		sqlmock.NewRows(columns))
+
+	exported := 0
+	count := func(*models.Runner) error {
+		exported++
+		return nil
+	}
+	assert.Nil(t, exportService.ExportRunners(context.Background(), models.RunnersFilter{Country: "Spain"}, count))
+	assert.Nil(t, exportService.ExportRunners(context.Background(), models.RunnersFilter{Year: 2023}, count))
+	assert.Equal(t, 1, exported)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPagedListAndExportKeepRunnerOwnData(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	runnersRepository := repositories.NewRunnersRepository(dbHandler)
+	runnersService := NewRunnersService(runnersRepository, nil, nil, nil, nil)
+	exportService := NewExportService(runnersRepository, nil)
+	principal := &models.Principal{Role: models.RoleRunner, RunnerID: "1"}
+
+	// el listado paginado y la exportación aplican la privacidad igual que GET /runner: a los demás runners, pero no al que consulta
+	mock.ExpectQuery(`LEFT JOIN runner_privacy`).WithArgs(20, 0, true, "1", "Spain").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best", "count"}))
+	mock.ExpectQuery(`LEFT JOIN runner_privacy`).WithArgs(true, "1", "Spain").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "first_name", "last_name", "age", "is_active", "country", "personal_best", "season_best"}))
+
+	_, responseErr := runnersService.GetRunnersPage(context.Background(), principal, models.RunnersFilter{Country: "Spain"}, 1, 20)
+	assert.Nil(t, responseErr)
+
+	filter, responseErr := exportService.RunnersFilter(principal, "Spain", "")
+	assert.Nil(t, responseErr)
+	assert.Nil(t, exportService.ExportRunners(context.Background(), filter, func(*models.Runner) error { return nil }))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteRunnerCommitError(t *testing.T) {
+	dbHandler, mock, _ := sqlmock.New()
+	defer dbHandler.Close()
+	runnersService := NewRunnersService(repositories.NewRunnersRepository(dbHandler), nil, repositories.NewOutboxRepository(dbHandler), nil, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE runners SET is_active").WithArgs("1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO outbox_events").WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("1", time.Now()))
+	mock.ExpectCommit().WillReturnError(errors.New("connection lost"))
+
+	// si el commit falla el runner no se ha borrado, y no se informa de que sí
+	responseErr := runnersService.DeleteRunner(context.Background(), nil, "1")
+	assert.Equal(t, &models.ResponseError{Message: "Failed to commit transaction", Status: http.StatusInternalServerError}, responseErr)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
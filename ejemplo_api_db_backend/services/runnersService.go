@@ -1,9 +1,14 @@
 package services
 
 import (
+	"context"
 	"net/http"
+	"runners-postgresql/cache"
 	"runners-postgresql/models"
 	"runners-postgresql/repositories"
+	"runners-postgresql/search"
+	"runners-postgresql/stats"
+	"runners-postgresql/tracing"
 	"strconv"
 	"time"
 )
@@ -11,25 +16,132 @@ import (
 type RunnersService struct {
 	runnersRepository *repositories.RunnersRepository
 	resultsRepository *repositories.ResultsRepository
+	outboxRepository  *repositories.OutboxRepository
+	clubsRepository   *repositories.ClubsRepository
+	runnersCache      *cache.ReadThrough // caché de GetRunner, con los resultados del runner
 }
 
-func NewRunnersService(runnersRepository *repositories.RunnersRepository, resultsRepository *repositories.ResultsRepository) *RunnersService {
+func NewRunnersService(runnersRepository *repositories.RunnersRepository, resultsRepository *repositories.ResultsRepository, outboxRepository *repositories.OutboxRepository, clubsRepository *repositories.ClubsRepository, runnersCache *cache.ReadThrough) *RunnersService {
 	return &RunnersService{
 		runnersRepository: runnersRepository,
 		resultsRepository: resultsRepository,
+		outboxRepository:  outboxRepository,
+		clubsRepository:   clubsRepository,
+		runnersCache:      runnersCache,
 	}
 }
 
-func (rs RunnersService) CreateRunner(runner *models.Runner) (*models.Runner, *models.ResponseError) {
+// CreateRunner crea un runner. Los runners que crea un administrador de club se dan de alta en su club
+func (rs RunnersService) CreateRunner(ctx context.Context, principal *models.Principal, runner *models.Runner) (*models.Runner, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.CreateRunner")
+	defer span.End()
+
 	responseErr := validateRunner(runner)
 	if responseErr != nil {
 		return nil, responseErr
 	}
 
-	return rs.runnersRepository.CreateRunner(runner)
+	if principal.ClubScoped() {
+		if principal.ClubID == "" {
+			return nil, &models.ResponseError{
+				Message: "Club administrator without club",
+				Status:  http.StatusForbidden,
+			}
+		}
+
+		return rs.runnersRepository.CreateRunnerInClub(ctx, runner, principal.ClubID)
+	}
+
+	return rs.runnersRepository.CreateRunner(ctx, runner)
 }
 
-func (rs RunnersService) UpdateRunner(runner *models.Runner) *models.ResponseError {
+// CreateRunners crea varios runners en una única transacción y devuelve el estado de cada uno. En modo atomic, si falla alguno no se crea ninguno
+func (rs RunnersService) CreateRunners(ctx context.Context, principal *models.Principal, batch *models.RunnersBatch) (*models.BatchResponse, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.CreateRunners")
+	defer span.End()
+
+	response, responseErr := newBatchResponse(batch.Mode, len(batch.Items))
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	// como en CreateRunner, los runners que crea un administrador de club se dan de alta en su club
+	clubId := ""
+	if principal.ClubScoped() {
+		if principal.ClubID == "" {
+			return nil, &models.ResponseError{
+				Message: "Club administrator without club",
+				Status:  http.StatusForbidden,
+			}
+		}
+		clubId = principal.ClubID
+	}
+
+	for i, runner := range batch.Items {
+		if runner == nil {
+			failBatchItem(response.Items[i], &models.ResponseError{
+				Message: "Invalid runner",
+				Status:  http.StatusBadRequest,
+			})
+			continue
+		}
+
+		responseErr = validateRunner(runner)
+		if responseErr != nil {
+			failBatchItem(response.Items[i], responseErr)
+		}
+	}
+
+	// un lote atomic con algún runner no válido no llega a la base de datos
+	if response.Mode == models.BatchAtomic && batchFailed(response) {
+		abortBatch(response)
+		return finishBatch(response), nil
+	}
+
+	transaction, err := repositories.BeginTransaction(ctx, rs.runnersRepository)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: "Failed to start transaction",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr = writeBatch(ctx, transaction, response, func(item *models.BatchItem) *models.ResponseError {
+		runner, responseErr := rs.runnersRepository.CreateRunnerInTransaction(ctx, transaction, batch.Items[item.Index], clubId)
+		if responseErr != nil {
+			return responseErr
+		}
+
+		item.Status = http.StatusOK
+		item.Data = runner
+		return nil
+	})
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return nil, responseErr
+	}
+
+	if response.Mode == models.BatchAtomic && batchFailed(response) {
+		repositories.RollbackTransaction(transaction)
+		abortBatch(response)
+		return finishBatch(response), nil
+	}
+
+	err = repositories.CommitTransaction(transaction)
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: "Failed to commit transaction",
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return finishBatch(response), nil
+}
+
+func (rs RunnersService) UpdateRunner(ctx context.Context, principal *models.Principal, runner *models.Runner) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "RunnersService.UpdateRunner")
+	defer span.End()
+
 	responseErr := validateRunnerId(runner.ID)
 	if responseErr != nil {
 		return responseErr
@@ -40,40 +152,177 @@ func (rs RunnersService) UpdateRunner(runner *models.Runner) *models.ResponseErr
 		return responseErr
 	}
 
-	return rs.runnersRepository.UpdateRunner(runner)
+	responseErr = authorizeRunnerWrite(ctx, rs.clubsRepository, principal, runner.ID)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	responseErr = rs.runnersRepository.UpdateRunner(ctx, runner)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	rs.runnersCache.Invalidate(ctx, runner.ID)
+
+	return nil
 }
 
-func (rs RunnersService) DeleteRunner(runnerId string) *models.ResponseError {
+func (rs RunnersService) DeleteRunner(ctx context.Context, principal *models.Principal, runnerId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "RunnersService.DeleteRunner")
+	defer span.End()
+
 	responseErr := validateRunnerId(runnerId)
 	if responseErr != nil {
 		return responseErr
 	}
 
-	return rs.runnersRepository.DeleteRunner(runnerId)
+	responseErr = authorizeRunnerWrite(ctx, rs.clubsRepository, principal, runnerId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	transaction, err := repositories.BeginTransaction(ctx, rs.runnersRepository)
+	if err != nil {
+		return &models.ResponseError{
+			Message: "Failed to start transaction",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr = rs.runnersRepository.DeleteRunner(ctx, transaction, runnerId)
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return responseErr
+	}
+
+	_, responseErr = rs.outboxRepository.InsertEvent(ctx, transaction, models.EventRunnerDeleted, runnerId, &models.RunnerDeletedEvent{
+		RunnerID: runnerId,
+	})
+	if responseErr != nil {
+		repositories.RollbackTransaction(transaction)
+		return responseErr
+	}
+
+	err = repositories.CommitTransaction(transaction)
+	if err != nil {
+		return &models.ResponseError{
+			Message: "Failed to commit transaction",
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	// invalidamos la caché una vez confirmado el cambio
+	rs.runnersCache.Invalidate(ctx, runnerId)
+
+	return nil
 }
 
-func (rs RunnersService) GetRunner(runnerId string) (*models.Runner, *models.ResponseError) {
+// GetRunner devuelve el runner con sus resultados. Si quien lo consulta es otro runner se aplican las preferencias de privacidad del runner
+func (rs RunnersService) GetRunner(ctx context.Context, principal *models.Principal, runnerId string) (*models.Runner, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.GetRunner")
+	defer span.End()
+
 	responseErr := validateRunnerId(runnerId)
 	if responseErr != nil {
 		return nil, responseErr
 	}
 
-	runner, responseErr := rs.runnersRepository.GetRunner(runnerId)
+	// si el runner no está en la caché lo leemos de la base de datos, junto con sus resultados
+	var runner models.Runner
+	responseErr = rs.runnersCache.Get(ctx, runnerId, &runner, func() (interface{}, *models.ResponseError) {
+		runner, responseErr := rs.runnersRepository.GetRunner(ctx, runnerId)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		results, responseErr := rs.resultsRepository.GetAllRunnersResults(ctx, runnerId)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		splits, responseErr := rs.resultsRepository.GetRunnerSplits(ctx, runnerId)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		for _, result := range results {
+			result.Splits = splits[result.ID]
+		}
+
+		runner.Results = results
+		runner.SplitAnalysis = stats.AnalyzeSplits(results)
+
+		privacy, responseErr := rs.runnersRepository.GetRunnerPrivacy(ctx, runnerId)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		runner.Privacy = privacy
+
+		return runner, nil
+	})
 	if responseErr != nil {
 		return nil, responseErr
 	}
 
-	results, responseErr := rs.resultsRepository.GetAllRunnersResults(runnerId)
+	if !principal.CanSeePrivateData(runner.ID) {
+		applyPrivacy(&runner)
+	}
+
+	return &runner, nil
+}
+
+// GetOwnRunner devuelve el runner asociado a la cuenta del usuario
+func (rs RunnersService) GetOwnRunner(ctx context.Context, principal *models.Principal) (*models.Runner, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.GetOwnRunner")
+	defer span.End()
+
+	responseErr := validateOwnRunner(principal)
 	if responseErr != nil {
 		return nil, responseErr
 	}
 
-	runner.Results = results
+	return rs.GetRunner(ctx, principal, principal.RunnerID)
+}
+
+// UpdateOwnRunner actualiza el perfil y las preferencias de privacidad del runner asociado a la cuenta del usuario. Si no se indican preferencias de privacidad se mantienen las que hubiera
+func (rs RunnersService) UpdateOwnRunner(ctx context.Context, principal *models.Principal, runner *models.Runner) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "RunnersService.UpdateOwnRunner")
+	defer span.End()
+
+	responseErr := validateOwnRunner(principal)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	runner.ID = principal.RunnerID
+	responseErr = validateRunner(runner)
+	if responseErr != nil {
+		return responseErr
+	}
 
-	return runner, nil
+	if runner.Privacy == nil {
+		runner.Privacy, responseErr = rs.runnersRepository.GetRunnerPrivacy(ctx, runner.ID)
+		if responseErr != nil {
+			return responseErr
+		}
+	}
+
+	responseErr = rs.runnersRepository.UpdateRunnerProfile(ctx, runner)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	rs.runnersCache.Invalidate(ctx, runner.ID)
+
+	return nil
 }
 
-func (rs RunnersService) GetRunnersBatch(country string, year string) ([]*models.Runner, *models.ResponseError) {
+// GetRunnersBatch devuelve los runners, filtrados por país o por año. Los runners solo ven los datos que los demás runners no ocultan
+func (rs RunnersService) GetRunnersBatch(ctx context.Context, principal *models.Principal, country string, year string) ([]*models.Runner, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.GetRunnersBatch")
+	defer span.End()
+
 	if country != "" && year != "" {
 		return nil, &models.ResponseError{
 			Message: "Only one parameter, country or year, can be passed",
@@ -81,31 +330,218 @@ func (rs RunnersService) GetRunnersBatch(country string, year string) ([]*models
 		}
 	}
 
+	public, ownRunnerId := privacyScope(principal)
+
 	if country != "" {
-		return rs.runnersRepository.GetRunnersByCountry(country)
+		return rs.runnersRepository.GetRunnersByCountry(ctx, country, public, ownRunnerId)
 	}
 
 	if year != "" {
-		intYear, err := strconv.Atoi(year)
-		if err != nil {
-			return nil, &models.ResponseError{
-				Message: "Invalid year",
-				Status:  http.StatusBadRequest,
-			}
+		intYear, responseErr := parseYear(year)
+		if responseErr != nil {
+			return nil, responseErr
 		}
 
-		currentYear := time.Now().Year()
-		if intYear < 0 || intYear > currentYear {
-			return nil, &models.ResponseError{
-				Message: "Invalid year",
-				Status:  http.StatusBadRequest,
-			}
+		return rs.runnersRepository.GetRunnersByYear(ctx, intYear, public, ownRunnerId)
+	}
+
+	return rs.runnersRepository.GetAllRunners(ctx, public, ownRunnerId)
+}
+
+// tamaño máximo de las páginas del listado de runners
+const maxRunnersPageSize = 100
+
+// MaxPage es la última página que se puede pedir en los listados paginados. Con páginas mayores el desplazamiento (page-1)*pageSize podría desbordarse
+const MaxPage = 10000
+
+// GetRunnersPage devuelve una página del listado de runners, filtrado por país o por año. Los runners solo ven los datos que los demás runners no ocultan
+func (rs RunnersService) GetRunnersPage(ctx context.Context, principal *models.Principal, filter models.RunnersFilter, page int, pageSize int) (*models.RunnerPage, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.GetRunnersPage")
+	defer span.End()
+
+	if filter.Country != "" && filter.Year != 0 {
+		return nil, &models.ResponseError{
+			Message: "Only one parameter, country or year, can be passed",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if filter.Year < 0 || filter.Year > time.Now().Year() {
+		return nil, &models.ResponseError{
+			Message: "Invalid year",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if page < 1 || page > MaxPage {
+		return nil, &models.ResponseError{
+			Message: "Invalid page",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if pageSize < 1 || pageSize > maxRunnersPageSize {
+		return nil, &models.ResponseError{
+			Message: "Invalid page size",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	filter.Public, filter.OwnRunnerID = privacyScope(principal)
+	runners, total, responseErr := rs.runnersRepository.GetRunnersPage(ctx, filter, pageSize, (page-1)*pageSize)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return &models.RunnerPage{
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+		Runners:  runners,
+	}, nil
+}
+
+// GetRunnerStats devuelve las estadísticas de los resultados del runner: progresión por distancia y año, ritmos, mejoras, posiciones y regularidad. Los demás runners no pueden verlas si el runner oculta sus resultados
+func (rs RunnersService) GetRunnerStats(ctx context.Context, principal *models.Principal, runnerId string) (*models.RunnerStats, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.GetRunnerStats")
+	defer span.End()
+
+	responseErr := validateRunnerId(runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	runner, responseErr := rs.runnersRepository.GetRunner(ctx, runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if runner.ID == "" {
+		return nil, &models.ResponseError{
+			Message: "Runner not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	visible, responseErr := rs.CanSeeResults(ctx, principal, runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if !visible {
+		return nil, &models.ResponseError{
+			Message: "Runner results are private",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	// la base de datos agrega los resultados por distancia y año, y el resto de estadísticas se calculan a partir de los agregados
+	aggregates, responseErr := rs.resultsRepository.GetResultsAggregates(ctx, runnerId)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return stats.Compute(runnerId, aggregates), nil
+}
+
+// CanSeeResults indica si el usuario puede ver los resultados y las marcas del runner: el personal y el propio runner siempre, y los demás runners solo si el runner no los oculta
+func (rs RunnersService) CanSeeResults(ctx context.Context, principal *models.Principal, runnerId string) (bool, *models.ResponseError) {
+	if principal.CanSeePrivateData(runnerId) {
+		return true, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "RunnersService.CanSeeResults")
+	defer span.End()
+
+	privacy, responseErr := rs.runnersRepository.GetRunnerPrivacy(ctx, runnerId)
+	if responseErr != nil {
+		return false, responseErr
+	}
+
+	return !privacy.HideResults, nil
+}
+
+// tamaño de página por defecto y máximo de la búsqueda de runners
+const defaultSearchPageSize = 20
+const maxSearchPageSize = 100
+
+// SearchRunners busca runners por nombre. Los runners no ven los datos que los demás runners ocultan; los administradores y el propio runner ven todos los suyos, como en el listado
+func (rs RunnersService) SearchRunners(ctx context.Context, principal *models.Principal, query string, page string, pageSize string) (*models.RunnerSearchPage, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "RunnersService.SearchRunners")
+	defer span.End()
+
+	// normalizamos aquí la búsqueda para que todos los backends comparen lo mismo
+	normalized := search.Normalize(query)
+	if len([]rune(normalized)) < 2 {
+		return nil, &models.ResponseError{
+			Message: "Invalid search query",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	intPage, responseErr := parsePageParam(page, 1, MaxPage, "Invalid page")
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	intPageSize, responseErr := parsePageParam(pageSize, defaultSearchPageSize, maxSearchPageSize, "Invalid page size")
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	public, ownRunnerId := privacyScope(principal)
+
+	matches, total, responseErr := rs.runnersRepository.SearchRunners(ctx, normalized, public, ownRunnerId, intPageSize, (intPage-1)*intPageSize)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return &models.RunnerSearchPage{
+		Query:    query,
+		Page:     intPage,
+		PageSize: intPageSize,
+		Total:    total,
+		Matches:  matches,
+	}, nil
+}
+
+// privacyScope indica a los repositorios cómo aplicar las preferencias de privacidad de los runners: solo se aplican si quien consulta es un runner (public), y nunca a sus propios datos (ownRunnerId)
+func privacyScope(principal *models.Principal) (bool, string) {
+	if !principal.IsRunner() {
+		return false, ""
+	}
+
+	return true, principal.RunnerID
+}
+
+// applyPrivacy oculta los datos que el runner no quiere mostrar a otros runners
+func applyPrivacy(runner *models.Runner) {
+	if runner.Privacy != nil {
+		if runner.Privacy.HideAge {
+			runner.Age = 0
+		}
+
+		if runner.Privacy.HideResults {
+			runner.Results = nil
+			runner.SplitAnalysis = nil
+			runner.PersonalBest = ""
+			runner.SeasonBest = ""
 		}
+	}
+
+	// las preferencias solo las ven el propio runner y los administradores
+	runner.Privacy = nil
+}
 
-		return rs.runnersRepository.GetRunnersByYear(intYear)
+func validateOwnRunner(principal *models.Principal) *models.ResponseError {
+	if principal == nil || principal.RunnerID == "" {
+		return &models.ResponseError{
+			Message: "User is not linked to a runner",
+			Status:  http.StatusForbidden,
+		}
 	}
 
-	return rs.runnersRepository.GetAllRunners()
+	return nil
 }
 
 func validateRunner(runner *models.Runner) *models.ResponseError {
@@ -139,6 +575,48 @@ func validateRunner(runner *models.Runner) *models.ResponseError {
 	return nil
 }
 
+// parseYear convierte el año recibido como query parameter. Un año vacío se devuelve como 0 (sin filtro)
+func parseYear(year string) (int, *models.ResponseError) {
+	if year == "" {
+		return 0, nil
+	}
+
+	intYear, err := strconv.Atoi(year)
+	if err != nil {
+		return 0, &models.ResponseError{
+			Message: "Invalid year",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	currentYear := time.Now().Year()
+	if intYear < 0 || intYear > currentYear {
+		return 0, &models.ResponseError{
+			Message: "Invalid year",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return intYear, nil
+}
+
+// parsePageParam convierte un parámetro de paginación. Si está vacío se usa el valor por defecto, y si max no es 0 el valor no puede superarlo
+func parsePageParam(value string, defaultValue int, max int, message string) (int, *models.ResponseError) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	intValue, err := strconv.Atoi(value)
+	if err != nil || intValue < 1 || (max != 0 && intValue > max) {
+		return 0, &models.ResponseError{
+			Message: message,
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return intValue, nil
+}
+
 func validateRunnerId(runnerId string) *models.ResponseError {
 	if runnerId == "" {
 		return &models.ResponseError{
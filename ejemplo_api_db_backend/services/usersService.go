@@ -1,25 +1,47 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"net/http"
+	"runners-postgresql/apikeys"
+	"runners-postgresql/cache"
+	"runners-postgresql/certs"
+	"runners-postgresql/logging"
 	"runners-postgresql/models"
 	"runners-postgresql/repositories"
+	"runners-postgresql/tracing"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// longitud mínima de las contraseñas de los usuarios nuevos
+const minPasswordLength = 8
+
 type UsersService struct {
-	usersRepository *repositories.UsersRepository
+	usersRepository   *repositories.UsersRepository
+	apiKeysRepository *repositories.APIKeysRepository
+	rolesCache        *cache.ReadThrough // caché del rol asociado a cada token y a cada clave de API
+	apiKeysUsage      *apiKeysUsage      // último uso de las claves de API guardado en la base de datos
 }
 
-func NewUsersService(usersRepository *repositories.UsersRepository) *UsersService {
+func NewUsersService(usersRepository *repositories.UsersRepository, apiKeysRepository *repositories.APIKeysRepository, rolesCache *cache.ReadThrough) *UsersService {
 	return &UsersService{
-		usersRepository: usersRepository,
+		usersRepository:   usersRepository,
+		apiKeysRepository: apiKeysRepository,
+		rolesCache:        rolesCache,
+		apiKeysUsage:      newAPIKeysUsage(),
 	}
 }
 
-func (us UsersService) Login(username string, password string) (string, *models.ResponseError) {
+func (us UsersService) Login(ctx context.Context, username string, password string) (string, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "UsersService.Login")
+	defer span.End()
+
 	// Validaciones
 	if username == "" || password == "" {
 		return "", &models.ResponseError{
@@ -29,12 +51,13 @@ func (us UsersService) Login(username string, password string) (string, *models.
 	}
 
 	// Comprueba si el usuario y contraseña los tenemos en la base de datos, y si los tenemos obtenemos su id
-	id, responseErr := us.usersRepository.LoginUser(username, password)
+	id, responseErr := us.usersRepository.LoginUser(ctx, username, password)
 	if responseErr != nil {
 		return "", responseErr
 	}
 
 	if id == "" {
+		logging.Warn(ctx, "Login failed", "username", username)
 		return "", &models.ResponseError{
 			Message: "Login failed",
 			Status:  http.StatusUnauthorized,
@@ -47,12 +70,52 @@ func (us UsersService) Login(username string, password string) (string, *models.
 		return "", responseErr
 	}
 	// Guarda el token de acceso en la base de datos asociado al usuario
-	us.usersRepository.SetAccessToken(accessToken, id)
+	us.usersRepository.SetAccessToken(ctx, accessToken, id)
+	logging.Info(ctx, "User logged in", "username", username)
+
+	return accessToken, nil
+}
+
+// LoginExternal inicia la sesión de un usuario autenticado por el proveedor de identidad. La primera vez se da de alta el usuario, y las siguientes se actualiza su rol, que es el que manda el proveedor. Devuelve un token de acceso como el de Login
+func (us UsersService) LoginExternal(ctx context.Context, identity *models.ExternalIdentity) (string, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "UsersService.LoginExternal")
+	defer span.End()
+
+	if identity.Issuer == "" || identity.Subject == "" || identity.Username == "" {
+		return "", &models.ResponseError{
+			Message: "Invalid external identity",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	id, previousToken, responseErr := us.usersRepository.UpsertExternalUser(ctx, identity)
+	if responseErr != nil {
+		return "", responseErr
+	}
+
+	accessToken, responseErr := generateAccessToken(identity.Username)
+	if responseErr != nil {
+		return "", responseErr
+	}
+
+	responseErr = us.usersRepository.SetAccessToken(ctx, accessToken, id)
+	if responseErr != nil {
+		return "", responseErr
+	}
+
+	// el token anterior deja de valer, y con él el rol que pudiera tener en la caché
+	if previousToken != "" {
+		us.rolesCache.Invalidate(ctx, tokenCacheKey(previousToken), principalCacheKey(previousToken))
+	}
+	logging.Info(ctx, "User logged in", "username", identity.Username, "issuer", identity.Issuer)
 
 	return accessToken, nil
 }
 
-func (us UsersService) Logout(accessToken string) *models.ResponseError {
+func (us UsersService) Logout(ctx context.Context, accessToken string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "UsersService.Logout")
+	defer span.End()
+
 	if accessToken == "" {
 		return &models.ResponseError{
 			Message: "Invalid access token",
@@ -60,10 +123,31 @@ func (us UsersService) Logout(accessToken string) *models.ResponseError {
 		}
 	}
 	// Elimina el token de acceso de la base de datos
-	return us.usersRepository.RemoveAccessToken(accessToken)
+	responseErr := us.usersRepository.RemoveAccessToken(ctx, accessToken)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	us.rolesCache.Invalidate(ctx, tokenCacheKey(accessToken), principalCacheKey(accessToken))
+
+	return nil
 }
 
-func (us UsersService) AuthorizeUser(accessToken string, expectedRoles []string) (bool, *models.ResponseError) {
+func (us UsersService) AuthorizeUser(ctx context.Context, accessToken string, expectedRoles []string) (bool, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "UsersService.AuthorizeUser")
+	defer span.End()
+
+	// los servicios internos se autentican con su certificado de cliente en lugar de con un token
+	if certPrincipal := certs.PrincipalFromContext(ctx); accessToken == "" && certPrincipal != nil {
+		return hasRole(certPrincipal, expectedRoles), nil
+	}
+
+	// los sistemas externos se autentican con una clave de API en la cabecera Authorization
+	if credentials := apikeys.FromContext(ctx); accessToken == "" && credentials != nil {
+		principal, responseErr := us.authenticateAPIKey(ctx, credentials, expectedRoles)
+		return principal != nil, responseErr
+	}
+
 	if accessToken == "" {
 		return false, &models.ResponseError{
 			Message: "Invalid access token",
@@ -71,7 +155,10 @@ func (us UsersService) AuthorizeUser(accessToken string, expectedRoles []string)
 		}
 	}
 
-	role, responseErr := us.usersRepository.GetUserRole(accessToken)
+	var role string
+	responseErr := us.rolesCache.Get(ctx, tokenCacheKey(accessToken), &role, func() (interface{}, *models.ResponseError) {
+		return us.usersRepository.GetUserRole(ctx, accessToken)
+	})
 	if responseErr != nil {
 		return false, responseErr
 	}
@@ -92,6 +179,326 @@ func (us UsersService) AuthorizeUser(accessToken string, expectedRoles []string)
 	return false, nil
 }
 
+// Authenticate devuelve el usuario asociado al token si su rol es uno de los esperados, o nil si no lo es. Lo usan los endpoints que necesitan saber quién hace la petición, por ejemplo para limitar los permisos de un administrador de club
+func (us UsersService) Authenticate(ctx context.Context, accessToken string, expectedRoles []string) (*models.Principal, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "UsersService.Authenticate")
+	defer span.End()
+
+	if certPrincipal := certs.PrincipalFromContext(ctx); accessToken == "" && certPrincipal != nil {
+		if !hasRole(certPrincipal, expectedRoles) {
+			return nil, nil
+		}
+		return certPrincipal, nil
+	}
+
+	if credentials := apikeys.FromContext(ctx); accessToken == "" && credentials != nil {
+		return us.authenticateAPIKey(ctx, credentials, expectedRoles)
+	}
+
+	if accessToken == "" {
+		return nil, &models.ResponseError{
+			Message: "Invalid access token",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	var principal *models.Principal
+	responseErr := us.rolesCache.Get(ctx, principalCacheKey(accessToken), &principal, func() (interface{}, *models.ResponseError) {
+		return us.usersRepository.GetPrincipal(ctx, accessToken)
+	})
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if principal == nil || principal.Role == "" {
+		return nil, &models.ResponseError{
+			Message: "Failed to authorize user",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	if !hasRole(principal, expectedRoles) {
+		return nil, nil
+	}
+
+	return principal, nil
+}
+
+// Identify devuelve el usuario del token, de la clave de API o del certificado de cliente de la petición, en el mismo orden que Authenticate pero sin comprobar el rol ni el permiso de la ruta, o nil si las credenciales no son válidas. Lo usan los límites de peticiones, que se aplican antes de llegar a los controladores, para llevar la cuenta de cada usuario
+func (us UsersService) Identify(ctx context.Context, accessToken string) *models.Principal {
+	if accessToken == "" {
+		if certPrincipal := certs.PrincipalFromContext(ctx); certPrincipal != nil {
+			return certPrincipal
+		}
+
+		credentials := apikeys.FromContext(ctx)
+		if credentials == nil {
+			return nil
+		}
+
+		apiKey, responseErr := us.getAPIKey(ctx, credentials.Key)
+		if responseErr != nil || apiKey == nil || apiKey.Expired(time.Now()) {
+			return nil
+		}
+		return apiKey.Principal()
+	}
+
+	var principal *models.Principal
+	responseErr := us.rolesCache.Get(ctx, principalCacheKey(accessToken), &principal, func() (interface{}, *models.ResponseError) {
+		return us.usersRepository.GetPrincipal(ctx, accessToken)
+	})
+	if responseErr != nil || principal == nil || principal.Role == "" {
+		return nil
+	}
+
+	return principal
+}
+
+// SetClubAdmin convierte al usuario en administrador del club
+func (us UsersService) SetClubAdmin(ctx context.Context, username string, clubId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "UsersService.SetClubAdmin")
+	defer span.End()
+
+	if username == "" {
+		return &models.ResponseError{
+			Message: "Invalid username",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	accessToken, responseErr := us.usersRepository.SetClubAdmin(ctx, username, clubId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	// si el usuario tiene sesión abierta, su rol cambia de inmediato
+	if accessToken != "" {
+		us.rolesCache.Invalidate(ctx, tokenCacheKey(accessToken), principalCacheKey(accessToken))
+	}
+
+	return nil
+}
+
+// LinkRunner asocia un runner a la cuenta del usuario, que a partir de entonces puede gestionar su perfil
+func (us UsersService) LinkRunner(ctx context.Context, username string, runnerId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "UsersService.LinkRunner")
+	defer span.End()
+
+	if username == "" {
+		return &models.ResponseError{
+			Message: "Invalid username",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if runnerId == "" {
+		return &models.ResponseError{
+			Message: "Invalid runner ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	accessToken, responseErr := us.usersRepository.LinkRunner(ctx, username, runnerId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if accessToken != "" {
+		us.rolesCache.Invalidate(ctx, principalCacheKey(accessToken))
+	}
+
+	return nil
+}
+
+// GetAllUsers devuelve las cuentas de usuario, sin contraseña ni token
+func (us UsersService) GetAllUsers(ctx context.Context) ([]*models.User, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "UsersService.GetAllUsers")
+	defer span.End()
+
+	return us.usersRepository.GetAllUsers(ctx)
+}
+
+// CreateUser da de alta un usuario que inicia sesión con usuario y contraseña
+func (us UsersService) CreateUser(ctx context.Context, user *models.User) (*models.User, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "UsersService.CreateUser")
+	defer span.End()
+
+	if user.Username == "" {
+		return nil, &models.ResponseError{
+			Message: "Invalid username",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if len(user.Password) < minPasswordLength {
+		return nil, &models.ResponseError{
+			Message: "Invalid password",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr := validateRole(user.Role, user.ClubID)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	response, responseErr := us.usersRepository.CreateUser(ctx, user)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	logging.Info(ctx, "User created", "user", response)
+	return response, nil
+}
+
+// SetUserRole cambia el rol del usuario. Si tiene sesión abierta, el rol cambia de inmediato. A los usuarios del proveedor de identidad se les vuelve a asignar el rol del proveedor en su siguiente login
+func (us UsersService) SetUserRole(ctx context.Context, username string, role string, clubId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "UsersService.SetUserRole")
+	defer span.End()
+
+	if username == "" {
+		return &models.ResponseError{
+			Message: "Invalid username",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr := validateRole(role, clubId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	accessToken, responseErr := us.usersRepository.SetUserRole(ctx, username, role, clubId)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if accessToken != "" {
+		us.rolesCache.Invalidate(ctx, tokenCacheKey(accessToken), principalCacheKey(accessToken))
+	}
+	logging.Info(ctx, "User role changed", "username", username, "role", role)
+
+	return nil
+}
+
+// authenticateAPIKey devuelve el usuario de la clave de API si la clave es válida, tiene el permiso que necesita la ruta y su rol es uno de los esperados, o nil si el rol no lo es
+func (us UsersService) authenticateAPIKey(ctx context.Context, credentials *apikeys.Credentials, expectedRoles []string) (*models.Principal, *models.ResponseError) {
+	apiKey, responseErr := us.getAPIKey(ctx, credentials.Key)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if apiKey == nil {
+		return nil, &models.ResponseError{
+			Message: "Failed to authorize user",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	if apiKey.Expired(time.Now()) {
+		return nil, &models.ResponseError{
+			Message: "API key expired",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	if credentials.Scope == "" {
+		return nil, &models.ResponseError{
+			Message: "API keys are not allowed on this endpoint",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	principal := apiKey.Principal()
+	if !principal.HasScope(credentials.Scope) {
+		return nil, &models.ResponseError{
+			Message: "Missing scope " + credentials.Scope,
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	if !hasRole(principal, expectedRoles) {
+		return nil, nil
+	}
+
+	us.touchAPIKey(ctx, apiKey)
+
+	return principal, nil
+}
+
+// touchAPIKey guarda en segundo plano que la clave se ha usado, sin retrasar la petición. Se guarda como mucho una vez por minuto y clave, para no escribir en la base de datos en cada petición
+func (us UsersService) touchAPIKey(ctx context.Context, apiKey *models.APIKey) {
+	if !us.apiKeysUsage.due(apiKey.ID, time.Now()) {
+		return
+	}
+
+	// la escritura no depende de la petición, que puede terminar antes
+	ctx = context.WithoutCancel(ctx)
+	us.apiKeysUsage.pending.Add(1)
+	go func() {
+		defer us.apiKeysUsage.pending.Done()
+		if responseErr := us.apiKeysRepository.TouchAPIKey(ctx, apiKey.ID); responseErr != nil {
+			logging.Warn(ctx, "Failed to save API key last use", "prefix", apiKey.Prefix, "error", responseErr.Message)
+		}
+	}()
+}
+
+// getAPIKey devuelve la clave de API, o nil si no existe
+func (us UsersService) getAPIKey(ctx context.Context, key string) (*models.APIKey, *models.ResponseError) {
+	hash := apikeys.Hash(key)
+
+	var apiKey *models.APIKey
+	responseErr := us.rolesCache.Get(ctx, apiKeyCacheKey(hash), &apiKey, func() (interface{}, *models.ResponseError) {
+		return us.apiKeysRepository.GetAPIKeyByHash(ctx, hash)
+	})
+
+	return apiKey, responseErr
+}
+
+// validateRole comprueba que el rol es de usuario. Solo los administradores de club tienen club
+func validateRole(role string, clubId string) *models.ResponseError {
+	if role != "admin" && role != models.RoleClubAdmin && role != models.RoleRunner {
+		return &models.ResponseError{
+			Message: "Invalid role",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if (role == models.RoleClubAdmin) != (clubId != "") {
+		return &models.ResponseError{
+			Message: "Invalid club ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return nil
+}
+
+func hasRole(principal *models.Principal, expectedRoles []string) bool {
+	for _, expectedRole := range expectedRoles {
+		if expectedRole == principal.Role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// en la caché no guardamos el token en claro, sino su hash
+func tokenCacheKey(accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func principalCacheKey(accessToken string) string {
+	return "principal:" + tokenCacheKey(accessToken)
+}
+
+// las claves de API ya se guardan por su hash
+func apiKeyCacheKey(hash string) string {
+	return "apikey:" + hash
+}
+
 func generateAccessToken(username string) (string, *models.ResponseError) {
 	// Creamos un token a partir del nombre de usuario. En la generación del token se utiliza el timestamp
 	hash, err := bcrypt.GenerateFromPassword([]byte(username), bcrypt.DefaultCost)
@@ -104,3 +511,32 @@ func generateAccessToken(username string) (string, *models.ResponseError) {
 	// codifica el token en base64 para que sea seguro para su transmisión
 	return base64.StdEncoding.EncodeToString(hash), nil
 }
+
+// intervalo mínimo entre dos escrituras del último uso de una clave de API
+const apiKeyTouchInterval = time.Minute
+
+// apiKeysUsage recuerda cuándo se ha guardado por última vez el uso de cada clave de API
+type apiKeysUsage struct {
+	mutex   sync.Mutex
+	touched map[string]time.Time
+	pending sync.WaitGroup // escrituras en curso
+}
+
+func newAPIKeysUsage() *apiKeysUsage {
+	return &apiKeysUsage{
+		touched: make(map[string]time.Time),
+	}
+}
+
+// due indica si toca guardar el uso de la clave, y si toca lo da por guardado para que las peticiones simultáneas no lo guarden otra vez
+func (u *apiKeysUsage) due(apiKeyId string, now time.Time) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if now.Sub(u.touched[apiKeyId]) < apiKeyTouchInterval {
+		return false
+	}
+
+	u.touched[apiKeyId] = now
+	return true
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"runners-postgresql/tracing"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Cabeceras que acompañan a cada entrega de un webhook
+const (
+	WebhookEventHeader     = "X-Runners-Event"
+	WebhookDeliveryHeader  = "X-Runners-Delivery"
+	WebhookTimestampHeader = "X-Runners-Timestamp"
+	WebhookSignatureHeader = "X-Runners-Signature"
+)
+
+type WebhookDispatcherConfig struct {
+	PollInterval   time.Duration // cada cuanto se consulta el outbox
+	BatchSize      int           // número máximo de eventos y entregas que se procesan en cada consulta
+	MaxAttempts    int           // intentos antes de mandar la entrega al dead letter
+	RetryBaseDelay time.Duration // espera tras el primer intento fallido; se duplica en cada intento
+	RetryMaxDelay  time.Duration // espera máxima entre intentos
+	RequestTimeout time.Duration // timeout de la llamada al webhook
+}
+
+// El dispatcher lee los eventos del outbox y los entrega a las URLs suscritas
+type WebhookDispatcher struct {
+	outboxRepository *repositories.OutboxRepository
+	client           *http.Client
+	config           WebhookDispatcherConfig
+}
+
+func NewWebhookDispatcher(outboxRepository *repositories.OutboxRepository, config WebhookDispatcherConfig) *WebhookDispatcher {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = 5 * time.Second
+	}
+	if config.RetryMaxDelay <= 0 {
+		config.RetryMaxDelay = time.Hour
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 10 * time.Second
+	}
+
+	return &WebhookDispatcher{
+		outboxRepository: outboxRepository,
+		client:           &http.Client{Timeout: config.RequestTimeout},
+		config:           config,
+	}
+}
+
+// Run procesa el outbox periódicamente hasta que se cancele el contexto. No vuelve hasta que termina la ronda de entregas en curso
+func (wd *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(wd.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// al parar, las entregas en curso terminan (como mucho tardan RequestTimeout) en lugar de abortarse
+			wd.dispatch(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+func (wd *WebhookDispatcher) dispatch(ctx context.Context) {
+	// generamos las entregas de los eventos nuevos
+	_, responseErr := wd.outboxRepository.FanOutPendingEvents(ctx, wd.config.BatchSize)
+	if responseErr != nil {
+		slog.ErrorContext(ctx, "Error while processing outbox events", "error", responseErr.Message)
+		return
+	}
+
+	// reservamos las entregas que toca intentar. Mientras dura la llamada el siguiente intento queda aplazado el timeout de la llamada
+	deliveries, responseErr := wd.outboxRepository.ClaimDueDeliveries(ctx, wd.config.BatchSize, 2*wd.config.RequestTimeout)
+	if responseErr != nil {
+		slog.ErrorContext(ctx, "Error while claiming webhook deliveries", "error", responseErr.Message)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			wd.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (wd *WebhookDispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	// cada entrega es una traza nueva, de la que cuelgan la llamada al webhook y la actualización de la entrega
+	ctx, span := tracing.Start(ctx, "WebhookDispatcher.deliver", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("webhook.delivery.id", delivery.ID),
+		attribute.String("webhook.event.type", delivery.Event.Type),
+		attribute.Int("webhook.delivery.attempt", delivery.Attempts),
+	))
+	defer span.End()
+
+	err := wd.deliver(ctx, delivery)
+	if err == nil {
+		responseErr := wd.outboxRepository.MarkDelivered(ctx, delivery.ID)
+		if responseErr != nil {
+			slog.ErrorContext(ctx, "Error while marking webhook delivery as delivered", "delivery", delivery.ID, "error", responseErr.Message)
+		}
+		return
+	}
+
+	// al agotar los intentos la entrega pasa al dead letter
+	dead := delivery.Attempts >= wd.config.MaxAttempts
+	nextAttemptAt := time.Now().Add(retryDelay(delivery.Attempts, wd.config.RetryBaseDelay, wd.config.RetryMaxDelay))
+	span.SetStatus(codes.Error, err.Error())
+	slog.WarnContext(ctx, "Webhook delivery failed", "delivery", delivery.ID, "attempt", delivery.Attempts, "error", err)
+
+	responseErr := wd.outboxRepository.MarkFailed(ctx, delivery.ID, err.Error(), nextAttemptAt, dead)
+	if responseErr != nil {
+		slog.ErrorContext(ctx, "Error while marking webhook delivery as failed", "delivery", delivery.ID, "error", responseErr.Message)
+	}
+}
+
+// deliver hace la llamada al webhook. Cualquier respuesta distinta de 2xx se considera un fallo
+func (wd *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, delivery.Event.Type)
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(delivery.Secret, timestamp, body))
+	// el receptor puede continuar la traza de la entrega con la cabecera traceparent
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := wd.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// leemos el cuerpo para poder reutilizar la conexión
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+// SignWebhook calcula la firma HMAC-SHA256 de una entrega. Se firma el timestamp junto con el cuerpo para que el receptor pueda rechazar entregas repetidas
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay calcula la espera tras el intento indicado con backoff exponencial: base, 2*base, 4*base... hasta max
+func retryDelay(attempt int, base time.Duration, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+
+	if delay > max {
+		return max
+	}
+
+	return delay
+}
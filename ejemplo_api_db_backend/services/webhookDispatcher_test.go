@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runners-postgresql/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration
+	}{
+		{name: "First_Attempt", attempt: 1, want: 5 * time.Second},
+		{name: "Second_Attempt", attempt: 2, want: 10 * time.Second},
+		{name: "Fourth_Attempt", attempt: 4, want: 40 * time.Second},
+		{name: "Capped", attempt: 30, want: time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, retryDelay(test.attempt, 5*time.Second, time.Minute))
+		})
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	delivery := &models.WebhookDelivery{
+		ID:     "delivery-1",
+		Secret: "secret",
+		Event: &models.Event{
+			ID:          "event-1",
+			Type:        models.EventRunnerPersonalBest,
+			AggregateID: "runner-1",
+			Payload:     json.RawMessage(`{"runner_id":"runner-1","personal_best":"02:05:00"}`),
+		},
+	}
+
+	// el receptor comprueba la firma tal y como lo haría un cliente
+	var received *models.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+
+		if r.Header.Get(WebhookSignatureHeader) != "sha256="+SignWebhook("secret", timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		assert.Equal(t, models.EventRunnerPersonalBest, r.Header.Get(WebhookEventHeader))
+		assert.Equal(t, "delivery-1", r.Header.Get(WebhookDeliveryHeader))
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatcher := NewWebhookDispatcher(nil, WebhookDispatcherConfig{})
+
+	delivery.URL = server.URL
+	err := dispatcher.deliver(context.Background(), delivery)
+	assert.NoError(t, err)
+	assert.Equal(t, "event-1", received.ID)
+	assert.JSONEq(t, string(delivery.Event.Payload), string(received.Payload))
+
+	// con otro secreto la firma no coincide y la entrega falla
+	delivery.Secret = "other"
+	err = dispatcher.deliver(context.Background(), delivery)
+	assert.EqualError(t, err, "webhook responded with status 401")
+}
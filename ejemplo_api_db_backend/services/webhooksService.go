@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"runners-postgresql/models"
+	"runners-postgresql/repositories"
+	"runners-postgresql/tracing"
+)
+
+type WebhooksService struct {
+	webhooksRepository *repositories.WebhooksRepository
+	outboxRepository   *repositories.OutboxRepository
+}
+
+func NewWebhooksService(webhooksRepository *repositories.WebhooksRepository, outboxRepository *repositories.OutboxRepository) *WebhooksService {
+	return &WebhooksService{
+		webhooksRepository: webhooksRepository,
+		outboxRepository:   outboxRepository,
+	}
+}
+
+func (ws WebhooksService) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "WebhooksService.CreateSubscription")
+	defer span.End()
+
+	responseErr := validateSubscription(subscription)
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	// si no nos indican un secreto lo generamos. Solo se devuelve en la respuesta de la creación
+	if subscription.Secret == "" {
+		secret, responseErr := generateWebhookSecret()
+		if responseErr != nil {
+			return nil, responseErr
+		}
+		subscription.Secret = secret
+	}
+
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []string{}
+	}
+	subscription.IsActive = true
+
+	return ws.webhooksRepository.CreateSubscription(ctx, subscription)
+}
+
+func (ws WebhooksService) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "WebhooksService.UpdateSubscription")
+	defer span.End()
+
+	if subscription.ID == "" {
+		return &models.ResponseError{
+			Message: "Invalid webhook subscription ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr := validateSubscription(subscription)
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []string{}
+	}
+
+	return ws.webhooksRepository.UpdateSubscription(ctx, subscription)
+}
+
+func (ws WebhooksService) DeleteSubscription(ctx context.Context, subscriptionId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "WebhooksService.DeleteSubscription")
+	defer span.End()
+
+	if subscriptionId == "" {
+		return &models.ResponseError{
+			Message: "Invalid webhook subscription ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return ws.webhooksRepository.DeleteSubscription(ctx, subscriptionId)
+}
+
+func (ws WebhooksService) GetSubscription(ctx context.Context, subscriptionId string) (*models.WebhookSubscription, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "WebhooksService.GetSubscription")
+	defer span.End()
+
+	if subscriptionId == "" {
+		return nil, &models.ResponseError{
+			Message: "Invalid webhook subscription ID",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return ws.webhooksRepository.GetSubscription(ctx, subscriptionId)
+}
+
+func (ws WebhooksService) GetAllSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "WebhooksService.GetAllSubscriptions")
+	defer span.End()
+
+	return ws.webhooksRepository.GetAllSubscriptions(ctx)
+}
+
+func (ws WebhooksService) GetDeliveries(ctx context.Context, subscriptionId string, status string) ([]*models.WebhookDelivery, *models.ResponseError) {
+	ctx, span := tracing.Start(ctx, "WebhooksService.GetDeliveries")
+	defer span.End()
+
+	if status != "" && status != models.DeliveryPending && status != models.DeliveryDelivered && status != models.DeliveryDead {
+		return nil, &models.ResponseError{
+			Message: "Invalid delivery status",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	return ws.outboxRepository.GetDeliveries(ctx, subscriptionId, status)
+}
+
+func (ws WebhooksService) RetryDelivery(ctx context.Context, subscriptionId string, deliveryId string) *models.ResponseError {
+	ctx, span := tracing.Start(ctx, "WebhooksService.RetryDelivery")
+	defer span.End()
+
+	return ws.outboxRepository.RetryDelivery(ctx, subscriptionId, deliveryId)
+}
+
+func validateSubscription(subscription *models.WebhookSubscription) *models.ResponseError {
+	webhookUrl, err := url.Parse(subscription.URL)
+	if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+		return &models.ResponseError{
+			Message: "Invalid webhook URL",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	for _, eventType := range subscription.EventTypes {
+		valid := false
+		for _, knownType := range models.EventTypes {
+			if eventType == knownType {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return &models.ResponseError{
+				Message: "Invalid event type " + eventType,
+				Status:  http.StatusBadRequest,
+			}
+		}
+	}
+
+	return nil
+}
+
+func generateWebhookSecret() (string, *models.ResponseError) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: "Failed to generate webhook secret",
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"math"
+	"runners-postgresql/models"
+	"sort"
+)
+
+// Tipos de split: cómo se reparte el tiempo entre las dos mitades de la carrera
+const (
+	SplitNegative = "negative" // la segunda mitad es más rápida
+	SplitPositive = "positive" // la segunda mitad es más lenta
+	SplitEven     = "even"
+)
+
+// punto de paso: distancia y tiempo desde la salida, en kilómetros y segundos
+type point struct {
+	distance float64
+	elapsed  float64
+}
+
+type segment struct {
+	from float64
+	to   float64
+}
+
+// AnalyzeSplits analiza los resultados que tienen tiempos de paso: cómo se reparte el tiempo entre las dos mitades de cada carrera, y el mejor tiempo del runner en cada tramo entre dos puntos de paso. Devuelve nil si ningún resultado tiene tiempos de paso
+func AnalyzeSplits(results []*models.Result) *models.SplitAnalysis {
+	analysis := &models.SplitAnalysis{
+		Races:        make([]*models.RaceSplits, 0),
+		BestSegments: make([]*models.SegmentBest, 0),
+	}
+	bests := make(map[segment]float64)
+	segmentBests := make(map[segment]*models.SegmentBest)
+
+	for _, result := range results {
+		points, ok := splitPoints(result)
+		if !ok {
+			continue
+		}
+
+		finish := points[len(points)-1]
+		firstHalf := elapsedAt(points, finish.distance/2)
+		secondHalf := finish.elapsed - firstHalf
+		difference := math.Round(secondHalf) - math.Round(firstHalf)
+
+		split := SplitEven
+		if difference < 0 {
+			split = SplitNegative
+		} else if difference > 0 {
+			split = SplitPositive
+		}
+
+		analysis.Races = append(analysis.Races, &models.RaceSplits{
+			ResultID:   result.ID,
+			Location:   result.Location,
+			Year:       result.Year,
+			Distance:   finish.distance,
+			FirstHalf:  FormatRaceTime(firstHalf),
+			SecondHalf: FormatRaceTime(secondHalf),
+			Difference: formatDifference(difference),
+			Split:      split,
+		})
+
+		for i := 1; i < len(points); i++ {
+			key := segment{from: points[i-1].distance, to: points[i].distance}
+			elapsed := points[i].elapsed - points[i-1].elapsed
+			if best, found := bests[key]; found && best <= elapsed {
+				continue
+			}
+
+			bests[key] = elapsed
+			segmentBests[key] = &models.SegmentBest{
+				From:      key.from,
+				To:        key.to,
+				Time:      FormatRaceTime(elapsed),
+				PacePerKm: formatPace(elapsed / (key.to - key.from)),
+				ResultID:  result.ID,
+				Location:  result.Location,
+				Year:      result.Year,
+			}
+		}
+	}
+
+	if len(analysis.Races) == 0 {
+		return nil
+	}
+
+	for _, segmentBest := range segmentBests {
+		analysis.BestSegments = append(analysis.BestSegments, segmentBest)
+	}
+	sort.Slice(analysis.BestSegments, func(i, j int) bool {
+		if analysis.BestSegments[i].From != analysis.BestSegments[j].From {
+			return analysis.BestSegments[i].From < analysis.BestSegments[j].From
+		}
+		return analysis.BestSegments[i].To < analysis.BestSegments[j].To
+	})
+
+	return analysis
+}
+
+// splitPoints devuelve los puntos de paso del resultado, empezando por la salida. Los tiempos de paso se validan al crear el resultado, así que uno que no se pueda leer descarta el resultado
+func splitPoints(result *models.Result) ([]point, bool) {
+	if len(result.Splits) == 0 {
+		return nil, false
+	}
+
+	points := make([]point, 0, len(result.Splits)+1)
+	points = append(points, point{})
+	for _, split := range result.Splits {
+		elapsed, err := ParseRaceTime(split.Time)
+		if err != nil {
+			return nil, false
+		}
+		points = append(points, point{distance: split.Distance, elapsed: elapsed})
+	}
+
+	return points, true
+}
+
+// elapsedAt estima el tiempo de paso en una distancia interpolando entre los puntos de paso anterior y posterior, es decir, suponiendo ritmo constante en el tramo
+func elapsedAt(points []point, distance float64) float64 {
+	for i := 1; i < len(points); i++ {
+		if points[i].distance >= distance {
+			previous, next := points[i-1], points[i]
+			return previous.elapsed + (next.elapsed-previous.elapsed)*(distance-previous.distance)/(next.distance-previous.distance)
+		}
+	}
+
+	return points[len(points)-1].elapsed
+}
+
+// formatDifference escribe una diferencia de tiempo en segundos con signo: +00:01:20 o -00:00:45
+func formatDifference(seconds float64) string {
+	if seconds < 0 {
+		return "-" + FormatRaceTime(-seconds)
+	}
+
+	return "+" + FormatRaceTime(seconds)
+}
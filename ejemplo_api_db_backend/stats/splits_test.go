@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"runners-postgresql/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeSplits(t *testing.T) {
+	analysis := AnalyzeSplits([]*models.Result{
+		// sin tiempos de paso: no cuenta
+		{ID: "1", RaceResult: "02:10:00", Year: 2022},
+		// primera mitad en 16:00 (interpolada entre los 4 y los 6 km) y segunda en 15:40
+		{ID: "2", Location: "Valencia", RaceResult: "00:31:40", Distance: 10, Year: 2023, Splits: []*models.Split{
+			{Distance: 4, Time: "00:12:40"},
+			{Distance: 6, Time: "00:19:20"},
+			{Distance: 10, Time: "00:31:40"},
+		}},
+		{ID: "3", Location: "Berlin", RaceResult: "00:32:00", Distance: 10, Year: 2023, Splits: []*models.Split{
+			{Distance: 4, Time: "00:12:00"},
+			{Distance: 6, Time: "00:18:00"},
+			{Distance: 10, Time: "00:32:00"},
+		}},
+	})
+	require.NotNil(t, analysis)
+
+	require.Len(t, analysis.Races, 2)
+	assert.Equal(t, "2", analysis.Races[0].ResultID)
+	assert.Equal(t, "00:16:00", analysis.Races[0].FirstHalf)
+	assert.Equal(t, "00:15:40", analysis.Races[0].SecondHalf)
+	assert.Equal(t, "-00:00:20", analysis.Races[0].Difference)
+	assert.Equal(t, SplitNegative, analysis.Races[0].Split)
+	assert.Equal(t, SplitPositive, analysis.Races[1].Split)
+
+	// el mejor tiempo de cada tramo puede ser de carreras distintas
+	require.Len(t, analysis.BestSegments, 3)
+	assert.Equal(t, &models.SegmentBest{From: 0, To: 4, Time: "00:12:00", PacePerKm: "3:00", ResultID: "3", Location: "Berlin", Year: 2023}, analysis.BestSegments[0])
+	assert.Equal(t, "2", analysis.BestSegments[2].ResultID)
+	assert.Equal(t, "00:12:20", analysis.BestSegments[2].Time)
+
+	assert.Nil(t, AnalyzeSplits([]*models.Result{{ID: "1", RaceResult: "02:10:00"}}))
+}
@@ -0,0 +1,228 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runners-postgresql/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kilómetros de una milla
+const kmPerMile = 1.609344
+
+// Aggregate agrupa los resultados por distancia y año con los mismos agregados que calcula la consulta de Postgres. La usan los backends que no pueden agregar en la base de datos
+func Aggregate(results []*models.Result) ([]*models.ResultsAggregate, error) {
+	type key struct {
+		distance float64
+		year     int
+	}
+
+	groups := make(map[key]*models.ResultsAggregate)
+	for _, result := range results {
+		seconds, err := ParseRaceTime(result.RaceResult)
+		if err != nil {
+			return nil, err
+		}
+
+		distance := result.Distance
+		if distance == 0 {
+			distance = models.MarathonDistance
+		}
+
+		group, found := groups[key{distance, result.Year}]
+		if !found {
+			group = &models.ResultsAggregate{Distance: distance, Year: result.Year, Best: seconds}
+			groups[key{distance, result.Year}] = group
+		}
+
+		group.Races++
+		group.Sum += seconds
+		group.SumOfSquares += seconds * seconds
+		group.Best = math.Min(group.Best, seconds)
+		if result.Position > 0 {
+			if group.BestPosition == 0 || result.Position < group.BestPosition {
+				group.BestPosition = result.Position
+			}
+			if result.Position > group.WorstPosition {
+				group.WorstPosition = result.Position
+			}
+		}
+	}
+
+	aggregates := make([]*models.ResultsAggregate, 0, len(groups))
+	for _, group := range groups {
+		aggregates = append(aggregates, group)
+	}
+
+	// el mismo orden que la consulta: por distancia y por año
+	sort.Slice(aggregates, func(i, j int) bool {
+		if aggregates[i].Distance != aggregates[j].Distance {
+			return aggregates[i].Distance < aggregates[j].Distance
+		}
+		return aggregates[i].Year < aggregates[j].Year
+	})
+
+	return aggregates, nil
+}
+
+// Compute calcula las estadísticas del runner a partir de los agregados por distancia y año, que tienen que estar ordenados por distancia y por año
+func Compute(runnerId string, aggregates []*models.ResultsAggregate) *models.RunnerStats {
+	runnerStats := &models.RunnerStats{
+		RunnerID:  runnerId,
+		Distances: make([]*models.DistanceStats, 0),
+	}
+
+	for start := 0; start < len(aggregates); {
+		end := start
+		for end < len(aggregates) && aggregates[end].Distance == aggregates[start].Distance {
+			end++
+		}
+
+		distanceStats := computeDistance(aggregates[start:end])
+		runnerStats.Races += distanceStats.Races
+		runnerStats.Distances = append(runnerStats.Distances, distanceStats)
+		start = end
+	}
+
+	return runnerStats
+}
+
+// computeDistance calcula las estadísticas de una distancia a partir de los agregados de cada año
+func computeDistance(years []*models.ResultsAggregate) *models.DistanceStats {
+	distance := years[0].Distance
+	total := &models.ResultsAggregate{Distance: distance, Best: years[0].Best}
+	progression := make([]*models.YearStats, 0, len(years))
+
+	for i, year := range years {
+		total.Races += year.Races
+		total.Sum += year.Sum
+		total.SumOfSquares += year.SumOfSquares
+		total.Best = math.Min(total.Best, year.Best)
+		total.BestPosition = bestPosition(total.BestPosition, year.BestPosition)
+		total.WorstPosition = max(total.WorstPosition, year.WorstPosition)
+
+		average := year.Sum / float64(year.Races)
+		yearStats := &models.YearStats{
+			Year:          year.Year,
+			Races:         year.Races,
+			Best:          FormatRaceTime(year.Best),
+			Average:       FormatRaceTime(average),
+			PacePerKm:     formatPace(average / distance),
+			PacePerMile:   formatPace(average / distance * kmPerMile),
+			BestPosition:  year.BestPosition,
+			WorstPosition: year.WorstPosition,
+		}
+		if i > 0 {
+			yearStats.Improvement = improvement(years[i-1].Best, year.Best)
+		}
+
+		progression = append(progression, yearStats)
+	}
+
+	average := total.Sum / float64(total.Races)
+	distanceStats := &models.DistanceStats{
+		Distance:      distance,
+		Races:         total.Races,
+		Best:          FormatRaceTime(total.Best),
+		Average:       FormatRaceTime(average),
+		PacePerKm:     formatPace(average / distance),
+		PacePerMile:   formatPace(average / distance * kmPerMile),
+		BestPosition:  total.BestPosition,
+		WorstPosition: total.WorstPosition,
+		Consistency:   consistency(total),
+		Progression:   progression,
+	}
+
+	if len(years) > 1 {
+		first, last, previous := years[0], years[len(years)-1], years[len(years)-2]
+		distanceStats.Improvement = improvement(first.Best, last.Best)
+
+		lastAverage := last.Sum / float64(last.Races)
+		previousAverage := previous.Sum / float64(previous.Races)
+		distanceStats.YearOverYear = &models.YearOverYear{
+			Year:               last.Year,
+			PreviousYear:       previous.Year,
+			Best:               FormatRaceTime(last.Best),
+			PreviousBest:       FormatRaceTime(previous.Best),
+			BestImprovement:    improvement(previous.Best, last.Best),
+			Average:            FormatRaceTime(lastAverage),
+			PreviousAverage:    FormatRaceTime(previousAverage),
+			AverageImprovement: improvement(previousAverage, lastAverage),
+			Races:              last.Races,
+			PreviousRaces:      previous.Races,
+		}
+	}
+
+	return distanceStats
+}
+
+// consistency puntúa de 0 a 100 la regularidad de las marcas: 100 menos el coeficiente de variación en porcentaje. Con menos de dos carreras no hay nada que medir
+func consistency(total *models.ResultsAggregate) *float64 {
+	if total.Races < 2 {
+		return nil
+	}
+
+	mean := total.Sum / float64(total.Races)
+	// los redondeos pueden dar una varianza ligeramente negativa cuando todas las marcas son iguales
+	variance := math.Max(total.SumOfSquares/float64(total.Races)-mean*mean, 0)
+	score := round(math.Max(100-math.Sqrt(variance)/mean*100, 0), 1)
+
+	return &score
+}
+
+// improvement es el porcentaje en que ha bajado la marca: positivo si es mejor que la anterior. Sin marca anterior no hay porcentaje, y una división por cero daría NaN o infinito, que no se pueden escribir en JSON
+func improvement(previous float64, current float64) *float64 {
+	if previous <= 0 {
+		return nil
+	}
+
+	percentage := round((previous-current)/previous*100, 2)
+	return &percentage
+}
+
+func bestPosition(a int, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+
+	return a
+}
+
+func round(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
+
+// ParseRaceTime convierte una marca con el formato hh:mm:ss, con fracciones de segundo opcionales, en segundos
+func ParseRaceTime(raceTime string) (float64, error) {
+	parts := strings.Split(raceTime, ":")
+	if len(parts) != 3 {
+		return 0, errors.New("invalid race result " + raceTime)
+	}
+
+	seconds := 0.0
+	for _, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value < 0 {
+			return 0, errors.New("invalid race result " + raceTime)
+		}
+		seconds = seconds*60 + value
+	}
+
+	return seconds, nil
+}
+
+// FormatRaceTime escribe una marca en segundos con el formato hh:mm:ss, redondeada al segundo
+func FormatRaceTime(seconds float64) string {
+	total := int(math.Round(seconds))
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, total/60%60, total%60)
+}
+
+// formatPace escribe un ritmo en segundos con el formato m:ss
+func formatPace(seconds float64) string {
+	total := int(math.Round(seconds))
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
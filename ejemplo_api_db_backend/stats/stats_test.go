@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"runners-postgresql/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFromResults(t *testing.T) {
+	aggregates, err := Aggregate([]*models.Result{
+		{RaceResult: "03:10:00", Position: 20, Year: 2022},
+		{RaceResult: "02:50:00", Position: 5, Year: 2023, Distance: models.MarathonDistance},
+		{RaceResult: "00:40:00", Year: 2023, Distance: 10},
+		{RaceResult: "03:00:00", Position: 10, Year: 2022},
+	})
+	require.NoError(t, err)
+
+	runnerStats := Compute("1", aggregates)
+	assert.Equal(t, 4, runnerStats.Races)
+	require.Len(t, runnerStats.Distances, 2)
+
+	// con una sola carrera no hay mejoras ni regularidad
+	tenK := runnerStats.Distances[0]
+	assert.Equal(t, 10.0, tenK.Distance)
+	assert.Equal(t, "4:00", tenK.PacePerKm)
+	assert.Equal(t, "6:26", tenK.PacePerMile)
+	assert.Nil(t, tenK.Improvement)
+	assert.Nil(t, tenK.Consistency)
+	assert.Nil(t, tenK.YearOverYear)
+
+	// los resultados sin distancia son de maratón
+	marathon := runnerStats.Distances[1]
+	assert.Equal(t, models.MarathonDistance, marathon.Distance)
+	assert.Equal(t, 3, marathon.Races)
+	assert.Equal(t, "02:50:00", marathon.Best)
+	assert.Equal(t, "03:00:00", marathon.Average)
+	assert.Equal(t, "4:16", marathon.PacePerKm)
+	assert.Equal(t, "6:52", marathon.PacePerMile)
+	assert.Equal(t, 5.56, *marathon.Improvement)
+	assert.Equal(t, 5, marathon.BestPosition)
+	assert.Equal(t, 20, marathon.WorstPosition)
+	assert.Equal(t, 95.5, *marathon.Consistency)
+
+	require.Len(t, marathon.Progression, 2)
+	assert.Equal(t, 2022, marathon.Progression[0].Year)
+	assert.Equal(t, "03:05:00", marathon.Progression[0].Average)
+	assert.Nil(t, marathon.Progression[0].Improvement)
+	assert.Equal(t, 5.56, *marathon.Progression[1].Improvement)
+
+	bestImprovement, averageImprovement := 5.56, 8.11
+	assert.Equal(t, &models.YearOverYear{
+		Year:               2023,
+		PreviousYear:       2022,
+		Best:               "02:50:00",
+		PreviousBest:       "03:00:00",
+		BestImprovement:    &bestImprovement,
+		Average:            "02:50:00",
+		PreviousAverage:    "03:05:00",
+		AverageImprovement: &averageImprovement,
+		Races:              1,
+		PreviousRaces:      2,
+	}, marathon.YearOverYear)
+
+	// sin marca anterior no hay mejora
+	assert.Nil(t, improvement(0, 10800))
+}
+
+func TestParseRaceTime(t *testing.T) {
+	seconds, err := ParseRaceTime("02:05:30.5")
+	require.NoError(t, err)
+	assert.Equal(t, 7530.5, seconds)
+	assert.Equal(t, "02:05:31", FormatRaceTime(seconds))
+
+	_, err = ParseRaceTime("2h05")
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogHandler añade a los logs que se escriben con contexto (slog.InfoContext, slog.ErrorContext...) los identificadores de la traza y del span
+func LogHandler(handler slog.Handler) slog.Handler {
+	return logHandler{handler}
+}
+
+type logHandler struct {
+	slog.Handler
+}
+
+func (h logHandler) Handle(ctx context.Context, record slog.Record) error {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if spanContext.HasTraceID() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	if spanContext.HasSpanID() {
+		record.AddAttrs(slog.String("span_id", spanContext.SpanID().String()))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return logHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h logHandler) WithGroup(name string) slog.Handler {
+	return logHandler{h.Handler.WithGroup(name)}
+}
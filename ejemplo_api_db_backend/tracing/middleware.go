@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cabecera de la respuesta con el identificador de la traza, para poder buscarla a partir de una petición concreta
+const TraceIDHeader = "X-Trace-Id"
+
+// Middleware crea un span por petición. Si la petición trae la cabecera traceparent el span continúa esa traza
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// igual que en las métricas, las peticiones que no coinciden con ninguna ruta se agrupan para no crear un nombre de span por URL
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+		spanCtx, span := Start(parent, ctx.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", ctx.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", ctx.Request.URL.Path),
+			))
+		defer span.End()
+
+		// los controladores pasan ctx.Request.Context() a los servicios, así que los spans de servicios y repositorios cuelgan de este
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		if span.SpanContext().HasTraceID() {
+			ctx.Header(TraceIDHeader, span.SpanContext().TraceID().String())
+		}
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// literales de cadena (con las comillas escapadas duplicándolas), parámetros ($1, $2...) y números sueltos
+	sqlLiterals   = regexp.MustCompile(`'(?:[^']|'')*'|\$\d+|\b\d+(?:\.\d+)?\b`)
+	sqlWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL quita de una sentencia los literales, que pueden contener datos de los runners, y la deja en una sola línea. Los parámetros se mantienen porque sus valores no forman parte de la sentencia
+func SanitizeSQL(query string) string {
+	query = sqlLiterals.ReplaceAllStringFunc(query, func(literal string) string {
+		if strings.HasPrefix(literal, "$") {
+			return literal
+		}
+		return "?"
+	})
+
+	return strings.TrimSpace(sqlWhitespace.ReplaceAllString(query, " "))
+}
+
+// OpenDB abre la base de datos con un driver que crea un span por cada consulta, con la sentencia sin literales. Solo se trazan las consultas que forman parte de una traza (peticiones HTTP, entregas de webhooks), de modo que las consultas periódicas del dispatcher o de las comprobaciones de salud no generan trazas sueltas
+func OpenDB(driverName string, dataSourceName string) (*sql.DB, error) {
+	// sql.Open no abre ninguna conexión: solo lo usamos para obtener el driver registrado con ese nombre
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	sqlDriver := db.Driver()
+	db.Close()
+
+	var connector driver.Connector = dsnConnector{dataSourceName: dataSourceName, driver: sqlDriver}
+	if driverContext, ok := sqlDriver.(driver.DriverContext); ok {
+		connector, err = driverContext.OpenConnector(dataSourceName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.OpenDB(tracedConnector{connector: connector, system: driverName}), nil
+}
+
+// para los drivers que no implementan driver.DriverContext
+type dsnConnector struct {
+	dataSourceName string
+	driver         driver.Driver
+}
+
+func (c dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dataSourceName)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type tracedConnector struct {
+	connector driver.Connector
+	system    string
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedConn{Conn: conn, system: c.system}, nil
+}
+
+func (c tracedConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// tracedConn delega en la conexión del driver. Las interfaces opcionales que el driver no implementa devuelven driver.ErrSkip, y database/sql usa su alternativa
+type tracedConn struct {
+	driver.Conn
+	system string
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.start(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	end(span, err)
+
+	return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.start(ctx, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	end(span, err)
+
+	return result, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+
+	return c.Conn.Prepare(query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, options)
+	}
+
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+
+	return driver.ErrSkip
+}
+
+func (c *tracedConn) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	statement := SanitizeSQL(query)
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+
+	return Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", c.system),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", statement),
+		))
+}
+
+// end termina el span de una consulta. Si el contexto no tenía traza el span es el del contexto (uno vacío), y terminarlo no tiene efecto
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runners-postgresql/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// nombre con el que se identifican los spans creados por la aplicación
+const instrumentationName = "runners-postgresql"
+
+// Init configura la propagación del contexto de traza con la cabecera traceparent de W3C y la exportación de los spans. Devuelve la función que exporta los spans pendientes al parar la aplicación
+func Init(config config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exporter sdktrace.SpanExporter
+	var closeExporter func() error
+	switch config.Exporter {
+	case "none":
+		// sin exportador los spans no se registran, pero se sigue propagando la traza que llega en traceparent y su identificador aparece en los logs
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		otlpExporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(config.Endpoint))
+		if err != nil {
+			return nil, fmt.Errorf("error while creating OTLP exporter: %w", err)
+		}
+		exporter = otlpExporter
+	case "file":
+		// un objeto JSON por span, pensado para pruebas y desarrollo
+		file, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("error while opening traces file: %w", err)
+		}
+		fileExporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error while creating file exporter: %w", err)
+		}
+		exporter = fileExporter
+		closeExporter = file.Close
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", config.Exporter)
+	}
+
+	provider := NewProvider(exporter, config)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeExporter != nil {
+			err = errors.Join(err, closeExporter())
+		}
+		return err
+	}, nil
+}
+
+// NewProvider crea el proveedor de spans con el muestreo de la configuración. Si la petición ya viene muestreada (o no) se respeta la decisión del llamante
+func NewProvider(exporter sdktrace.SpanExporter, config config.TracingConfig) *sdktrace.TracerProvider {
+	serviceResource, _ := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", config.ServiceName)))
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(serviceResource),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+}
+
+// Start crea un span hijo del que haya en el contexto: ctx, span := tracing.Start(ctx, "ResultsService.CreateResult"); defer span.End()
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, options...)
+}
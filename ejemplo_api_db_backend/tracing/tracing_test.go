@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runners-postgresql/config"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM runners WHERE id = $1":                          "SELECT * FROM runners WHERE id = $1",
+		"SELECT *\n\tFROM runners\n\tWHERE country = 'Spain' LIMIT 10": "SELECT * FROM runners WHERE country = ? LIMIT ?",
+		"UPDATE runners SET first_name = 'O''Brien' WHERE id = $12":    "UPDATE runners SET first_name = ? WHERE id = $12",
+		"SELECT t1.id FROM results t1 WHERE race_result > 3.5":         "SELECT t1.id FROM results t1 WHERE race_result > ?",
+	}
+
+	for query, expected := range tests {
+		assert.Equal(t, expected, SanitizeSQL(query), query)
+	}
+}
+
+func TestMiddlewareContinuesTraceparent(t *testing.T) {
+	exporter := setTestProvider(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/runner/:id", func(ctx *gin.Context) {
+		_, span := Start(ctx.Request.Context(), "RunnersService.GetRunner")
+		span.End()
+		ctx.Status(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/runner/1", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", recorder.Header().Get(TraceIDHeader))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	service, server := spans[0], spans[1]
+	assert.Equal(t, "GET /runner/:id", server.Name)
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.True(t, server.Parent.IsRemote())
+	assert.Contains(t, server.Attributes, attribute.Int("http.response.status_code", http.StatusOK))
+	assert.Equal(t, server.SpanContext.SpanID(), service.Parent.SpanID())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", service.SpanContext.TraceID().String())
+}
+
+func TestOpenDBTracesQueriesInTrace(t *testing.T) {
+	exporter := setTestProvider(t)
+	_, mock, err := sqlmock.NewWithDSN("tracing-test")
+	require.NoError(t, err)
+	db, err := OpenDB("sqlmock", "tracing-test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM results").WithArgs("1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM results").WithArgs("2").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// sin traza en el contexto la consulta no crea ningún span
+	_, err = db.ExecContext(context.Background(), "DELETE FROM results WHERE id = $1", "1")
+	require.NoError(t, err)
+	assert.Empty(t, exporter.GetSpans())
+
+	ctx, parent := Start(context.Background(), "ResultsService.DeleteResult")
+	_, err = db.ExecContext(ctx, "DELETE FROM results\n\tWHERE id = $1 AND race_result > 'xx'", "2")
+	require.NoError(t, err)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "DELETE", spans[0].Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	assert.Contains(t, spans[0].Attributes, attribute.String("db.query.text", "DELETE FROM results WHERE id = $1 AND race_result > ?"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInitFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Init(config.TracingConfig{Exporter: "file", File: file, SampleRatio: 1, ServiceName: "runners"})
+	require.NoError(t, err)
+	t.Cleanup(func() { otel.SetTracerProvider(sdktrace.NewTracerProvider()) })
+
+	_, span := Start(context.Background(), "ResultsService.CreateResult")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"ResultsService.CreateResult"`)
+	assert.Contains(t, string(content), span.SpanContext().TraceID().String())
+}
+
+func setTestProvider(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	return exporter
+}
@@ -0,0 +1,152 @@
+package versioning
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"runners-postgresql/config"
+	"runners-postgresql/metrics"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Versiones de la API REST. Cada una tiene su grupo de rutas: /v1/runner, /v2/runner
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+var Versions = []string{V1, V2}
+
+// Cómo se ha elegido la versión de la petición
+const (
+	NegotiatedByPath    = "path"    // la ruta empieza por la versión
+	NegotiatedByAccept  = "accept"  // la cabecera Accept pide la versión
+	NegotiatedByDefault = "default" // la petición no indica versión y se usa la de la configuración
+)
+
+// media type con la versión: application/vnd.runners.v2+json
+const vendorMediaType = "application/vnd.runners."
+
+type negotiationKey struct{}
+
+// Supported indica si la versión existe
+func Supported(version string) bool {
+	for _, supported := range Versions {
+		if version == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FromPath devuelve la versión por la que empieza la ruta ("/v2/runner" es v2), o "" si no empieza por ninguna
+func FromPath(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if Supported(segment) {
+		return segment
+	}
+
+	return ""
+}
+
+// Unversioned quita la versión del principio de la ruta o de la plantilla de Gin: "/v1/runner/:id" es "/runner/:id"
+func Unversioned(path string) string {
+	version := FromPath(path)
+	if version == "" {
+		return path
+	}
+
+	return strings.TrimPrefix(path, "/"+version)
+}
+
+// FromAccept devuelve la versión que pide la cabecera Accept, con el media type de la versión (application/vnd.runners.v2+json) o con el parámetro version (application/json; version=2). Devuelve "" si no pide ninguna
+func FromAccept(accept string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		if strings.HasPrefix(mediaType, vendorMediaType) && strings.HasSuffix(mediaType, "+json") {
+			return strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorMediaType), "+json")
+		}
+
+		if mediaType == "application/json" && params["version"] != "" {
+			return "v" + strings.TrimPrefix(params["version"], "v")
+		}
+	}
+
+	return ""
+}
+
+// Handler lleva las peticiones sin versión en la ruta a la versión que pide su cabecera Accept, o a defaultVersion si no pide ninguna, añadiendo la versión al principio de la ruta antes de que Gin busque la ruta.
+// Las rutas de unversioned (las sondas, la especificación, GraphQL) no tienen versión. Si la cabecera Accept pide una versión que no existe responde 406
+func Handler(handler http.Handler, defaultVersion string, unversioned ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if FromPath(r.URL.Path) != "" || isUnversioned(r.URL.Path, unversioned) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// la respuesta depende de la cabecera Accept, así que las cachés no pueden compartirla entre versiones
+		w.Header().Add("Vary", "Accept")
+
+		negotiation := NegotiatedByAccept
+		version := FromAccept(r.Header.Get("Accept"))
+		if version == "" {
+			negotiation = NegotiatedByDefault
+			version = defaultVersion
+		}
+
+		if !Supported(version) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusNotAcceptable)
+			w.Write([]byte(`{"message":"Unsupported API version"}`))
+			return
+		}
+
+		r.URL.Path = "/" + version + r.URL.Path
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = "/" + version + r.URL.RawPath
+		}
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), negotiationKey{}, negotiation)))
+	})
+}
+
+// Middleware se usa en el grupo de rutas de cada versión. Cuenta las peticiones por versión, y si la versión es la obsoleta de la configuración añade las cabeceras Deprecation (RFC 9745) y Sunset (RFC 8594)
+func Middleware(version string, versioningConfig config.VersioningConfig) gin.HandlerFunc {
+	deprecated := versioningConfig.Deprecated == version
+	deprecatedSince, sunset := versioningConfig.DeprecationDates()
+
+	return func(ctx *gin.Context) {
+		negotiation, found := ctx.Request.Context().Value(negotiationKey{}).(string)
+		if !found {
+			negotiation = NegotiatedByPath
+		}
+		metrics.APIVersionRequests.WithLabelValues(version, negotiation).Inc()
+
+		if deprecated {
+			ctx.Header("Deprecation", "@"+strconv.FormatInt(deprecatedSince.Unix(), 10))
+			if !sunset.IsZero() {
+				ctx.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			ctx.Header("Link", `</docs/>; rel="deprecation"; type="text/html"`)
+		}
+
+		ctx.Next()
+	}
+}
+
+func isUnversioned(path string, unversioned []string) bool {
+	for _, prefix := range unversioned {
+		if path == prefix || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
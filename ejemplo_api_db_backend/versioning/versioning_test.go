@@ -0,0 +1,68 @@
+package versioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runners-postgresql/config"
+	"runners-postgresql/metrics"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerNegotiatesVersion(t *testing.T) {
+	versioningConfig := config.VersioningConfig{Default: V1, Deprecated: V1, DeprecatedSince: "2026-10-19", Sunset: "2027-04-30"}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	for _, version := range Versions {
+		group := router.Group("/"+version, Middleware(version, versioningConfig))
+		group.GET("/runner", func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, ctx.FullPath())
+		})
+	}
+	router.GET("/healthz", func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	handler := Handler(router, versioningConfig.Default, "/healthz")
+
+	serve := func(path string, accept string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set("Accept", accept)
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// sin versión se usa la de la configuración, que está obsoleta
+	recorder := serve("/runner", "application/json")
+	assert.Equal(t, "/v1/runner", recorder.Body.String())
+	assert.Equal(t, "@1792368000", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", recorder.Header().Get("Sunset"))
+	assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+
+	// la cabecera Accept elige la versión con el media type o con el parámetro version
+	recorder = serve("/runner", "application/vnd.runners.v2+json")
+	assert.Equal(t, "/v2/runner", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("Deprecation"))
+	assert.Equal(t, "/v2/runner", serve("/runner", "text/html, application/json; version=2").Body.String())
+
+	// la versión de la ruta tiene preferencia sobre la cabecera
+	assert.Equal(t, "/v1/runner", serve("/v1/runner", "application/vnd.runners.v2+json").Body.String())
+
+	assert.Equal(t, http.StatusNotAcceptable, serve("/runner", "application/vnd.runners.v3+json").Code)
+	assert.Equal(t, http.StatusNoContent, serve("/healthz", "application/vnd.runners.v3+json").Code)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.APIVersionRequests.WithLabelValues(V2, NegotiatedByAccept)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.APIVersionRequests.WithLabelValues(V1, NegotiatedByDefault)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.APIVersionRequests.WithLabelValues(V1, NegotiatedByPath)))
+}
+
+func TestUnversioned(t *testing.T) {
+	assert.Equal(t, "/runner/:id", Unversioned("/v1/runner/:id"))
+	assert.Equal(t, "/live/results", Unversioned("/v2/live/results"))
+	assert.Equal(t, "/healthz", Unversioned("/healthz"))
+	assert.Equal(t, "/v3/runner", Unversioned("/v3/runner"))
+}